@@ -1,34 +1,294 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
 	"log"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+	"wallet-service/internal/audit"
 	"wallet-service/internal/config"
 	"wallet-service/internal/db"
+	"wallet-service/internal/partition"
+	"wallet-service/internal/ratelimit"
+	"wallet-service/internal/retry"
 	"wallet-service/internal/wallet/handler"
 	"wallet-service/internal/wallet/handler/middleware"
+	"wallet-service/internal/wallet/model"
 	"wallet-service/internal/wallet/repository"
 	"wallet-service/internal/wallet/service"
+	"wallet-service/migrations"
 )
 
 func main() {
-	cfg := config.LoadConfig()
-	gormDb := db.NewPostgres(cfg.DBUrl)
+	cfg, err := config.Load(os.Args[1:])
+	if err != nil {
+		log.Fatalf("invalid configuration: %v", err)
+	}
+	setupLogger(cfg.Log)
+	log.Printf("Loaded configuration:\n%s", cfg)
 
 	var (
-		walletRepo    repository.WalletRepository = repository.NewWalletRepository(gormDb)
-		walletService *service.WalletService      = service.NewWalletService(walletRepo)
-		walletHandler *handler.WalletHandler      = handler.NewWalletHandler(walletService)
+		gormDb       *gorm.DB
+		walletRepo   repository.WalletRepository
+		scheduleRepo repository.ScheduleRepository
+		auditRepo    repository.AuditRepository
+		interestRepo repository.InterestRepository
+		feeRepo      repository.FeeRepository
+		rateRepo     repository.RateRepository
+		eventRepo    repository.EventRepository
+		customerRepo repository.CustomerRepository
 	)
+	switch cfg.Storage {
+	case "memory":
+		log.Println("Using in-memory storage, data will be lost on restart")
+		memWallets := repository.NewMemoryWalletRepository()
+		for _, w := range seedWallets() {
+			if err := memWallets.SeedWallet(w); err != nil {
+				log.Fatalf("seeding wallet %s: %v", w.ID, err)
+			}
+		}
+		memSchedules := repository.NewMemoryScheduleRepository()
+		memSchedules.SetAuditLog(memWallets.AuditLog())
+		memInterest := repository.NewMemoryInterestRepository()
+		memInterest.SetAuditLog(memWallets.AuditLog())
+		memWallets.SetInterestRepository(memInterest)
+		memFees := repository.NewMemoryFeeRepository()
+		memFees.SetAuditLog(memWallets.AuditLog())
+		memRates := repository.NewMemoryRateRepository()
+		memRates.SetAuditLog(memWallets.AuditLog())
+		memCustomers := repository.NewMemoryCustomerRepository()
+		memCustomers.SetAuditLog(memWallets.AuditLog())
+		walletRepo, scheduleRepo, auditRepo = memWallets, memSchedules, memWallets.AuditLog()
+		interestRepo, feeRepo, rateRepo = memInterest, memFees, memRates
+		eventRepo, customerRepo = memWallets.EventLog(), memCustomers
+	default:
+		gormDb = db.NewPostgres(cfg.DB)
+		if cfg.DB.Migrate {
+			if err := migrate(gormDb); err != nil {
+				log.Fatalf("migrating the database: %v", err)
+			}
+		}
+		walletRepo = repository.NewWalletRepository(gormDb)
+		if len(cfg.DB.ReplicaURLs) > 0 {
+			walletRepo = repository.NewReplicatedWalletRepository(gormDb, newReplicas(cfg.DB, gormDb))
+		}
+		scheduleRepo = repository.NewScheduleRepository(gormDb)
+		auditRepo = repository.NewAuditRepository(gormDb)
+		interestRepo = repository.NewInterestRepository(gormDb)
+		feeRepo = repository.NewFeeRepository(gormDb)
+		rateRepo = repository.NewRateRepository(gormDb)
+		eventRepo = repository.NewEventRepository(gormDb)
+		customerRepo = repository.NewCustomerRepository(gormDb)
+	}
+
+	svcOpts := []service.Option{
+		service.WithConcurrencyMode(service.ConcurrencyMode(cfg.Concurrency)),
+		service.WithRetryPolicy(retry.Policy{
+			MaxAttempts: cfg.Retry.MaxAttempts,
+			BaseDelay:   cfg.Retry.BaseDelay,
+			MaxDelay:    cfg.Retry.MaxDelay,
+			Multiplier:  cfg.Retry.Multiplier,
+			Jitter:      cfg.Retry.Jitter,
+		}),
+	}
+	if cfg.Features.Enabled("wallet_sharding") {
+		svcOpts = append(svcOpts, service.WithSharding())
+	}
+	feeService := service.NewFeeService(feeRepo)
+	if cfg.Fees.RevenueWallet != "" {
+		svcOpts = append(svcOpts, service.WithFees(feeService, uuid.MustParse(cfg.Fees.RevenueWallet)))
+	}
+	if cfg.Coalesce.Enabled {
+		svcOpts = append(svcOpts, service.WithDepositCoalescing(cfg.Coalesce.Window, cfg.Coalesce.MaxBatch))
+	}
+	tenants := middleware.NewTenantResolver(cfg.Tenants.TrustHeader)
+	policies := make(map[string]service.TenantPolicy, len(cfg.Tenants.List))
+	for _, t := range cfg.Tenants.List {
+		tenants.AddTenant(t.ID, t.APIKeys...)
+		policies[t.ID] = service.TenantPolicy{Currencies: t.Currencies, MaxOperationAmount: t.MaxOperationAmount}
+	}
+	svcOpts = append(svcOpts, service.WithTenantPolicies(policies), service.WithCustomers(customerRepo))
+
+	var (
+		walletService   *service.WalletService   = service.NewWalletService(walletRepo, svcOpts...)
+		walletHandler   *handler.WalletHandler   = handler.NewWalletHandler(walletService)
+		scheduleService *service.ScheduleService = service.NewScheduleService(scheduleRepo, walletService,
+			service.WithScheduleRetry(cfg.Schedules.MaxRetries, cfg.Schedules.RetryDelay))
+		scheduleHandler *handler.ScheduleHandler = handler.NewScheduleHandler(scheduleService)
+		interestService *service.InterestService = service.NewInterestService(interestRepo, walletService)
+		fxService       *service.FXService       = service.NewFXService(rateRepo, walletService,
+			service.WithSpread(cfg.FX.Spread), service.WithRounding(service.RoundingMode(cfg.FX.Rounding)))
+		fxHandler       *handler.FXHandler       = handler.NewFXHandler(fxService)
+		eventService    *service.EventService    = service.NewEventService(eventRepo, walletService)
+		eventHandler    *handler.EventHandler    = handler.NewEventHandler(eventService, cfg.Events.Heartbeat)
+		customerHandler *handler.CustomerHandler = handler.NewCustomerHandler(service.NewCustomerService(customerRepo, walletService))
+	)
+
+	if cfg.FX.RatesFile != "" {
+		if err := loadRates(fxService, cfg.FX.RatesFile); err != nil {
+			log.Fatalf("loading exchange rates: %v", err)
+		}
+	}
+
+	go eventService.Run(context.Background())
+	if cfg.Schedules.Enabled {
+		worker := service.NewScheduleWorker(scheduleService, cfg.Schedules.PollInterval, cfg.Schedules.BatchSize)
+		go worker.Run(context.Background())
+	}
+	if cfg.Snapshots.Enabled {
+		worker := service.NewSnapshotWorker(walletService, cfg.Snapshots.Interval, cfg.Snapshots.Lag)
+		go worker.Run(context.Background())
+	}
+	if cfg.Interest.Enabled {
+		worker := service.NewInterestWorker(interestService, cfg.Interest.PollInterval, cfg.Interest.Lag)
+		go worker.Run(context.Background())
+	}
+	if cfg.Partitions.Enabled && gormDb != nil {
+		manager := partition.NewManager(gormDb, cfg.Partitions.MonthsAhead, cfg.Partitions.RetentionMonths, cfg.Partitions.ArchiveDir, cfg.Partitions.DropArchived)
+		go partition.NewWorker(manager, cfg.Partitions.Interval).Run(context.Background())
+	}
 
 	app := fiber.New(fiber.Config{
 		ErrorHandler: middleware.ErrorHandler,
+		ReadTimeout:  cfg.HTTP.ReadTimeout,
+		WriteTimeout: cfg.HTTP.WriteTimeout,
+		IdleTimeout:  cfg.HTTP.IdleTimeout,
+		BodyLimit:    cfg.HTTP.BodyLimit,
 	})
 
-	api := app.Group("/api/v1")
-	api.Get("/wallets/:wallet_uuid", walletHandler.GetWalletBalance)
-	api.Post("/wallet", walletHandler.UpdateWalletBalance)
+	app.Use(middleware.AuditContext())
+	app.Use(middleware.ReadYourWrites())
+
+	docsHandler, err := handler.NewDocsHandler()
+	if err != nil {
+		log.Fatalf("loading the OpenAPI document: %v", err)
+	}
+	handlers := handler.Handlers{
+		Wallet:   walletHandler,
+		Schedule: scheduleHandler,
+		FX:       fxHandler,
+		Events:   eventHandler,
+		Customer: customerHandler,
+		Docs:     docsHandler,
+	}
+	routes := handler.RouteConfig{Tenant: tenants.Client(), AdminTenant: tenants.Admin()}
+	if cfg.RateLimit.Enabled {
+		limiter := newRateLimiter(cfg.RateLimit, gormDb)
+		for _, t := range cfg.Tenants.List {
+			limiter.AddClient(t.APIKeys...)
+		}
+		routes.ClientLimit, routes.WalletLimit = limiter.PerClient(), limiter.PerWallet()
+	}
+	if cfg.Admin.Token != "" {
+		handlers.Admin = handler.NewAdminHandler(walletService)
+		handlers.Audit = handler.NewAuditHandler(service.NewAuditService(auditRepo))
+		handlers.Interest = handler.NewInterestHandler(interestService)
+		handlers.Fee = handler.NewFeeHandler(feeService)
+		routes.AdminAuth = middleware.AdminAuth(cfg.Admin.Token)
+	}
+	handler.RegisterRoutes(app, handlers, routes)
+
+	addr := ":" + cfg.HTTP.Port
+	if cfg.TLS.Enabled {
+		log.Printf("Starting server with TLS on port %s", cfg.HTTP.Port)
+		log.Fatal(app.ListenTLS(addr, cfg.TLS.CertFile, cfg.TLS.KeyFile))
+	}
+	log.Printf("Starting server on port %s", cfg.HTTP.Port)
+	log.Fatal(app.Listen(addr))
+}
+
+// seedWallets mirrors migrations/002_seed_wallets.sql for in-memory runs.
+func seedWallets() []model.Wallet {
+	return []model.Wallet{
+		{ID: uuid.MustParse("11111111-1111-1111-1111-111111111111"), Balance: 0},
+		{ID: uuid.MustParse("22222222-2222-2222-2222-222222222222"), Balance: 10000},
+	}
+}
+
+// migrate applies the pending migrations embedded from migrations/.
+func migrate(gormDb *gorm.DB) error {
+	sqlDB, err := gormDb.DB()
+	if err != nil {
+		return err
+	}
+	applied, err := db.Migrate(context.Background(), sqlDB, migrations.FS)
+	for _, name := range applied {
+		log.Printf("Applied migration %s", name)
+	}
+	return err
+}
+
+// newReplicas opens the read replicas and keeps checking their health.
+// Reads go to the primary until a replica passes its first check.
+func newReplicas(cfg config.DBConfig, primary *gorm.DB) *db.Replicas {
+	reads := db.NewReplicas(primary, cfg.ReplicaMaxLag)
+	for i, url := range cfg.ReplicaURLs {
+		replica, err := db.NewReplica(cfg, url)
+		if err != nil {
+			log.Fatalf("opening read replica %d: %v", i+1, err)
+		}
+		reads.Add(fmt.Sprintf("replica-%d", i+1), replica)
+	}
+	go reads.Run(context.Background(), cfg.ReplicaCheckInterval)
+	return reads
+}
+
+// loadRates stores the exchange rates of the CSV file at path.
+func loadRates(svc *service.FXService, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	ctx := audit.NewContext(context.Background(), audit.Info{Actor: "rates-file", RequestID: path})
+	n, err := svc.LoadRates(ctx, f)
+	if err != nil {
+		return err
+	}
+	log.Printf("Loaded %d exchange rates from %s", n, path)
+	return nil
+}
+
+func newRateLimiter(cfg config.RateLimitConfig, gormDb *gorm.DB) *middleware.RateLimiter {
+	var store ratelimit.Store
+	switch cfg.Backend {
+	case "postgres":
+		store = ratelimit.NewPostgresStore(gormDb)
+	default:
+		store = ratelimit.NewMemoryStore()
+	}
+
+	go func() {
+		for range time.Tick(time.Minute) {
+			if err := store.Sweep(context.Background(), 10*time.Minute); err != nil {
+				log.Printf("rate limiter sweep failed: %v", err)
+			}
+		}
+	}()
+
+	return middleware.NewRateLimiter(store,
+		ratelimit.Limit{Rate: cfg.ClientRate, Burst: cfg.ClientBurst},
+		ratelimit.Limit{Rate: cfg.WalletRate, Burst: cfg.WalletBurst},
+	)
+}
+
+func setupLogger(cfg config.LogConfig) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(strings.ToLower(cfg.Level))); err != nil {
+		level = slog.LevelInfo
+	}
 
-	log.Printf("Starting server on port %s", cfg.Port)
-	log.Fatal(app.Listen(":" + cfg.Port))
+	opts := &slog.HandlerOptions{Level: level}
+	var h slog.Handler = slog.NewTextHandler(os.Stderr, opts)
+	if cfg.Format == "json" {
+		h = slog.NewJSONHandler(os.Stderr, opts)
+	}
+	slog.SetDefault(slog.New(h))
 }
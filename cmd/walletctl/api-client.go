@@ -0,0 +1,310 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"wallet-service/internal/dto"
+	"wallet-service/internal/wallet/model"
+	"wallet-service/internal/wallet/repository"
+	"wallet-service/internal/wallet/service"
+)
+
+// apiClient implements backend against the server's admin API.
+type apiClient struct {
+	base  string
+	token string
+	actor string
+	http  *http.Client
+}
+
+func newAPIClient(baseURL, token, actor string) *apiClient {
+	return &apiClient{
+		base:  strings.TrimSuffix(baseURL, "/") + "/api/v1/admin",
+		token: token,
+		actor: actor,
+		http:  &http.Client{Timeout: 5 * time.Minute},
+	}
+}
+
+func (c *apiClient) CreateWallet(ctx context.Context, req dto.CreateWalletRequest) (*model.Wallet, error) {
+	var resp dto.WalletDetailsResponse
+	if err := c.do(ctx, http.MethodPost, "/wallets", nil, req, &resp); err != nil {
+		return nil, err
+	}
+	return walletFromResponse(resp), nil
+}
+
+func (c *apiClient) LookupWallet(ctx context.Context, id uuid.UUID) (*model.Wallet, error) {
+	return c.wallet(ctx, http.MethodGet, "/wallets/"+id.String())
+}
+
+func (c *apiClient) FreezeWallet(ctx context.Context, id uuid.UUID) (*model.Wallet, error) {
+	return c.wallet(ctx, http.MethodPost, "/wallets/"+id.String()+"/freeze")
+}
+
+func (c *apiClient) UnfreezeWallet(ctx context.Context, id uuid.UUID) (*model.Wallet, error) {
+	return c.wallet(ctx, http.MethodPost, "/wallets/"+id.String()+"/unfreeze")
+}
+
+func (c *apiClient) CloseWallet(ctx context.Context, id uuid.UUID) (*model.Wallet, error) {
+	return c.wallet(ctx, http.MethodPost, "/wallets/"+id.String()+"/close")
+}
+
+func (c *apiClient) SetCreditLimit(ctx context.Context, id uuid.UUID, limit float64) (*model.Wallet, error) {
+	var resp dto.WalletDetailsResponse
+	req := dto.SetCreditLimitRequest{CreditLimit: &limit}
+	if err := c.do(ctx, http.MethodPut, "/wallets/"+id.String()+"/credit-limit", nil, req, &resp); err != nil {
+		return nil, err
+	}
+	return walletFromResponse(resp), nil
+}
+
+func (c *apiClient) wallet(ctx context.Context, method, path string) (*model.Wallet, error) {
+	var resp dto.WalletDetailsResponse
+	if err := c.do(ctx, method, path, nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return walletFromResponse(resp), nil
+}
+
+func (c *apiClient) ListOperations(ctx context.Context, id uuid.UUID, filter repository.OperationFilter) ([]model.Operation, error) {
+	query := periodValues(filter.From, filter.To)
+	query.Set("limit", strconv.Itoa(filter.Limit))
+	if filter.ExternalRef != "" {
+		query.Set("externalRef", filter.ExternalRef)
+	}
+	if filter.Description != "" {
+		query.Set("description", filter.Description)
+	}
+	for k, v := range filter.Metadata {
+		query.Set("metadata."+k, v)
+	}
+
+	var resp []dto.WalletOperationResponse
+	if err := c.do(ctx, http.MethodGet, "/wallets/"+id.String()+"/operations", query, nil, &resp); err != nil {
+		return nil, err
+	}
+	ops := make([]model.Operation, len(resp))
+	for i, r := range resp {
+		ops[i] = operationFromResponse(r)
+	}
+	return ops, nil
+}
+
+func (c *apiClient) Adjust(ctx context.Context, req dto.AdjustmentRequest) (*model.Operation, error) {
+	var resp dto.WalletOperationResponse
+	if err := c.do(ctx, http.MethodPost, "/wallets/"+req.WalletID.String()+"/adjustments", nil, req, &resp); err != nil {
+		return nil, err
+	}
+	op := operationFromResponse(resp)
+	return &op, nil
+}
+
+func (c *apiClient) Reconcile(ctx context.Context, id uuid.UUID) (*service.Reconciliation, error) {
+	var resp dto.ReconciliationResponse
+	if err := c.do(ctx, http.MethodGet, "/wallets/"+id.String()+"/reconciliation", nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	rec := reconciliationFromResponse(resp)
+	return &rec, nil
+}
+
+func (c *apiClient) ReconcileAll(ctx context.Context) (int, []service.Reconciliation, error) {
+	var resp dto.ReconciliationReportResponse
+	if err := c.do(ctx, http.MethodGet, "/reconciliation", nil, nil, &resp); err != nil {
+		return 0, nil, err
+	}
+	mismatches := make([]service.Reconciliation, len(resp.Mismatches))
+	for i, r := range resp.Mismatches {
+		mismatches[i] = reconciliationFromResponse(r)
+	}
+	return resp.Checked, mismatches, nil
+}
+
+// CheckInvariants is not offered by the admin API: it reads the whole
+// ledger across tenants.
+func (c *apiClient) CheckInvariants(ctx context.Context, settled time.Time) (*service.InvariantReport, error) {
+	return nil, errors.New("check reads the database directly; run it without --api-url")
+}
+
+func (c *apiClient) Statement(ctx context.Context, id uuid.UUID, from, to time.Time) (*service.Statement, error) {
+	var resp dto.StatementResponse
+	if err := c.do(ctx, http.MethodGet, "/wallets/"+id.String()+"/statement", periodValues(from, to), nil, &resp); err != nil {
+		return nil, err
+	}
+
+	st := &service.Statement{
+		WalletID:       resp.WalletID,
+		From:           from,
+		To:             to,
+		OpeningBalance: resp.OpeningBalance,
+		ClosingBalance: resp.ClosingBalance,
+		Lines:          make([]service.StatementLine, len(resp.Lines)),
+	}
+	for i, l := range resp.Lines {
+		st.Lines[i] = service.StatementLine{
+			Operation: model.Operation{
+				ID:        l.OperationID,
+				WalletID:  resp.WalletID,
+				Type:      l.OperationType,
+				Amount:    l.Amount,
+				CreatedAt: l.CreatedAt,
+			},
+			Balance: l.Balance,
+		}
+	}
+	return st, nil
+}
+
+func (c *apiClient) ListAuditEntries(ctx context.Context, filter repository.AuditFilter) ([]model.AuditEntry, error) {
+	query := periodValues(filter.From, filter.To)
+	query.Set("limit", strconv.Itoa(filter.Limit))
+	if filter.Actor != "" {
+		query.Set("actor", filter.Actor)
+	}
+	if filter.WalletID != uuid.Nil {
+		query.Set("walletId", filter.WalletID.String())
+	}
+
+	var resp []dto.AuditEntryResponse
+	if err := c.do(ctx, http.MethodGet, "/audit", query, nil, &resp); err != nil {
+		return nil, err
+	}
+	entries := make([]model.AuditEntry, len(resp))
+	for i, r := range resp {
+		entries[i] = model.AuditEntry{
+			ID:        r.ID,
+			Actor:     r.Actor,
+			Action:    r.Action,
+			WalletID:  r.WalletID,
+			RequestID: r.RequestID,
+			Before:    r.Before,
+			After:     r.After,
+			CreatedAt: r.CreatedAt,
+		}
+	}
+	return entries, nil
+}
+
+// do sends the request and decodes a successful JSON response into out.
+// Error responses are turned into an error carrying the server's message.
+func (c *apiClient) do(ctx context.Context, method, path string, query url.Values, body, out any) error {
+	u := c.base + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	var reqBody io.Reader
+	if body != nil {
+		buf, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(buf)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u, reqBody)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	if c.actor != "" {
+		req.Header.Set("X-Actor", c.actor)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return responseError(resp)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// responseError turns a problem document into an error: the failing fields
+// of a validation problem, otherwise its detail.
+func responseError(resp *http.Response) error {
+	var problem struct {
+		Detail string            `json:"detail"`
+		Errors map[string]string `json:"errors"`
+	}
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<16))
+	if json.Unmarshal(raw, &problem) != nil || (problem.Detail == "" && len(problem.Errors) == 0) {
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(raw)))
+	}
+	if len(problem.Errors) == 0 {
+		return errors.New(problem.Detail)
+	}
+
+	msgs := make([]string, 0, len(problem.Errors))
+	for _, msg := range problem.Errors {
+		msgs = append(msgs, msg)
+	}
+	sort.Strings(msgs)
+	return errors.New(strings.Join(msgs, "; "))
+}
+
+func periodValues(from, to time.Time) url.Values {
+	query := url.Values{}
+	if !from.IsZero() {
+		query.Set("from", from.Format(time.RFC3339))
+	}
+	if !to.IsZero() {
+		query.Set("to", to.Format(time.RFC3339))
+	}
+	return query
+}
+
+func walletFromResponse(r dto.WalletDetailsResponse) *model.Wallet {
+	// The API reports the total balance only, so it is put on the wallet
+	// row here; ShardCount still tells whether the wallet is sharded.
+	return &model.Wallet{
+		ID:          r.WalletID,
+		Balance:     r.Balance,
+		Status:      model.WalletStatus(r.Status),
+		Product:     r.Product,
+		Currency:    r.Currency,
+		Label:       r.Label,
+		CreditLimit: r.CreditLimit,
+		ShardCount:  r.ShardCount,
+		Version:     r.Version,
+		CreatedAt:   r.CreatedAt,
+	}
+}
+
+func operationFromResponse(r dto.WalletOperationResponse) model.Operation {
+	createdAt, _ := time.Parse(time.RFC3339, r.CreatedAt)
+	return model.Operation{
+		ID:          r.OperationID,
+		WalletID:    r.WalletID,
+		Type:        r.OperationType,
+		Amount:      r.Amount,
+		Description: r.Description,
+		Metadata:    r.Metadata,
+		ExternalRef: r.ExternalRef,
+		CreatedAt:   createdAt,
+	}
+}
+
+func reconciliationFromResponse(r dto.ReconciliationResponse) service.Reconciliation {
+	return service.Reconciliation{WalletID: r.WalletID, Balance: r.Balance, Ledger: r.Ledger}
+}
@@ -0,0 +1,450 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"github.com/google/uuid"
+	"io"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+	"wallet-service/internal/dto"
+	"wallet-service/internal/wallet/model"
+	"wallet-service/internal/wallet/repository"
+	"wallet-service/internal/wallet/service"
+)
+
+func cmdCreate(ctx context.Context, b backend, args []string) error {
+	fs := newFlagSet("create")
+	id := fs.String("id", "", "wallet ID (random if omitted)")
+	product := fs.String("product", "", "product the wallet belongs to")
+	currency := fs.String("currency", model.DefaultCurrency, "ISO 4217 currency of the wallet")
+	customer := fs.String("customer", "", "external ID of the customer owning the wallet")
+	label := fs.String("label", "", "label of the wallet among the customer's wallets")
+	if _, err := parseArgs(fs, args, 0); err != nil {
+		return err
+	}
+
+	if *label != "" && *customer == "" {
+		return fmt.Errorf("--label needs --customer")
+	}
+	req := dto.CreateWalletRequest{Product: *product, Currency: strings.ToUpper(*currency), CustomerID: *customer, Label: *label}
+	if *id != "" {
+		var err error
+		if req.WalletID, err = uuid.Parse(*id); err != nil {
+			return fmt.Errorf("invalid wallet ID %q", *id)
+		}
+	}
+
+	wallet, err := b.CreateWallet(ctx, req)
+	if err != nil {
+		return err
+	}
+	printWallet(wallet)
+	return nil
+}
+
+// cmdWallet runs a command that takes a wallet ID and prints the wallet.
+func cmdWallet(ctx context.Context, name string, args []string, do func(context.Context, uuid.UUID) (*model.Wallet, error)) error {
+	pos, err := parseArgs(newFlagSet(name), args, 1)
+	if err != nil {
+		return err
+	}
+	id, err := parseWalletID(pos[0])
+	if err != nil {
+		return err
+	}
+
+	wallet, err := do(ctx, id)
+	if err != nil {
+		return err
+	}
+	printWallet(wallet)
+	return nil
+}
+
+func cmdCreditLimit(ctx context.Context, b backend, args []string) error {
+	pos, err := parseArgs(newFlagSet("credit-limit"), args, 2)
+	if err != nil {
+		return err
+	}
+	id, err := parseWalletID(pos[0])
+	if err != nil {
+		return err
+	}
+	limit, err := strconv.ParseFloat(pos[1], 64)
+	if err != nil || limit < 0 {
+		return fmt.Errorf("credit limit must be a non-negative number, got %q", pos[1])
+	}
+
+	wallet, err := b.SetCreditLimit(ctx, id, limit)
+	if err != nil {
+		return err
+	}
+	printWallet(wallet)
+	return nil
+}
+
+func cmdOps(ctx context.Context, b backend, args []string) error {
+	fs := newFlagSet("ops")
+	from, to := periodFlags(fs)
+	limit := fs.Int("limit", 100, "maximum operations to list")
+	ref := fs.String("ref", "", "only the operation with this external reference")
+	search := fs.String("search", "", "only operations whose description contains this")
+	meta := metadataFlag{}
+	fs.Var(meta, "meta", "only operations with this metadata, as key=value; repeatable")
+	pos, err := parseArgs(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	id, err := parseWalletID(pos[0])
+	if err != nil {
+		return err
+	}
+
+	filter := repository.OperationFilter{From: from.t, To: to.t, Limit: *limit, ExternalRef: *ref, Description: *search}
+	if len(meta) > 0 {
+		filter.Metadata = meta
+	}
+	ops, err := b.ListOperations(ctx, id, filter)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CREATED\tOPERATION\tTYPE\tAMOUNT\tREF\tDESCRIPTION")
+	for _, op := range ops {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%.2f\t%s\t%s\n", op.CreatedAt.UTC().Format(time.RFC3339), op.ID, op.Type, op.SignedAmount(),
+			orDash([]byte(op.ExternalRef)), op.Description)
+	}
+	return w.Flush()
+}
+
+func cmdAdjust(ctx context.Context, b backend, args []string) error {
+	fs := newFlagSet("adjust")
+	reason := fs.String("reason", "", "why the adjustment is made (required)")
+	actor := fs.String("actor", currentUser(), "who makes the adjustment")
+	pos, err := parseArgs(fs, args, 3)
+	if err != nil {
+		return err
+	}
+	id, err := parseWalletID(pos[0])
+	if err != nil {
+		return err
+	}
+	amount, err := strconv.ParseFloat(pos[2], 64)
+	if err != nil || amount <= 0 {
+		return fmt.Errorf("amount must be a positive number, got %q", pos[2])
+	}
+	switch pos[1] {
+	case "credit":
+	case "debit":
+		amount = -amount
+	default:
+		return fmt.Errorf("direction must be credit or debit, got %q", pos[1])
+	}
+	if *reason == "" || *actor == "" {
+		return fmt.Errorf("adjust needs --reason and --actor")
+	}
+
+	op, err := b.Adjust(ctx, dto.AdjustmentRequest{WalletID: id, Amount: amount, Reason: *reason, Actor: *actor})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stdout, "%s %s %.2f on wallet %s\n", op.ID, op.Type, op.Amount, op.WalletID)
+	return nil
+}
+
+func cmdReconcile(ctx context.Context, b backend, args []string) error {
+	fs := newFlagSet("reconcile")
+	all := fs.Bool("all", false, "reconcile every wallet")
+	pos, err := parseArgs(fs, args, -1)
+	if err != nil {
+		return err
+	}
+	if *all != (len(pos) == 0) {
+		return fmt.Errorf("reconcile takes either a wallet ID or --all")
+	}
+
+	var (
+		checked    = 1
+		mismatches []service.Reconciliation
+	)
+	if *all {
+		checked, mismatches, err = b.ReconcileAll(ctx)
+		if err != nil {
+			return err
+		}
+	} else {
+		id, err := parseWalletID(pos[0])
+		if err != nil {
+			return err
+		}
+		rec, err := b.Reconcile(ctx, id)
+		if err != nil {
+			return err
+		}
+		if !rec.Balanced() {
+			mismatches = append(mismatches, *rec)
+		}
+	}
+
+	fmt.Fprintf(os.Stdout, "%d wallet(s) checked, %d mismatch(es)\n", checked, len(mismatches))
+	if len(mismatches) == 0 {
+		return nil
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "WALLET\tBALANCE\tLEDGER\tDIFFERENCE")
+	for _, rec := range mismatches {
+		fmt.Fprintf(w, "%s\t%.2f\t%.2f\t%.2f\n", rec.WalletID, rec.Balance, rec.Ledger, rec.Difference())
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return errMismatch
+}
+
+// cmdCheck reports every violation of the ledger's invariants and exits
+// non-zero if there is any; it is meant to run nightly.
+func cmdCheck(ctx context.Context, b backend, args []string) error {
+	fs := newFlagSet("check")
+	settle := fs.Duration("settle", time.Minute, "leave out operations younger than this")
+	if _, err := parseArgs(fs, args, 0); err != nil {
+		return err
+	}
+
+	report, err := b.CheckInvariants(ctx, time.Now().Add(-*settle))
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stdout, "%d wallet(s) and %d operation(s) checked, %d violation(s)\n",
+		report.Wallets, report.Operations, len(report.Violations))
+	if report.OK() {
+		return nil
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "INVARIANT\tWALLET\tOPERATION\tDETAIL")
+	for _, v := range report.Violations {
+		op := "-"
+		if v.OperationID != uuid.Nil {
+			op = v.OperationID.String()
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", v.Invariant, v.WalletID, op, v.Detail)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return errMismatch
+}
+
+func cmdStatement(ctx context.Context, b backend, args []string) (err error) {
+	fs := newFlagSet("statement")
+	from, to := periodFlags(fs)
+	out := fs.String("out", "", "write the CSV to this file instead of stdout")
+	pos, err := parseArgs(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	id, err := parseWalletID(pos[0])
+	if err != nil {
+		return err
+	}
+
+	st, err := b.Statement(ctx, id, from.t, to.t)
+	if err != nil {
+		return err
+	}
+
+	var dst io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+		}()
+		dst = f
+	}
+	return writeStatement(dst, st)
+}
+
+// writeStatement renders st as CSV: an opening balance row, one row per
+// operation with the running balance, and a closing balance row.
+func writeStatement(dst io.Writer, st *service.Statement) error {
+	w := csv.NewWriter(dst)
+	_ = w.Write([]string{"date", "operation_id", "type", "amount", "balance"})
+	_ = w.Write([]string{formatTime(st.From), "", "OPENING_BALANCE", "", formatAmount(st.OpeningBalance)})
+	for _, line := range st.Lines {
+		_ = w.Write([]string{
+			formatTime(line.Operation.CreatedAt),
+			line.Operation.ID.String(),
+			line.Operation.Type,
+			formatAmount(line.Operation.SignedAmount()),
+			formatAmount(line.Balance),
+		})
+	}
+	_ = w.Write([]string{formatTime(st.To), "", "CLOSING_BALANCE", "", formatAmount(st.ClosingBalance)})
+	w.Flush()
+	return w.Error()
+}
+
+func cmdAudit(ctx context.Context, b backend, args []string) error {
+	fs := newFlagSet("audit")
+	from, to := periodFlags(fs)
+	actor := fs.String("actor", "", "only entries by this actor")
+	wallet := fs.String("wallet", "", "only entries about this wallet")
+	limit := fs.Int("limit", 100, "maximum entries to show")
+	if _, err := parseArgs(fs, args, 0); err != nil {
+		return err
+	}
+
+	filter := repository.AuditFilter{Actor: *actor, From: from.t, To: to.t, Limit: *limit}
+	if *wallet != "" {
+		id, err := parseWalletID(*wallet)
+		if err != nil {
+			return err
+		}
+		filter.WalletID = id
+	}
+
+	entries, err := b.ListAuditEntries(ctx, filter)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TIME\tACTOR\tACTION\tWALLET\tREQUEST\tBEFORE\tAFTER")
+	for _, e := range entries {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			formatTime(e.CreatedAt), e.Actor, e.Action, e.WalletID, e.RequestID, orDash(e.Before), orDash(e.After))
+	}
+	return w.Flush()
+}
+
+func orDash(raw []byte) string {
+	if len(raw) == 0 {
+		return "-"
+	}
+	return string(raw)
+}
+
+func printWallet(wallet *model.Wallet) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "ID\t%s\n", wallet.ID)
+	fmt.Fprintf(w, "Status\t%s\n", wallet.Status)
+	if wallet.Product != "" {
+		fmt.Fprintf(w, "Product\t%s\n", wallet.Product)
+	}
+	if wallet.Label != "" {
+		fmt.Fprintf(w, "Label\t%s\n", wallet.Label)
+	}
+	fmt.Fprintf(w, "Balance\t%.2f %s\n", wallet.TotalBalance(), wallet.Currency)
+	if wallet.CreditLimit > 0 {
+		fmt.Fprintf(w, "Credit limit\t%.2f %s (%.2f available)\n", wallet.CreditLimit, wallet.Currency, wallet.AvailableCredit())
+	}
+	if wallet.Sharded() {
+		fmt.Fprintf(w, "Shards\t%d\n", wallet.ShardCount)
+	}
+	fmt.Fprintf(w, "Version\t%d\n", wallet.Version)
+	fmt.Fprintf(w, "Created\t%s\n", wallet.CreatedAt.UTC().Format(time.RFC3339))
+	_ = w.Flush()
+}
+
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	return fs
+}
+
+// parseArgs parses flags that may appear before, between or after the
+// positional arguments, of which exactly want are expected unless want is
+// negative.
+func parseArgs(fs *flag.FlagSet, args []string, want int) ([]string, error) {
+	var pos []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, fmt.Errorf("%s: %w", fs.Name(), err)
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			break
+		}
+		pos = append(pos, args[0])
+		args = args[1:]
+	}
+	if want >= 0 && len(pos) != want {
+		return nil, fmt.Errorf("%s: expected %d argument(s), got %d\n\n%s", fs.Name(), want, len(pos), usage)
+	}
+	return pos, nil
+}
+
+func parseWalletID(s string) (uuid.UUID, error) {
+	id, err := uuid.Parse(s)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("invalid wallet ID %q", s)
+	}
+	return id, nil
+}
+
+// metadataFlag collects key=value pairs.
+type metadataFlag map[string]string
+
+func (f metadataFlag) String() string { return "" }
+
+func (f metadataFlag) Set(v string) error {
+	key, value, ok := strings.Cut(v, "=")
+	if !ok || key == "" {
+		return fmt.Errorf("want key=value, got %q", v)
+	}
+	f[key] = value
+	return nil
+}
+
+// timeFlag accepts an RFC 3339 timestamp or a date, taken as midnight UTC.
+type timeFlag struct{ t time.Time }
+
+func (f *timeFlag) String() string { return formatTime(f.t) }
+
+func (f *timeFlag) Set(v string) error {
+	for _, layout := range []string{time.RFC3339, time.DateOnly} {
+		if t, err := time.Parse(layout, v); err == nil {
+			f.t = t
+			return nil
+		}
+	}
+	return fmt.Errorf("want an RFC 3339 timestamp or YYYY-MM-DD, got %q", v)
+}
+
+func periodFlags(fs *flag.FlagSet) (from, to *timeFlag) {
+	from, to = &timeFlag{}, &timeFlag{}
+	fs.Var(from, "from", "start of the period (inclusive)")
+	fs.Var(to, "to", "end of the period (exclusive)")
+	return from, to
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func formatAmount(v float64) string {
+	return strconv.FormatFloat(v, 'f', 2, 64)
+}
+
+func currentUser() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
@@ -0,0 +1,168 @@
+// Command walletctl administers wallets: it creates, inspects, freezes and
+// closes them, sets their credit limits, lists their operations, makes
+// manual adjustments, runs reconciliation, checks the ledger's invariants
+// and exports statements.
+//
+// By default it talks to the database configured the same way as the
+// server (config.env, CONFIG_FILE, DB_URL, ...). With --api-url it uses
+// the server's admin API instead.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/google/uuid"
+	"os"
+	"os/signal"
+	"time"
+	"wallet-service/internal/audit"
+	"wallet-service/internal/config"
+	"wallet-service/internal/db"
+	"wallet-service/internal/dto"
+	"wallet-service/internal/wallet/model"
+	"wallet-service/internal/wallet/repository"
+	"wallet-service/internal/wallet/service"
+)
+
+const usage = `usage: walletctl [--api-url URL --token TOKEN] <command> [flags] [args]
+
+commands:
+  create     [--id UUID] [--product NAME] [--currency CODE]
+             [--customer ID [--label LABEL]]
+                                                 create an empty wallet
+  show       <wallet>                            show a wallet
+  freeze     <wallet>                            stop customer operations
+  unfreeze   <wallet>                            allow customer operations again
+  close      <wallet>                            close a wallet with zero balance
+  credit-limit <wallet> <amount>                 set how far below zero it may go
+  ops        <wallet> [--from T] [--to T] [--limit N] [--ref REF]
+             [--search TEXT] [--meta KEY=VALUE]...
+                                                 list operations, oldest first
+  adjust     <wallet> credit|debit <amount> --reason TEXT [--actor NAME]
+                                                 make a manual adjustment
+  reconcile  <wallet> | --all                    compare balances with operations
+  check      [--settle D]                        check the ledger's invariants
+  statement  <wallet> [--from T] [--to T] [--out FILE]
+                                                 export a statement as CSV
+  audit      [--actor A] [--wallet W] [--from T] [--to T] [--limit N]
+                                                 show the audit log, newest first
+
+Times are RFC 3339 timestamps or dates (YYYY-MM-DD, UTC); --to is exclusive.
+`
+
+// backend is implemented by databaseBackend and by apiClient for the admin
+// API.
+type backend interface {
+	CreateWallet(ctx context.Context, req dto.CreateWalletRequest) (*model.Wallet, error)
+	LookupWallet(ctx context.Context, id uuid.UUID) (*model.Wallet, error)
+	FreezeWallet(ctx context.Context, id uuid.UUID) (*model.Wallet, error)
+	UnfreezeWallet(ctx context.Context, id uuid.UUID) (*model.Wallet, error)
+	CloseWallet(ctx context.Context, id uuid.UUID) (*model.Wallet, error)
+	SetCreditLimit(ctx context.Context, id uuid.UUID, limit float64) (*model.Wallet, error)
+	ListOperations(ctx context.Context, id uuid.UUID, filter repository.OperationFilter) ([]model.Operation, error)
+	Adjust(ctx context.Context, req dto.AdjustmentRequest) (*model.Operation, error)
+	Reconcile(ctx context.Context, id uuid.UUID) (*service.Reconciliation, error)
+	ReconcileAll(ctx context.Context) (int, []service.Reconciliation, error)
+	CheckInvariants(ctx context.Context, settled time.Time) (*service.InvariantReport, error)
+	Statement(ctx context.Context, id uuid.UUID, from, to time.Time) (*service.Statement, error)
+	ListAuditEntries(ctx context.Context, filter repository.AuditFilter) ([]model.AuditEntry, error)
+}
+
+// databaseBackend works on the database directly through the service
+// layer.
+type databaseBackend struct {
+	*service.WalletService
+	*service.AuditService
+}
+
+// errMismatch makes reconcile and check exit non-zero without printing an
+// error.
+var errMismatch = errors.New("reconciliation mismatch")
+
+func main() {
+	fs := flag.NewFlagSet("walletctl", flag.ExitOnError)
+	fs.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	apiURL := fs.String("api-url", os.Getenv("WALLETCTL_API_URL"), "admin API base URL, e.g. http://localhost:8080; talk to the database if empty")
+	token := fs.String("token", os.Getenv("WALLETCTL_TOKEN"), "admin API bearer token")
+	_ = fs.Parse(os.Args[1:])
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	actor := currentUser()
+	var b backend
+	if *apiURL != "" {
+		b = newAPIClient(*apiURL, *token, actor)
+	} else {
+		db, err := newDatabaseBackend()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "walletctl: %v\n", err)
+			os.Exit(1)
+		}
+		b = db
+		ctx = audit.NewContext(ctx, audit.Info{Actor: "walletctl:" + actor, RequestID: uuid.NewString()})
+	}
+
+	err := run(ctx, b, fs.Arg(0), fs.Args()[1:])
+	switch {
+	case err == nil:
+	case errors.Is(err, errMismatch):
+		os.Exit(1)
+	default:
+		fmt.Fprintf(os.Stderr, "walletctl: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func newDatabaseBackend() (*databaseBackend, error) {
+	cfg, err := config.Load(nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+	if cfg.Storage != "postgres" {
+		return nil, fmt.Errorf("storage %q has no shared state to administer; use --api-url", cfg.Storage)
+	}
+	gormDb := db.NewPostgres(cfg.DB)
+	wallets := service.NewWalletService(repository.NewWalletRepository(gormDb),
+		service.WithConcurrencyMode(service.ConcurrencyMode(cfg.Concurrency)),
+		service.WithCustomers(repository.NewCustomerRepository(gormDb)))
+	return &databaseBackend{wallets, service.NewAuditService(repository.NewAuditRepository(gormDb))}, nil
+}
+
+func run(ctx context.Context, b backend, cmd string, args []string) error {
+	switch cmd {
+	case "create":
+		return cmdCreate(ctx, b, args)
+	case "show":
+		return cmdWallet(ctx, cmd, args, b.LookupWallet)
+	case "freeze":
+		return cmdWallet(ctx, cmd, args, b.FreezeWallet)
+	case "unfreeze":
+		return cmdWallet(ctx, cmd, args, b.UnfreezeWallet)
+	case "close":
+		return cmdWallet(ctx, cmd, args, b.CloseWallet)
+	case "credit-limit":
+		return cmdCreditLimit(ctx, b, args)
+	case "ops":
+		return cmdOps(ctx, b, args)
+	case "adjust":
+		return cmdAdjust(ctx, b, args)
+	case "reconcile":
+		return cmdReconcile(ctx, b, args)
+	case "check":
+		return cmdCheck(ctx, b, args)
+	case "statement":
+		return cmdStatement(ctx, b, args)
+	case "audit":
+		return cmdAudit(ctx, b, args)
+	default:
+		return fmt.Errorf("unknown command %q\n\n%s", cmd, usage)
+	}
+}
@@ -0,0 +1,38 @@
+// Package audit carries the identity of the caller and the request through
+// a context, so that the service layer can attribute the changes it makes.
+package audit
+
+import "context"
+
+// Info identifies who made a call and as part of which request.
+type Info struct {
+	Actor     string
+	RequestID string
+}
+
+// Unknown is the actor recorded when a context carries no Info.
+const Unknown = "unknown"
+
+type contextKey struct{}
+
+func NewContext(ctx context.Context, info Info) context.Context {
+	return context.WithValue(ctx, contextKey{}, info)
+}
+
+// FromContext returns the Info stored in ctx. The actor defaults to
+// Unknown.
+func FromContext(ctx context.Context) Info {
+	info, _ := ctx.Value(contextKey{}).(Info)
+	if info.Actor == "" {
+		info.Actor = Unknown
+	}
+	return info
+}
+
+// WithActor returns a copy of ctx whose Info names actor, keeping the
+// request ID.
+func WithActor(ctx context.Context, actor string) context.Context {
+	info, _ := ctx.Value(contextKey{}).(Info)
+	info.Actor = actor
+	return NewContext(ctx, info)
+}
@@ -0,0 +1,315 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/BurntSushi/toml"
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// binding ties a single configuration value to its environment variable and
+// command-line flag.
+type binding struct {
+	env    string
+	flag   string
+	usage  string
+	isBool bool
+	set    func(c *Config, v string) error
+}
+
+func bindings() []binding {
+	return []binding{
+		strBinding("STORAGE", "storage", "wallet storage backend: postgres or memory", func(c *Config) *string { return &c.Storage }),
+		strBinding("CONCURRENCY_MODE", "concurrency", "balance update locking: pessimistic or optimistic", func(c *Config) *string { return &c.Concurrency }),
+		strBinding("APP_PORT", "port", "HTTP listen port", func(c *Config) *string { return &c.HTTP.Port }),
+		durBinding("HTTP_READ_TIMEOUT", "http-read-timeout", "HTTP read timeout", func(c *Config) *time.Duration { return &c.HTTP.ReadTimeout }),
+		durBinding("HTTP_WRITE_TIMEOUT", "http-write-timeout", "HTTP write timeout", func(c *Config) *time.Duration { return &c.HTTP.WriteTimeout }),
+		durBinding("HTTP_IDLE_TIMEOUT", "http-idle-timeout", "HTTP keep-alive idle timeout", func(c *Config) *time.Duration { return &c.HTTP.IdleTimeout }),
+		intBinding("HTTP_BODY_LIMIT", "http-body-limit", "maximum request body size in bytes", func(c *Config) *int { return &c.HTTP.BodyLimit }),
+
+		strBinding("DB_URL", "db-url", "Postgres connection string", func(c *Config) *string { return &c.DB.URL }),
+		boolBinding("DB_MIGRATE", "db-migrate", "apply pending database migrations at startup", func(c *Config) *bool { return &c.DB.Migrate }),
+		intBinding("DB_MAX_OPEN_CONNS", "db-max-open-conns", "maximum open database connections", func(c *Config) *int { return &c.DB.MaxOpenConns }),
+		intBinding("DB_MAX_IDLE_CONNS", "db-max-idle-conns", "maximum idle database connections", func(c *Config) *int { return &c.DB.MaxIdleConns }),
+		durBinding("DB_CONN_MAX_LIFETIME", "db-conn-max-lifetime", "maximum lifetime of a database connection", func(c *Config) *time.Duration { return &c.DB.ConnMaxLifetime }),
+		durBinding("DB_CONN_MAX_IDLE_TIME", "db-conn-max-idle-time", "maximum idle time of a database connection", func(c *Config) *time.Duration { return &c.DB.ConnMaxIdleTime }),
+		{
+			env:   "DB_REPLICA_URLS",
+			flag:  "db-replica-urls",
+			usage: "comma-separated connection strings of read replicas",
+			set: func(c *Config, v string) error {
+				c.DB.ReplicaURLs = nil
+				for _, dsn := range strings.Split(v, ",") {
+					if dsn = strings.TrimSpace(dsn); dsn != "" {
+						c.DB.ReplicaURLs = append(c.DB.ReplicaURLs, dsn)
+					}
+				}
+				return nil
+			},
+		},
+		durBinding("DB_REPLICA_CHECK_INTERVAL", "db-replica-check-interval", "how often read replicas are health checked", func(c *Config) *time.Duration { return &c.DB.ReplicaCheckInterval }),
+		durBinding("DB_REPLICA_MAX_LAG", "db-replica-max-lag", "replication lag beyond which a replica gets no reads; 0 for any", func(c *Config) *time.Duration { return &c.DB.ReplicaMaxLag }),
+
+		intBinding("RETRY_MAX_ATTEMPTS", "retry-max-attempts", "maximum attempts for retryable transactions", func(c *Config) *int { return &c.Retry.MaxAttempts }),
+		durBinding("RETRY_BASE_DELAY", "retry-base-delay", "delay before the first retry", func(c *Config) *time.Duration { return &c.Retry.BaseDelay }),
+		durBinding("RETRY_MAX_DELAY", "retry-max-delay", "upper bound for a single retry delay", func(c *Config) *time.Duration { return &c.Retry.MaxDelay }),
+		floatBinding("RETRY_MULTIPLIER", "retry-multiplier", "backoff multiplier between retries", func(c *Config) *float64 { return &c.Retry.Multiplier }),
+		floatBinding("RETRY_JITTER", "retry-jitter", "random jitter fraction applied to retry delays (0..1)", func(c *Config) *float64 { return &c.Retry.Jitter }),
+
+		boolBinding("RATE_LIMIT_ENABLED", "rate-limit", "enable request rate limiting", func(c *Config) *bool { return &c.RateLimit.Enabled }),
+		strBinding("RATE_LIMIT_BACKEND", "rate-limit-backend", "rate limit storage: memory or postgres", func(c *Config) *string { return &c.RateLimit.Backend }),
+		floatBinding("RATE_LIMIT_CLIENT_RATE", "rate-limit-client-rate", "requests per second allowed per API client", func(c *Config) *float64 { return &c.RateLimit.ClientRate }),
+		intBinding("RATE_LIMIT_CLIENT_BURST", "rate-limit-client-burst", "burst size per API client", func(c *Config) *int { return &c.RateLimit.ClientBurst }),
+		floatBinding("RATE_LIMIT_WALLET_RATE", "rate-limit-wallet-rate", "operations per second allowed per wallet", func(c *Config) *float64 { return &c.RateLimit.WalletRate }),
+		intBinding("RATE_LIMIT_WALLET_BURST", "rate-limit-wallet-burst", "burst size per wallet", func(c *Config) *int { return &c.RateLimit.WalletBurst }),
+
+		boolBinding("COALESCE_ENABLED", "coalesce", "group concurrent deposits to one wallet into a single transaction", func(c *Config) *bool { return &c.Coalesce.Enabled }),
+		durBinding("COALESCE_WINDOW", "coalesce-window", "how long a deposit waits for others to join its batch", func(c *Config) *time.Duration { return &c.Coalesce.Window }),
+		intBinding("COALESCE_MAX_BATCH", "coalesce-max-batch", "maximum deposits applied in one transaction", func(c *Config) *int { return &c.Coalesce.MaxBatch }),
+
+		boolBinding("SCHEDULES_ENABLED", "schedules", "run the scheduled operations worker", func(c *Config) *bool { return &c.Schedules.Enabled }),
+		durBinding("SCHEDULES_POLL_INTERVAL", "schedules-poll-interval", "how often the worker looks for due schedules", func(c *Config) *time.Duration { return &c.Schedules.PollInterval }),
+		intBinding("SCHEDULES_BATCH_SIZE", "schedules-batch-size", "maximum schedules claimed per poll", func(c *Config) *int { return &c.Schedules.BatchSize }),
+		intBinding("SCHEDULES_MAX_RETRIES", "schedules-max-retries", "retries of a run that failed for insufficient funds", func(c *Config) *int { return &c.Schedules.MaxRetries }),
+		durBinding("SCHEDULES_RETRY_DELAY", "schedules-retry-delay", "delay before retrying a failed run", func(c *Config) *time.Duration { return &c.Schedules.RetryDelay }),
+
+		boolBinding("SNAPSHOTS_ENABLED", "snapshots", "run the balance snapshot worker", func(c *Config) *bool { return &c.Snapshots.Enabled }),
+		durBinding("SNAPSHOTS_INTERVAL", "snapshots-interval", "time between balance snapshots", func(c *Config) *time.Duration { return &c.Snapshots.Interval }),
+		durBinding("SNAPSHOTS_LAG", "snapshots-lag", "how far behind the clock snapshots are taken", func(c *Config) *time.Duration { return &c.Snapshots.Lag }),
+
+		boolBinding("INTEREST_ENABLED", "interest", "run the interest accrual worker", func(c *Config) *bool { return &c.Interest.Enabled }),
+		durBinding("INTEREST_POLL_INTERVAL", "interest-poll-interval", "how often the worker checks for a day to accrue", func(c *Config) *time.Duration { return &c.Interest.PollInterval }),
+		durBinding("INTEREST_LAG", "interest-lag", "how long after midnight UTC a day is accrued", func(c *Config) *time.Duration { return &c.Interest.Lag }),
+
+		boolBinding("PARTITIONS_ENABLED", "partitions", "run the operations partition worker", func(c *Config) *bool { return &c.Partitions.Enabled }),
+		durBinding("PARTITIONS_INTERVAL", "partitions-interval", "how often the worker creates and archives partitions", func(c *Config) *time.Duration { return &c.Partitions.Interval }),
+		intBinding("PARTITIONS_MONTHS_AHEAD", "partitions-months-ahead", "months of operations partitions created ahead of time", func(c *Config) *int { return &c.Partitions.MonthsAhead }),
+		intBinding("PARTITIONS_RETENTION_MONTHS", "partitions-retention-months", "months of operations kept before archiving; 0 keeps them all", func(c *Config) *int { return &c.Partitions.RetentionMonths }),
+		strBinding("PARTITIONS_ARCHIVE_DIR", "partitions-archive-dir", "directory archived operations are written to", func(c *Config) *string { return &c.Partitions.ArchiveDir }),
+		boolBinding("PARTITIONS_DROP_ARCHIVED", "partitions-drop-archived", "drop archived partitions instead of leaving them detached", func(c *Config) *bool { return &c.Partitions.DropArchived }),
+
+		strBinding("FEES_REVENUE_WALLET", "fees-revenue-wallet", "wallet credited with fees; fees are not charged when empty", func(c *Config) *string { return &c.Fees.RevenueWallet }),
+
+		strBinding("FX_RATES_FILE", "fx-rates-file", "CSV of exchange rates loaded at startup", func(c *Config) *string { return &c.FX.RatesFile }),
+		floatBinding("FX_SPREAD", "fx-spread", "fraction of the mid rate kept on conversions", func(c *Config) *float64 { return &c.FX.Spread }),
+		strBinding("FX_ROUNDING", "fx-rounding", "rounding of converted amounts: half_up, half_even or down", func(c *Config) *string { return &c.FX.Rounding }),
+
+		durBinding("EVENTS_HEARTBEAT", "events-heartbeat", "interval of heartbeats on idle event streams", func(c *Config) *time.Duration { return &c.Events.Heartbeat }),
+
+		strBinding("ADMIN_TOKEN", "admin-token", "bearer token for the admin API; the API is disabled when empty", func(c *Config) *string { return &c.Admin.Token }),
+
+		boolBinding("TENANTS_TRUST_HEADER", "tenants-trust-header", "let client requests without a known API key name their tenant in X-Tenant-ID", func(c *Config) *bool { return &c.Tenants.TrustHeader }),
+
+		strBinding("LOG_LEVEL", "log-level", "log level: debug, info, warn or error", func(c *Config) *string { return &c.Log.Level }),
+		strBinding("LOG_FORMAT", "log-format", "log format: text or json", func(c *Config) *string { return &c.Log.Format }),
+
+		{
+			env:   "FEATURES",
+			flag:  "features",
+			usage: "comma-separated feature flags, e.g. a,b=false",
+			set: func(c *Config, v string) error {
+				return c.Features.parse(v)
+			},
+		},
+
+		boolBinding("TLS_ENABLED", "tls", "serve HTTPS", func(c *Config) *bool { return &c.TLS.Enabled }),
+		strBinding("TLS_CERT_FILE", "tls-cert-file", "TLS certificate file", func(c *Config) *string { return &c.TLS.CertFile }),
+		strBinding("TLS_KEY_FILE", "tls-key-file", "TLS private key file", func(c *Config) *string { return &c.TLS.KeyFile }),
+	}
+}
+
+// Load resolves the configuration from defaults, the config file (given by
+// --config or CONFIG_FILE), the environment and args, then validates it.
+// Passing nil args skips flag parsing.
+func Load(args []string) (*Config, error) {
+	if err := godotenv.Load("config.env"); err != nil {
+		log.Println("config.env not found, using system env")
+	}
+
+	binds := bindings()
+	flags, file, err := parseFlags(binds, args)
+	if err != nil {
+		return nil, err
+	}
+	if file == "" {
+		file = os.Getenv("CONFIG_FILE")
+	}
+
+	cfg := Default()
+	if file != "" {
+		if err := cfg.loadFile(file); err != nil {
+			return nil, err
+		}
+		cfg.File = file
+	}
+
+	var errs []error
+	for _, b := range binds {
+		if v, ok := os.LookupEnv(b.env); ok {
+			if err := b.set(cfg, v); err != nil {
+				errs = append(errs, fmt.Errorf("env %s: %w", b.env, err))
+			}
+		}
+	}
+	for _, b := range binds {
+		if v, ok := flags[b.flag]; ok {
+			if err := b.set(cfg, v); err != nil {
+				errs = append(errs, fmt.Errorf("flag --%s: %w", b.flag, err))
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// parseFlags collects the raw values of the flags present in args so they
+// can be applied after the file and environment layers.
+func parseFlags(binds []binding, args []string) (map[string]string, string, error) {
+	values := make(map[string]string)
+	if args == nil {
+		return values, "", nil
+	}
+
+	fs := flag.NewFlagSet(filepath.Base(os.Args[0]), flag.ContinueOnError)
+	var file string
+	fs.StringVar(&file, "config", "", "path to a YAML or TOML config file (env CONFIG_FILE)")
+	for _, b := range binds {
+		name := b.flag
+		usage := fmt.Sprintf("%s (env %s)", b.usage, b.env)
+		record := func(v string) error {
+			values[name] = v
+			return nil
+		}
+		if b.isBool {
+			fs.BoolFunc(name, usage, record)
+		} else {
+			fs.Func(name, usage, record)
+		}
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return nil, "", err
+	}
+	if fs.NArg() > 0 {
+		return nil, "", fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+	return values, file, nil
+}
+
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read config file: %w", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("parse %s: %w", path, err)
+		}
+	case ".toml":
+		md, err := toml.Decode(string(data), c)
+		if err != nil {
+			return fmt.Errorf("parse %s: %w", path, err)
+		}
+		if undecoded := md.Undecoded(); len(undecoded) > 0 {
+			return fmt.Errorf("parse %s: unknown key %q", path, undecoded[0].String())
+		}
+	default:
+		return fmt.Errorf("config file %s: unsupported format, use .yaml, .yml or .toml", path)
+	}
+
+	if c.Features == nil {
+		c.Features = FeatureFlags{}
+	}
+	return nil
+}
+
+func (f FeatureFlags) parse(v string) error {
+	for _, item := range strings.Split(v, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		name, raw, hasValue := strings.Cut(item, "=")
+		on := true
+		if hasValue {
+			b, err := strconv.ParseBool(raw)
+			if err != nil {
+				return fmt.Errorf("feature %q: %w", name, err)
+			}
+			on = b
+		}
+		f[strings.TrimSpace(name)] = on
+	}
+	return nil
+}
+
+func strBinding(env, name, usage string, field func(*Config) *string) binding {
+	return binding{env: env, flag: name, usage: usage, set: func(c *Config, v string) error {
+		*field(c) = v
+		return nil
+	}}
+}
+
+func intBinding(env, name, usage string, field func(*Config) *int) binding {
+	return binding{env: env, flag: name, usage: usage, set: func(c *Config, v string) error {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", v)
+		}
+		*field(c) = n
+		return nil
+	}}
+}
+
+func floatBinding(env, name, usage string, field func(*Config) *float64) binding {
+	return binding{env: env, flag: name, usage: usage, set: func(c *Config, v string) error {
+		n, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", v)
+		}
+		*field(c) = n
+		return nil
+	}}
+}
+
+func durBinding(env, name, usage string, field func(*Config) *time.Duration) binding {
+	return binding{env: env, flag: name, usage: usage, set: func(c *Config, v string) error {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", v)
+		}
+		*field(c) = d
+		return nil
+	}}
+}
+
+func boolBinding(env, name, usage string, field func(*Config) *bool) binding {
+	return binding{env: env, flag: name, usage: usage, isBool: true, set: func(c *Config, v string) error {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", v)
+		}
+		*field(c) = b
+		return nil
+	}}
+}
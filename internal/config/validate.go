@@ -0,0 +1,293 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"wallet-service/internal/tenant"
+)
+
+// Validate reports every invalid setting at once so a misconfigured
+// deployment can be fixed in a single pass.
+func (c *Config) Validate() error {
+	var errs []error
+	fail := func(field, format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%s: %s", field, fmt.Sprintf(format, args...)))
+	}
+
+	if port, err := strconv.Atoi(c.HTTP.Port); err != nil || port < 1 || port > 65535 {
+		fail("http.port", "must be a number between 1 and 65535, got %q", c.HTTP.Port)
+	}
+	if c.HTTP.ReadTimeout <= 0 {
+		fail("http.read_timeout", "must be positive")
+	}
+	if c.HTTP.WriteTimeout <= 0 {
+		fail("http.write_timeout", "must be positive")
+	}
+	if c.HTTP.IdleTimeout < 0 {
+		fail("http.idle_timeout", "must not be negative")
+	}
+	if c.HTTP.BodyLimit <= 0 {
+		fail("http.body_limit", "must be positive")
+	}
+
+	switch c.Storage {
+	case "postgres", "memory":
+	default:
+		fail("storage", "must be postgres or memory, got %q", c.Storage)
+	}
+	switch c.Concurrency {
+	case "pessimistic", "optimistic":
+	default:
+		fail("concurrency", "must be pessimistic or optimistic, got %q", c.Concurrency)
+	}
+
+	if c.DB.URL == "" && c.usesPostgres() {
+		fail("db.url", "is required (set DB_URL or --db-url)")
+	} else if strings.Contains(c.DB.URL, "://") {
+		if u, err := url.Parse(c.DB.URL); err != nil {
+			fail("db.url", "is not a valid URL")
+		} else if u.Scheme != "postgres" && u.Scheme != "postgresql" {
+			fail("db.url", "unsupported scheme %q", u.Scheme)
+		}
+	}
+	for i, dsn := range c.DB.ReplicaURLs {
+		field := fmt.Sprintf("db.replica_urls[%d]", i)
+		if !strings.Contains(dsn, "://") {
+			continue
+		}
+		if u, err := url.Parse(dsn); err != nil {
+			fail(field, "is not a valid URL")
+		} else if u.Scheme != "postgres" && u.Scheme != "postgresql" {
+			fail(field, "unsupported scheme %q", u.Scheme)
+		}
+	}
+	if len(c.DB.ReplicaURLs) > 0 {
+		if c.DB.ReplicaCheckInterval <= 0 {
+			fail("db.replica_check_interval", "must be positive")
+		}
+		if c.DB.ReplicaMaxLag < 0 {
+			fail("db.replica_max_lag", "must not be negative")
+		}
+	}
+	if c.DB.MaxOpenConns < 1 {
+		fail("db.max_open_conns", "must be at least 1")
+	}
+	if c.DB.MaxIdleConns < 0 || c.DB.MaxIdleConns > c.DB.MaxOpenConns {
+		fail("db.max_idle_conns", "must be between 0 and db.max_open_conns (%d)", c.DB.MaxOpenConns)
+	}
+	if c.DB.ConnMaxLifetime < 0 {
+		fail("db.conn_max_lifetime", "must not be negative")
+	}
+	if c.DB.ConnMaxIdleTime < 0 {
+		fail("db.conn_max_idle_time", "must not be negative")
+	}
+
+	if c.Retry.MaxAttempts < 1 {
+		fail("retry.max_attempts", "must be at least 1")
+	}
+	if c.Retry.BaseDelay < 0 {
+		fail("retry.base_delay", "must not be negative")
+	}
+	if c.Retry.MaxDelay < c.Retry.BaseDelay {
+		fail("retry.max_delay", "must not be less than retry.base_delay")
+	}
+	if c.Retry.Multiplier < 1 {
+		fail("retry.multiplier", "must be at least 1")
+	}
+	if c.Retry.Jitter < 0 || c.Retry.Jitter > 1 {
+		fail("retry.jitter", "must be between 0 and 1")
+	}
+
+	if c.RateLimit.Enabled {
+		switch c.RateLimit.Backend {
+		case "memory":
+		case "postgres":
+			if c.Storage != "postgres" {
+				fail("rate_limit.backend", "postgres backend requires postgres storage")
+			}
+		default:
+			fail("rate_limit.backend", "must be memory or postgres, got %q", c.RateLimit.Backend)
+		}
+		if c.RateLimit.ClientRate < 0 {
+			fail("rate_limit.client_rate", "must not be negative")
+		}
+		if c.RateLimit.ClientRate > 0 && c.RateLimit.ClientBurst < 1 {
+			fail("rate_limit.client_burst", "must be at least 1")
+		}
+		if c.RateLimit.WalletRate < 0 {
+			fail("rate_limit.wallet_rate", "must not be negative")
+		}
+		if c.RateLimit.WalletRate > 0 && c.RateLimit.WalletBurst < 1 {
+			fail("rate_limit.wallet_burst", "must be at least 1")
+		}
+	}
+
+	if c.Coalesce.Enabled {
+		if c.Coalesce.Window <= 0 || c.Coalesce.Window > time.Second {
+			fail("coalesce.window", "must be positive and at most 1s")
+		}
+		if c.Coalesce.MaxBatch < 1 {
+			fail("coalesce.max_batch", "must be at least 1")
+		}
+	}
+
+	if c.Schedules.Enabled {
+		if c.Schedules.PollInterval <= 0 {
+			fail("schedules.poll_interval", "must be positive")
+		}
+		if c.Schedules.BatchSize < 1 {
+			fail("schedules.batch_size", "must be at least 1")
+		}
+	}
+	if c.Schedules.MaxRetries < 0 {
+		fail("schedules.max_retries", "must not be negative")
+	}
+	if c.Schedules.RetryDelay <= 0 {
+		fail("schedules.retry_delay", "must be positive")
+	}
+
+	if c.Snapshots.Enabled {
+		if c.Snapshots.Interval < time.Minute {
+			fail("snapshots.interval", "must be at least 1m")
+		}
+		if c.Snapshots.Lag < 0 {
+			fail("snapshots.lag", "must not be negative")
+		}
+	}
+
+	if c.Interest.Enabled {
+		if c.Interest.PollInterval <= 0 {
+			fail("interest.poll_interval", "must be positive")
+		}
+		if c.Interest.Lag < 0 {
+			fail("interest.lag", "must not be negative")
+		}
+	}
+
+	if c.Partitions.Enabled {
+		if c.Partitions.Interval <= 0 {
+			fail("partitions.interval", "must be positive")
+		}
+		if c.Partitions.MonthsAhead < 1 {
+			fail("partitions.months_ahead", "must be at least 1")
+		}
+		if c.Partitions.RetentionMonths < 0 {
+			fail("partitions.retention_months", "must not be negative")
+		}
+		if c.Partitions.RetentionMonths > 0 && c.Partitions.ArchiveDir == "" {
+			fail("partitions.archive_dir", "is required when partitions are archived")
+		}
+	}
+
+	if c.Fees.RevenueWallet != "" {
+		if _, err := uuid.Parse(c.Fees.RevenueWallet); err != nil {
+			fail("fees.revenue_wallet", "must be a wallet UUID")
+		}
+	}
+
+	if c.FX.RatesFile != "" {
+		if _, err := os.Stat(c.FX.RatesFile); err != nil {
+			fail("fx.rates_file", "%v", err)
+		}
+	}
+	if c.FX.Spread < 0 || c.FX.Spread >= 1 {
+		fail("fx.spread", "must be at least 0 and below 1")
+	}
+	switch c.FX.Rounding {
+	case "half_up", "half_even", "down":
+	default:
+		fail("fx.rounding", "must be half_up, half_even or down, got %q", c.FX.Rounding)
+	}
+
+	if c.Events.Heartbeat < time.Second {
+		fail("events.heartbeat", "must be at least 1s")
+	}
+
+	if c.Admin.Token != "" && len(c.Admin.Token) < 16 {
+		fail("admin.token", "must be at least 16 characters")
+	}
+
+	// The default tenant always exists; listing it sets its keys and
+	// policy.
+	tenantIDs := make(map[string]bool)
+	apiKeys := make(map[string]bool)
+	for i, t := range c.Tenants.List {
+		field := fmt.Sprintf("tenants.list[%d]", i)
+		switch {
+		case t.ID == "" || len(t.ID) > tenant.MaxIDLength:
+			fail(field+".id", "must be 1 to %d characters", tenant.MaxIDLength)
+		case tenantIDs[t.ID]:
+			fail(field+".id", "duplicate tenant %q", t.ID)
+		}
+		tenantIDs[t.ID] = true
+		for _, key := range t.APIKeys {
+			if key == "" || apiKeys[key] {
+				fail(field+".api_keys", "must be distinct and non-empty")
+				break
+			}
+			apiKeys[key] = true
+		}
+		for _, cur := range t.Currencies {
+			if !currencyCode.MatchString(cur) {
+				fail(field+".currencies", "must be ISO 4217 codes, got %q", cur)
+			}
+		}
+		if t.MaxOperationAmount < 0 {
+			fail(field+".max_operation_amount", "must not be negative")
+		}
+	}
+
+	switch strings.ToLower(c.Log.Level) {
+	case "debug", "info", "warn", "error":
+	default:
+		fail("log.level", "must be one of debug, info, warn, error, got %q", c.Log.Level)
+	}
+	switch c.Log.Format {
+	case "text", "json":
+	default:
+		fail("log.format", "must be text or json, got %q", c.Log.Format)
+	}
+
+	if c.TLS.Enabled {
+		if c.TLS.CertFile == "" {
+			fail("tls.cert_file", "is required when TLS is enabled")
+		} else if _, err := os.Stat(c.TLS.CertFile); err != nil {
+			fail("tls.cert_file", "%v", err)
+		}
+		if c.TLS.KeyFile == "" {
+			fail("tls.key_file", "is required when TLS is enabled")
+		} else if _, err := os.Stat(c.TLS.KeyFile); err != nil {
+			fail("tls.key_file", "%v", err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (c *Config) usesPostgres() bool {
+	return c.Storage == "postgres" || (c.RateLimit.Enabled && c.RateLimit.Backend == "postgres")
+}
+
+var currencyCode = regexp.MustCompile(`^[A-Z]{3}$`)
+
+var dsnPassword = regexp.MustCompile(`(?i)(password\s*=\s*)('[^']*'|\S+)`)
+
+// redactDSN masks the password in both URL and key=value style DSNs.
+func redactDSN(dsn string) string {
+	if strings.Contains(dsn, "://") {
+		if u, err := url.Parse(dsn); err == nil {
+			return u.Redacted()
+		}
+		return "<unparseable>"
+	}
+	return dsnPassword.ReplaceAllString(dsn, "${1}xxxxx")
+}
@@ -0,0 +1,69 @@
+package db
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"github.com/jackc/pgx/v5/pgconn"
+	"io"
+	"net"
+	"strings"
+	"syscall"
+)
+
+const (
+	codeSerializationFailure = "40001"
+	codeDeadlockDetected     = "40P01"
+	codeLockNotAvailable     = "55P03"
+	codeAdminShutdown        = "57P01"
+	codeCrashShutdown        = "57P02"
+	codeCannotConnectNow     = "57P03"
+	codeUniqueViolation      = "23505"
+)
+
+// IsUniqueViolation reports whether err is a unique constraint violation on
+// the named constraint.
+func IsUniqueViolation(err error, constraint string) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == codeUniqueViolation && pgErr.ConstraintName == constraint
+}
+
+// ClassifyRetryable reports whether err is a transient database failure
+// worth retrying the whole transaction for, and a short reason for
+// logs and metrics.
+func ClassifyRetryable(err error) (bool, string) {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false, ""
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch {
+		case pgErr.Code == codeDeadlockDetected:
+			return true, "deadlock"
+		case pgErr.Code == codeSerializationFailure:
+			return true, "serialization_failure"
+		case pgErr.Code == codeLockNotAvailable:
+			return true, "lock_timeout"
+		case pgErr.Code == codeAdminShutdown, pgErr.Code == codeCrashShutdown, pgErr.Code == codeCannotConnectNow,
+			strings.HasPrefix(pgErr.Code, "08"):
+			return true, "connection"
+		}
+		return false, ""
+	}
+
+	if pgconn.SafeToRetry(err) ||
+		errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, io.ErrUnexpectedEOF) {
+		return true, "connection"
+	}
+
+	var netErr *net.OpError
+	if errors.As(err, &netErr) {
+		return true, "connection"
+	}
+	return false, ""
+}
@@ -0,0 +1,41 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"syscall"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassifyRetryable(t *testing.T) {
+	cases := []struct {
+		name      string
+		err       error
+		retryable bool
+		reason    string
+	}{
+		{"deadlock", &pgconn.PgError{Code: "40P01"}, true, "deadlock"},
+		{"serialization failure", &pgconn.PgError{Code: "40001"}, true, "serialization_failure"},
+		{"lock timeout", &pgconn.PgError{Code: "55P03"}, true, "lock_timeout"},
+		{"connection failure", &pgconn.PgError{Code: "08006"}, true, "connection"},
+		{"wrapped deadlock", fmt.Errorf("tx: %w", &pgconn.PgError{Code: "40P01"}), true, "deadlock"},
+		{"connection reset", fmt.Errorf("read: %w", syscall.ECONNRESET), true, "connection"},
+		{"unexpected eof", io.ErrUnexpectedEOF, true, "connection"},
+		{"unique violation", &pgconn.PgError{Code: "23505"}, false, ""},
+		{"context canceled", context.Canceled, false, ""},
+		{"plain error", errors.New("boom"), false, ""},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			retryable, reason := ClassifyRetryable(tc.err)
+			assert.Equal(t, tc.retryable, retryable)
+			assert.Equal(t, tc.reason, reason)
+		})
+	}
+}
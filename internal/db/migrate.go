@@ -0,0 +1,119 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+)
+
+// migrateLock is the advisory lock held while migrating, so that service
+// instances starting together apply each migration once.
+const migrateLock = 0x77616c6c6574 // "wallet"
+
+// Migrate applies the *.sql files of fsys that schema_migrations does not
+// list yet, in file name order, each in its own transaction, and returns
+// the names of those applied.
+func Migrate(ctx context.Context, sqlDB *sql.DB, fsys fs.FS) ([]string, error) {
+	names, err := migrationNames(fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", migrateLock); err != nil {
+		return nil, err
+	}
+	defer conn.ExecContext(context.WithoutCancel(ctx), "SELECT pg_advisory_unlock($1)", migrateLock)
+
+	if _, err := conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		name VARCHAR(255) PRIMARY KEY,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	)`); err != nil {
+		return nil, err
+	}
+	done := make(map[string]bool)
+	rows, err := conn.QueryContext(ctx, "SELECT name FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		done[name] = true
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+
+	var applied []string
+	for _, name := range names {
+		if done[name] {
+			continue
+		}
+		body, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return applied, err
+		}
+		if err := applyMigration(ctx, conn, name, string(body)); err != nil {
+			return applied, fmt.Errorf("migration %s: %w", name, err)
+		}
+		applied = append(applied, name)
+	}
+	return applied, nil
+}
+
+func applyMigration(ctx context.Context, conn *sql.Conn, name, body string) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Without arguments the statement goes over the simple query protocol,
+	// which runs every statement of the file.
+	if _, err := tx.ExecContext(ctx, body); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "INSERT INTO schema_migrations (name) VALUES ($1)", name); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// migrationNames lists the *.sql files at the root of fsys in the order
+// they are applied.
+func migrationNames(fsys fs.FS) ([]string, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		if !e.IsDir() && path.Ext(e.Name()) == ".sql" {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+	for i := 1; i < len(names); i++ {
+		if prefix(names[i]) == prefix(names[i-1]) {
+			return nil, fmt.Errorf("migrations %s and %s share a number", names[i-1], names[i])
+		}
+	}
+	return names, nil
+}
+
+func prefix(name string) string {
+	n, _, _ := strings.Cut(name, "_")
+	return n
+}
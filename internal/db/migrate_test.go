@@ -0,0 +1,37 @@
+package db
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"wallet-service/migrations"
+)
+
+func TestMigrationNames(t *testing.T) {
+	fsys := fstest.MapFS{
+		"010_interest.sql":  {},
+		"002_seed.sql":      {},
+		"001_init.sql":      {},
+		"migrations.go":     {},
+		"sub/003_other.sql": {},
+	}
+
+	names, err := migrationNames(fsys)
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"001_init.sql", "002_seed.sql", "010_interest.sql"}, names)
+
+	fsys["002_other.sql"] = &fstest.MapFile{}
+	_, err = migrationNames(fsys)
+	assert.ErrorContains(t, err, "share a number")
+}
+
+func TestMigrationNames_Embedded(t *testing.T) {
+	names, err := migrationNames(migrations.FS)
+
+	require.NoError(t, err)
+	assert.Equal(t, "001_init.sql", names[0])
+}
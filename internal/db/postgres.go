@@ -4,13 +4,26 @@ import (
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"log"
+	"wallet-service/internal/config"
 )
 
-func NewPostgres(dsn string) *gorm.DB {
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+func NewPostgres(cfg config.DBConfig) *gorm.DB {
+	db, err := gorm.Open(postgres.Open(cfg.URL), &gorm.Config{})
 	if err != nil {
 		log.Fatal(err)
 	}
+	if err := RegisterTenantScope(db); err != nil {
+		log.Fatal(err)
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		log.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
 
 	log.Println("Successfully connected to postgres database")
 	return db
@@ -0,0 +1,148 @@
+package db
+
+import (
+	"context"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"log/slog"
+	"sync/atomic"
+	"time"
+	"wallet-service/internal/config"
+)
+
+type primaryKey struct{}
+
+// WithPrimary returns a context whose reads go to the primary, so that they
+// see every write committed before they started.
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+// ReadsFromPrimary reports whether ctx asks for reads from the primary.
+func ReadsFromPrimary(ctx context.Context) bool {
+	on, _ := ctx.Value(primaryKey{}).(bool)
+	return on
+}
+
+// Replicas routes reads to read replicas of the primary, in turn. Reads go
+// to the primary when their context asks for it or no replica is healthy.
+// A replica is unhealthy from the time it fails a check, or a read on a
+// broken connection, until it passes a check.
+type Replicas struct {
+	primary  *gorm.DB
+	replicas []*replica
+	maxLag   time.Duration
+	next     atomic.Uint64
+}
+
+type replica struct {
+	db      *gorm.DB
+	name    string
+	healthy atomic.Bool
+}
+
+// NewReplicas returns a router sending every read to primary until
+// replicas are added. A replica more than maxLag behind fails checks,
+// unless maxLag is zero.
+func NewReplicas(primary *gorm.DB, maxLag time.Duration) *Replicas {
+	return &Replicas{primary: primary, maxLag: maxLag}
+}
+
+// Add adds a replica, named for logs. It is unhealthy until checked.
+// Replicas are added before the router is used.
+func (r *Replicas) Add(name string, db *gorm.DB) {
+	r.replicas = append(r.replicas, &replica{db: db, name: name})
+}
+
+// Read runs fn on a replica, or on the primary as described above. If the
+// replica's connection fails, fn runs again on the primary.
+func (r *Replicas) Read(ctx context.Context, fn func(db *gorm.DB) error) error {
+	rep := r.pick(ctx)
+	if rep == nil {
+		return fn(r.primary)
+	}
+	err := fn(rep.db)
+	if retryable, reason := ClassifyRetryable(err); retryable && reason == "connection" {
+		if rep.healthy.Swap(false) {
+			slog.Warn("read replica failed, reading from the primary", "replica", rep.name, "err", err)
+		}
+		return fn(r.primary)
+	}
+	return err
+}
+
+func (r *Replicas) pick(ctx context.Context) *replica {
+	if ReadsFromPrimary(ctx) {
+		return nil
+	}
+	n := uint64(len(r.replicas))
+	start := r.next.Add(1)
+	for i := uint64(0); i < n; i++ {
+		if rep := r.replicas[(start+i)%n]; rep.healthy.Load() {
+			return rep
+		}
+	}
+	return nil
+}
+
+// replicationLag is how far a replica's replay trails the primary. A
+// replica that replayed all it received is caught up, however long ago the
+// last transaction was.
+const replicationLag = `SELECT COALESCE(CASE
+	WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+	ELSE EXTRACT(EPOCH FROM NOW() - pg_last_xact_replay_timestamp())
+END, 0)`
+
+// Check checks every replica once and records whether it is healthy.
+func (r *Replicas) Check(ctx context.Context) {
+	for _, rep := range r.replicas {
+		var lag float64
+		err := rep.db.WithContext(ctx).Raw(replicationLag).Scan(&lag).Error
+		healthy := err == nil && (r.maxLag == 0 || time.Duration(lag*float64(time.Second)) <= r.maxLag)
+		switch was := rep.healthy.Swap(healthy); {
+		case healthy && !was:
+			slog.Info("read replica is healthy", "replica", rep.name)
+		case !healthy && was && err != nil:
+			slog.Warn("read replica is unhealthy", "replica", rep.name, "err", err)
+		case !healthy && was:
+			slog.Warn("read replica is lagging", "replica", rep.name, "lag", lag)
+		}
+	}
+}
+
+// Run checks the replicas once per interval until ctx is done.
+func (r *Replicas) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		r.Check(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// NewReplica opens a connection pool to the replica at url, sized like the
+// primary's. Unlike NewPostgres it does not fail if the replica is down.
+func NewReplica(cfg config.DBConfig, url string) (*gorm.DB, error) {
+	db, err := gorm.Open(postgres.Open(url), &gorm.Config{DisableAutomaticPing: true})
+	if err != nil {
+		return nil, err
+	}
+	if err := RegisterTenantScope(db); err != nil {
+		return nil, err
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+	return db, nil
+}
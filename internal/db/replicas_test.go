@@ -0,0 +1,66 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestReplicas_Read(t *testing.T) {
+	ctx := context.Background()
+	primary, one, two := &gorm.DB{}, &gorm.DB{}, &gorm.DB{}
+	r := NewReplicas(primary, 0)
+	served := func(ctx context.Context) *gorm.DB {
+		var got *gorm.DB
+		assert.NoError(t, r.Read(ctx, func(conn *gorm.DB) error {
+			got = conn
+			return nil
+		}))
+		return got
+	}
+
+	assert.Same(t, primary, served(ctx), "no replicas")
+
+	r.Add("one", one)
+	r.Add("two", two)
+	assert.Same(t, primary, served(ctx), "replicas start unhealthy")
+
+	r.replicas[0].healthy.Store(true)
+	r.replicas[1].healthy.Store(true)
+	first, second := served(ctx), served(ctx)
+	assert.ElementsMatch(t, []*gorm.DB{one, two}, []*gorm.DB{first, second}, "replicas take turns")
+	assert.Same(t, primary, served(WithPrimary(ctx)))
+
+	r.replicas[1].healthy.Store(false)
+	assert.Same(t, one, served(ctx))
+	assert.Same(t, one, served(ctx))
+}
+
+func TestReplicas_ReadFallsBack(t *testing.T) {
+	ctx := context.Background()
+	primary, replica := &gorm.DB{}, &gorm.DB{}
+	r := NewReplicas(primary, 0)
+	r.Add("replica", replica)
+	r.replicas[0].healthy.Store(true)
+
+	notFound := errors.New("record not found")
+	err := r.Read(ctx, func(conn *gorm.DB) error { return notFound })
+	assert.ErrorIs(t, err, notFound, "query errors are the caller's")
+	assert.True(t, r.replicas[0].healthy.Load())
+
+	var tries []*gorm.DB
+	err = r.Read(ctx, func(conn *gorm.DB) error {
+		tries = append(tries, conn)
+		if conn == replica {
+			return syscall.ECONNREFUSED
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []*gorm.DB{replica, primary}, tries)
+	assert.False(t, r.replicas[0].healthy.Load(), "a broken replica waits for its next check")
+}
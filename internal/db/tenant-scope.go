@@ -0,0 +1,39 @@
+package db
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"wallet-service/internal/tenant"
+)
+
+// RegisterTenantScope confines every query, update and delete on a model
+// with a TenantID field to the tenant of the statement's context. Contexts
+// without a tenant, those of background work, see every tenant. Inserts
+// are left alone: rows get their tenant from the caller or the database.
+func RegisterTenantScope(db *gorm.DB) error {
+	cb := db.Callback()
+	if err := cb.Query().Before("gorm:query").Register("tenant:scope_query", scopeToTenant); err != nil {
+		return err
+	}
+	if err := cb.Row().Before("gorm:row").Register("tenant:scope_row", scopeToTenant); err != nil {
+		return err
+	}
+	if err := cb.Update().Before("gorm:update").Register("tenant:scope_update", scopeToTenant); err != nil {
+		return err
+	}
+	return cb.Delete().Before("gorm:delete").Register("tenant:scope_delete", scopeToTenant)
+}
+
+func scopeToTenant(tx *gorm.DB) {
+	id, ok := tenant.FromContext(tx.Statement.Context)
+	if !ok || tx.Statement.Schema == nil {
+		return
+	}
+	field := tx.Statement.Schema.LookUpField("TenantID")
+	if field == nil {
+		return
+	}
+	tx.Statement.AddClause(clause.Where{Exprs: []clause.Expression{
+		clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: field.DBName}, Value: id},
+	}})
+}
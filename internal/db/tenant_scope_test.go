@@ -0,0 +1,64 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"wallet-service/internal/tenant"
+)
+
+type scopedRow struct {
+	ID       int
+	TenantID string
+	Balance  int
+}
+
+type sharedRow struct {
+	ID int
+}
+
+func newDryRunDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{
+		DryRun:                 true,
+		SkipDefaultTransaction: true,
+		Logger:                 logger.Discard,
+		DisableAutomaticPing:   true,
+	})
+	require.NoError(t, err)
+	require.NoError(t, RegisterTenantScope(db))
+	return db
+}
+
+func TestTenantScope(t *testing.T) {
+	db := newDryRunDB(t)
+	ctx := tenant.NewContext(context.Background(), "retail")
+
+	cases := map[string]func(tx *gorm.DB) *gorm.DB{
+		"query":  func(tx *gorm.DB) *gorm.DB { return tx.Where("id = ?", 1).Find(&scopedRow{}) },
+		"row":    func(tx *gorm.DB) *gorm.DB { return tx.Model(&scopedRow{}).Select("MAX(id)").Scan(new(int)) },
+		"update": func(tx *gorm.DB) *gorm.DB { return tx.Model(&scopedRow{}).Where("id = ?", 1).Update("balance", 5) },
+		"delete": func(tx *gorm.DB) *gorm.DB { return tx.Where("id = ?", 1).Delete(&scopedRow{}) },
+	}
+	for name, run := range cases {
+		t.Run(name, func(t *testing.T) {
+			scoped := run(db.WithContext(ctx)).Statement
+			assert.Contains(t, scoped.SQL.String(), `"scoped_rows"."tenant_id" = $`)
+			assert.Contains(t, scoped.Vars, "retail")
+
+			background := run(db.WithContext(context.Background())).Statement
+			assert.NotContains(t, background.SQL.String(), "tenant_id")
+		})
+	}
+
+	t.Run("model without tenant", func(t *testing.T) {
+		stmt := db.WithContext(ctx).Find(&sharedRow{}).Statement
+		assert.NotContains(t, stmt.SQL.String(), "tenant_id")
+	})
+}
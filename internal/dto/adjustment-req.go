@@ -0,0 +1,12 @@
+package dto
+
+import "github.com/google/uuid"
+
+// AdjustmentRequest is a manual correction of a wallet's balance. A
+// positive amount credits the wallet, a negative one debits it.
+type AdjustmentRequest struct {
+	WalletID uuid.UUID `json:"-"`
+	Amount   float64   `json:"amount" validate:"required"`
+	Reason   string    `json:"reason" validate:"required,max=1000"`
+	Actor    string    `json:"actor" validate:"required,max=100"`
+}
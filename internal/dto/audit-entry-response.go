@@ -0,0 +1,18 @@
+package dto
+
+import (
+	"encoding/json"
+	"github.com/google/uuid"
+	"time"
+)
+
+type AuditEntryResponse struct {
+	ID        int64           `json:"id"`
+	Actor     string          `json:"actor"`
+	Action    string          `json:"action"`
+	WalletID  uuid.UUID       `json:"walletId"`
+	RequestID string          `json:"requestId,omitempty"`
+	Before    json.RawMessage `json:"before,omitempty"`
+	After     json.RawMessage `json:"after,omitempty"`
+	CreatedAt time.Time       `json:"createdAt"`
+}
@@ -0,0 +1,12 @@
+package dto
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+type BalanceAsOfResponse struct {
+	WalletID uuid.UUID `json:"walletId"`
+	Balance  float64   `json:"balance"`
+	AsOf     time.Time `json:"asOf"`
+}
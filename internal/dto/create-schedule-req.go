@@ -0,0 +1,11 @@
+package dto
+
+import "time"
+
+type CreateScheduleRequest struct {
+	OperationType string     `json:"operationType" validate:"required,oneof=DEPOSIT WITHDRAW"`
+	Amount        float64    `json:"amount" validate:"required,gt=0"`
+	Cron          string     `json:"cron" validate:"excluded_with=Interval"`
+	Interval      string     `json:"interval"`
+	StartAt       *time.Time `json:"startAt"`
+}
@@ -0,0 +1,15 @@
+package dto
+
+import "github.com/google/uuid"
+
+type CreateWalletRequest struct {
+	// WalletID is optional; a random ID is used if it is omitted.
+	WalletID uuid.UUID `json:"walletId"`
+	Product  string    `json:"product" validate:"max=50"`
+	// Currency is an ISO 4217 code, model.DefaultCurrency if omitted.
+	Currency string `json:"currency" validate:"omitempty,len=3,alpha,uppercase"`
+	// CustomerID is the external ID of the customer owning the wallet, if
+	// any. Label names the wallet among the customer's and needs one.
+	CustomerID string `json:"customerId" validate:"max=100"`
+	Label      string `json:"label" validate:"omitempty,max=50,excluded_without=CustomerID"`
+}
@@ -0,0 +1,8 @@
+package dto
+
+type CreateCustomerRequest struct {
+	// ExternalID is the customer's ID in the client's systems. Customers
+	// are looked up by it.
+	ExternalID string `json:"externalId" validate:"required,max=100"`
+	Name       string `json:"name" validate:"max=200"`
+}
@@ -0,0 +1,30 @@
+package dto
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+type CustomerResponse struct {
+	ExternalID string    `json:"externalId"`
+	Name       string    `json:"name,omitempty"`
+	TenantID   string    `json:"tenantId"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+type CustomerWalletResponse struct {
+	WalletID uuid.UUID `json:"walletId"`
+	Label    string    `json:"label,omitempty"`
+	Balance  float64   `json:"balance"`
+	Currency string    `json:"currency"`
+	Status   string    `json:"status"`
+	Product  string    `json:"product,omitempty"`
+}
+
+// CustomerBalanceResponse is the total balance of a customer's wallets in
+// one currency.
+type CustomerBalanceResponse struct {
+	Currency string  `json:"currency"`
+	Balance  float64 `json:"balance"`
+	Wallets  int     `json:"wallets"`
+}
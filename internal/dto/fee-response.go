@@ -0,0 +1,28 @@
+package dto
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+type FeeScheduleResponse struct {
+	ScheduleID    uuid.UUID `json:"scheduleId"`
+	OperationType string    `json:"operationType"`
+	Product       string    `json:"product,omitempty"`
+	Flat          float64   `json:"flat"`
+	Percent       float64   `json:"percent"`
+	Tiers         []FeeTier `json:"tiers"`
+	MinFee        *float64  `json:"minFee,omitempty"`
+	MaxFee        *float64  `json:"maxFee,omitempty"`
+	UpdatedAt     time.Time `json:"updatedAt"`
+}
+
+// FeeQuoteResponse prices an operation before it is made. Total is the
+// amount plus the fee.
+type FeeQuoteResponse struct {
+	WalletID      uuid.UUID `json:"walletId"`
+	OperationType string    `json:"operationType"`
+	Amount        float64   `json:"amount"`
+	Fee           float64   `json:"fee"`
+	Total         float64   `json:"total"`
+}
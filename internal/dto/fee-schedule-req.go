@@ -0,0 +1,20 @@
+package dto
+
+// FeeScheduleRequest sets the fee on one operation type for a product, or
+// for every product without a schedule of its own when Product is empty.
+// FX_OUT schedules price FX transfers, on the amount debited.
+type FeeScheduleRequest struct {
+	OperationType string    `json:"operationType" validate:"required,oneof=WITHDRAW FX_OUT"`
+	Product       string    `json:"product" validate:"max=50"`
+	Flat          float64   `json:"flat" validate:"gte=0"`
+	Percent       float64   `json:"percent" validate:"gte=0,lte=1"`
+	Tiers         []FeeTier `json:"tiers" validate:"dive"`
+	MinFee        *float64  `json:"minFee" validate:"omitempty,gte=0"`
+	MaxFee        *float64  `json:"maxFee" validate:"omitempty,gte=0"`
+}
+
+type FeeTier struct {
+	From    float64 `json:"from" validate:"gte=0"`
+	Flat    float64 `json:"flat" validate:"gte=0"`
+	Percent float64 `json:"percent" validate:"gte=0,lte=1"`
+}
@@ -0,0 +1,32 @@
+package dto
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// FXTransferRequest moves Amount, in the source wallet's currency, to a
+// wallet in another currency.
+type FXTransferRequest struct {
+	FromWalletID uuid.UUID `json:"fromWalletId" validate:"required"`
+	ToWalletID   uuid.UUID `json:"toWalletId" validate:"required"`
+	Amount       float64   `json:"amount" validate:"required,gt=0"`
+
+	// OperationID, when set, is used for the debit leg instead of a random
+	// ID, as in WalletOperationRequest.
+	OperationID uuid.UUID `json:"-"`
+}
+
+type SetExchangeRatesRequest struct {
+	Rates []ExchangeRate `json:"rates" validate:"required,min=1,dive"`
+}
+
+// ExchangeRate prices one unit of Base in Quote from ValidFrom until
+// ValidTo, or until a later rate takes over if ValidTo is omitted.
+type ExchangeRate struct {
+	Base      string     `json:"base" validate:"required,len=3,alpha,uppercase"`
+	Quote     string     `json:"quote" validate:"required,len=3,alpha,uppercase,nefield=Base"`
+	Rate      float64    `json:"rate" validate:"required,gt=0"`
+	ValidFrom time.Time  `json:"validFrom" validate:"required"`
+	ValidTo   *time.Time `json:"validTo,omitempty"`
+}
@@ -0,0 +1,17 @@
+package dto
+
+type FXQuoteResponse struct {
+	From      string  `json:"from"`
+	To        string  `json:"to"`
+	Amount    float64 `json:"amount"`
+	Converted float64 `json:"converted"`
+	MidRate   float64 `json:"midRate"`
+	Spread    float64 `json:"spread"`
+	Rate      float64 `json:"rate"`
+}
+
+type FXTransferResponse struct {
+	Debit      WalletOperationResponse `json:"debit"`
+	Credit     WalletOperationResponse `json:"credit"`
+	Conversion FXQuoteResponse         `json:"conversion"`
+}
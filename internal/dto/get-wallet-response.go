@@ -5,4 +5,9 @@ import "github.com/google/uuid"
 type GetWalletResponse struct {
 	WalletID uuid.UUID `json:"walletId"`
 	Balance  float64   `json:"balance"`
+	Currency string    `json:"currency"`
+	// CreditLimit is the approved overdraft and AvailableCredit the part of
+	// it not drawn on; both are zero for wallets without one.
+	CreditLimit     float64 `json:"creditLimit"`
+	AvailableCredit float64 `json:"availableCredit"`
 }
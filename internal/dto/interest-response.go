@@ -0,0 +1,26 @@
+package dto
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+type InterestRuleResponse struct {
+	RuleID        uuid.UUID  `json:"ruleId"`
+	WalletID      *uuid.UUID `json:"walletId,omitempty"`
+	Product       string     `json:"product,omitempty"`
+	AnnualRate    float64    `json:"annualRate"`
+	OverdraftRate float64    `json:"overdraftRate"`
+	DayCount      string     `json:"dayCount"`
+	CreatedAt     time.Time  `json:"createdAt"`
+	UpdatedAt     time.Time  `json:"updatedAt"`
+}
+
+type InterestAccrualResponse struct {
+	Date       string     `json:"date"`
+	Balance    float64    `json:"balance"`
+	AnnualRate float64    `json:"annualRate"`
+	DayCount   string     `json:"dayCount"`
+	Amount     float64    `json:"amount"`
+	PayoutID   *uuid.UUID `json:"payoutId,omitempty"`
+}
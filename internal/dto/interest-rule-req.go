@@ -0,0 +1,14 @@
+package dto
+
+import "github.com/google/uuid"
+
+// InterestRuleRequest sets the interest of one wallet or of a product;
+// exactly one of WalletID and Product must be given. OverdraftRate is
+// charged on negative balances and defaults to none.
+type InterestRuleRequest struct {
+	WalletID      *uuid.UUID `json:"walletId"`
+	Product       string     `json:"product" validate:"max=50"`
+	AnnualRate    *float64   `json:"annualRate" validate:"required,gte=0,lte=1"`
+	OverdraftRate float64    `json:"overdraftRate" validate:"gte=0,lte=1"`
+	DayCount      string     `json:"dayCount" validate:"required,oneof=ACT/365 ACT/360 ACT/ACT"`
+}
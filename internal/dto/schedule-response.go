@@ -0,0 +1,30 @@
+package dto
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+type ScheduleResponse struct {
+	ScheduleID    uuid.UUID `json:"scheduleId"`
+	WalletID      uuid.UUID `json:"walletId"`
+	OperationType string    `json:"operationType"`
+	Amount        float64   `json:"amount"`
+	Cron          string    `json:"cron,omitempty"`
+	Interval      string    `json:"interval,omitempty"`
+	Status        string    `json:"status"`
+	NextRunAt     time.Time `json:"nextRunAt"`
+	DueAt         time.Time `json:"dueAt"`
+	Attempts      int       `json:"attempts"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
+
+type ScheduleRunResponse struct {
+	RunID        uuid.UUID  `json:"runId"`
+	ScheduledFor time.Time  `json:"scheduledFor"`
+	Attempt      int        `json:"attempt"`
+	Status       string     `json:"status"`
+	OperationID  *uuid.UUID `json:"operationId,omitempty"`
+	Error        string     `json:"error,omitempty"`
+	CreatedAt    time.Time  `json:"createdAt"`
+}
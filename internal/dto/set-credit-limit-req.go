@@ -0,0 +1,5 @@
+package dto
+
+type SetCreditLimitRequest struct {
+	CreditLimit *float64 `json:"creditLimit" validate:"required,gte=0"`
+}
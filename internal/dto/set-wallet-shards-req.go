@@ -0,0 +1,5 @@
+package dto
+
+type SetWalletShardsRequest struct {
+	Shards *int `json:"shards" validate:"required,gte=0,lte=64"`
+}
@@ -0,0 +1,50 @@
+package dto
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+type WalletDetailsResponse struct {
+	WalletID uuid.UUID `json:"walletId"`
+	Balance  float64   `json:"balance"`
+	Status   string    `json:"status"`
+	Product  string    `json:"product,omitempty"`
+	Currency string    `json:"currency"`
+	TenantID string    `json:"tenantId"`
+	Label    string    `json:"label,omitempty"`
+	// CreditLimit is how far below zero the balance may be debited.
+	CreditLimit float64   `json:"creditLimit"`
+	ShardCount  int       `json:"shardCount"`
+	Version     int64     `json:"version"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+type ReconciliationResponse struct {
+	WalletID   uuid.UUID `json:"walletId"`
+	Balance    float64   `json:"balance"`
+	Ledger     float64   `json:"ledger"`
+	Difference float64   `json:"difference"`
+}
+
+type ReconciliationReportResponse struct {
+	Checked    int                      `json:"checked"`
+	Mismatches []ReconciliationResponse `json:"mismatches"`
+}
+
+type StatementLineResponse struct {
+	OperationID   uuid.UUID `json:"operationId"`
+	OperationType string    `json:"operationType"`
+	Amount        float64   `json:"amount"`
+	Balance       float64   `json:"balance"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
+
+type StatementResponse struct {
+	WalletID       uuid.UUID               `json:"walletId"`
+	From           *time.Time              `json:"from,omitempty"`
+	To             *time.Time              `json:"to,omitempty"`
+	OpeningBalance float64                 `json:"openingBalance"`
+	ClosingBalance float64                 `json:"closingBalance"`
+	Lines          []StatementLineResponse `json:"lines"`
+}
@@ -0,0 +1,16 @@
+package dto
+
+import "github.com/google/uuid"
+
+// WalletEventResponse is one message of a wallet's event stream. A
+// "balance" event reports the balance when the stream was opened, an
+// "operation" event a committed operation and the balance when it was
+// recorded. ID numbers the wallet's events in commit order; it is what a
+// client resumes the stream after.
+type WalletEventResponse struct {
+	ID        int64                    `json:"id"`
+	Type      string                   `json:"type"`
+	WalletID  uuid.UUID                `json:"walletId"`
+	Balance   float64                  `json:"balance"`
+	Operation *WalletOperationResponse `json:"operation,omitempty"`
+}
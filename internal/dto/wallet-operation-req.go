@@ -6,4 +6,17 @@ type WalletOperationRequest struct {
 	WalletID      uuid.UUID `json:"walletId" validate:"required"`
 	OperationType string    `json:"operationType" validate:"required,oneof=DEPOSIT WITHDRAW"`
 	Amount        float64   `json:"amount" validate:"required,gt=0"`
+	Description   string    `json:"description" validate:"max=500"`
+	// Metadata is any JSON object of at most model.MaxMetadataSize bytes.
+	Metadata map[string]any `json:"metadata"`
+	// ExternalRef identifies the operation in another system, such as a
+	// payment provider; a wallet accepts each reference once.
+	ExternalRef string `json:"externalRef" validate:"max=100"`
+
+	// ExpectedVersion is taken from the If-Match header, not the body.
+	ExpectedVersion *int64 `json:"-"`
+	// OperationID, when set, is used instead of a random ID so that
+	// replaying the request fails with a duplicate instead of applying it
+	// twice.
+	OperationID uuid.UUID `json:"-"`
 }
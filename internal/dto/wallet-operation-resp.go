@@ -3,9 +3,13 @@ package dto
 import "github.com/google/uuid"
 
 type WalletOperationResponse struct {
-	OperationID   uuid.UUID `json:"operationId"`
-	WalletID      uuid.UUID `json:"walletId"`
-	OperationType string    `json:"operationType"`
-	Amount        float64   `json:"amount"`
-	CreatedAt     string    `json:"createdAt"`
+	OperationID   uuid.UUID      `json:"operationId"`
+	WalletID      uuid.UUID      `json:"walletId"`
+	OperationType string         `json:"operationType"`
+	Amount        float64        `json:"amount"`
+	Fee           float64        `json:"fee,omitempty"`
+	Description   string         `json:"description,omitempty"`
+	Metadata      map[string]any `json:"metadata,omitempty"`
+	ExternalRef   string         `json:"externalRef,omitempty"`
+	CreatedAt     string         `json:"createdAt"`
 }
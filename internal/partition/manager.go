@@ -0,0 +1,306 @@
+// Package partition keeps up the monthly partitions of the operations
+// table, set up by migrations/019_operation_partitions.sql: it creates them
+// ahead of time and archives old ones to compressed files.
+package partition
+
+import (
+	"context"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"gorm.io/gorm"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"time"
+	"wallet-service/internal/wallet/model"
+)
+
+// Partition is a monthly partition of the operations table. It holds the
+// operations created in [Start, End), in UTC.
+type Partition struct {
+	Name  string
+	Start time.Time
+	End   time.Time
+}
+
+// monthOf returns the partition for the month of t.
+func monthOf(t time.Time) Partition {
+	t = t.UTC()
+	start := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return Partition{
+		Name:  fmt.Sprintf("operations_%04d_%02d", start.Year(), start.Month()),
+		Start: start,
+		End:   start.AddDate(0, 1, 0),
+	}
+}
+
+var partitionName = regexp.MustCompile(`^operations_(\d{4})_(\d{2})$`)
+
+// parseName returns the partition named name, or false for the default
+// partition and any other table.
+func parseName(name string) (Partition, bool) {
+	m := partitionName.FindStringSubmatch(name)
+	if m == nil {
+		return Partition{}, false
+	}
+	t, err := time.Parse("2006-01", m[1]+"-"+m[2])
+	if err != nil {
+		return Partition{}, false
+	}
+	return monthOf(t), true
+}
+
+// archivable returns the partitions that ended retention months or more
+// before the month of now.
+func archivable(parts []Partition, now time.Time, retention int) []Partition {
+	cutoff := monthOf(now).Start.AddDate(0, -retention, 0)
+	var out []Partition
+	for _, p := range parts {
+		if !p.End.After(cutoff) {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// Manager creates and archives the partitions of the operations table.
+type Manager struct {
+	db        *gorm.DB
+	ahead     int
+	retention int
+	dir       string
+	drop      bool
+}
+
+// NewManager returns a Manager creating partitions ahead months in advance
+// and archiving to dir those that ended retention months ago. A zero
+// retention archives nothing.
+//
+// Archived partitions are detached and renamed with a _detached suffix,
+// then left for an operator to drop once their files are safe. With drop
+// set they are dropped right away, which is only sound if dir is durable
+// storage shared by every instance, such as a network volume: whichever
+// instance archives a partition holds the only copy of its operations.
+func NewManager(db *gorm.DB, ahead, retention int, dir string, drop bool) *Manager {
+	return &Manager{db: db, ahead: ahead, retention: retention, dir: dir, drop: drop}
+}
+
+// Partitions lists the monthly partitions of the operations table, oldest
+// first.
+func (m *Manager) Partitions(ctx context.Context) ([]Partition, error) {
+	var names []string
+	err := m.db.WithContext(ctx).Raw(`SELECT c.relname FROM pg_inherits i
+		JOIN pg_class c ON c.oid = i.inhrelid
+		WHERE i.inhparent = 'operations'::regclass`).Scan(&names).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var parts []Partition
+	for _, name := range names {
+		if p, ok := parseName(name); ok {
+			parts = append(parts, p)
+		}
+	}
+	sort.Slice(parts, func(i, j int) bool { return parts[i].Start.Before(parts[j].Start) })
+	return parts, nil
+}
+
+// Ensure creates the missing partitions from the month of now to ahead
+// months after it, and returns those it created. Operations of a month
+// without a partition wait in the default partition until it is created.
+func (m *Manager) Ensure(ctx context.Context, now time.Time) ([]Partition, error) {
+	parts, err := m.Partitions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	exists := make(map[string]bool, len(parts))
+	for _, p := range parts {
+		exists[p.Name] = true
+	}
+
+	var created []Partition
+	first := monthOf(now).Start
+	for i := 0; i <= m.ahead; i++ {
+		p := monthOf(first.AddDate(0, i, 0))
+		if exists[p.Name] {
+			continue
+		}
+		var name string
+		if err := m.db.WithContext(ctx).Raw("SELECT operations_partition(?)", p.Start.Format(time.DateOnly)).Scan(&name).Error; err != nil {
+			return created, fmt.Errorf("partition %s: %w", p.Name, err)
+		}
+		created = append(created, p)
+	}
+	return created, nil
+}
+
+// Archive exports each partition past retention to a gzipped CSV file,
+// records the net total of each wallet's operations in it and a balance
+// snapshot at its end, then detaches it, and drops it if the Manager was
+// told to. The file is then the only
+// copy of the operations of a dropped partition.
+func (m *Manager) Archive(ctx context.Context, now time.Time) ([]model.OperationArchive, error) {
+	if m.retention <= 0 {
+		return nil, nil
+	}
+	parts, err := m.Partitions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var archived []model.OperationArchive
+	for _, p := range archivable(parts, now, m.retention) {
+		rec, err := m.archive(ctx, p)
+		if err != nil {
+			return archived, fmt.Errorf("archiving %s: %w", p.Name, err)
+		}
+		if rec != nil {
+			archived = append(archived, *rec)
+		}
+	}
+	return archived, nil
+}
+
+// archive archives p in one transaction, so that the partition stays in
+// place unless its file is written, read back whole and its totals
+// recorded. It returns nil if another instance archived p first.
+func (m *Manager) archive(ctx context.Context, p Partition) (*model.OperationArchive, error) {
+	table := pgx.Identifier{p.Name}.Sanitize()
+	path := filepath.Join(m.dir, p.Name+".csv.gz")
+
+	var rec *model.OperationArchive
+	err := m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext('operations_partition'))").Error; err != nil {
+			return err
+		}
+		var exists bool
+		if err := tx.Raw("SELECT to_regclass(?) IS NOT NULL", p.Name).Scan(&exists).Error; err != nil {
+			return err
+		}
+		if !exists {
+			return nil
+		}
+		if err := tx.Exec("LOCK TABLE " + table + " IN SHARE MODE").Error; err != nil {
+			return err
+		}
+
+		n, err := export(tx.Table(p.Name), path)
+		if err != nil {
+			return err
+		}
+		var rows int64
+		if err := tx.Table(p.Name).Count(&rows).Error; err != nil {
+			return err
+		}
+		if err := verify(path, rows); err != nil {
+			return err
+		}
+		err = tx.Exec(`INSERT INTO operation_archive_totals (wallet_id, range_start, range_end, tenant_id, net, operations)
+			SELECT wallet_id, ?, ?, MIN(tenant_id), SUM(CASE WHEN type IN ? THEN -amount ELSE amount END), COUNT(*)
+			FROM `+table+` GROUP BY wallet_id`, p.Start, p.End, model.DebitTypes).Error
+		if err != nil {
+			return err
+		}
+		rec = &model.OperationArchive{Name: p.Name, RangeStart: p.Start, RangeEnd: p.End, File: path, Operations: n}
+		if err := tx.Create(rec).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("ALTER TABLE operations DETACH PARTITION " + table).Error; err != nil {
+			return err
+		}
+		// Balances can no longer be summed from inside the month, so each
+		// wallet gets a snapshot at its end to start from instead.
+		if err := tx.Exec("SELECT operations_archive_snapshots(?, ?)", p.Start, p.End).Error; err != nil {
+			return err
+		}
+		if m.drop {
+			return tx.Exec("DROP TABLE " + table).Error
+		}
+		return tx.Exec("ALTER TABLE " + table + " RENAME TO " + pgx.Identifier{p.Name + "_detached"}.Sanitize()).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
+
+// export writes the operations of q, oldest first, to the file at path and
+// returns how many there were. The file appears complete or not at all.
+func export(q *gorm.DB, path string) (int64, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return 0, err
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	w, err := newWriter(f)
+	if err != nil {
+		return 0, err
+	}
+	rows, err := q.Order("created_at, id").Rows()
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var op model.Operation
+		if err := q.ScanRows(rows, &op); err != nil {
+			return 0, err
+		}
+		if err := w.Write(&op); err != nil {
+			return 0, err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	if err := w.Close(); err != nil {
+		return 0, err
+	}
+	if err := f.Sync(); err != nil {
+		return 0, err
+	}
+	if err := f.Close(); err != nil {
+		return 0, err
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		return 0, err
+	}
+	return w.n, syncDir(filepath.Dir(path))
+}
+
+// syncDir flushes the entries of the directory at path to disk, so that a
+// file renamed into it survives a crash.
+func syncDir(path string) error {
+	d, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+// verify reads back the archive file at path and checks that it holds
+// want operations.
+func verify(path string, want int64) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	n, err := count(f)
+	if err != nil {
+		return fmt.Errorf("reading back %s: %w", path, err)
+	}
+	if n != want {
+		return fmt.Errorf("%s holds %d operations, want %d", path, n, want)
+	}
+	return nil
+}
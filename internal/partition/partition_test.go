@@ -0,0 +1,101 @@
+package partition
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/csv"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"wallet-service/internal/wallet/model"
+)
+
+func TestMonthOf(t *testing.T) {
+	// Late on the 31st in New York is already the next month in UTC.
+	ny := time.FixedZone("EST", -5*3600)
+	p := monthOf(time.Date(2026, 1, 31, 22, 0, 0, 0, ny))
+
+	assert.Equal(t, "operations_2026_02", p.Name)
+	assert.Equal(t, time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC), p.Start)
+	assert.Equal(t, time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), p.End)
+}
+
+func TestParseName(t *testing.T) {
+	p, ok := parseName("operations_2025_12")
+	require.True(t, ok)
+	assert.Equal(t, monthOf(time.Date(2025, 12, 15, 0, 0, 0, 0, time.UTC)), p)
+
+	for _, name := range []string{"operations_default", "operations_2025_13", "operations_2025_1", "wallets"} {
+		_, ok := parseName(name)
+		assert.False(t, ok, name)
+	}
+}
+
+func TestArchivable(t *testing.T) {
+	var parts []Partition
+	for m := time.January; m <= time.December; m++ {
+		parts = append(parts, monthOf(time.Date(2025, m, 1, 0, 0, 0, 0, time.UTC)))
+	}
+	now := time.Date(2025, 10, 19, 12, 0, 0, 0, time.UTC)
+
+	got := archivable(parts, now, 6)
+
+	require.Len(t, got, 3, "January to March ended six months before October")
+	assert.Equal(t, "operations_2025_03", got[2].Name)
+	assert.Empty(t, archivable(parts, now, 12))
+}
+
+func TestWriter(t *testing.T) {
+	parent := uuid.New()
+	ops := []model.Operation{
+		{ID: uuid.New(), WalletID: uuid.New(), TenantID: "acme", Type: model.OperationDeposit, Amount: 12.5,
+			CreatedAt: time.Date(2025, 3, 4, 5, 6, 7, 0, time.UTC), Description: "salary, March",
+			Metadata: model.Metadata{"source": "payroll"}, ExternalRef: "pay-1"},
+		{ID: uuid.New(), WalletID: uuid.New(), TenantID: "default", Type: model.OperationFee, Amount: 0.1,
+			ParentID: &parent, CreatedAt: time.Date(2025, 3, 4, 5, 6, 8, 0, time.UTC)},
+	}
+
+	var buf bytes.Buffer
+	w, err := newWriter(&buf)
+	require.NoError(t, err)
+	for i := range ops {
+		require.NoError(t, w.Write(&ops[i]))
+	}
+	require.NoError(t, w.Close())
+	assert.EqualValues(t, 2, w.n)
+
+	gz, err := gzip.NewReader(&buf)
+	require.NoError(t, err)
+	records, err := csv.NewReader(gz).ReadAll()
+	require.NoError(t, err)
+
+	require.Len(t, records, 3)
+	assert.Equal(t, header, records[0])
+	assert.Equal(t, []string{
+		ops[0].ID.String(), ops[0].WalletID.String(), "acme", "DEPOSIT", "12.50", "",
+		"2025-03-04T05:06:07Z", "salary, March", `{"source":"payroll"}`, "pay-1",
+	}, records[1])
+	assert.Equal(t, parent.String(), records[2][5])
+	assert.Equal(t, "0.10", records[2][4])
+}
+
+func TestCount(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := newWriter(&buf)
+	require.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		require.NoError(t, w.Write(&model.Operation{ID: uuid.New(), WalletID: uuid.New(), Type: model.OperationDeposit, Amount: 1}))
+	}
+	require.NoError(t, w.Close())
+
+	n, err := count(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, n)
+
+	_, err = count(bytes.NewReader(buf.Bytes()[:buf.Len()-4]))
+	assert.Error(t, err, "a truncated file does not read back")
+}
@@ -0,0 +1,50 @@
+package partition
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// Worker periodically creates the partitions of the coming months and
+// archives those past retention.
+type Worker struct {
+	m        *Manager
+	interval time.Duration
+}
+
+func NewWorker(m *Manager, interval time.Duration) *Worker {
+	return &Worker{m: m, interval: interval}
+}
+
+// Run keeps up the partitions once per interval until ctx is done.
+// Workers in several processes take turns through an advisory lock.
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		now := time.Now()
+		created, err := w.m.Ensure(ctx, now)
+		if err != nil && ctx.Err() == nil {
+			slog.Error("creating operation partitions failed", "err", err)
+		}
+		for _, p := range created {
+			slog.Info("created operation partition", "partition", p.Name, "start", p.Start)
+		}
+
+		archived, err := w.m.Archive(ctx, now)
+		if err != nil && ctx.Err() == nil {
+			slog.Error("archiving operation partitions failed", "err", err)
+		}
+		for _, a := range archived {
+			slog.Info("archived operation partition", "partition", a.Name, "file", a.File, "operations", a.Operations)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
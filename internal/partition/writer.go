@@ -0,0 +1,97 @@
+package partition
+
+import (
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"time"
+	"wallet-service/internal/wallet/model"
+)
+
+// header names the columns of an archive file, a gzipped CSV file with one
+// record per operation.
+var header = []string{
+	"id", "wallet_id", "tenant_id", "type", "amount", "parent_id",
+	"created_at", "description", "metadata", "external_ref",
+}
+
+type writer struct {
+	gz  *gzip.Writer
+	csv *csv.Writer
+	n   int64
+}
+
+func newWriter(w io.Writer) (*writer, error) {
+	gz := gzip.NewWriter(w)
+	out := &writer{gz: gz, csv: csv.NewWriter(gz)}
+	if err := out.csv.Write(header); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (w *writer) Write(op *model.Operation) error {
+	var parent, metadata string
+	if op.ParentID != nil {
+		parent = op.ParentID.String()
+	}
+	if op.Metadata != nil {
+		buf, err := json.Marshal(op.Metadata)
+		if err != nil {
+			return err
+		}
+		metadata = string(buf)
+	}
+	w.n++
+	return w.csv.Write([]string{
+		op.ID.String(), op.WalletID.String(), op.TenantID, op.Type,
+		strconv.FormatFloat(op.Amount, 'f', 2, 64), parent,
+		op.CreatedAt.UTC().Format(time.RFC3339Nano), op.Description, metadata, op.ExternalRef,
+	})
+}
+
+// Close flushes the records written and ends the gzip stream, leaving the
+// underlying writer open.
+func (w *writer) Close() error {
+	w.csv.Flush()
+	if err := w.csv.Error(); err != nil {
+		return err
+	}
+	return w.gz.Close()
+}
+
+// count reads back an archive file written by a writer and returns how
+// many operations it holds, failing unless it decompresses and parses to
+// the end.
+func count(r io.Reader) (int64, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return 0, err
+	}
+	defer gz.Close()
+	rd := csv.NewReader(gz)
+	rd.FieldsPerRecord = len(header)
+	first, err := rd.Read()
+	if err != nil {
+		return 0, err
+	}
+	if !slices.Equal(first, header) {
+		return 0, fmt.Errorf("unexpected header %q", first)
+	}
+	var n int64
+	for {
+		_, err := rd.Read()
+		if errors.Is(err, io.EOF) {
+			return n, nil
+		}
+		if err != nil {
+			return n, err
+		}
+		n++
+	}
+}
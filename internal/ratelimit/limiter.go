@@ -0,0 +1,61 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// Limit describes a token bucket: Burst tokens at most, refilled at Rate
+// tokens per second. A zero Rate disables the limit.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+func (l Limit) Disabled() bool {
+	return l.Rate <= 0
+}
+
+type Result struct {
+	Allowed    bool
+	Remaining  int
+	RetryAfter time.Duration
+}
+
+// Store keeps token buckets by key. Implementations must make Take atomic
+// for a given key, across every process sharing the store.
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+	// Sweep drops buckets that have not been touched for longer than idle.
+	Sweep(ctx context.Context, idle time.Duration) error
+}
+
+type bucket struct {
+	tokens    float64
+	updatedAt time.Time
+}
+
+func newBucket(limit Limit, now time.Time) bucket {
+	return bucket{tokens: float64(limit.Burst), updatedAt: now}
+}
+
+// take refills the bucket for the time elapsed since its last update and
+// tries to consume a single token.
+func (b *bucket) take(limit Limit, now time.Time) Result {
+	if elapsed := now.Sub(b.updatedAt).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(float64(limit.Burst), b.tokens+elapsed*limit.Rate)
+		b.updatedAt = now
+	}
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return Result{Allowed: true, Remaining: int(b.tokens)}
+	}
+
+	wait := (1 - b.tokens) / limit.Rate
+	return Result{
+		Allowed:    false,
+		RetryAfter: time.Duration(math.Ceil(wait * float64(time.Second))),
+	}
+}
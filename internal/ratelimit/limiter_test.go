@@ -0,0 +1,82 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestStore(now *time.Time) *memoryStore {
+	s := NewMemoryStore().(*memoryStore)
+	s.now = func() time.Time { return *now }
+	return s
+}
+
+func TestMemoryStore_BurstThenReject(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1_700_000_000, 0)
+	store := newTestStore(&now)
+	limit := Limit{Rate: 2, Burst: 3}
+
+	for i := 0; i < 3; i++ {
+		res, err := store.Take(ctx, "k", limit)
+		assert.NoError(t, err)
+		assert.True(t, res.Allowed)
+		assert.Equal(t, 2-i, res.Remaining)
+	}
+
+	res, err := store.Take(ctx, "k", limit)
+	assert.NoError(t, err)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, 500*time.Millisecond, res.RetryAfter)
+}
+
+func TestMemoryStore_Refill(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1_700_000_000, 0)
+	store := newTestStore(&now)
+	limit := Limit{Rate: 1, Burst: 1}
+
+	res, _ := store.Take(ctx, "k", limit)
+	assert.True(t, res.Allowed)
+	res, _ = store.Take(ctx, "k", limit)
+	assert.False(t, res.Allowed)
+
+	now = now.Add(time.Second)
+	res, _ = store.Take(ctx, "k", limit)
+	assert.True(t, res.Allowed)
+
+	now = now.Add(time.Hour)
+	res, _ = store.Take(ctx, "k", limit)
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 0, res.Remaining, "refill must be capped at the burst size")
+}
+
+func TestMemoryStore_KeysAreIndependent(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1_700_000_000, 0)
+	store := newTestStore(&now)
+	limit := Limit{Rate: 1, Burst: 1}
+
+	a, _ := store.Take(ctx, "a", limit)
+	b, _ := store.Take(ctx, "b", limit)
+
+	assert.True(t, a.Allowed)
+	assert.True(t, b.Allowed)
+}
+
+func TestMemoryStore_Sweep(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1_700_000_000, 0)
+	store := newTestStore(&now)
+
+	_, _ = store.Take(ctx, "old", Limit{Rate: 1, Burst: 1})
+	now = now.Add(time.Hour)
+	_, _ = store.Take(ctx, "fresh", Limit{Rate: 1, Burst: 1})
+
+	assert.NoError(t, store.Sweep(ctx, time.Minute))
+	assert.NotContains(t, store.buckets, "old")
+	assert.Contains(t, store.buckets, "fresh")
+}
@@ -0,0 +1,50 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type memoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	now     func() time.Time
+}
+
+// NewMemoryStore returns a Store local to the current process.
+func NewMemoryStore() Store {
+	return &memoryStore{
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+func (s *memoryStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	now := s.now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, ok := s.buckets[key]
+	if !ok {
+		nb := newBucket(limit, now)
+		b = &nb
+		s.buckets[key] = b
+	}
+	return b.take(limit, now), nil
+}
+
+func (s *memoryStore) Sweep(ctx context.Context, idle time.Duration) error {
+	cutoff := s.now().Add(-idle)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, b := range s.buckets {
+		if b.updatedAt.Before(cutoff) {
+			delete(s.buckets, key)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package ratelimit
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+type bucketRow struct {
+	Key       string `gorm:"primaryKey"`
+	Tokens    float64
+	UpdatedAt time.Time `gorm:"autoUpdateTime:false"`
+}
+
+func (bucketRow) TableName() string {
+	return "rate_limit_buckets"
+}
+
+type postgresStore struct {
+	db *gorm.DB
+}
+
+// NewPostgresStore returns a Store backed by the rate_limit_buckets table so
+// that limits are shared by every service instance.
+func NewPostgresStore(db *gorm.DB) Store {
+	return &postgresStore{db: db}
+}
+
+func (s *postgresStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	var res Result
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+
+		seed := bucketRow{Key: key, Tokens: float64(limit.Burst), UpdatedAt: now}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&seed).Error; err != nil {
+			return err
+		}
+
+		var row bucketRow
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&row, "key = ?", key).Error; err != nil {
+			return err
+		}
+
+		b := bucket{tokens: row.Tokens, updatedAt: row.UpdatedAt}
+		res = b.take(limit, now)
+
+		return tx.Model(&bucketRow{}).Where("key = ?", key).Updates(map[string]any{
+			"tokens":     b.tokens,
+			"updated_at": b.updatedAt,
+		}).Error
+	})
+	return res, err
+}
+
+func (s *postgresStore) Sweep(ctx context.Context, idle time.Duration) error {
+	return s.db.WithContext(ctx).
+		Where("updated_at < ?", time.Now().Add(-idle)).
+		Delete(&bucketRow{}).Error
+}
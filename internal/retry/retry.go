@@ -0,0 +1,115 @@
+package retry
+
+import (
+	"context"
+	"expvar"
+	"log/slog"
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// Policy controls how often and how long to wait before retrying.
+// Delays grow exponentially from BaseDelay by Multiplier, are capped at
+// MaxDelay and then reduced by a random fraction of up to Jitter so that
+// competing callers spread out instead of colliding again.
+type Policy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+	Multiplier  float64
+	Jitter      float64
+}
+
+func DefaultPolicy() Policy {
+	return Policy{
+		MaxAttempts: 10,
+		BaseDelay:   50 * time.Millisecond,
+		MaxDelay:    time.Second,
+		Multiplier:  2,
+		Jitter:      0.5,
+	}
+}
+
+// Classifier reports whether err is transient and the call may be retried.
+// The returned reason is used for logs and metrics.
+type Classifier func(err error) (retryable bool, reason string)
+
+// Stats is published under /api/v1/admin/debug/vars as "retry". Keys are
+// "<name>.attempts", "<name>.retries", "<name>.exhausted" and
+// "<name>.reason.<reason>".
+var Stats = expvar.NewMap("retry")
+
+// Delay returns the backoff before retry number n (starting at 1), without
+// jitter.
+func (p Policy) Delay(n int) time.Duration {
+	d := float64(p.BaseDelay) * math.Pow(p.Multiplier, float64(n-1))
+	if max := float64(p.MaxDelay); p.MaxDelay > 0 && d > max {
+		d = max
+	}
+	return time.Duration(d)
+}
+
+func (p Policy) jittered(n int) time.Duration {
+	d := p.Delay(n)
+	if p.Jitter <= 0 {
+		return d
+	}
+	return time.Duration(float64(d) * (1 - p.Jitter*rand.Float64()))
+}
+
+// Do calls fn until it succeeds, returns an error the classifier does not
+// consider retryable, the attempts are exhausted or ctx is done. It returns
+// the last error of fn, or the context error if ctx ended while waiting.
+func Do(ctx context.Context, name string, p Policy, classify Classifier, fn func() error) error {
+	attempts := p.MaxAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		Stats.Add(name+".attempts", 1)
+		if err = fn(); err == nil {
+			if attempt > 1 {
+				slog.Info("operation succeeded after retry", "op", name, "attempts", attempt)
+			}
+			return nil
+		}
+
+		retryable, reason := classify(err)
+		if !retryable {
+			return err
+		}
+		Stats.Add(name+".reason."+reason, 1)
+
+		if attempt >= attempts {
+			Stats.Add(name+".exhausted", 1)
+			slog.Warn("retries exhausted", "op", name, "attempts", attempt, "reason", reason, "err", err)
+			return err
+		}
+
+		delay := p.jittered(attempt)
+		Stats.Add(name+".retries", 1)
+		slog.Debug("retrying operation", "op", name, "attempt", attempt, "reason", reason, "delay", delay, "err", err)
+
+		if werr := wait(ctx, delay); werr != nil {
+			return werr
+		}
+	}
+}
+
+func wait(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,91 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var errTransient = errors.New("transient")
+
+func classify(err error) (bool, string) {
+	return errors.Is(err, errTransient), "transient"
+}
+
+func fastPolicy(attempts int) Policy {
+	return Policy{MaxAttempts: attempts, BaseDelay: time.Millisecond, MaxDelay: 2 * time.Millisecond, Multiplier: 2}
+}
+
+func TestPolicy_DelayGrowsAndCaps(t *testing.T) {
+	p := Policy{BaseDelay: 10 * time.Millisecond, MaxDelay: 50 * time.Millisecond, Multiplier: 2}
+
+	assert.Equal(t, 10*time.Millisecond, p.Delay(1))
+	assert.Equal(t, 20*time.Millisecond, p.Delay(2))
+	assert.Equal(t, 40*time.Millisecond, p.Delay(3))
+	assert.Equal(t, 50*time.Millisecond, p.Delay(4))
+}
+
+func TestPolicy_JitterStaysWithinBounds(t *testing.T) {
+	p := Policy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second, Multiplier: 1, Jitter: 0.5}
+
+	for i := 0; i < 100; i++ {
+		d := p.jittered(1)
+		assert.GreaterOrEqual(t, d, 50*time.Millisecond)
+		assert.LessOrEqual(t, d, 100*time.Millisecond)
+	}
+}
+
+func TestDo_RetriesTransientErrors(t *testing.T) {
+	calls := 0
+
+	err := Do(context.Background(), "test", fastPolicy(5), classify, func() error {
+		calls++
+		if calls < 3 {
+			return errTransient
+		}
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, calls)
+}
+
+func TestDo_StopsOnPermanentError(t *testing.T) {
+	permanent := errors.New("permanent")
+	calls := 0
+
+	err := Do(context.Background(), "test", fastPolicy(5), classify, func() error {
+		calls++
+		return permanent
+	})
+
+	assert.ErrorIs(t, err, permanent)
+	assert.Equal(t, 1, calls)
+}
+
+func TestDo_GivesUpAfterMaxAttempts(t *testing.T) {
+	calls := 0
+
+	err := Do(context.Background(), "test", fastPolicy(4), classify, func() error {
+		calls++
+		return errTransient
+	})
+
+	assert.ErrorIs(t, err, errTransient)
+	assert.Equal(t, 4, calls)
+}
+
+func TestDo_StopsWaitingWhenContextEnds(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	p := Policy{MaxAttempts: 3, BaseDelay: time.Hour, MaxDelay: time.Hour, Multiplier: 1}
+
+	start := time.Now()
+	err := Do(ctx, "test", p, classify, func() error { return errTransient })
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), time.Second)
+}
@@ -0,0 +1,43 @@
+// Package tenant carries the tenant a request acts for through a context.
+// Repositories confine their queries to it, so one tenant's wallets are
+// invisible to another's requests.
+package tenant
+
+import "context"
+
+// Default is the tenant of requests that name none, and of the wallets
+// that existed before tenants were introduced.
+const Default = "default"
+
+// MaxIDLength bounds tenant IDs, matching the tenant_id columns.
+const MaxIDLength = 50
+
+type contextKey struct{}
+
+// unscoped replaces the tenant of a context confined to one.
+type unscoped struct{}
+
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// Unscoped returns a context that is not confined to a tenant, for the
+// service's own bookkeeping within a tenant's request, such as crediting
+// the fee revenue wallet.
+func Unscoped(ctx context.Context) context.Context {
+	return context.WithValue(ctx, contextKey{}, unscoped{})
+}
+
+// FromContext returns the tenant stored in ctx. ok is false for background
+// work, which is not confined to a tenant.
+func FromContext(ctx context.Context) (id string, ok bool) {
+	id, ok = ctx.Value(contextKey{}).(string)
+	return id, ok
+}
+
+// Visible reports whether a row belonging to tenant id may be seen from
+// ctx.
+func Visible(ctx context.Context, id string) bool {
+	t, ok := FromContext(ctx)
+	return !ok || t == id
+}
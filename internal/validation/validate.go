@@ -18,6 +18,16 @@ func FormatValidationErrors(err error) map[string]string {
 				errors[field] = field + " must be one of [" + e.Param() + "]"
 			case "gt":
 				errors[field] = field + " must be greater than " + e.Param()
+			case "gte":
+				errors[field] = field + " must be at least " + e.Param()
+			case "lte":
+				errors[field] = field + " must be at most " + e.Param()
+			case "max":
+				errors[field] = field + " must be at most " + e.Param() + " characters"
+			case "excluded_with":
+				errors[field] = field + " cannot be combined with " + toCamelCase(e.Param())
+			case "excluded_without":
+				errors[field] = field + " requires " + toCamelCase(e.Param())
 			default:
 				errors[field] = "invalid value for " + field
 			}
@@ -0,0 +1,314 @@
+package handler
+
+import (
+	"context"
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"strings"
+	"time"
+	"wallet-service/internal/db"
+	"wallet-service/internal/dto"
+	"wallet-service/internal/wallet/model"
+	"wallet-service/internal/wallet/repository"
+	"wallet-service/internal/wallet/service"
+	svcErrors "wallet-service/internal/wallet/service/errors"
+)
+
+const (
+	adminOperationsLimit    = 100
+	adminMaxOperationsLimit = 1000
+)
+
+// AdminHandler serves the staff-only wallet administration API used by
+// walletctl.
+type AdminHandler struct {
+	svc      *service.WalletService
+	validate *validator.Validate
+}
+
+func NewAdminHandler(svc *service.WalletService) *AdminHandler {
+	return &AdminHandler{
+		svc:      svc,
+		validate: validator.New(),
+	}
+}
+
+func (h *AdminHandler) CreateWallet(c *fiber.Ctx) error {
+	var req dto.CreateWalletRequest
+	if len(c.Body()) > 0 {
+		if err := parseBody(c, h.validate, &req); err != nil {
+			return err
+		}
+	}
+
+	wallet, err := h.svc.CreateWallet(c.UserContext(), req)
+	if err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusCreated).JSON(walletDetails(wallet))
+}
+
+func (h *AdminHandler) GetWallet(c *fiber.Ctx) error {
+	walletId, err := uuidParam(c, "wallet_uuid")
+	if err != nil {
+		return err
+	}
+
+	wallet, err := h.svc.LookupWallet(c.UserContext(), walletId)
+	if err != nil {
+		return err
+	}
+	return c.JSON(walletDetails(wallet))
+}
+
+func (h *AdminHandler) FreezeWallet(c *fiber.Ctx) error {
+	return h.changeStatus(c, h.svc.FreezeWallet)
+}
+
+func (h *AdminHandler) UnfreezeWallet(c *fiber.Ctx) error {
+	return h.changeStatus(c, h.svc.UnfreezeWallet)
+}
+
+func (h *AdminHandler) CloseWallet(c *fiber.Ctx) error {
+	return h.changeStatus(c, h.svc.CloseWallet)
+}
+
+func (h *AdminHandler) changeStatus(c *fiber.Ctx, change func(ctx context.Context, id uuid.UUID) (*model.Wallet, error)) error {
+	walletId, err := uuidParam(c, "wallet_uuid")
+	if err != nil {
+		return err
+	}
+
+	wallet, err := change(c.UserContext(), walletId)
+	if err != nil {
+		return err
+	}
+	return c.JSON(walletDetails(wallet))
+}
+
+func (h *AdminHandler) SetCreditLimit(c *fiber.Ctx) error {
+	walletId, err := uuidParam(c, "wallet_uuid")
+	if err != nil {
+		return err
+	}
+	var req dto.SetCreditLimitRequest
+	if err := parseBody(c, h.validate, &req); err != nil {
+		return err
+	}
+
+	wallet, err := h.svc.SetCreditLimit(c.UserContext(), walletId, *req.CreditLimit)
+	if err != nil {
+		return err
+	}
+	return c.JSON(walletDetails(wallet))
+}
+
+// SetShards spreads the wallet's balance over shard rows. It honours
+// If-Match like the client API's wallet operations.
+func (h *AdminHandler) SetShards(c *fiber.Ctx) error {
+	walletId, err := uuidParam(c, "wallet_uuid")
+	if err != nil {
+		return err
+	}
+	var req dto.SetWalletShardsRequest
+	if err := parseBody(c, h.validate, &req); err != nil {
+		return err
+	}
+	expected, err := ifMatchVersion(c)
+	if err != nil {
+		return err
+	}
+
+	if err := h.svc.SetWalletShards(c.UserContext(), walletId, *req.Shards, expected); err != nil {
+		return err
+	}
+	wallet, err := h.svc.LookupWallet(db.WithPrimary(c.UserContext()), walletId)
+	if err != nil {
+		return err
+	}
+	c.Set(fiber.HeaderETag, walletETag(wallet.Revision()))
+	return c.JSON(walletDetails(wallet))
+}
+
+// ListOperations serves the wallet's operations, oldest first, filtered by
+// the optional from, to, externalRef, description and metadata.<key> query
+// parameters.
+func (h *AdminHandler) ListOperations(c *fiber.Ctx) error {
+	walletId, err := uuidParam(c, "wallet_uuid")
+	if err != nil {
+		return err
+	}
+	filter := repository.OperationFilter{
+		ExternalRef: c.Query("externalRef"),
+		Description: c.Query("description"),
+		Limit:       c.QueryInt("limit", adminOperationsLimit),
+	}
+	if filter.From, filter.To, err = periodQuery(c); err != nil {
+		return err
+	}
+	if filter.Limit < 1 || filter.Limit > adminMaxOperationsLimit {
+		return svcErrors.InvalidParameter("limit", "limit must be between 1 and 1000")
+	}
+	for name, value := range c.Queries() {
+		if key, ok := strings.CutPrefix(name, "metadata."); ok && key != "" {
+			if filter.Metadata == nil {
+				filter.Metadata = make(map[string]string)
+			}
+			filter.Metadata[key] = value
+		}
+	}
+
+	ops, err := h.svc.ListOperations(c.UserContext(), walletId, filter)
+	if err != nil {
+		return err
+	}
+
+	resp := make([]dto.WalletOperationResponse, len(ops))
+	for i := range ops {
+		resp[i] = operationResponse(&ops[i])
+	}
+	return c.JSON(resp)
+}
+
+func (h *AdminHandler) Adjust(c *fiber.Ctx) error {
+	walletId, err := uuidParam(c, "wallet_uuid")
+	if err != nil {
+		return err
+	}
+
+	var req dto.AdjustmentRequest
+	if err := parseBody(c, h.validate, &req); err != nil {
+		return err
+	}
+	req.WalletID = walletId
+
+	op, err := h.svc.Adjust(c.UserContext(), req)
+	if err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusCreated).JSON(operationResponse(op))
+}
+
+func (h *AdminHandler) ReconcileWallet(c *fiber.Ctx) error {
+	walletId, err := uuidParam(c, "wallet_uuid")
+	if err != nil {
+		return err
+	}
+
+	rec, err := h.svc.Reconcile(c.UserContext(), walletId)
+	if err != nil {
+		return err
+	}
+	return c.JSON(reconciliationResponse(*rec))
+}
+
+func (h *AdminHandler) ReconcileAll(c *fiber.Ctx) error {
+	checked, mismatches, err := h.svc.ReconcileAll(c.UserContext())
+	if err != nil {
+		return err
+	}
+
+	resp := dto.ReconciliationReportResponse{
+		Checked:    checked,
+		Mismatches: make([]dto.ReconciliationResponse, len(mismatches)),
+	}
+	for i, rec := range mismatches {
+		resp.Mismatches[i] = reconciliationResponse(rec)
+	}
+	return c.JSON(resp)
+}
+
+func (h *AdminHandler) Statement(c *fiber.Ctx) error {
+	walletId, err := uuidParam(c, "wallet_uuid")
+	if err != nil {
+		return err
+	}
+	from, to, err := periodQuery(c)
+	if err != nil {
+		return err
+	}
+
+	st, err := h.svc.Statement(c.UserContext(), walletId, from, to)
+	if err != nil {
+		return err
+	}
+
+	resp := dto.StatementResponse{
+		WalletID:       st.WalletID,
+		OpeningBalance: st.OpeningBalance,
+		ClosingBalance: st.ClosingBalance,
+		Lines:          make([]dto.StatementLineResponse, len(st.Lines)),
+	}
+	if !from.IsZero() {
+		resp.From = &from
+	}
+	if !to.IsZero() {
+		resp.To = &to
+	}
+	for i, line := range st.Lines {
+		resp.Lines[i] = dto.StatementLineResponse{
+			OperationID:   line.Operation.ID,
+			OperationType: line.Operation.Type,
+			Amount:        line.Operation.Amount,
+			Balance:       line.Balance,
+			CreatedAt:     line.Operation.CreatedAt.UTC(),
+		}
+	}
+	return c.JSON(resp)
+}
+
+// periodQuery reads the optional from and to query parameters.
+func periodQuery(c *fiber.Ctx) (from, to time.Time, err error) {
+	for name, dst := range map[string]*time.Time{"from": &from, "to": &to} {
+		v := c.Query(name)
+		if v == "" {
+			continue
+		}
+		t, perr := time.Parse(time.RFC3339, v)
+		if perr != nil {
+			return time.Time{}, time.Time{}, svcErrors.InvalidParameter(name, name+" must be an RFC 3339 timestamp")
+		}
+		*dst = t
+	}
+	return from, to, nil
+}
+
+func walletDetails(w *model.Wallet) dto.WalletDetailsResponse {
+	return dto.WalletDetailsResponse{
+		WalletID:    w.ID,
+		Balance:     w.TotalBalance(),
+		Status:      string(w.Status),
+		Product:     w.Product,
+		Currency:    w.Currency,
+		TenantID:    w.TenantID,
+		Label:       w.Label,
+		CreditLimit: w.CreditLimit,
+		ShardCount:  w.ShardCount,
+		Version:     w.Revision(),
+		CreatedAt:   w.CreatedAt.UTC(),
+	}
+}
+
+func operationResponse(op *model.Operation) dto.WalletOperationResponse {
+	return dto.WalletOperationResponse{
+		OperationID:   op.ID,
+		WalletID:      op.WalletID,
+		OperationType: op.Type,
+		Amount:        op.Amount,
+		Fee:           op.Fee,
+		Description:   op.Description,
+		Metadata:      op.Metadata,
+		ExternalRef:   op.ExternalRef,
+		CreatedAt:     op.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+func reconciliationResponse(rec service.Reconciliation) dto.ReconciliationResponse {
+	return dto.ReconciliationResponse{
+		WalletID:   rec.WalletID,
+		Balance:    rec.Balance,
+		Ledger:     rec.Ledger,
+		Difference: rec.Difference(),
+	}
+}
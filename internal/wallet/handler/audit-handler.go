@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"wallet-service/internal/dto"
+	"wallet-service/internal/wallet/repository"
+	"wallet-service/internal/wallet/service"
+	svcErrors "wallet-service/internal/wallet/service/errors"
+)
+
+const auditEntriesLimit = 100
+
+type AuditHandler struct {
+	svc *service.AuditService
+}
+
+func NewAuditHandler(svc *service.AuditService) *AuditHandler {
+	return &AuditHandler{svc: svc}
+}
+
+// ListAuditEntries serves the audit log, newest first, filtered by the
+// optional actor, walletId, from and to query parameters.
+func (h *AuditHandler) ListAuditEntries(c *fiber.Ctx) error {
+	filter := repository.AuditFilter{
+		Actor: c.Query("actor"),
+		Limit: c.QueryInt("limit", auditEntriesLimit),
+	}
+	if filter.Limit < 1 || filter.Limit > service.MaxAuditEntries {
+		return svcErrors.InvalidParameter("limit", "limit must be between 1 and 1000")
+	}
+	if v := c.Query("walletId"); v != "" {
+		walletId, err := uuid.Parse(v)
+		if err != nil {
+			return svcErrors.InvalidParameter("walletId", "invalid wallet UUID")
+		}
+		filter.WalletID = walletId
+	}
+	var err error
+	if filter.From, filter.To, err = periodQuery(c); err != nil {
+		return err
+	}
+
+	entries, err := h.svc.ListAuditEntries(c.UserContext(), filter)
+	if err != nil {
+		return err
+	}
+
+	resp := make([]dto.AuditEntryResponse, len(entries))
+	for i, e := range entries {
+		resp[i] = dto.AuditEntryResponse{
+			ID:        e.ID,
+			Actor:     e.Actor,
+			Action:    e.Action,
+			WalletID:  e.WalletID,
+			RequestID: e.RequestID,
+			Before:    e.Before,
+			After:     e.After,
+			CreatedAt: e.CreatedAt.UTC(),
+		}
+	}
+	return c.JSON(resp)
+}
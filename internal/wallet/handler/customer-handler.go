@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"net/url"
+	"wallet-service/internal/dto"
+	"wallet-service/internal/wallet/model"
+	"wallet-service/internal/wallet/service"
+	svcErrors "wallet-service/internal/wallet/service/errors"
+)
+
+// maxExternalIDLength is the longest customer external ID accepted.
+const maxExternalIDLength = 100
+
+// CustomerHandler serves customers and the wallets they own. Clients
+// address customers by their external ID.
+type CustomerHandler struct {
+	svc      *service.CustomerService
+	validate *validator.Validate
+}
+
+func NewCustomerHandler(svc *service.CustomerService) *CustomerHandler {
+	return &CustomerHandler{
+		svc:      svc,
+		validate: validator.New(),
+	}
+}
+
+func (h *CustomerHandler) CreateCustomer(c *fiber.Ctx) error {
+	var req dto.CreateCustomerRequest
+	if err := parseBody(c, h.validate, &req); err != nil {
+		return err
+	}
+
+	customer, err := h.svc.CreateCustomer(c.UserContext(), req)
+	if err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusCreated).JSON(customerResponse(customer))
+}
+
+func (h *CustomerHandler) GetCustomer(c *fiber.Ctx) error {
+	externalID, err := customerParam(c)
+	if err != nil {
+		return err
+	}
+
+	customer, err := h.svc.GetCustomer(c.UserContext(), externalID)
+	if err != nil {
+		return err
+	}
+	return c.JSON(customerResponse(customer))
+}
+
+func (h *CustomerHandler) ListWallets(c *fiber.Ctx) error {
+	externalID, err := customerParam(c)
+	if err != nil {
+		return err
+	}
+
+	wallets, err := h.svc.ListWallets(c.UserContext(), externalID)
+	if err != nil {
+		return err
+	}
+
+	resp := make([]dto.CustomerWalletResponse, len(wallets))
+	for i := range wallets {
+		resp[i] = customerWalletResponse(&wallets[i])
+	}
+	return c.JSON(resp)
+}
+
+func (h *CustomerHandler) GetWalletByLabel(c *fiber.Ctx) error {
+	externalID, err := customerParam(c)
+	if err != nil {
+		return err
+	}
+	label, err := url.PathUnescape(c.Params("label"))
+	if err != nil || label == "" {
+		return svcErrors.InvalidParameter("label", "invalid wallet label")
+	}
+
+	wallet, err := h.svc.WalletByLabel(c.UserContext(), externalID, label)
+	if err != nil {
+		return err
+	}
+	return c.JSON(customerWalletResponse(wallet))
+}
+
+// Balances reports the customer's total balance in each currency its
+// wallets hold.
+func (h *CustomerHandler) Balances(c *fiber.Ctx) error {
+	externalID, err := customerParam(c)
+	if err != nil {
+		return err
+	}
+
+	balances, err := h.svc.Balances(c.UserContext(), externalID)
+	if err != nil {
+		return err
+	}
+
+	resp := make([]dto.CustomerBalanceResponse, len(balances))
+	for i, b := range balances {
+		resp[i] = dto.CustomerBalanceResponse{
+			Currency: b.Currency,
+			Balance:  b.Balance,
+			Wallets:  b.Wallets,
+		}
+	}
+	return c.JSON(resp)
+}
+
+// customerParam reads the customer's external ID from the route.
+func customerParam(c *fiber.Ctx) (string, error) {
+	id, err := url.PathUnescape(c.Params("customer_id"))
+	if err != nil || id == "" || len(id) > maxExternalIDLength {
+		return "", svcErrors.InvalidParameter("customer_id", "invalid customer ID")
+	}
+	return id, nil
+}
+
+func customerResponse(cu *model.Customer) dto.CustomerResponse {
+	return dto.CustomerResponse{
+		ExternalID: cu.ExternalID,
+		Name:       cu.Name,
+		TenantID:   cu.TenantID,
+		CreatedAt:  cu.CreatedAt.UTC(),
+	}
+}
+
+func customerWalletResponse(w *model.Wallet) dto.CustomerWalletResponse {
+	return dto.CustomerWalletResponse{
+		WalletID: w.ID,
+		Label:    w.Label,
+		Balance:  w.TotalBalance(),
+		Currency: w.Currency,
+		Status:   string(w.Status),
+		Product:  w.Product,
+	}
+}
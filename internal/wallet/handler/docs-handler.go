@@ -0,0 +1,30 @@
+package handler
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"wallet-service/internal/wallet/handler/openapi"
+)
+
+// DocsHandler serves the OpenAPI document of the API and a page that
+// renders it.
+type DocsHandler struct {
+	spec []byte
+}
+
+func NewDocsHandler() (*DocsHandler, error) {
+	spec, err := openapi.JSON()
+	if err != nil {
+		return nil, err
+	}
+	return &DocsHandler{spec: spec}, nil
+}
+
+func (h *DocsHandler) Spec(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	return c.Send(h.spec)
+}
+
+func (h *DocsHandler) Page(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+	return c.Send(openapi.Page)
+}
@@ -0,0 +1,213 @@
+package handler
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/gofiber/contrib/websocket"
+	"github.com/gofiber/fiber/v2"
+	"log/slog"
+	"strconv"
+	"time"
+	"wallet-service/internal/dto"
+	"wallet-service/internal/wallet/model"
+	"wallet-service/internal/wallet/service"
+	svcErrors "wallet-service/internal/wallet/service/errors"
+)
+
+// subscriptionLocal passes a socket's subscription through the upgrade.
+const subscriptionLocal = "subscription"
+
+// EventHandler streams wallet events over Server-Sent Events and
+// WebSocket. Both start with a "balance" event and then send an
+// "operation" event per committed operation; idle streams are kept open
+// with a heartbeat.
+type EventHandler struct {
+	svc       *service.EventService
+	heartbeat time.Duration
+	upgrade   fiber.Handler
+}
+
+func NewEventHandler(svc *service.EventService, heartbeat time.Duration) *EventHandler {
+	h := &EventHandler{svc: svc, heartbeat: heartbeat}
+	h.upgrade = websocket.New(h.serveSocket)
+	return h
+}
+
+// Stream serves the events as Server-Sent Events. Browsers reconnecting
+// send the Last-Event-ID header and get the events they missed; the
+// lastEventId query parameter does the same for a first connection.
+func (h *EventHandler) Stream(c *fiber.Ctx) error {
+	lastEventID := c.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("lastEventId")
+	}
+	sub, err := h.subscribe(c, lastEventID)
+	if err != nil {
+		return err
+	}
+
+	c.Set(fiber.HeaderContentType, "text/event-stream")
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Set("X-Accel-Buffering", "no")
+	// The server's write timeout is set once per response; a stream pushes
+	// it back on every write instead.
+	conn := c.Context().Conn()
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer sub.Close()
+		flush := func() error {
+			if err := conn.SetWriteDeadline(time.Now().Add(h.heartbeat)); err != nil {
+				return err
+			}
+			return w.Flush()
+		}
+		send := func(event dto.WalletEventResponse) error {
+			data, err := json.Marshal(event)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+			return flush()
+		}
+		ping := func() error {
+			w.WriteString(": ping\n\n")
+			return flush()
+		}
+		// A closed connection shows as a failed write, at the latest on the
+		// next heartbeat.
+		h.stream(context.Background(), sub, send, ping)
+	})
+	return nil
+}
+
+// Socket serves the events over a WebSocket, one JSON message per event.
+// Browsers cannot set headers on WebSocket requests, so resuming is done
+// with the lastEventId query parameter.
+func (h *EventHandler) Socket(c *fiber.Ctx) error {
+	if !websocket.IsWebSocketUpgrade(c) {
+		return svcErrors.ErrUpgradeRequired
+	}
+	sub, err := h.subscribe(c, c.Query("lastEventId"))
+	if err != nil {
+		return err
+	}
+
+	c.Locals(subscriptionLocal, sub)
+	if err := h.upgrade(c); err != nil {
+		sub.Close()
+		return svcErrors.ErrUpgradeRequired
+	}
+	return nil
+}
+
+func (h *EventHandler) serveSocket(conn *websocket.Conn) {
+	sub := conn.Locals(subscriptionLocal).(*service.Subscription)
+	defer sub.Close()
+
+	// Upgraded connections have no deadlines. Clients answer pings with
+	// pongs, so a connection quiet for two heartbeats is gone.
+	alive := func() error {
+		return conn.SetReadDeadline(time.Now().Add(2 * h.heartbeat))
+	}
+	if alive() != nil {
+		return
+	}
+	conn.SetPongHandler(func(string) error { return alive() })
+
+	// Messages from the client are ignored; reading them is how a closed
+	// connection is noticed.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		defer cancel()
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	send := func(event dto.WalletEventResponse) error {
+		if err := conn.SetWriteDeadline(time.Now().Add(h.heartbeat)); err != nil {
+			return err
+		}
+		return conn.WriteJSON(event)
+	}
+	ping := func() error {
+		return conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(h.heartbeat))
+	}
+	h.stream(ctx, sub, send, ping)
+}
+
+// subscribe opens a subscription after the given event ID, or after the
+// wallet's latest event if it is empty.
+func (h *EventHandler) subscribe(c *fiber.Ctx, lastEventID string) (*service.Subscription, error) {
+	walletId, err := uuidParam(c, "wallet_uuid")
+	if err != nil {
+		return nil, err
+	}
+
+	var after *int64
+	if lastEventID != "" {
+		id, err := strconv.ParseInt(lastEventID, 10, 64)
+		if err != nil || id < 0 {
+			return nil, svcErrors.InvalidParameter("lastEventId", "lastEventId must be an event ID")
+		}
+		after = &id
+	}
+	return h.svc.Subscribe(c.UserContext(), walletId, after)
+}
+
+// stream sends the balance and then the subscription's events until ctx is
+// done or sending fails, pinging after every heartbeat interval.
+func (h *EventHandler) stream(ctx context.Context, sub *service.Subscription, send func(dto.WalletEventResponse) error, ping func() error) {
+	err := send(dto.WalletEventResponse{
+		ID:       sub.Cursor(),
+		Type:     "balance",
+		WalletID: sub.WalletID,
+		Balance:  sub.Balance,
+	})
+	if err != nil {
+		return
+	}
+
+	ticker := time.NewTicker(h.heartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if ping() != nil {
+				return
+			}
+		case <-sub.Ready():
+			// A failed read ends the stream; the client resumes where it
+			// left off.
+			events, err := sub.Fetch(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					slog.Error("reading wallet events failed", "wallet", sub.WalletID, "err", err)
+				}
+				return
+			}
+			for i := range events {
+				if send(eventResponse(&events[i])) != nil {
+					return
+				}
+			}
+		}
+	}
+}
+
+func eventResponse(e *model.WalletEvent) dto.WalletEventResponse {
+	op := operationResponse(&e.Operation)
+	return dto.WalletEventResponse{
+		ID:        e.Seq,
+		Type:      "operation",
+		WalletID:  e.WalletID,
+		Balance:   e.Balance,
+		Operation: &op,
+	}
+}
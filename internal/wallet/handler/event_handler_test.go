@@ -0,0 +1,155 @@
+package handler
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fasthttp/websocket"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"wallet-service/internal/dto"
+	"wallet-service/internal/wallet/handler/middleware"
+	"wallet-service/internal/wallet/model"
+	"wallet-service/internal/wallet/repository"
+	"wallet-service/internal/wallet/service"
+)
+
+// startEventServer serves the event routes on a real listener, with server
+// timeouts far shorter than the streams are kept open.
+func startEventServer(t *testing.T) (addr string, wallets *service.WalletService, id uuid.UUID) {
+	t.Helper()
+	id = uuid.New()
+	repo := repository.NewMemoryWalletRepository(model.Wallet{ID: id, Balance: 10})
+	wallets = service.NewWalletService(repo)
+	events := service.NewEventService(repo.EventLog(), wallets)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go events.Run(ctx)
+
+	app := fiber.New(fiber.Config{
+		ErrorHandler:          middleware.ErrorHandler,
+		DisableStartupMessage: true,
+		ReadTimeout:           200 * time.Millisecond,
+		WriteTimeout:          200 * time.Millisecond,
+	})
+	RegisterRoutes(app, Handlers{Events: NewEventHandler(events, 100*time.Millisecond)}, RouteConfig{})
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go app.Listener(ln)
+	t.Cleanup(func() { app.Shutdown() })
+	return ln.Addr().String(), wallets, id
+}
+
+func deposit(t *testing.T, wallets *service.WalletService, id uuid.UUID, amount float64) {
+	t.Helper()
+	_, err := wallets.UpdateWalletBalance(context.Background(), dto.WalletOperationRequest{WalletID: id, OperationType: "DEPOSIT", Amount: amount})
+	require.NoError(t, err)
+}
+
+func TestEventHandler_ServerSentEvents(t *testing.T) {
+	addr, wallets, id := startEventServer(t)
+	deposit(t, wallets, id, 1)
+
+	req, err := http.NewRequest("GET", "http://"+addr+"/api/v1/wallets/"+id.String()+"/events", nil)
+	require.NoError(t, err)
+	req.Header.Set("Last-Event-ID", "0")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, "text/event-stream", resp.Header.Get(fiber.HeaderContentType))
+
+	events := make(chan dto.WalletEventResponse)
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			if data, ok := strings.CutPrefix(scanner.Text(), "data: "); ok {
+				var e dto.WalletEventResponse
+				if json.Unmarshal([]byte(data), &e) == nil {
+					events <- e
+				}
+			}
+		}
+		close(events)
+	}()
+
+	balance := <-events
+	assert.Equal(t, "balance", balance.Type)
+	assert.Equal(t, float64(11), balance.Balance)
+	missed := <-events
+	assert.Equal(t, "operation", missed.Type)
+	assert.Equal(t, float64(1), missed.Operation.Amount)
+
+	// Outlive the server's write timeout before the next event.
+	time.Sleep(500 * time.Millisecond)
+	deposit(t, wallets, id, 2)
+	select {
+	case e, ok := <-events:
+		require.True(t, ok, "stream closed")
+		assert.Equal(t, float64(2), e.Operation.Amount)
+		assert.Equal(t, float64(13), e.Balance)
+		assert.Greater(t, e.ID, missed.ID)
+	case <-time.After(time.Second):
+		t.Fatal("no event")
+	}
+}
+
+func TestEventHandler_WebSocket(t *testing.T) {
+	addr, wallets, id := startEventServer(t)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws://"+addr+"/api/v1/wallets/"+id.String()+"/events/ws", nil)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// Pings are only answered while reading.
+	events := make(chan dto.WalletEventResponse)
+	go func() {
+		for {
+			var e dto.WalletEventResponse
+			if conn.ReadJSON(&e) != nil {
+				close(events)
+				return
+			}
+			events <- e
+		}
+	}()
+
+	balance := <-events
+	assert.Equal(t, "balance", balance.Type)
+	assert.Equal(t, float64(10), balance.Balance)
+
+	// Outlive the server's read and write timeouts before the next event.
+	time.Sleep(500 * time.Millisecond)
+	deposit(t, wallets, id, 5)
+	select {
+	case e, ok := <-events:
+		require.True(t, ok, "connection closed")
+		assert.Equal(t, "operation", e.Type)
+		assert.Equal(t, float64(15), e.Balance)
+	case <-time.After(time.Second):
+		t.Fatal("no event")
+	}
+}
+
+func TestEventHandler_Errors(t *testing.T) {
+	addr, _, id := startEventServer(t)
+
+	for path, status := range map[string]int{
+		"/api/v1/wallets/" + uuid.NewString() + "/events":          fiber.StatusNotFound,
+		"/api/v1/wallets/" + id.String() + "/events?lastEventId=x": fiber.StatusBadRequest,
+		"/api/v1/wallets/" + id.String() + "/events/ws":            fiber.StatusUpgradeRequired,
+	} {
+		resp, err := http.Get("http://" + addr + path)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, status, resp.StatusCode, path)
+	}
+}
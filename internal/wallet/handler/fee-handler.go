@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"wallet-service/internal/dto"
+	"wallet-service/internal/wallet/model"
+	"wallet-service/internal/wallet/service"
+)
+
+// FeeHandler serves the admin API for fee schedules.
+type FeeHandler struct {
+	svc      *service.FeeService
+	validate *validator.Validate
+}
+
+func NewFeeHandler(svc *service.FeeService) *FeeHandler {
+	return &FeeHandler{
+		svc:      svc,
+		validate: validator.New(),
+	}
+}
+
+func (h *FeeHandler) SetSchedule(c *fiber.Ctx) error {
+	var req dto.FeeScheduleRequest
+	if err := parseBody(c, h.validate, &req); err != nil {
+		return err
+	}
+
+	schedule, err := h.svc.SetSchedule(c.UserContext(), req)
+	if err != nil {
+		return err
+	}
+	return c.JSON(feeScheduleResponse(schedule))
+}
+
+func (h *FeeHandler) ListSchedules(c *fiber.Ctx) error {
+	schedules, err := h.svc.ListSchedules(c.UserContext())
+	if err != nil {
+		return err
+	}
+
+	resp := make([]dto.FeeScheduleResponse, len(schedules))
+	for i := range schedules {
+		resp[i] = feeScheduleResponse(&schedules[i])
+	}
+	return c.JSON(resp)
+}
+
+func (h *FeeHandler) DeleteSchedule(c *fiber.Ctx) error {
+	scheduleId, err := uuidParam(c, "schedule_uuid")
+	if err != nil {
+		return err
+	}
+
+	if err := h.svc.DeleteSchedule(c.UserContext(), scheduleId); err != nil {
+		return err
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func feeScheduleResponse(f *model.FeeSchedule) dto.FeeScheduleResponse {
+	resp := dto.FeeScheduleResponse{
+		ScheduleID:    f.ID,
+		OperationType: f.OperationType,
+		Product:       f.Product,
+		Flat:          f.Flat,
+		Percent:       f.Percent,
+		Tiers:         make([]dto.FeeTier, len(f.Tiers)),
+		MinFee:        f.MinFee,
+		MaxFee:        f.MaxFee,
+		UpdatedAt:     f.UpdatedAt.UTC(),
+	}
+	for i, t := range f.Tiers {
+		resp.Tiers[i] = dto.FeeTier{From: t.From, Flat: t.Flat, Percent: t.Percent}
+	}
+	return resp
+}
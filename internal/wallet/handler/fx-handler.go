@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"strconv"
+	"strings"
+	"time"
+	"wallet-service/internal/dto"
+	"wallet-service/internal/wallet/service"
+	svcErrors "wallet-service/internal/wallet/service/errors"
+)
+
+type FXHandler struct {
+	svc      *service.FXService
+	validate *validator.Validate
+}
+
+func NewFXHandler(svc *service.FXService) *FXHandler {
+	return &FXHandler{
+		svc:      svc,
+		validate: validator.New(),
+	}
+}
+
+// Quote prices converting the amount query parameter from one currency to
+// another at the current rate.
+func (h *FXHandler) Quote(c *fiber.Ctx) error {
+	from, to := strings.ToUpper(c.Query("from")), strings.ToUpper(c.Query("to"))
+	if from == "" || to == "" {
+		return svcErrors.InvalidParameter("from", "from and to currencies are required")
+	}
+	amount, err := strconv.ParseFloat(c.Query("amount"), 64)
+	if err != nil {
+		return svcErrors.InvalidParameter("amount", "amount must be a number")
+	}
+
+	conv, err := h.svc.Convert(c.UserContext(), amount, from, to, time.Now())
+	if err != nil {
+		return err
+	}
+	return c.JSON(fxQuoteResponse(conv))
+}
+
+func (h *FXHandler) Transfer(c *fiber.Ctx) error {
+	var req dto.FXTransferRequest
+	if err := parseBody(c, h.validate, &req); err != nil {
+		return err
+	}
+
+	transfer, err := h.svc.Transfer(c.UserContext(), req)
+	if err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusCreated).JSON(dto.FXTransferResponse{
+		Debit:      operationResponse(transfer.Debit),
+		Credit:     operationResponse(transfer.Credit),
+		Conversion: fxQuoteResponse(&transfer.Conversion),
+	})
+}
+
+func (h *FXHandler) SetRates(c *fiber.Ctx) error {
+	var req dto.SetExchangeRatesRequest
+	if err := parseBody(c, h.validate, &req); err != nil {
+		return err
+	}
+
+	if err := h.svc.SetRates(c.UserContext(), req.Rates); err != nil {
+		return err
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// ListRates serves the stored rates, newest first per pair, filtered by the
+// optional base and quote query parameters.
+func (h *FXHandler) ListRates(c *fiber.Ctx) error {
+	rates, err := h.svc.ListRates(c.UserContext(), strings.ToUpper(c.Query("base")), strings.ToUpper(c.Query("quote")))
+	if err != nil {
+		return err
+	}
+
+	resp := make([]dto.ExchangeRate, len(rates))
+	for i, r := range rates {
+		resp[i] = dto.ExchangeRate{
+			Base:      r.Base,
+			Quote:     r.Quote,
+			Rate:      r.Rate,
+			ValidFrom: r.ValidFrom.UTC(),
+			ValidTo:   r.ValidTo,
+		}
+	}
+	return c.JSON(resp)
+}
+
+func fxQuoteResponse(conv *service.Conversion) dto.FXQuoteResponse {
+	return dto.FXQuoteResponse{
+		From:      conv.From,
+		To:        conv.To,
+		Amount:    conv.Amount,
+		Converted: conv.Converted,
+		MidRate:   conv.MidRate,
+		Spread:    conv.Spread,
+		Rate:      conv.Rate,
+	}
+}
@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"time"
+	"wallet-service/internal/dto"
+	"wallet-service/internal/wallet/model"
+	"wallet-service/internal/wallet/service"
+)
+
+// InterestHandler serves the admin API for interest rules and accruals.
+type InterestHandler struct {
+	svc      *service.InterestService
+	validate *validator.Validate
+}
+
+func NewInterestHandler(svc *service.InterestService) *InterestHandler {
+	return &InterestHandler{
+		svc:      svc,
+		validate: validator.New(),
+	}
+}
+
+func (h *InterestHandler) SetRule(c *fiber.Ctx) error {
+	var req dto.InterestRuleRequest
+	if err := parseBody(c, h.validate, &req); err != nil {
+		return err
+	}
+
+	rule, err := h.svc.SetRule(c.UserContext(), req)
+	if err != nil {
+		return err
+	}
+	return c.JSON(interestRuleResponse(rule))
+}
+
+func (h *InterestHandler) ListRules(c *fiber.Ctx) error {
+	rules, err := h.svc.ListRules(c.UserContext())
+	if err != nil {
+		return err
+	}
+
+	resp := make([]dto.InterestRuleResponse, len(rules))
+	for i := range rules {
+		resp[i] = interestRuleResponse(&rules[i])
+	}
+	return c.JSON(resp)
+}
+
+func (h *InterestHandler) DeleteRule(c *fiber.Ctx) error {
+	ruleId, err := uuidParam(c, "rule_uuid")
+	if err != nil {
+		return err
+	}
+
+	if err := h.svc.DeleteRule(c.UserContext(), ruleId); err != nil {
+		return err
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// ListAccruals serves a wallet's daily accruals, filtered by the optional
+// from and to query parameters.
+func (h *InterestHandler) ListAccruals(c *fiber.Ctx) error {
+	walletId, err := uuidParam(c, "wallet_uuid")
+	if err != nil {
+		return err
+	}
+	from, to, err := periodQuery(c)
+	if err != nil {
+		return err
+	}
+
+	accruals, err := h.svc.ListAccruals(c.UserContext(), walletId, from, to)
+	if err != nil {
+		return err
+	}
+
+	resp := make([]dto.InterestAccrualResponse, len(accruals))
+	for i, a := range accruals {
+		resp[i] = dto.InterestAccrualResponse{
+			Date:       a.Date.Format(time.DateOnly),
+			Balance:    a.Balance,
+			AnnualRate: a.AnnualRate,
+			DayCount:   string(a.DayCount),
+			Amount:     a.Amount,
+			PayoutID:   a.PayoutID,
+		}
+	}
+	return c.JSON(resp)
+}
+
+func interestRuleResponse(r *model.InterestRule) dto.InterestRuleResponse {
+	return dto.InterestRuleResponse{
+		RuleID:        r.ID,
+		WalletID:      r.WalletID,
+		Product:       r.Product,
+		AnnualRate:    r.AnnualRate,
+		OverdraftRate: r.OverdraftRate,
+		DayCount:      string(r.DayCount),
+		CreatedAt:     r.CreatedAt.UTC(),
+		UpdatedAt:     r.UpdatedAt.UTC(),
+	}
+}
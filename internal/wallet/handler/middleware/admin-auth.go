@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"github.com/gofiber/fiber/v2"
+	"strings"
+	"wallet-service/internal/audit"
+	svcErrors "wallet-service/internal/wallet/service/errors"
+)
+
+// AdminAuth admits only requests carrying "Authorization: Bearer <token>".
+// The caller is recorded as "admin", or "admin:<name>" if an X-Actor
+// header names them.
+func AdminAuth(token string) fiber.Handler {
+	want := []byte(token)
+	return func(c *fiber.Ctx) error {
+		got, ok := strings.CutPrefix(c.Get(fiber.HeaderAuthorization), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), want) != 1 {
+			c.Set(fiber.HeaderWWWAuthenticate, `Bearer realm="admin"`)
+			return WriteProblem(c, svcErrors.ErrUnauthorized)
+		}
+
+		actor := "admin"
+		if name := c.Get(ActorHeader); name != "" && len(name) <= maxActorNameLength {
+			actor += ":" + name
+		}
+		c.SetUserContext(audit.WithActor(c.UserContext(), actor))
+		return c.Next()
+	}
+}
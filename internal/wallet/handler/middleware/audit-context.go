@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"wallet-service/internal/audit"
+)
+
+const (
+	RequestIDHeader = "X-Request-ID"
+	// ActorHeader names the person behind an admin API call. The admin
+	// token is shared, so the name is taken on trust.
+	ActorHeader = "X-Actor"
+
+	maxRequestIDLength = 100
+	maxActorNameLength = 100
+)
+
+// AuditContext attaches the caller and a request ID to the request's user
+// context, where the service layer picks them up for the audit log. A
+// request ID sent by the client is kept and echoed back; otherwise one is
+// generated.
+func AuditContext() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		requestID := c.Get(RequestIDHeader)
+		if requestID == "" || len(requestID) > maxRequestIDLength {
+			requestID = uuid.NewString()
+		}
+		c.Set(RequestIDHeader, requestID)
+
+		actor := "ip:" + c.IP()
+		if apiKey := c.Get(APIKeyHeader); apiKey != "" {
+			actor = "key:" + apiKeyFingerprint(apiKey)
+		}
+
+		c.SetUserContext(audit.NewContext(c.UserContext(), audit.Info{Actor: actor, RequestID: requestID}))
+		return c.Next()
+	}
+}
@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"wallet-service/internal/audit"
+)
+
+const testAdminToken = "0123456789abcdef"
+
+func newAuditedApp() *fiber.App {
+	app := fiber.New()
+	app.Use(AuditContext())
+	whoami := func(c *fiber.Ctx) error {
+		info := audit.FromContext(c.UserContext())
+		return c.SendString(info.Actor + " " + info.RequestID)
+	}
+	app.Get("/whoami", whoami)
+	app.Get("/admin/whoami", AdminAuth(testAdminToken), whoami)
+	return app
+}
+
+func whoami(t *testing.T, app *fiber.App, path string, headers map[string]string) (string, string) {
+	t.Helper()
+	req := httptest.NewRequest("GET", path, nil)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	require.Equal(t, fiber.StatusOK, resp.StatusCode)
+	buf := make([]byte, 256)
+	n, _ := resp.Body.Read(buf)
+	return string(buf[:n]), resp.Header.Get(RequestIDHeader)
+}
+
+func TestAuditContext(t *testing.T) {
+	app := newAuditedApp()
+
+	body, requestID := whoami(t, app, "/whoami", map[string]string{APIKeyHeader: "secret", RequestIDHeader: "req-1"})
+	assert.Equal(t, "key:"+apiKeyFingerprint("secret")+" req-1", body)
+	assert.Equal(t, "req-1", requestID)
+
+	body, requestID = whoami(t, app, "/whoami", nil)
+	assert.NotEmpty(t, requestID)
+	assert.Equal(t, "ip:0.0.0.0 "+requestID, body)
+
+	body, _ = whoami(t, app, "/admin/whoami", map[string]string{
+		fiber.HeaderAuthorization: "Bearer " + testAdminToken,
+		ActorHeader:               "alice",
+		RequestIDHeader:           "req-2",
+	})
+	assert.Equal(t, "admin:alice req-2", body)
+}
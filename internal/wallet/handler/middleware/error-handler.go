@@ -1,27 +1,70 @@
 package middleware
 
 import (
+	"errors"
 	"github.com/gofiber/fiber/v2"
 	"log"
+	"net/http"
+	"strings"
+	"wallet-service/internal/audit"
 	svcErrors "wallet-service/internal/wallet/service/errors"
 )
 
+const (
+	// ProblemContentType is the media type of error responses, RFC 7807.
+	ProblemContentType = "application/problem+json"
+
+	problemTypePrefix = "urn:wallet-service:problem:"
+)
+
+// ErrorHandler renders every error returned by a handler as a problem
+// document. Domain errors are found with errors.As, however deeply they are
+// wrapped; Fiber's own errors, such as an unknown route, keep their status;
+// anything else is logged and reported as an internal error.
 func ErrorHandler(c *fiber.Ctx, err error) error {
-	switch err {
-	case svcErrors.ErrWalletNotFound:
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
-	case svcErrors.ErrInvalidOperation:
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
-	case svcErrors.ErrInsufficientFunds:
-		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
-	case svcErrors.ErrInvalidAmount:
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	var (
+		problem  *svcErrors.Error
+		fiberErr *fiber.Error
+	)
+	switch {
+	case errors.As(err, &problem):
+	case errors.As(err, &fiberErr):
+		problem = svcErrors.New(statusCode(fiberErr.Code), fiberErr.Code, fiberErr.Message)
 	default:
-
 		log.Printf("unexpected error: %v", err)
+		problem = svcErrors.ErrInternal
+	}
+	return WriteProblem(c, problem)
+}
+
+// WriteProblem writes problem as the response. Middleware that rejects a
+// request calls it directly, so the response has the same shape whether or
+// not the app installs ErrorHandler.
+func WriteProblem(c *fiber.Ctx, problem *svcErrors.Error) error {
+	body := fiber.Map{
+		"type":     problemTypePrefix + problem.Code,
+		"title":    http.StatusText(problem.Status),
+		"status":   problem.Status,
+		"detail":   problem.Message,
+		"code":     problem.Code,
+		"instance": c.Path(),
+	}
+	if requestID := audit.FromContext(c.UserContext()).RequestID; requestID != "" {
+		body["requestId"] = requestID
+	}
+	for k, v := range problem.Details {
+		if _, taken := body[k]; !taken {
+			body[k] = v
+		}
+	}
+	return c.Status(problem.Status).JSON(body, ProblemContentType)
+}
 
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "internal server error",
-		})
+// statusCode derives a code from an HTTP status: 404 is "not_found".
+func statusCode(status int) string {
+	text := http.StatusText(status)
+	if text == "" {
+		return "http_error"
 	}
+	return strings.ToLower(strings.NewReplacer(" ", "_", "-", "_", "'", "").Replace(text))
 }
@@ -0,0 +1,92 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	svcErrors "wallet-service/internal/wallet/service/errors"
+)
+
+func newProblemApp() *fiber.App {
+	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+	app.Use(AuditContext())
+	app.Get("/wrapped", func(c *fiber.Ctx) error {
+		return fmt.Errorf("withdraw: %w", svcErrors.ErrInsufficientFunds)
+	})
+	app.Get("/validation", func(c *fiber.Ctx) error {
+		return svcErrors.Validation(map[string]string{"amount": "amount is required"})
+	})
+	app.Get("/unexpected", func(c *fiber.Ctx) error {
+		return errors.New("connection reset")
+	})
+	return app
+}
+
+func getProblem(t *testing.T, app *fiber.App, path string) (int, map[string]any) {
+	t.Helper()
+	req := httptest.NewRequest("GET", path, nil)
+	req.Header.Set(RequestIDHeader, "req-1")
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, ProblemContentType, resp.Header.Get(fiber.HeaderContentType))
+	var problem map[string]any
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&problem))
+	return resp.StatusCode, problem
+}
+
+func TestErrorHandler(t *testing.T) {
+	app := newProblemApp()
+
+	status, problem := getProblem(t, app, "/wrapped")
+	assert.Equal(t, fiber.StatusConflict, status)
+	assert.Equal(t, map[string]any{
+		"type":      "urn:wallet-service:problem:insufficient_funds",
+		"title":     "Conflict",
+		"status":    float64(fiber.StatusConflict),
+		"detail":    "insufficient funds",
+		"code":      "insufficient_funds",
+		"instance":  "/wrapped",
+		"requestId": "req-1",
+	}, problem)
+
+	status, problem = getProblem(t, app, "/validation")
+	assert.Equal(t, fiber.StatusBadRequest, status)
+	assert.Equal(t, "validation_failed", problem["code"])
+	assert.Equal(t, map[string]any{"amount": "amount is required"}, problem["errors"])
+
+	status, problem = getProblem(t, app, "/unexpected")
+	assert.Equal(t, fiber.StatusInternalServerError, status)
+	assert.Equal(t, "internal_error", problem["code"])
+	assert.Equal(t, "internal server error", problem["detail"])
+
+	status, problem = getProblem(t, app, "/missing")
+	assert.Equal(t, fiber.StatusNotFound, status)
+	assert.Equal(t, "not_found", problem["code"])
+}
+
+func TestAdminAuth_Problem(t *testing.T) {
+	// The problem is written by the middleware itself, so it does not
+	// depend on the app's error handler.
+	status, problem := getProblem(t, newAuditedApp(), "/admin/whoami")
+	assert.Equal(t, fiber.StatusUnauthorized, status)
+	assert.Equal(t, "unauthorized", problem["code"])
+	assert.Equal(t, "req-1", problem["requestId"])
+}
+
+func TestError_IsMatchesCopies(t *testing.T) {
+	err := fmt.Errorf("lookup: %w", svcErrors.InvalidParameter("wallet_uuid", "invalid wallet UUID"))
+	assert.ErrorIs(t, err, svcErrors.ErrInvalidParameter)
+	assert.NotErrorIs(t, err, svcErrors.ErrValidation)
+
+	var problem *svcErrors.Error
+	require.ErrorAs(t, err, &problem)
+	assert.Equal(t, "wallet_uuid", problem.Details["parameter"])
+	assert.Empty(t, svcErrors.ErrInvalidParameter.Details, "copies leave the sentinel untouched")
+}
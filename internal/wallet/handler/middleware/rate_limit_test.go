@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"wallet-service/internal/ratelimit"
+)
+
+func newLimitedApp(client, wallet ratelimit.Limit) *fiber.App {
+	limiter := NewRateLimiter(ratelimit.NewMemoryStore(), client, wallet)
+	limiter.AddClient("client-a", "client-b")
+	app := fiber.New()
+	app.Use(limiter.PerClient())
+	app.Post("/wallet", limiter.PerWallet(), func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+	return app
+}
+
+func postWallet(t *testing.T, app *fiber.App, apiKey string, walletID uuid.UUID) int {
+	t.Helper()
+	body := `{"walletId":"` + walletID.String() + `","operationType":"DEPOSIT","amount":1}`
+	req := httptest.NewRequest("POST", "/wallet", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if apiKey != "" {
+		req.Header.Set(APIKeyHeader, apiKey)
+	}
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	if resp.StatusCode == fiber.StatusTooManyRequests {
+		assert.NotEmpty(t, resp.Header.Get(fiber.HeaderRetryAfter))
+	}
+	return resp.StatusCode
+}
+
+func TestRateLimiter_PerClient(t *testing.T) {
+	app := newLimitedApp(ratelimit.Limit{Rate: 0.1, Burst: 2}, ratelimit.Limit{})
+
+	assert.Equal(t, fiber.StatusOK, postWallet(t, app, "client-a", uuid.New()))
+	assert.Equal(t, fiber.StatusOK, postWallet(t, app, "client-a", uuid.New()))
+	assert.Equal(t, fiber.StatusTooManyRequests, postWallet(t, app, "client-a", uuid.New()))
+	assert.Equal(t, fiber.StatusOK, postWallet(t, app, "client-b", uuid.New()))
+}
+
+func TestRateLimiter_UnknownKeysShareIPBucket(t *testing.T) {
+	app := newLimitedApp(ratelimit.Limit{Rate: 0.1, Burst: 2}, ratelimit.Limit{})
+
+	assert.Equal(t, fiber.StatusOK, postWallet(t, app, "made-up-1", uuid.New()))
+	assert.Equal(t, fiber.StatusOK, postWallet(t, app, "", uuid.New()))
+	assert.Equal(t, fiber.StatusTooManyRequests, postWallet(t, app, "made-up-2", uuid.New()), "unknown keys are limited by IP")
+	assert.Equal(t, fiber.StatusOK, postWallet(t, app, "client-a", uuid.New()))
+}
+
+func TestRateLimiter_PerWallet(t *testing.T) {
+	app := newLimitedApp(ratelimit.Limit{}, ratelimit.Limit{Rate: 0.1, Burst: 1})
+	hot := uuid.New()
+
+	assert.Equal(t, fiber.StatusOK, postWallet(t, app, "client-a", hot))
+	assert.Equal(t, fiber.StatusTooManyRequests, postWallet(t, app, "client-b", hot))
+	assert.Equal(t, fiber.StatusOK, postWallet(t, app, "client-b", uuid.New()))
+}
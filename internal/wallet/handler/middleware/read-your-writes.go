@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"strconv"
+	"wallet-service/internal/db"
+)
+
+// ReadYourWritesHeader asks for the request's reads to go to the primary
+// database, so that they see the client's earlier writes however far the
+// read replicas lag.
+const ReadYourWritesHeader = "X-Read-Your-Writes"
+
+// ReadYourWrites routes the reads of requests with ReadYourWritesHeader set
+// to a true value to the primary database.
+func ReadYourWrites() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if on, err := strconv.ParseBool(c.Get(ReadYourWritesHeader)); err == nil && on {
+			c.SetUserContext(db.WithPrimary(c.UserContext()))
+		}
+		return c.Next()
+	}
+}
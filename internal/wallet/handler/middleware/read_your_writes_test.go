@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"wallet-service/internal/db"
+)
+
+func TestReadYourWrites(t *testing.T) {
+	app := fiber.New()
+	app.Use(ReadYourWrites())
+	app.Get("/", func(c *fiber.Ctx) error {
+		return c.SendString(strconv.FormatBool(db.ReadsFromPrimary(c.UserContext())))
+	})
+
+	for header, want := range map[string]string{"": "false", "true": "true", "1": "true", "false": "false", "maybe": "false"} {
+		req := httptest.NewRequest("GET", "/", nil)
+		if header != "" {
+			req.Header.Set(ReadYourWritesHeader, header)
+		}
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		buf := make([]byte, 16)
+		n, _ := resp.Body.Read(buf)
+		assert.Equal(t, want, string(buf[:n]), "header %q", header)
+	}
+}
@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"wallet-service/internal/tenant"
+	svcErrors "wallet-service/internal/wallet/service/errors"
+)
+
+// TenantHeader names the tenant a request acts for.
+const TenantHeader = "X-Tenant-ID"
+
+// TenantResolver decides which tenant a request acts for and attaches it
+// to the request's user context, where the repositories pick it up.
+type TenantResolver struct {
+	// keys maps API key fingerprints to their tenant.
+	keys        map[string]string
+	known       map[string]struct{}
+	trustHeader bool
+}
+
+// NewTenantResolver resolves client requests from their API key. With
+// trustHeader, client requests without a known key may name their tenant
+// in the X-Tenant-ID header, e.g. behind a gateway that authenticated them.
+func NewTenantResolver(trustHeader bool) *TenantResolver {
+	return &TenantResolver{
+		keys:        make(map[string]string),
+		known:       map[string]struct{}{tenant.Default: {}},
+		trustHeader: trustHeader,
+	}
+}
+
+// AddTenant makes the tenant known and assigns it the API keys.
+func (r *TenantResolver) AddTenant(id string, apiKeys ...string) {
+	r.known[id] = struct{}{}
+	for _, key := range apiKeys {
+		r.keys[apiKeyFingerprint(key)] = id
+	}
+}
+
+// Client resolves the tenant of a client request: that of its API key, or
+// the trusted header, or the default tenant. A header naming a tenant the
+// caller may not act for is rejected.
+func (r *TenantResolver) Client() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		header := c.Get(TenantHeader)
+		id := tenant.Default
+		if owner, ok := r.keyTenant(c.Get(APIKeyHeader)); ok {
+			if header != "" && header != owner {
+				return WriteProblem(c, svcErrors.ErrTenantForbidden)
+			}
+			id = owner
+		} else if header != "" {
+			if !r.trustHeader {
+				return WriteProblem(c, svcErrors.ErrTenantForbidden)
+			}
+			if _, ok := r.known[header]; !ok {
+				return WriteProblem(c, svcErrors.ErrUnknownTenant)
+			}
+			id = header
+		}
+
+		c.SetUserContext(tenant.NewContext(c.UserContext(), id))
+		return c.Next()
+	}
+}
+
+// Admin resolves the tenant of an admin request from the header, which
+// admins may set to any known tenant, or else the default tenant. It must
+// run after AdminAuth.
+func (r *TenantResolver) Admin() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id := c.Get(TenantHeader)
+		if id == "" {
+			id = tenant.Default
+		}
+		if _, ok := r.known[id]; !ok {
+			return WriteProblem(c, svcErrors.ErrUnknownTenant)
+		}
+
+		c.SetUserContext(tenant.NewContext(c.UserContext(), id))
+		return c.Next()
+	}
+}
+
+func (r *TenantResolver) keyTenant(apiKey string) (string, bool) {
+	if apiKey == "" {
+		return "", false
+	}
+	id, ok := r.keys[apiKeyFingerprint(apiKey)]
+	return id, ok
+}
@@ -0,0 +1,96 @@
+package middleware
+
+import (
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"wallet-service/internal/tenant"
+)
+
+func newTenantApp(trustHeader bool) *fiber.App {
+	r := NewTenantResolver(trustHeader)
+	r.AddTenant("acme", "acme-key")
+	r.AddTenant("globex")
+
+	whichTenant := func(c *fiber.Ctx) error {
+		id, _ := tenant.FromContext(c.UserContext())
+		return c.SendString(id)
+	}
+	app := fiber.New()
+	app.Get("/tenant", r.Client(), whichTenant)
+	app.Get("/admin/tenant", AdminAuth(testAdminToken), r.Admin(), whichTenant)
+	return app
+}
+
+func resolveTenant(t *testing.T, app *fiber.App, path string, headers map[string]string) (int, string) {
+	t.Helper()
+	req := httptest.NewRequest("GET", path, nil)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp.StatusCode, string(body)
+}
+
+func TestTenantResolver_Client(t *testing.T) {
+	app := newTenantApp(false)
+
+	for _, tc := range []struct {
+		name    string
+		headers map[string]string
+		status  int
+		tenant  string
+	}{
+		{"no key", nil, fiber.StatusOK, tenant.Default},
+		{"unknown key", map[string]string{APIKeyHeader: "other"}, fiber.StatusOK, tenant.Default},
+		{"tenant key", map[string]string{APIKeyHeader: "acme-key"}, fiber.StatusOK, "acme"},
+		{"header repeats key tenant", map[string]string{APIKeyHeader: "acme-key", TenantHeader: "acme"}, fiber.StatusOK, "acme"},
+		{"header contradicts key", map[string]string{APIKeyHeader: "acme-key", TenantHeader: "globex"}, fiber.StatusForbidden, ""},
+		{"untrusted header", map[string]string{TenantHeader: "globex"}, fiber.StatusForbidden, ""},
+	} {
+		status, body := resolveTenant(t, app, "/tenant", tc.headers)
+		assert.Equal(t, tc.status, status, tc.name)
+		if tc.status == fiber.StatusOK {
+			assert.Equal(t, tc.tenant, body, tc.name)
+		}
+	}
+}
+
+func TestTenantResolver_TrustedHeader(t *testing.T) {
+	app := newTenantApp(true)
+
+	status, body := resolveTenant(t, app, "/tenant", map[string]string{TenantHeader: "globex"})
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Equal(t, "globex", body)
+
+	status, _ = resolveTenant(t, app, "/tenant", map[string]string{TenantHeader: "initech"})
+	assert.Equal(t, fiber.StatusBadRequest, status)
+
+	// A key still wins over the header.
+	status, _ = resolveTenant(t, app, "/tenant", map[string]string{APIKeyHeader: "acme-key", TenantHeader: "globex"})
+	assert.Equal(t, fiber.StatusForbidden, status)
+}
+
+func TestTenantResolver_Admin(t *testing.T) {
+	app := newTenantApp(false)
+	auth := "Bearer " + testAdminToken
+
+	status, body := resolveTenant(t, app, "/admin/tenant", map[string]string{fiber.HeaderAuthorization: auth})
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Equal(t, tenant.Default, body)
+
+	status, body = resolveTenant(t, app, "/admin/tenant", map[string]string{fiber.HeaderAuthorization: auth, TenantHeader: "globex"})
+	assert.Equal(t, fiber.StatusOK, status)
+	assert.Equal(t, "globex", body)
+
+	status, _ = resolveTenant(t, app, "/admin/tenant", map[string]string{fiber.HeaderAuthorization: auth, TenantHeader: "initech"})
+	assert.Equal(t, fiber.StatusBadRequest, status)
+}
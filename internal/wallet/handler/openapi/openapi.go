@@ -0,0 +1,36 @@
+// Package openapi holds the OpenAPI 3 document of the HTTP API, written by
+// hand in openapi.yaml. Tests in the handler package check it against the
+// registered routes and the DTOs.
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed openapi.yaml
+var spec []byte
+
+// Page renders /openapi.json with Redoc.
+//
+//go:embed docs.html
+var Page []byte
+
+// Document returns the parsed document.
+func Document() (map[string]any, error) {
+	var doc map[string]any
+	if err := yaml.Unmarshal(spec, &doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// JSON returns the document as JSON, the form it is served in.
+func JSON() ([]byte, error) {
+	doc, err := Document()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(doc)
+}
@@ -0,0 +1,32 @@
+package handler
+
+import (
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"strings"
+	"wallet-service/internal/validation"
+	svcErrors "wallet-service/internal/wallet/service/errors"
+)
+
+// uuidParam reads a UUID route parameter such as "wallet_uuid".
+func uuidParam(c *fiber.Ctx, name string) (uuid.UUID, error) {
+	id, err := uuid.Parse(c.Params(name))
+	if err != nil {
+		what := strings.TrimSuffix(name, "_uuid")
+		return uuid.Nil, svcErrors.InvalidParameter(name, "invalid "+what+" UUID")
+	}
+	return id, nil
+}
+
+// parseBody decodes the request body into out and validates it, reporting
+// failures as problems the error handler can render.
+func parseBody(c *fiber.Ctx, validate *validator.Validate, out any) error {
+	if err := c.BodyParser(out); err != nil {
+		return svcErrors.ErrMalformedBody
+	}
+	if err := validate.Struct(out); err != nil {
+		return svcErrors.Validation(validation.FormatValidationErrors(err))
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package handler
+
+import "github.com/gofiber/fiber/v2"
+
+// Handlers serve the routes of the HTTP API. The admin handlers are only
+// used when the admin API is enabled.
+type Handlers struct {
+	Wallet   *WalletHandler
+	Schedule *ScheduleHandler
+	FX       *FXHandler
+	Events   *EventHandler
+	Customer *CustomerHandler
+	Docs     *DocsHandler
+
+	Admin    *AdminHandler
+	Audit    *AuditHandler
+	Interest *InterestHandler
+	Fee      *FeeHandler
+}
+
+// RouteConfig holds the middleware the routes are wrapped in. Nil
+// middleware is left out.
+type RouteConfig struct {
+	// ClientLimit rate limits every API call, WalletLimit the wallet
+	// operations.
+	ClientLimit fiber.Handler
+	WalletLimit fiber.Handler
+	// AdminAuth guards the admin API, which is not routed without it.
+	AdminAuth fiber.Handler
+	// Tenant and AdminTenant decide the tenant client and admin requests
+	// act for.
+	Tenant      fiber.Handler
+	AdminTenant fiber.Handler
+}
+
+// RegisterRoutes routes the HTTP API on app. The OpenAPI document served at
+// /openapi.json describes exactly these routes.
+func RegisterRoutes(app *fiber.App, h Handlers, cfg RouteConfig) {
+	app.Get("/openapi.json", h.Docs.Spec)
+	app.Get("/docs", h.Docs.Page)
+
+	api := app.Group("/api/v1")
+	walletOps := []fiber.Handler{h.Wallet.UpdateWalletBalance}
+	if cfg.ClientLimit != nil {
+		api.Use(cfg.ClientLimit)
+	}
+	if cfg.WalletLimit != nil {
+		walletOps = append([]fiber.Handler{cfg.WalletLimit}, walletOps...)
+	}
+	// The admin API is routed below /api/v1 too, so the client tenant is
+	// resolved per route rather than for the whole group.
+	client := func(handlers ...fiber.Handler) []fiber.Handler {
+		if cfg.Tenant == nil {
+			return handlers
+		}
+		return append([]fiber.Handler{cfg.Tenant}, handlers...)
+	}
+	api.Get("/wallets/:wallet_uuid", client(h.Wallet.GetWalletBalance)...)
+	api.Get("/wallets/:wallet_uuid/balance", client(h.Wallet.GetBalanceAsOf)...)
+	api.Post("/wallet", client(walletOps...)...)
+	api.Post("/wallet/quote", client(h.Wallet.QuoteFee)...)
+	api.Get("/wallets/:wallet_uuid/events", client(h.Events.Stream)...)
+	api.Get("/wallets/:wallet_uuid/events/ws", client(h.Events.Socket)...)
+
+	api.Post("/wallets/:wallet_uuid/schedules", client(h.Schedule.CreateSchedule)...)
+	api.Get("/wallets/:wallet_uuid/schedules", client(h.Schedule.ListSchedules)...)
+	api.Get("/schedules/:schedule_uuid", client(h.Schedule.GetSchedule)...)
+	api.Get("/schedules/:schedule_uuid/runs", client(h.Schedule.ListRuns)...)
+	api.Post("/schedules/:schedule_uuid/pause", client(h.Schedule.PauseSchedule)...)
+	api.Post("/schedules/:schedule_uuid/resume", client(h.Schedule.ResumeSchedule)...)
+	api.Post("/schedules/:schedule_uuid/cancel", client(h.Schedule.CancelSchedule)...)
+
+	api.Get("/customers/:customer_id", client(h.Customer.GetCustomer)...)
+	api.Get("/customers/:customer_id/wallets", client(h.Customer.ListWallets)...)
+	api.Get("/customers/:customer_id/wallets/:label", client(h.Customer.GetWalletByLabel)...)
+	api.Get("/customers/:customer_id/balances", client(h.Customer.Balances)...)
+
+	api.Get("/fx/quote", client(h.FX.Quote)...)
+	api.Post("/fx/transfers", client(h.FX.Transfer)...)
+
+	if cfg.AdminAuth == nil {
+		return
+	}
+	admin := api.Group("/admin", cfg.AdminAuth)
+	if cfg.AdminTenant != nil {
+		admin.Use(cfg.AdminTenant)
+	}
+	admin.Post("/wallets", h.Admin.CreateWallet)
+	admin.Post("/customers", h.Customer.CreateCustomer)
+	admin.Get("/wallets/:wallet_uuid", h.Admin.GetWallet)
+	admin.Post("/wallets/:wallet_uuid/freeze", h.Admin.FreezeWallet)
+	admin.Post("/wallets/:wallet_uuid/unfreeze", h.Admin.UnfreezeWallet)
+	admin.Post("/wallets/:wallet_uuid/close", h.Admin.CloseWallet)
+	admin.Put("/wallets/:wallet_uuid/credit-limit", h.Admin.SetCreditLimit)
+	admin.Put("/wallets/:wallet_uuid/shards", h.Admin.SetShards)
+	admin.Get("/wallets/:wallet_uuid/operations", h.Admin.ListOperations)
+	admin.Post("/wallets/:wallet_uuid/adjustments", h.Admin.Adjust)
+	admin.Get("/wallets/:wallet_uuid/reconciliation", h.Admin.ReconcileWallet)
+	admin.Get("/wallets/:wallet_uuid/statement", h.Admin.Statement)
+	admin.Get("/reconciliation", h.Admin.ReconcileAll)
+	admin.Get("/audit", h.Audit.ListAuditEntries)
+	admin.Put("/interest/rules", h.Interest.SetRule)
+	admin.Get("/interest/rules", h.Interest.ListRules)
+	admin.Delete("/interest/rules/:rule_uuid", h.Interest.DeleteRule)
+	admin.Get("/wallets/:wallet_uuid/interest/accruals", h.Interest.ListAccruals)
+	admin.Put("/fees/schedules", h.Fee.SetSchedule)
+	admin.Get("/fees/schedules", h.Fee.ListSchedules)
+	admin.Delete("/fees/schedules/:schedule_uuid", h.Fee.DeleteSchedule)
+	admin.Put("/fx/rates", h.FX.SetRates)
+	admin.Get("/fx/rates", h.FX.ListRates)
+	admin.Get("/debug/vars", Vars)
+}
@@ -0,0 +1,184 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"wallet-service/internal/dto"
+	"wallet-service/internal/wallet/handler/middleware"
+	"wallet-service/internal/wallet/handler/openapi"
+	"wallet-service/internal/wallet/repository"
+	"wallet-service/internal/wallet/service"
+)
+
+var routeParam = regexp.MustCompile(`:(\w+)`)
+
+func openAPIDocument(t *testing.T) map[string]any {
+	t.Helper()
+	doc, err := openapi.Document()
+	require.NoError(t, err)
+	return doc
+}
+
+// TestOpenAPI_Routes checks that the document describes exactly the routes
+// RegisterRoutes makes, with the admin API enabled.
+func TestOpenAPI_Routes(t *testing.T) {
+	app := fiber.New()
+	pass := func(c *fiber.Ctx) error { return c.Next() }
+	RegisterRoutes(app, Handlers{}, RouteConfig{ClientLimit: pass, WalletLimit: pass, AdminAuth: pass})
+
+	var routes []string
+	for _, r := range app.GetRoutes(true) {
+		if r.Method == fiber.MethodHead {
+			continue // Fiber adds HEAD for every GET route.
+		}
+		routes = append(routes, r.Method+" "+routeParam.ReplaceAllString(r.Path, "{$1}"))
+	}
+
+	var documented []string
+	for path, item := range openAPIDocument(t)["paths"].(map[string]any) {
+		for method := range item.(map[string]any) {
+			documented = append(documented, strings.ToUpper(method)+" "+path)
+		}
+	}
+
+	sort.Strings(routes)
+	sort.Strings(documented)
+	assert.Equal(t, routes, documented)
+}
+
+// TestOpenAPI_Schemas checks each DTO schema against the JSON fields of the
+// DTO and the fields its validation requires.
+func TestOpenAPI_Schemas(t *testing.T) {
+	schemas := openAPIDocument(t)["components"].(map[string]any)["schemas"].(map[string]any)
+
+	for _, v := range []any{
+		dto.AdjustmentRequest{},
+		dto.AuditEntryResponse{},
+		dto.BalanceAsOfResponse{},
+		dto.CreateCustomerRequest{},
+		dto.CreateScheduleRequest{},
+		dto.CreateWalletRequest{},
+		dto.CustomerBalanceResponse{},
+		dto.CustomerResponse{},
+		dto.CustomerWalletResponse{},
+		dto.ExchangeRate{},
+		dto.FeeQuoteResponse{},
+		dto.FeeScheduleRequest{},
+		dto.FeeScheduleResponse{},
+		dto.FeeTier{},
+		dto.FXQuoteResponse{},
+		dto.FXTransferRequest{},
+		dto.FXTransferResponse{},
+		dto.GetWalletResponse{},
+		dto.InterestAccrualResponse{},
+		dto.InterestRuleRequest{},
+		dto.InterestRuleResponse{},
+		dto.ReconciliationReportResponse{},
+		dto.ReconciliationResponse{},
+		dto.ScheduleResponse{},
+		dto.ScheduleRunResponse{},
+		dto.SetCreditLimitRequest{},
+		dto.SetExchangeRatesRequest{},
+		dto.SetWalletShardsRequest{},
+		dto.StatementLineResponse{},
+		dto.StatementResponse{},
+		dto.WalletDetailsResponse{},
+		dto.WalletEventResponse{},
+		dto.WalletOperationRequest{},
+		dto.WalletOperationResponse{},
+	} {
+		typ := reflect.TypeOf(v)
+		t.Run(typ.Name(), func(t *testing.T) {
+			schema, ok := schemas[typ.Name()].(map[string]any)
+			require.True(t, ok, "no schema for dto.%s", typ.Name())
+
+			var fields, required []string
+			for i := 0; i < typ.NumField(); i++ {
+				f := typ.Field(i)
+				name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+				if name == "-" {
+					continue
+				}
+				fields = append(fields, name)
+				if strings.HasPrefix(f.Tag.Get("validate"), "required") {
+					required = append(required, name)
+				}
+			}
+
+			var properties []string
+			for name := range schema["properties"].(map[string]any) {
+				properties = append(properties, name)
+			}
+			var documented []string
+			for _, name := range asSlice(schema["required"]) {
+				documented = append(documented, name.(string))
+			}
+
+			assert.ElementsMatch(t, fields, properties, "properties")
+			assert.ElementsMatch(t, required, documented, "required properties")
+		})
+	}
+}
+
+func TestDocsHandler(t *testing.T) {
+	docs, err := NewDocsHandler()
+	require.NoError(t, err)
+	app := fiber.New()
+	RegisterRoutes(app, Handlers{Docs: docs}, RouteConfig{})
+
+	for path, contentType := range map[string]string{
+		"/openapi.json": fiber.MIMEApplicationJSON,
+		"/docs":         fiber.MIMETextHTMLCharsetUTF8,
+	} {
+		resp, err := app.Test(httptest.NewRequest("GET", path, nil))
+		require.NoError(t, err)
+		assert.Equal(t, fiber.StatusOK, resp.StatusCode, path)
+		assert.Equal(t, contentType, resp.Header.Get(fiber.HeaderContentType), path)
+	}
+}
+
+func asSlice(v any) []any {
+	s, _ := v.([]any)
+	return s
+}
+
+func TestVars(t *testing.T) {
+	app := fiber.New()
+	app.Get("/vars", Vars)
+
+	resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/vars", nil))
+	require.NoError(t, err)
+	var vars map[string]json.RawMessage
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&vars))
+
+	assert.Contains(t, vars, "memstats")
+	assert.NotContains(t, vars, "cmdline")
+}
+
+func TestFeeHandler_SetScheduleOperationTypes(t *testing.T) {
+	h := NewFeeHandler(service.NewFeeService(repository.NewMemoryFeeRepository()))
+	app := fiber.New(fiber.Config{ErrorHandler: middleware.ErrorHandler})
+	app.Put("/fees/schedules", h.SetSchedule)
+
+	for opType, want := range map[string]int{
+		"WITHDRAW": fiber.StatusOK,
+		"FX_OUT":   fiber.StatusOK,
+		"DEPOSIT":  fiber.StatusBadRequest,
+	} {
+		req := httptest.NewRequest(fiber.MethodPut, "/fees/schedules", strings.NewReader(`{"operationType":"`+opType+`","flat":1}`))
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		assert.Equal(t, want, resp.StatusCode, opType)
+	}
+}
@@ -0,0 +1,146 @@
+package handler
+
+import (
+	"context"
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"time"
+	"wallet-service/internal/dto"
+	"wallet-service/internal/wallet/model"
+	"wallet-service/internal/wallet/service"
+)
+
+const scheduleRunsLimit = 50
+
+type ScheduleHandler struct {
+	svc      *service.ScheduleService
+	validate *validator.Validate
+}
+
+func NewScheduleHandler(svc *service.ScheduleService) *ScheduleHandler {
+	return &ScheduleHandler{
+		svc:      svc,
+		validate: validator.New(),
+	}
+}
+
+func (h *ScheduleHandler) CreateSchedule(c *fiber.Ctx) error {
+	walletId, err := uuidParam(c, "wallet_uuid")
+	if err != nil {
+		return err
+	}
+
+	var req dto.CreateScheduleRequest
+	if err := parseBody(c, h.validate, &req); err != nil {
+		return err
+	}
+
+	sch, err := h.svc.CreateSchedule(c.UserContext(), walletId, req)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(scheduleResponse(sch))
+}
+
+func (h *ScheduleHandler) ListSchedules(c *fiber.Ctx) error {
+	walletId, err := uuidParam(c, "wallet_uuid")
+	if err != nil {
+		return err
+	}
+
+	schedules, err := h.svc.ListSchedules(c.UserContext(), walletId)
+	if err != nil {
+		return err
+	}
+
+	resp := make([]dto.ScheduleResponse, len(schedules))
+	for i := range schedules {
+		resp[i] = scheduleResponse(&schedules[i])
+	}
+	return c.JSON(resp)
+}
+
+func (h *ScheduleHandler) GetSchedule(c *fiber.Ctx) error {
+	id, err := uuidParam(c, "schedule_uuid")
+	if err != nil {
+		return err
+	}
+
+	sch, err := h.svc.GetSchedule(c.UserContext(), id)
+	if err != nil {
+		return err
+	}
+	return c.JSON(scheduleResponse(sch))
+}
+
+func (h *ScheduleHandler) ListRuns(c *fiber.Ctx) error {
+	id, err := uuidParam(c, "schedule_uuid")
+	if err != nil {
+		return err
+	}
+
+	runs, err := h.svc.ListRuns(c.UserContext(), id, scheduleRunsLimit)
+	if err != nil {
+		return err
+	}
+
+	resp := make([]dto.ScheduleRunResponse, len(runs))
+	for i, run := range runs {
+		resp[i] = dto.ScheduleRunResponse{
+			RunID:        run.ID,
+			ScheduledFor: run.ScheduledFor,
+			Attempt:      run.Attempt,
+			Status:       string(run.Status),
+			OperationID:  run.OperationID,
+			Error:        run.Error,
+			CreatedAt:    run.CreatedAt,
+		}
+	}
+	return c.JSON(resp)
+}
+
+func (h *ScheduleHandler) PauseSchedule(c *fiber.Ctx) error {
+	return h.changeStatus(c, h.svc.PauseSchedule)
+}
+
+func (h *ScheduleHandler) ResumeSchedule(c *fiber.Ctx) error {
+	return h.changeStatus(c, h.svc.ResumeSchedule)
+}
+
+func (h *ScheduleHandler) CancelSchedule(c *fiber.Ctx) error {
+	return h.changeStatus(c, h.svc.CancelSchedule)
+}
+
+func (h *ScheduleHandler) changeStatus(c *fiber.Ctx, change func(ctx context.Context, id uuid.UUID) (*model.Schedule, error)) error {
+	id, err := uuidParam(c, "schedule_uuid")
+	if err != nil {
+		return err
+	}
+
+	sch, err := change(c.UserContext(), id)
+	if err != nil {
+		return err
+	}
+	return c.JSON(scheduleResponse(sch))
+}
+
+func scheduleResponse(sch *model.Schedule) dto.ScheduleResponse {
+	resp := dto.ScheduleResponse{
+		ScheduleID:    sch.ID,
+		WalletID:      sch.WalletID,
+		OperationType: sch.OperationType,
+		Amount:        sch.Amount,
+		Cron:          sch.Cron,
+		Status:        string(sch.Status),
+		NextRunAt:     sch.NextRunAt.UTC(),
+		DueAt:         sch.DueAt.UTC(),
+		Attempts:      sch.Attempts,
+		CreatedAt:     sch.CreatedAt.UTC().Truncate(time.Second),
+	}
+	if sch.IntervalSeconds > 0 {
+		resp.Interval = sch.Interval().String()
+	}
+	return resp
+}
@@ -0,0 +1,28 @@
+package handler
+
+import (
+	"expvar"
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Vars serves the published expvar variables as one JSON object, the
+// retry and deposit coalescing stats among them. The command line is left
+// out, as its flags may carry secrets.
+func Vars(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	w := c.Response().BodyWriter()
+	sep := "{"
+	expvar.Do(func(kv expvar.KeyValue) {
+		if kv.Key == "cmdline" {
+			return
+		}
+		fmt.Fprintf(w, "%s%q:%s", sep, kv.Key, kv.Value)
+		sep = ","
+	})
+	if sep == "{" {
+		fmt.Fprint(w, sep)
+	}
+	fmt.Fprint(w, "}")
+	return nil
+}
@@ -4,11 +4,13 @@ import (
 	"context"
 	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
-	"github.com/google/uuid"
+	"strconv"
+	"strings"
 	"time"
 	"wallet-service/internal/dto"
-	"wallet-service/internal/validation"
+	"wallet-service/internal/wallet/model"
 	"wallet-service/internal/wallet/service"
+	svcErrors "wallet-service/internal/wallet/service/errors"
 )
 
 type WalletHandler struct {
@@ -24,35 +26,54 @@ func NewWalletHandler(svc *service.WalletService) *WalletHandler {
 }
 
 func (h *WalletHandler) GetWalletBalance(c *fiber.Ctx) error {
-	walletId, err := uuid.Parse(c.Params("wallet_uuid"))
+	walletId, err := uuidParam(c, "wallet_uuid")
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid wallet UUID"})
+		return err
 	}
 
-	balance, err := h.svc.GetWallet(c.Context(), walletId)
+	wallet, err := h.svc.LookupWallet(c.UserContext(), walletId)
 	if err != nil {
 		return err
 	}
 
-	return c.JSON(dto.GetWalletResponse{
+	c.Set(fiber.HeaderETag, walletETag(wallet.Revision()))
+	return c.JSON(walletResponse(wallet))
+}
+
+// GetBalanceAsOf reports the balance the wallet had at the time given by
+// the asOf query parameter, as recorded by its operations.
+func (h *WalletHandler) GetBalanceAsOf(c *fiber.Ctx) error {
+	walletId, err := uuidParam(c, "wallet_uuid")
+	if err != nil {
+		return err
+	}
+	asOf, err := time.Parse(time.RFC3339, c.Query("asOf"))
+	if err != nil {
+		return svcErrors.InvalidParameter("asOf", "asOf must be an RFC 3339 timestamp")
+	}
+
+	balance, err := h.svc.BalanceAsOf(c.UserContext(), walletId, asOf)
+	if err != nil {
+		return err
+	}
+	return c.JSON(dto.BalanceAsOfResponse{
 		WalletID: walletId,
 		Balance:  balance,
+		AsOf:     asOf.UTC(),
 	})
 }
 
 func (h *WalletHandler) UpdateWalletBalance(c *fiber.Ctx) error {
 	var req dto.WalletOperationRequest
-	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "invalid request body.",
-		})
+	if err := parseBody(c, h.validate, &req); err != nil {
+		return err
 	}
 
-	if err := h.validate.Struct(req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"errors": validation.FormatValidationErrors(err),
-		})
+	expected, err := ifMatchVersion(c)
+	if err != nil {
+		return err
 	}
+	req.ExpectedVersion = expected
 
 	ctx, cancel := context.WithTimeout(c.UserContext(), 10*time.Second)
 	defer cancel()
@@ -62,13 +83,59 @@ func (h *WalletHandler) UpdateWalletBalance(c *fiber.Ctx) error {
 		return err
 	}
 
-	resp := dto.WalletOperationResponse{
-		OperationID:   op.ID,
-		WalletID:      op.WalletID,
-		OperationType: op.Type,
-		Amount:        op.Amount,
-		CreatedAt:     op.CreatedAt.Format(time.RFC3339),
+	return c.Status(fiber.StatusOK).JSON(operationResponse(op))
+}
+
+// QuoteFee prices the fee of the operation in the body without making it.
+func (h *WalletHandler) QuoteFee(c *fiber.Ctx) error {
+	var req dto.WalletOperationRequest
+	if err := parseBody(c, h.validate, &req); err != nil {
+		return err
+	}
+
+	fee, err := h.svc.QuoteFee(c.UserContext(), req)
+	if err != nil {
+		return err
+	}
+	return c.JSON(dto.FeeQuoteResponse{
+		WalletID:      req.WalletID,
+		OperationType: req.OperationType,
+		Amount:        req.Amount,
+		Fee:           fee,
+		Total:         req.Amount + fee,
+	})
+}
+
+func walletResponse(w *model.Wallet) dto.GetWalletResponse {
+	return dto.GetWalletResponse{
+		WalletID:        w.ID,
+		Balance:         w.TotalBalance(),
+		Currency:        w.Currency,
+		CreditLimit:     w.CreditLimit,
+		AvailableCredit: w.AvailableCredit(),
+	}
+}
+
+func walletETag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// ifMatchVersion returns the wallet version named by the If-Match header, or
+// nil if the header is absent or "*". If-Match uses strong comparison, so a
+// weak or malformed tag can never match and fails the precondition.
+func ifMatchVersion(c *fiber.Ctx) (*int64, error) {
+	header := strings.TrimSpace(c.Get(fiber.HeaderIfMatch))
+	if header == "" || header == "*" {
+		return nil, nil
 	}
 
-	return c.Status(fiber.StatusOK).JSON(resp)
+	tag, ok := strings.CutPrefix(header, `"`)
+	if ok {
+		tag, ok = strings.CutSuffix(tag, `"`)
+	}
+	version, err := strconv.ParseInt(tag, 10, 64)
+	if !ok || err != nil {
+		return nil, svcErrors.ErrPreconditionFailed
+	}
+	return &version, nil
 }
@@ -0,0 +1,146 @@
+package integration_test
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/csv"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"wallet-service/internal/dto"
+	"wallet-service/internal/partition"
+	"wallet-service/internal/wallet/model"
+	"wallet-service/internal/wallet/repository"
+	"wallet-service/internal/wallet/service"
+)
+
+func TestOperationPartitions(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewWalletRepository(testDB)
+	wallet := createTestWallet(testDB, 0)
+
+	// An operation from a month long gone waits in the default partition
+	// until its month gets one.
+	old := time.Now().UTC().AddDate(-5, 0, 0)
+	for _, op := range []model.Operation{
+		{Type: model.OperationDeposit, Amount: 30, CreatedAt: old},
+		{Type: model.OperationWithdraw, Amount: 5, CreatedAt: old.Add(time.Hour)},
+		{Type: model.OperationDeposit, Amount: 1},
+	} {
+		op.ID, op.WalletID = uuid.New(), wallet.ID
+		require.NoError(t, repo.SaveOperationTx(ctx, &op))
+	}
+	require.NoError(t, repo.UpdateWalletTx(ctx, wallet.ID, 26))
+
+	dir := t.TempDir()
+	manager := partition.NewManager(testDB, 2, 12, dir, false)
+	var name string
+	require.NoError(t, testDB.Raw("SELECT operations_partition(?)", old.Format(time.DateOnly)).Scan(&name).Error)
+	_, err := manager.Ensure(ctx, time.Now())
+	require.NoError(t, err)
+
+	dup := model.Operation{ID: uuid.New(), WalletID: wallet.ID, Type: model.OperationDeposit, Amount: 1, ExternalRef: "ref-1"}
+	require.NoError(t, repo.SaveOperationTx(ctx, &dup))
+	dup.ID = uuid.New()
+	assert.ErrorIs(t, repo.SaveOperationTx(ctx, &dup), repository.ErrDuplicateExternalRef, "references are unique across partitions")
+	require.NoError(t, repo.UpdateWalletTx(ctx, wallet.ID, 27))
+
+	archived, err := manager.Archive(ctx, time.Now())
+	require.NoError(t, err)
+	var rec *model.OperationArchive
+	for i := range archived {
+		if archived[i].Name == name {
+			rec = &archived[i]
+		}
+	}
+	require.NotNil(t, rec, "partition %s archived", name)
+
+	f, err := os.Open(rec.File)
+	require.NoError(t, err)
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	require.NoError(t, err)
+	records, err := csv.NewReader(gz).ReadAll()
+	require.NoError(t, err)
+	assert.EqualValues(t, len(records)-1, rec.Operations)
+	var detached bool
+	require.NoError(t, testDB.Raw("SELECT to_regclass(?) IS NOT NULL", name+"_detached").Scan(&detached).Error)
+	assert.True(t, detached, "archived partitions are left for an operator to drop")
+
+	ops, err := repo.ListOperations(ctx, wallet.ID, repository.OperationFilter{})
+	require.NoError(t, err)
+	assert.Len(t, ops, 2, "archived operations are gone")
+	total, err := repo.OperationsNetTotal(ctx, wallet.ID, time.Time{}, time.Time{})
+	require.NoError(t, err)
+	assert.InDelta(t, 27, total, 0.001, "archived operations still count")
+	total, err = repo.OperationsNetTotal(ctx, wallet.ID, time.Time{}, rec.RangeEnd)
+	require.NoError(t, err)
+	assert.InDelta(t, 25, total, 0.001)
+	_, err = repo.OperationsNetTotal(ctx, wallet.ID, time.Time{}, old.Add(30*time.Minute))
+	assert.ErrorIs(t, err, repository.ErrRangeArchived, "the month's operations are only known in total")
+	_, err = repo.OperationsNetTotal(ctx, wallet.ID, old, time.Time{})
+	assert.ErrorIs(t, err, repository.ErrRangeArchived)
+
+	reconciliation, err := service.NewWalletService(repo).Reconcile(ctx, wallet.ID)
+	require.NoError(t, err)
+	assert.True(t, reconciliation.Balanced())
+}
+
+func TestOperationPartitions_SnapshotInArchivedMonth(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewWalletRepository(testDB)
+	svc := service.NewWalletService(repo)
+	wallet := createTestWallet(testDB, 0)
+
+	old := time.Now().UTC().AddDate(-6, 0, 0)
+	for _, op := range []model.Operation{
+		{Type: model.OperationDeposit, Amount: 40, CreatedAt: old},
+		{Type: model.OperationDeposit, Amount: 2},
+	} {
+		op.ID, op.WalletID = uuid.New(), wallet.ID
+		require.NoError(t, repo.SaveOperationTx(ctx, &op))
+	}
+	require.NoError(t, repo.UpdateWalletTx(ctx, wallet.ID, 42))
+	// The daily snapshot taken the next day falls inside the month.
+	require.NoError(t, repo.SaveBalanceSnapshot(ctx, &model.BalanceSnapshot{WalletID: wallet.ID, TakenAt: old.Add(24 * time.Hour), Balance: 40}))
+
+	var name string
+	require.NoError(t, testDB.Raw("SELECT operations_partition(?)", old.Format(time.DateOnly)).Scan(&name).Error)
+	manager := partition.NewManager(testDB, 2, 12, t.TempDir(), false)
+	archived, err := manager.Archive(ctx, time.Now())
+	require.NoError(t, err)
+	var rec *model.OperationArchive
+	for i := range archived {
+		if archived[i].Name == name {
+			rec = &archived[i]
+		}
+	}
+	require.NotNil(t, rec, "partition %s archived", name)
+
+	snap, err := repo.LatestBalanceSnapshot(ctx, wallet.ID, time.Now())
+	require.NoError(t, err)
+	assert.True(t, snap.TakenAt.Equal(rec.RangeEnd), "snapshot at the end of the archived month")
+	assert.InDelta(t, 40, snap.Balance, 0.001)
+
+	balance, err := svc.BalanceAsOf(ctx, wallet.ID, time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.InDelta(t, 42, balance, 0.001)
+	st, err := svc.Statement(ctx, wallet.ID, rec.RangeEnd, time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.InDelta(t, 40, st.OpeningBalance, 0.001)
+	_, err = svc.SnapshotBalances(ctx, time.Now())
+	require.NoError(t, err)
+
+	rate := 0.05
+	interest := service.NewInterestService(repository.NewInterestRepository(testDB), svc)
+	_, err = interest.SetRule(ctx, dto.InterestRuleRequest{WalletID: &wallet.ID, AnnualRate: &rate})
+	require.NoError(t, err)
+	accrued, _, err := interest.RunInterest(ctx, time.Now().UTC())
+	require.NoError(t, err)
+	assert.Positive(t, accrued)
+}
@@ -0,0 +1,69 @@
+package integration_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"wallet-service/internal/wallet/model"
+	"wallet-service/internal/wallet/repository"
+	"wallet-service/internal/wallet/service"
+)
+
+func TestSeededWalletsReconcile(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewWalletRepository(testDB)
+	seeded := uuid.MustParse("22222222-2222-2222-2222-222222222222")
+
+	ops, err := repo.ListOperations(ctx, seeded, repository.OperationFilter{})
+	require.NoError(t, err)
+	openings := 0
+	for _, op := range ops {
+		if op.Type == model.OperationOpening {
+			openings++
+		}
+	}
+	assert.Equal(t, 1, openings, "the seeded balance has one opening operation")
+	rec, err := service.NewWalletService(repo).Reconcile(ctx, seeded)
+	require.NoError(t, err)
+	assert.True(t, rec.Balanced(), "%+v", rec)
+}
+
+func TestDatabaseInvariants(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewWalletRepository(testDB)
+	wallet := createTestWallet(testDB, 10)
+
+	zero := &model.Operation{ID: uuid.New(), WalletID: wallet.ID, Type: model.OperationDeposit, Amount: 0}
+	assert.Error(t, repo.SaveOperationTx(ctx, zero), "amounts must be positive")
+	unknown := &model.Operation{ID: uuid.New(), WalletID: wallet.ID, Type: "BONUS", Amount: 1}
+	assert.Error(t, repo.SaveOperationTx(ctx, unknown), "types must be known")
+
+	overdraw := func(tx repository.WalletRepository) error {
+		return tx.UpdateWalletTx(ctx, wallet.ID, -1)
+	}
+	assert.Error(t, repo.WithTx(ctx, overdraw), "only wallets with a credit line go negative")
+	require.NoError(t, repo.WithTx(ctx, func(tx repository.WalletRepository) error {
+		if err := tx.UpdateCreditLimitTx(ctx, wallet.ID, 5); err != nil {
+			return err
+		}
+		return overdraw(tx)
+	}))
+	assert.Error(t, repo.WithTx(ctx, func(tx repository.WalletRepository) error {
+		return tx.UpdateCreditLimitTx(ctx, wallet.ID, 0)
+	}), "an overdrawn wallet keeps its credit line")
+
+	debit := func(typ string) func(tx repository.WalletRepository) error {
+		return func(tx repository.WalletRepository) error {
+			if err := tx.UpdateWalletTx(ctx, wallet.ID, -6); err != nil {
+				return err
+			}
+			return tx.SaveOperationTx(ctx, &model.Operation{ID: uuid.New(), WalletID: wallet.ID, Type: typ, Amount: 5})
+		}
+	}
+	assert.Error(t, repo.WithTx(ctx, debit(model.OperationWithdraw)), "debits stop at the credit limit")
+	assert.NoError(t, repo.WithTx(ctx, debit(model.OperationOverdraft)), "overdraft interest may go past it")
+}
@@ -0,0 +1,31 @@
+package integration_test
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"wallet-service/internal/wallet/model"
+	"wallet-service/internal/wallet/repository"
+	"wallet-service/internal/wallet/repository/repositorytest"
+)
+
+func TestPostgresWalletRepository_Conformance(t *testing.T) {
+	repositorytest.Run(t, func(t *testing.T) repositorytest.Harness {
+		return repositorytest.Harness{
+			Repo: repository.NewWalletRepository(testDB),
+			CreateWallet: func(t *testing.T, w model.Wallet) {
+				require.NoError(t, testDB.Create(&w).Error)
+			},
+			Operations: func(t *testing.T, walletID uuid.UUID) []model.Operation {
+				var ops []model.Operation
+				require.NoError(t, testDB.Where("wallet_id = ?", walletID).Order("created_at").Find(&ops).Error)
+				return ops
+			},
+			CreateCustomer: func(t *testing.T, c model.Customer) {
+				require.NoError(t, testDB.Create(&c).Error)
+			},
+		}
+	})
+}
@@ -3,6 +3,8 @@ package integration_test
 import (
 	"context"
 	"fmt"
+	"log"
+	"os"
 	"sync"
 	"testing"
 
@@ -16,14 +18,26 @@ import (
 	"wallet-service/internal/wallet/model"
 	"wallet-service/internal/wallet/repository"
 	"wallet-service/internal/wallet/service"
+	"wallet-service/migrations"
 )
 
 var testDB *gorm.DB
 
 func TestMain(m *testing.M) {
-	cfg := config.LoadConfig()
-	testDB = db.NewPostgres(cfg.DBUrl)
-	m.Run()
+	cfg, err := config.Load(nil)
+	if err != nil {
+		log.Printf("skipping integration tests: %v", err)
+		os.Exit(0)
+	}
+	testDB = db.NewPostgres(cfg.DB)
+	sqlDB, err := testDB.DB()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if _, err := db.Migrate(context.Background(), sqlDB, migrations.FS); err != nil {
+		log.Fatalf("migrating the test database: %v", err)
+	}
+	os.Exit(m.Run())
 }
 
 func createTestWallet(db *gorm.DB, initialBalance float64) *model.Wallet {
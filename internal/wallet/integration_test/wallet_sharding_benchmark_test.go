@@ -0,0 +1,50 @@
+package integration_test
+
+import (
+	"context"
+	"testing"
+
+	"wallet-service/internal/dto"
+	"wallet-service/internal/wallet/repository"
+	"wallet-service/internal/wallet/service"
+)
+
+// Run with: go test ./internal/wallet/integration_test -run '^$' -bench Deposits -cpu 8
+// Every iteration deposits into the same hot wallet. The sharded variant
+// spreads the row locks over 16 shard rows instead of the single wallet row.
+func benchmarkHotWalletDeposits(b *testing.B, shards int) {
+	ctx := context.Background()
+	wallet := createTestWallet(testDB, 0)
+	repo := repository.NewWalletRepository(testDB)
+
+	var opts []service.Option
+	if shards > 0 {
+		opts = append(opts, service.WithSharding())
+	}
+	svc := service.NewWalletService(repo, opts...)
+	if shards > 0 {
+		if err := svc.SetWalletShards(ctx, wallet.ID, shards, nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	req := dto.WalletOperationRequest{WalletID: wallet.ID, OperationType: "DEPOSIT", Amount: 1}
+
+	b.SetParallelism(8)
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if _, err := svc.UpdateWalletBalance(ctx, req); err != nil {
+				b.Error(err)
+			}
+		}
+	})
+}
+
+func BenchmarkDeposits_SingleRow(b *testing.B) {
+	benchmarkHotWalletDeposits(b, 0)
+}
+
+func BenchmarkDeposits_Sharded(b *testing.B) {
+	benchmarkHotWalletDeposits(b, 16)
+}
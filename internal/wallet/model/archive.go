@@ -0,0 +1,30 @@
+package model
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// OperationArchive records a partition of the operations table that was
+// exported to File and dropped. It held the operations created in
+// [RangeStart, RangeEnd).
+type OperationArchive struct {
+	Name       string `gorm:"type:varchar(63);primaryKey"`
+	RangeStart time.Time
+	RangeEnd   time.Time
+	File       string `gorm:"type:text;not null"`
+	Operations int64
+	ArchivedAt time.Time `gorm:"autoCreateTime"`
+}
+
+// OperationArchiveTotal is the net total of a wallet's operations in an
+// archived partition, which ledger totals count in place of the
+// operations.
+type OperationArchiveTotal struct {
+	WalletID   uuid.UUID `gorm:"type:uuid;primaryKey"`
+	RangeStart time.Time `gorm:"primaryKey"`
+	RangeEnd   time.Time
+	TenantID   string  `gorm:"type:varchar(50);not null;default:default"`
+	Net        float64 `gorm:"type:decimal(20,2);not null"`
+	Operations int64
+}
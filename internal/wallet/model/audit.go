@@ -0,0 +1,22 @@
+package model
+
+import (
+	"encoding/json"
+	"github.com/google/uuid"
+	"time"
+)
+
+// AuditEntry records one mutating call. Entries are only ever appended;
+// the database rejects updates and deletes.
+type AuditEntry struct {
+	ID        int64     `gorm:"primaryKey;autoIncrement"`
+	Actor     string    `gorm:"type:varchar(200);not null"`
+	Action    string    `gorm:"type:varchar(50);not null"`
+	WalletID  uuid.UUID `gorm:"type:uuid;not null"`
+	RequestID string    `gorm:"type:varchar(100);not null;default:''"`
+	// Before and After hold the target's state as JSON. Before is empty
+	// when the call created the target.
+	Before    json.RawMessage `gorm:"type:jsonb"`
+	After     json.RawMessage `gorm:"type:jsonb"`
+	CreatedAt time.Time       `gorm:"autoCreateTime"`
+}
@@ -0,0 +1,16 @@
+package model
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// Customer owns wallets. Clients know a customer by ExternalID, the ID it
+// has in their own systems, which is unique within the tenant.
+type Customer struct {
+	ID         uuid.UUID `gorm:"type:uuid;primaryKey"`
+	TenantID   string    `gorm:"type:varchar(50);not null;default:default"`
+	ExternalID string    `gorm:"type:varchar(100);not null"`
+	Name       string    `gorm:"type:varchar(200);not null;default:''"`
+	CreatedAt  time.Time `gorm:"autoCreateTime"`
+}
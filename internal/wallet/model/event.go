@@ -0,0 +1,22 @@
+package model
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// WalletEvent records a committed operation together with the wallet's
+// balance when the operation was recorded. Events are written by the
+// database as operations are inserted. Seq numbers a wallet's events in
+// commit order without gaps, so a client can resume a stream after the
+// last event it saw.
+type WalletEvent struct {
+	ID          int64     `gorm:"primaryKey;autoIncrement"`
+	WalletID    uuid.UUID `gorm:"type:uuid;not null"`
+	Seq         int64     `gorm:"not null"`
+	OperationID uuid.UUID `gorm:"type:uuid;not null"`
+	Balance     float64   `gorm:"type:decimal(20,2);not null"`
+	CreatedAt   time.Time `gorm:"autoCreateTime"`
+
+	Operation Operation `gorm:"foreignKey:OperationID"`
+}
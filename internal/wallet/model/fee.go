@@ -0,0 +1,81 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"github.com/google/uuid"
+	"math"
+	"time"
+)
+
+// FeeSchedule sets the fee charged on operations of one type, either for
+// the wallets of a product or, with an empty Product, for every wallet
+// whose product has no schedule of its own.
+//
+// The fee is Flat plus Percent of the amount, taken from the tier with the
+// highest From not above the amount, or from the schedule itself if no
+// tier applies. It is then held between MinFee and MaxFee, when set.
+type FeeSchedule struct {
+	ID            uuid.UUID `gorm:"type:uuid;primaryKey"`
+	OperationType string    `gorm:"type:varchar(10);not null"`
+	Product       string    `gorm:"type:varchar(50);not null;default:''"`
+	Flat          float64   `gorm:"type:decimal(20,2);not null;default:0"`
+	Percent       float64   `gorm:"type:decimal(9,6);not null;default:0"`
+	Tiers         FeeTiers  `gorm:"type:jsonb;not null;default:'[]'"`
+	MinFee        *float64  `gorm:"type:decimal(20,2)"`
+	MaxFee        *float64  `gorm:"type:decimal(20,2)"`
+	CreatedAt     time.Time `gorm:"autoCreateTime"`
+	UpdatedAt     time.Time `gorm:"autoUpdateTime"`
+}
+
+// FeeTier replaces the schedule's Flat and Percent for amounts of at least
+// From.
+type FeeTier struct {
+	From    float64 `json:"from"`
+	Flat    float64 `json:"flat"`
+	Percent float64 `json:"percent"`
+}
+
+// FeeTiers is stored as a JSON array.
+type FeeTiers []FeeTier
+
+func (t FeeTiers) Value() (driver.Value, error) {
+	if t == nil {
+		return "[]", nil
+	}
+	buf, err := json.Marshal(t)
+	return string(buf), err
+}
+
+func (t *FeeTiers) Scan(src any) error {
+	switch v := src.(type) {
+	case []byte:
+		return json.Unmarshal(v, t)
+	case string:
+		return json.Unmarshal([]byte(v), t)
+	case nil:
+		*t = nil
+		return nil
+	}
+	return errors.New("fee tiers: unsupported column type")
+}
+
+// Fee returns the fee charged on amount, rounded to the cent.
+func (f *FeeSchedule) Fee(amount float64) float64 {
+	flat, percent, from := f.Flat, f.Percent, math.Inf(-1)
+	for _, tier := range f.Tiers {
+		if tier.From <= amount && tier.From > from {
+			flat, percent, from = tier.Flat, tier.Percent, tier.From
+		}
+	}
+
+	fee := flat + amount*percent
+	if f.MinFee != nil && fee < *f.MinFee {
+		fee = *f.MinFee
+	}
+	if f.MaxFee != nil && fee > *f.MaxFee {
+		fee = *f.MaxFee
+	}
+	return math.Round(fee*100) / 100
+}
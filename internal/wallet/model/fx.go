@@ -0,0 +1,36 @@
+package model
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// ExchangeRate is the mid-market price of one unit of Base in Quote,
+// valid from ValidFrom until ValidTo, or until superseded when ValidTo is
+// nil.
+type ExchangeRate struct {
+	Base      string    `gorm:"type:char(3);primaryKey"`
+	Quote     string    `gorm:"type:char(3);primaryKey"`
+	ValidFrom time.Time `gorm:"primaryKey"`
+	ValidTo   *time.Time
+	Rate      float64   `gorm:"type:decimal(20,10);not null"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+// Covers reports whether the rate is valid at t.
+func (r *ExchangeRate) Covers(t time.Time) bool {
+	return !t.Before(r.ValidFrom) && (r.ValidTo == nil || t.Before(*r.ValidTo))
+}
+
+// FXLeg records the conversion behind one leg of an FX transfer. Both legs
+// carry the same values; Rate is what the customer got, MidRate less the
+// spread.
+type FXLeg struct {
+	OperationID  uuid.UUID `gorm:"type:uuid;primaryKey"`
+	FromCurrency string    `gorm:"type:char(3);not null"`
+	ToCurrency   string    `gorm:"type:char(3);not null"`
+	MidRate      float64   `gorm:"type:decimal(20,10);not null"`
+	Spread       float64   `gorm:"type:decimal(9,6);not null"`
+	Rate         float64   `gorm:"type:decimal(20,10);not null"`
+	CreatedAt    time.Time `gorm:"autoCreateTime"`
+}
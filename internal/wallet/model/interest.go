@@ -0,0 +1,58 @@
+package model
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// DayCount is the convention that turns an annual rate into a daily one.
+type DayCount string
+
+const (
+	DayCountActual365    DayCount = "ACT/365"
+	DayCountActual360    DayCount = "ACT/360"
+	DayCountActualActual DayCount = "ACT/ACT"
+)
+
+// DaysInYear returns the number of days the annual rate is divided by for
+// the interest accrued on date.
+func (d DayCount) DaysInYear(date time.Time) float64 {
+	switch d {
+	case DayCountActual360:
+		return 360
+	case DayCountActualActual:
+		return float64(time.Date(date.Year(), 12, 31, 0, 0, 0, 0, time.UTC).YearDay())
+	default:
+		return 365
+	}
+}
+
+// InterestRule sets the interest paid on one wallet, or on every wallet of
+// a product that has no rule of its own. Exactly one of WalletID and
+// Product is set. AnnualRate applies to positive balances; OverdraftRate
+// is charged on negative ones.
+type InterestRule struct {
+	ID            uuid.UUID  `gorm:"type:uuid;primaryKey"`
+	WalletID      *uuid.UUID `gorm:"type:uuid"`
+	Product       string     `gorm:"type:varchar(50);not null;default:''"`
+	AnnualRate    float64    `gorm:"type:decimal(9,6);not null"`
+	OverdraftRate float64    `gorm:"type:decimal(9,6);not null;default:0"`
+	DayCount      DayCount   `gorm:"type:varchar(10);not null"`
+	CreatedAt     time.Time  `gorm:"autoCreateTime"`
+	UpdatedAt     time.Time  `gorm:"autoUpdateTime"`
+}
+
+// InterestAccrual is one day's interest on a wallet, computed on its
+// end-of-day balance at AnnualRate, the rule's rate that applied to it.
+// Interest on an overdraft is negative. Accruals are settled monthly;
+// PayoutID is the INTEREST or OVERDRAFT operation that settled this one.
+type InterestAccrual struct {
+	WalletID   uuid.UUID  `gorm:"type:uuid;primaryKey"`
+	Date       time.Time  `gorm:"type:date;primaryKey"`
+	Balance    float64    `gorm:"type:decimal(20,2);not null"`
+	AnnualRate float64    `gorm:"type:decimal(9,6);not null"`
+	DayCount   DayCount   `gorm:"type:varchar(10);not null"`
+	Amount     float64    `gorm:"type:decimal(20,10);not null"`
+	PayoutID   *uuid.UUID `gorm:"type:uuid"`
+	CreatedAt  time.Time  `gorm:"autoCreateTime"`
+}
@@ -1,14 +1,115 @@
 package model
 
 import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
 	"github.com/google/uuid"
+	"slices"
 	"time"
 )
 
+const (
+	OperationDeposit  = "DEPOSIT"
+	OperationWithdraw = "WITHDRAW"
+	// OperationAdjCredit and OperationAdjDebit are manual corrections made
+	// by staff. Each has an Adjustment recording why.
+	OperationAdjCredit = "ADJ_CREDIT"
+	OperationAdjDebit  = "ADJ_DEBIT"
+	// OperationInterest pays out interest accrued under an InterestRule,
+	// and OperationOverdraft charges the interest accrued on an overdraft.
+	OperationInterest  = "INTEREST"
+	OperationOverdraft = "OVERDRAFT"
+	// OperationFee charges the fee of another operation to its wallet, and
+	// OperationFeeIncome credits that fee to the revenue wallet. Both link
+	// to the charged operation through ParentID.
+	OperationFee       = "FEE"
+	OperationFeeIncome = "FEE_INCOME"
+	// OperationFXOut and OperationFXIn are the legs of an FX transfer, each
+	// with an FXLeg recording the rate used. The FX_IN leg links to the
+	// FX_OUT leg through ParentID.
+	OperationFXOut = "FX_OUT"
+	OperationFXIn  = "FX_IN"
+	// OperationOpening credits the balance a wallet was seeded with, which
+	// no other operation accounts for. It is dated when the wallet was
+	// created.
+	OperationOpening = "OPENING"
+)
+
+// MaxMetadataSize bounds the JSON encoding of an operation's metadata, in
+// bytes.
+const MaxMetadataSize = 4096
+
+// DebitTypes lists the operation types that decrease a balance.
+var DebitTypes = []string{OperationWithdraw, OperationAdjDebit, OperationFee, OperationFXOut, OperationOverdraft}
+
+// OperationTypes lists every operation type; the database rejects others.
+var OperationTypes = []string{
+	OperationDeposit, OperationWithdraw, OperationAdjCredit, OperationAdjDebit, OperationInterest,
+	OperationOverdraft, OperationFee, OperationFeeIncome, OperationFXOut, OperationFXIn, OperationOpening,
+}
+
 type Operation struct {
-	ID        uuid.UUID `gorm:"type:uuid;primaryKey"`
-	WalletID  uuid.UUID `gorm:"type:uuid;not null"`
-	Type      string    `gorm:"type:varchar(10);not null"`
-	Amount    float64   `gorm:"type:decimal(10,2);not null"`
-	CreatedAt time.Time `gorm:"autoCreateTime"`
+	ID        uuid.UUID  `gorm:"type:uuid;primaryKey"`
+	WalletID  uuid.UUID  `gorm:"type:uuid;not null"`
+	Type      string     `gorm:"type:varchar(10);not null"`
+	Amount    float64    `gorm:"type:decimal(20,2);not null"`
+	ParentID  *uuid.UUID `gorm:"type:uuid"`
+	CreatedAt time.Time  `gorm:"autoCreateTime"`
+	// TenantID is always that of the wallet; the database copies it over
+	// on insert.
+	TenantID string `gorm:"type:varchar(50);not null;default:default"`
+
+	// Description, Metadata and ExternalRef are supplied by the client and
+	// stored as given. ExternalRef, e.g. a payment provider's transaction
+	// ID, is unique per wallet when set.
+	Description string   `gorm:"type:varchar(500);not null;default:''"`
+	Metadata    Metadata `gorm:"type:jsonb"`
+	ExternalRef string   `gorm:"type:varchar(100);not null;default:''"`
+
+	// Fee is the fee charged for the operation, recorded as a separate FEE
+	// operation. It is only set on operations returned by WalletService.
+	Fee float64 `gorm:"-"`
+}
+
+// SignedAmount returns the amount with the sign of its effect on the
+// balance.
+func (o *Operation) SignedAmount() float64 {
+	if slices.Contains(DebitTypes, o.Type) {
+		return -o.Amount
+	}
+	return o.Amount
+}
+
+// Metadata is free-form client data stored as a JSON object.
+type Metadata map[string]any
+
+func (m Metadata) Value() (driver.Value, error) {
+	if m == nil {
+		return nil, nil
+	}
+	buf, err := json.Marshal(m)
+	return string(buf), err
+}
+
+func (m *Metadata) Scan(src any) error {
+	switch v := src.(type) {
+	case []byte:
+		return json.Unmarshal(v, m)
+	case string:
+		return json.Unmarshal([]byte(v), m)
+	case nil:
+		*m = nil
+		return nil
+	}
+	return errors.New("operation metadata: unsupported column type")
+}
+
+// Adjustment records why a manual adjustment operation was made and by
+// whom.
+type Adjustment struct {
+	OperationID uuid.UUID `gorm:"type:uuid;primaryKey"`
+	Reason      string    `gorm:"type:text;not null"`
+	Actor       string    `gorm:"type:varchar(100);not null"`
+	CreatedAt   time.Time `gorm:"autoCreateTime"`
 }
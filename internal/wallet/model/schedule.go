@@ -0,0 +1,74 @@
+package model
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+type ScheduleStatus string
+
+const (
+	ScheduleActive    ScheduleStatus = "ACTIVE"
+	SchedulePaused    ScheduleStatus = "PAUSED"
+	ScheduleCancelled ScheduleStatus = "CANCELLED"
+	// ScheduleCompleted marks a one-off schedule that has run.
+	ScheduleCompleted ScheduleStatus = "COMPLETED"
+	// ScheduleFailed marks a schedule that can never run again, e.g.
+	// because its wallet no longer exists or a one-off run gave up.
+	ScheduleFailed ScheduleStatus = "FAILED"
+)
+
+// Schedule is a standing order that applies an operation to a wallet on a
+// cron expression, at a fixed interval, or once at NextRunAt when neither
+// is set.
+type Schedule struct {
+	ID              uuid.UUID      `gorm:"type:uuid;primaryKey"`
+	WalletID        uuid.UUID      `gorm:"type:uuid;not null;index"`
+	OperationType   string         `gorm:"type:varchar(10);not null"`
+	Amount          float64        `gorm:"type:decimal(10,2);not null"`
+	Cron            string         `gorm:"type:varchar(100);not null;default:''"`
+	IntervalSeconds int64          `gorm:"not null;default:0"`
+	Status          ScheduleStatus `gorm:"type:varchar(10);not null"`
+	// TenantID is the tenant of the wallet; the worker runs the schedule
+	// on its behalf.
+	TenantID string `gorm:"type:varchar(50);not null;default:default"`
+	// NextRunAt is the occurrence the schedule is due for. DueAt is when
+	// the worker should next attempt it; it moves ahead of NextRunAt while
+	// a failed run is being retried.
+	NextRunAt time.Time `gorm:"not null"`
+	DueAt     time.Time `gorm:"not null"`
+	// Attempts counts failed attempts at the current occurrence.
+	Attempts  int       `gorm:"not null;default:0"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+}
+
+func (s *Schedule) Interval() time.Duration {
+	return time.Duration(s.IntervalSeconds) * time.Second
+}
+
+// Recurring reports whether the schedule has occurrences after NextRunAt.
+func (s *Schedule) Recurring() bool {
+	return s.Cron != "" || s.IntervalSeconds > 0
+}
+
+type ScheduleRunStatus string
+
+const (
+	RunSucceeded ScheduleRunStatus = "SUCCEEDED"
+	// RunRetrying marks a failed attempt that will be tried again.
+	RunRetrying ScheduleRunStatus = "RETRYING"
+	RunFailed   ScheduleRunStatus = "FAILED"
+)
+
+// ScheduleRun records the outcome of one attempt to execute a schedule.
+type ScheduleRun struct {
+	ID           uuid.UUID         `gorm:"type:uuid;primaryKey"`
+	ScheduleID   uuid.UUID         `gorm:"type:uuid;not null;index"`
+	ScheduledFor time.Time         `gorm:"not null"`
+	Attempt      int               `gorm:"not null"`
+	Status       ScheduleRunStatus `gorm:"type:varchar(10);not null"`
+	OperationID  *uuid.UUID        `gorm:"type:uuid"`
+	Error        string            `gorm:"type:text;not null;default:''"`
+	CreatedAt    time.Time         `gorm:"autoCreateTime"`
+}
@@ -0,0 +1,16 @@
+package model
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+// BalanceSnapshot records a wallet's balance as derived from its
+// operations: the net total of those created before TakenAt. Point-in-time
+// queries start from the latest snapshot instead of the first operation.
+type BalanceSnapshot struct {
+	WalletID  uuid.UUID `gorm:"type:uuid;primaryKey"`
+	TakenAt   time.Time `gorm:"primaryKey"`
+	Balance   float64   `gorm:"type:decimal(20,2);not null"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
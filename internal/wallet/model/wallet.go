@@ -5,8 +5,89 @@ import (
 	"time"
 )
 
+type WalletStatus string
+
+const (
+	WalletActive WalletStatus = "ACTIVE"
+	// WalletFrozen wallets reject customer operations but still accept
+	// manual adjustments.
+	WalletFrozen WalletStatus = "FROZEN"
+	// WalletClosed wallets accept nothing and cannot be reopened.
+	WalletClosed WalletStatus = "CLOSED"
+)
+
+// DefaultCurrency is the currency of wallets created without one.
+const DefaultCurrency = "USD"
+
 type Wallet struct {
-	ID        uuid.UUID `gorm:"type:uuid;primaryKey"`
-	Balance   float64   `gorm:"type:decimal(10,2);default:0"`
+	ID         uuid.UUID    `gorm:"type:uuid;primaryKey"`
+	Balance    float64      `gorm:"type:decimal(20,2);default:0"`
+	ShardCount int          `gorm:"not null;default:0"`
+	Status     WalletStatus `gorm:"type:varchar(10);not null;default:ACTIVE"`
+	// TenantID is the tenant owning the wallet. Requests made for another
+	// tenant do not see it at all.
+	TenantID string `gorm:"type:varchar(50);not null;default:default"`
+	// CustomerID is the customer owning the wallet, if any. Label tells a
+	// customer's wallets apart, e.g. "main" or "savings"; it is unique per
+	// customer when set.
+	CustomerID *uuid.UUID `gorm:"type:uuid"`
+	Label      string     `gorm:"type:varchar(50);not null;default:''"`
+	// Product names the kind of account the wallet is, e.g. "savings";
+	// interest rules can apply to a whole product.
+	Product string `gorm:"type:varchar(50);not null;default:''"`
+	// Currency is the ISO 4217 code of the balance. It never changes;
+	// money moves between currencies through FX transfers.
+	Currency string `gorm:"type:char(3);not null;default:USD"`
+	// CreditLimit is the approved overdraft: debits may take the balance
+	// down to -CreditLimit.
+	CreditLimit float64 `gorm:"type:decimal(20,2);not null;default:0"`
+	// Version is incremented by every write to the wallet row. Credits to
+	// shard rows bump the shard's version instead; see Revision.
+	Version   int64     `gorm:"not null;default:0"`
 	CreatedAt time.Time `gorm:"autoCreateTime"`
+
+	// ShardBalance is the sum of the wallet's shard rows, loaded together
+	// with the wallet. It is never written back.
+	ShardBalance float64 `gorm:"->"`
+	// ShardVersion is the sum of the versions of the wallet's shard rows,
+	// loaded like ShardBalance.
+	ShardVersion int64 `gorm:"->"`
+}
+
+// TotalBalance is the spendable balance: the wallet row plus its shards.
+func (w *Wallet) TotalBalance() float64 {
+	return w.Balance + w.ShardBalance
+}
+
+// Available is what the wallet can be debited: its balance plus its credit
+// limit.
+func (w *Wallet) Available() float64 {
+	return w.TotalBalance() + w.CreditLimit
+}
+
+// AvailableCredit is the part of the credit limit not drawn on. It is zero
+// when the limit was lowered below the current overdraft.
+func (w *Wallet) AvailableCredit() float64 {
+	return max(0, min(w.CreditLimit, w.Available()))
+}
+
+// Revision counts the writes to the wallet row and its shards, and is what
+// ETags and If-Match preconditions carry. It never goes back: replacing
+// the shards folds the versions of the old ones into Version.
+func (w *Wallet) Revision() int64 {
+	return w.Version + w.ShardVersion
+}
+
+func (w *Wallet) Sharded() bool {
+	return w.ShardCount > 0
+}
+
+// WalletShard holds a slice of a hot wallet's balance so that concurrent
+// deposits can lock different rows.
+type WalletShard struct {
+	WalletID uuid.UUID `gorm:"type:uuid;primaryKey"`
+	ShardNo  int       `gorm:"primaryKey"`
+	Balance  float64   `gorm:"type:decimal(20,2);not null;default:0"`
+	// Version is incremented by every write to the shard row.
+	Version int64 `gorm:"not null;default:0"`
 }
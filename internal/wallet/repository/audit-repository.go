@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"sort"
+	"sync"
+	"time"
+	"wallet-service/internal/wallet/model"
+)
+
+// AuditFilter selects audit entries. Zero fields match everything; From is
+// inclusive and To exclusive.
+type AuditFilter struct {
+	Actor    string
+	WalletID uuid.UUID
+	From, To time.Time
+	Limit    int
+}
+
+func (f AuditFilter) matches(e *model.AuditEntry) bool {
+	return (f.Actor == "" || e.Actor == f.Actor) &&
+		(f.WalletID == uuid.Nil || e.WalletID == f.WalletID) &&
+		(f.From.IsZero() || !e.CreatedAt.Before(f.From)) &&
+		(f.To.IsZero() || e.CreatedAt.Before(f.To))
+}
+
+// AuditRepository reads the audit log. Entries are written by the
+// repositories whose changes they describe, inside the same transaction.
+type AuditRepository interface {
+	// ListAuditEntries returns up to filter.Limit matching entries, newest
+	// first.
+	ListAuditEntries(ctx context.Context, filter AuditFilter) ([]model.AuditEntry, error)
+}
+
+type auditRepository struct {
+	db *gorm.DB
+}
+
+func NewAuditRepository(db *gorm.DB) AuditRepository {
+	return &auditRepository{db: db}
+}
+
+func (r *auditRepository) ListAuditEntries(ctx context.Context, filter AuditFilter) ([]model.AuditEntry, error) {
+	q := r.db.WithContext(ctx).Order("created_at DESC, id DESC").Limit(filter.Limit)
+	if filter.Actor != "" {
+		q = q.Where("actor = ?", filter.Actor)
+	}
+	if filter.WalletID != uuid.Nil {
+		q = q.Where("wallet_id = ?", filter.WalletID)
+	}
+	if !filter.From.IsZero() {
+		q = q.Where("created_at >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		q = q.Where("created_at < ?", filter.To)
+	}
+
+	var entries []model.AuditEntry
+	if err := q.Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// MemoryAuditLog is an AuditRepository kept in process memory. The memory
+// repositories append to it when their transactions commit.
+type MemoryAuditLog struct {
+	mu      sync.RWMutex
+	entries []model.AuditEntry
+	nextID  int64
+}
+
+var _ AuditRepository = (*MemoryAuditLog)(nil)
+
+func NewMemoryAuditLog() *MemoryAuditLog {
+	return &MemoryAuditLog{}
+}
+
+func (l *MemoryAuditLog) ListAuditEntries(ctx context.Context, filter AuditFilter) ([]model.AuditEntry, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	var entries []model.AuditEntry
+	for i := len(l.entries) - 1; i >= 0; i-- {
+		if filter.matches(&l.entries[i]) {
+			entries = append(entries, l.entries[i])
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].CreatedAt.After(entries[j].CreatedAt) })
+	if filter.Limit > 0 && len(entries) > filter.Limit {
+		entries = entries[:filter.Limit]
+	}
+	return entries, nil
+}
+
+func (l *MemoryAuditLog) append(entries ...model.AuditEntry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, e := range entries {
+		l.nextID++
+		e.ID = l.nextID
+		l.entries = append(l.entries, e)
+	}
+}
+
+// stampAuditEntry fills in the fields the database would set on insert.
+func stampAuditEntry(e *model.AuditEntry) {
+	if e.CreatedAt.IsZero() {
+		e.CreatedAt = time.Now()
+	}
+}
@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"gorm.io/gorm"
+	"wallet-service/internal/db"
+	"wallet-service/internal/wallet/model"
+)
+
+// ErrDuplicateCustomer is returned by CreateCustomerTx when the tenant
+// already has a customer with the same external ID.
+var ErrDuplicateCustomer = errors.New("customer already exists")
+
+type CustomerRepository interface {
+	CreateCustomerTx(ctx context.Context, customer *model.Customer) error
+	// GetCustomerByExternalID returns the customer with the external ID in
+	// the context's tenant, or gorm.ErrRecordNotFound.
+	GetCustomerByExternalID(ctx context.Context, externalID string) (*model.Customer, error)
+
+	SaveAuditEntryTx(ctx context.Context, entry *model.AuditEntry) error
+	WithTx(ctx context.Context, fn func(txRepo CustomerRepository) error) error
+}
+
+type customerRepository struct {
+	db *gorm.DB
+}
+
+func NewCustomerRepository(db *gorm.DB) CustomerRepository {
+	return &customerRepository{db: db}
+}
+
+func (r *customerRepository) CreateCustomerTx(ctx context.Context, customer *model.Customer) error {
+	err := r.db.WithContext(ctx).Create(customer).Error
+	if db.IsUniqueViolation(err, "customers_tenant_id_external_id_key") {
+		return ErrDuplicateCustomer
+	}
+	return err
+}
+
+func (r *customerRepository) GetCustomerByExternalID(ctx context.Context, externalID string) (*model.Customer, error) {
+	var customer model.Customer
+	if err := r.db.WithContext(ctx).First(&customer, "external_id = ?", externalID).Error; err != nil {
+		return nil, err
+	}
+	return &customer, nil
+}
+
+func (r *customerRepository) SaveAuditEntryTx(ctx context.Context, entry *model.AuditEntry) error {
+	return r.db.WithContext(ctx).Create(entry).Error
+}
+
+func (r *customerRepository) WithTx(ctx context.Context, fn func(txRepo CustomerRepository) error) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(&customerRepository{db: tx})
+	})
+}
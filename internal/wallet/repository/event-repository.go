@@ -0,0 +1,197 @@
+package repository
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/stdlib"
+	"gorm.io/gorm"
+	"sync"
+	"wallet-service/internal/wallet/model"
+)
+
+// eventChannel is the channel migrations/014_wallet_events.sql notifies
+// with the wallet ID of every new event.
+const eventChannel = "wallet_events"
+
+// EventRepository reads the wallet event log. Events are written as
+// operations are saved, inside the same transaction.
+type EventRepository interface {
+	// ListEvents returns up to limit events of the wallet numbered above
+	// after, oldest first, each with its operation.
+	ListEvents(ctx context.Context, walletID uuid.UUID, after int64, limit int) ([]model.WalletEvent, error)
+	// Head returns the wallet's balance and the number of its latest
+	// event, or 0 if it has none, both as of the same moment.
+	Head(ctx context.Context, walletID uuid.UUID) (balance float64, seq int64, err error)
+	// Listen calls notify with the wallet of each event committed by any
+	// service instance until ctx is done or listening fails. Events
+	// committed before ready is called may not be notified.
+	Listen(ctx context.Context, ready func(), notify func(walletID uuid.UUID)) error
+}
+
+type eventRepository struct {
+	db *gorm.DB
+}
+
+func NewEventRepository(db *gorm.DB) EventRepository {
+	return &eventRepository{db: db}
+}
+
+func (r *eventRepository) ListEvents(ctx context.Context, walletID uuid.UUID, after int64, limit int) ([]model.WalletEvent, error) {
+	var events []model.WalletEvent
+	err := r.db.WithContext(ctx).
+		Preload("Operation").
+		Where("wallet_id = ? AND seq > ?", walletID, after).
+		Order("seq").
+		Limit(limit).
+		Find(&events).Error
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// Head reads both in one statement, so from one snapshot.
+func (r *eventRepository) Head(ctx context.Context, walletID uuid.UUID) (float64, int64, error) {
+	var head struct {
+		Balance float64
+		Seq     int64
+	}
+	res := r.db.WithContext(ctx).Raw(`SELECT
+			w.balance + COALESCE((SELECT SUM(s.balance) FROM wallet_shards s WHERE s.wallet_id = w.id), 0) AS balance,
+			COALESCE((SELECT q.seq FROM wallet_event_seqs q WHERE q.wallet_id = w.id), 0) AS seq
+		FROM wallets w WHERE w.id = ?`, walletID).Scan(&head)
+	if res.Error != nil {
+		return 0, 0, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return 0, 0, gorm.ErrRecordNotFound
+	}
+	return head.Balance, head.Seq, nil
+}
+
+// Listen holds one pooled connection for as long as it listens. The
+// connection is discarded afterwards rather than returned to the pool
+// still subscribed.
+func (r *eventRepository) Listen(ctx context.Context, ready func(), notify func(walletID uuid.UUID)) error {
+	sqlDB, err := r.db.DB()
+	if err != nil {
+		return err
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	return conn.Raw(func(driverConn any) error {
+		pgConn := driverConn.(*stdlib.Conn).Conn()
+		if _, err := pgConn.Exec(ctx, "LISTEN "+eventChannel); err != nil {
+			return errors.Join(err, driver.ErrBadConn)
+		}
+		ready()
+		for {
+			n, err := pgConn.WaitForNotification(ctx)
+			if err != nil {
+				return errors.Join(err, driver.ErrBadConn)
+			}
+			if walletID, err := uuid.Parse(n.Payload); err == nil {
+				notify(walletID)
+			}
+		}
+	})
+}
+
+// MemoryEventLog is an EventRepository kept in process memory. The memory
+// wallet repository appends to it when its transactions commit.
+type MemoryEventLog struct {
+	mu        sync.RWMutex
+	events    []model.WalletEvent
+	nextID    int64
+	seqs      map[uuid.UUID]int64
+	listeners map[*func(uuid.UUID)]struct{}
+
+	// head, set by the wallet repository appending to the log, returns a
+	// wallet's committed balance and latest event number together.
+	head func(walletID uuid.UUID) (float64, int64)
+}
+
+var _ EventRepository = (*MemoryEventLog)(nil)
+
+func NewMemoryEventLog() *MemoryEventLog {
+	return &MemoryEventLog{
+		seqs:      make(map[uuid.UUID]int64),
+		listeners: make(map[*func(uuid.UUID)]struct{}),
+	}
+}
+
+func (l *MemoryEventLog) ListEvents(ctx context.Context, walletID uuid.UUID, after int64, limit int) ([]model.WalletEvent, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	var events []model.WalletEvent
+	for _, e := range l.events {
+		if e.WalletID == walletID && e.Seq > after {
+			events = append(events, e)
+		}
+		if limit > 0 && len(events) == limit {
+			break
+		}
+	}
+	return events, nil
+}
+
+func (l *MemoryEventLog) Head(ctx context.Context, walletID uuid.UUID) (float64, int64, error) {
+	if l.head != nil {
+		balance, seq := l.head(walletID)
+		return balance, seq, nil
+	}
+	return 0, l.lastSeq(walletID), nil
+}
+
+func (l *MemoryEventLog) lastSeq(walletID uuid.UUID) int64 {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.seqs[walletID]
+}
+
+func (l *MemoryEventLog) Listen(ctx context.Context, ready func(), notify func(walletID uuid.UUID)) error {
+	l.mu.Lock()
+	l.listeners[&notify] = struct{}{}
+	l.mu.Unlock()
+	defer func() {
+		l.mu.Lock()
+		delete(l.listeners, &notify)
+		l.mu.Unlock()
+	}()
+
+	ready()
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (l *MemoryEventLog) append(events ...model.WalletEvent) {
+	if len(events) == 0 {
+		return
+	}
+
+	l.mu.Lock()
+	for _, e := range events {
+		l.nextID++
+		l.seqs[e.WalletID]++
+		e.ID, e.Seq = l.nextID, l.seqs[e.WalletID]
+		l.events = append(l.events, e)
+	}
+	listeners := make([]func(uuid.UUID), 0, len(l.listeners))
+	for notify := range l.listeners {
+		listeners = append(listeners, *notify)
+	}
+	l.mu.Unlock()
+
+	for _, notify := range listeners {
+		for _, e := range events {
+			notify(e.WalletID)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"wallet-service/internal/wallet/model"
+)
+
+type FeeRepository interface {
+	GetSchedule(ctx context.Context, id uuid.UUID) (*model.FeeSchedule, error)
+	// FindSchedule returns the schedule for the operation type and product,
+	// or gorm.ErrRecordNotFound. An empty product finds the default
+	// schedule.
+	FindSchedule(ctx context.Context, opType, product string) (*model.FeeSchedule, error)
+	ListSchedules(ctx context.Context) ([]model.FeeSchedule, error)
+	// SaveScheduleTx creates the schedule or, if its ID exists, updates it.
+	SaveScheduleTx(ctx context.Context, schedule *model.FeeSchedule) error
+	DeleteScheduleTx(ctx context.Context, id uuid.UUID) error
+
+	SaveAuditEntryTx(ctx context.Context, entry *model.AuditEntry) error
+	WithTx(ctx context.Context, fn func(txRepo FeeRepository) error) error
+}
+
+type feeRepository struct {
+	db *gorm.DB
+}
+
+func NewFeeRepository(db *gorm.DB) FeeRepository {
+	return &feeRepository{db: db}
+}
+
+func (r *feeRepository) GetSchedule(ctx context.Context, id uuid.UUID) (*model.FeeSchedule, error) {
+	var schedule model.FeeSchedule
+	if err := r.db.WithContext(ctx).First(&schedule, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+func (r *feeRepository) FindSchedule(ctx context.Context, opType, product string) (*model.FeeSchedule, error) {
+	var schedule model.FeeSchedule
+	err := r.db.WithContext(ctx).
+		Where("operation_type = ? AND product = ?", opType, product).
+		First(&schedule).Error
+	if err != nil {
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+func (r *feeRepository) ListSchedules(ctx context.Context) ([]model.FeeSchedule, error) {
+	var schedules []model.FeeSchedule
+	if err := r.db.WithContext(ctx).Order("operation_type, product").Find(&schedules).Error; err != nil {
+		return nil, err
+	}
+	return schedules, nil
+}
+
+func (r *feeRepository) SaveScheduleTx(ctx context.Context, schedule *model.FeeSchedule) error {
+	return r.db.WithContext(ctx).Save(schedule).Error
+}
+
+func (r *feeRepository) DeleteScheduleTx(ctx context.Context, id uuid.UUID) error {
+	res := r.db.WithContext(ctx).Delete(&model.FeeSchedule{}, "id = ?", id)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *feeRepository) SaveAuditEntryTx(ctx context.Context, entry *model.AuditEntry) error {
+	return r.db.WithContext(ctx).Create(entry).Error
+}
+
+func (r *feeRepository) WithTx(ctx context.Context, fn func(txRepo FeeRepository) error) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(&feeRepository{db: tx})
+	})
+}
@@ -0,0 +1,158 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+	"wallet-service/internal/wallet/model"
+)
+
+type InterestRepository interface {
+	GetRule(ctx context.Context, id uuid.UUID) (*model.InterestRule, error)
+	// FindRule returns the wallet's own rule if walletID is set, otherwise
+	// the product's rule, or gorm.ErrRecordNotFound.
+	FindRule(ctx context.Context, walletID *uuid.UUID, product string) (*model.InterestRule, error)
+	ListRules(ctx context.Context) ([]model.InterestRule, error)
+	// SaveRuleTx creates the rule or, if its ID exists, updates it.
+	SaveRuleTx(ctx context.Context, rule *model.InterestRule) error
+	DeleteRuleTx(ctx context.Context, id uuid.UUID) error
+
+	// LastAccrualDate returns the date of the wallet's latest accrual, or
+	// the zero time if it has none.
+	LastAccrualDate(ctx context.Context, walletID uuid.UUID) (time.Time, error)
+	// SaveAccrual stores a unless the wallet already has an accrual for
+	// that date.
+	SaveAccrual(ctx context.Context, a *model.InterestAccrual) error
+	// ListAccruals returns the wallet's accruals dated in [from, to), oldest
+	// first. Zero times leave that end open.
+	ListAccruals(ctx context.Context, walletID uuid.UUID, from, to time.Time) ([]model.InterestAccrual, error)
+	// UnpaidAccruals returns the wallet's accruals dated before the given
+	// date that have not been paid out, oldest first: those with a positive
+	// amount, or with overdraft set those with a negative one.
+	// Payouts mark the accruals they pay through the wallet repository, in
+	// the transaction posting them.
+	UnpaidAccruals(ctx context.Context, walletID uuid.UUID, before time.Time, overdraft bool) ([]model.InterestAccrual, error)
+
+	SaveAuditEntryTx(ctx context.Context, entry *model.AuditEntry) error
+	WithTx(ctx context.Context, fn func(txRepo InterestRepository) error) error
+}
+
+type interestRepository struct {
+	db *gorm.DB
+}
+
+func NewInterestRepository(db *gorm.DB) InterestRepository {
+	return &interestRepository{db: db}
+}
+
+func (r *interestRepository) GetRule(ctx context.Context, id uuid.UUID) (*model.InterestRule, error) {
+	var rule model.InterestRule
+	if err := r.db.WithContext(ctx).First(&rule, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+func (r *interestRepository) FindRule(ctx context.Context, walletID *uuid.UUID, product string) (*model.InterestRule, error) {
+	q := r.db.WithContext(ctx)
+	if walletID != nil {
+		q = q.Where("wallet_id = ?", *walletID)
+	} else {
+		q = q.Where("wallet_id IS NULL AND product = ?", product)
+	}
+
+	var rule model.InterestRule
+	if err := q.First(&rule).Error; err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+func (r *interestRepository) ListRules(ctx context.Context) ([]model.InterestRule, error) {
+	var rules []model.InterestRule
+	if err := r.db.WithContext(ctx).Order("created_at, id").Find(&rules).Error; err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+func (r *interestRepository) SaveRuleTx(ctx context.Context, rule *model.InterestRule) error {
+	return r.db.WithContext(ctx).Save(rule).Error
+}
+
+func (r *interestRepository) DeleteRuleTx(ctx context.Context, id uuid.UUID) error {
+	res := r.db.WithContext(ctx).Delete(&model.InterestRule{}, "id = ?", id)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *interestRepository) LastAccrualDate(ctx context.Context, walletID uuid.UUID) (time.Time, error) {
+	var a model.InterestAccrual
+	err := r.db.WithContext(ctx).Where("wallet_id = ?", walletID).Order("date DESC").First(&a).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	return a.Date, nil
+}
+
+func (r *interestRepository) SaveAccrual(ctx context.Context, a *model.InterestAccrual) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(a).Error
+}
+
+func (r *interestRepository) ListAccruals(ctx context.Context, walletID uuid.UUID, from, to time.Time) ([]model.InterestAccrual, error) {
+	q := r.db.WithContext(ctx).Where("wallet_id = ?", walletID).Order("date")
+	if !from.IsZero() {
+		q = q.Where("date >= ?", from)
+	}
+	if !to.IsZero() {
+		q = q.Where("date < ?", to)
+	}
+
+	var accruals []model.InterestAccrual
+	if err := q.Find(&accruals).Error; err != nil {
+		return nil, err
+	}
+	return accruals, nil
+}
+
+func (r *interestRepository) UnpaidAccruals(ctx context.Context, walletID uuid.UUID, before time.Time, overdraft bool) ([]model.InterestAccrual, error) {
+	var accruals []model.InterestAccrual
+	err := r.db.WithContext(ctx).
+		Where("wallet_id = ? AND date < ? AND payout_id IS NULL", walletID, before).
+		Where(unpaidSign(overdraft)).
+		Order("date").
+		Find(&accruals).Error
+	if err != nil {
+		return nil, err
+	}
+	return accruals, nil
+}
+
+// unpaidSign selects interest earned, or overdraft interest owed.
+func unpaidSign(overdraft bool) string {
+	if overdraft {
+		return "amount < 0"
+	}
+	return "amount > 0"
+}
+
+func (r *interestRepository) SaveAuditEntryTx(ctx context.Context, entry *model.AuditEntry) error {
+	return r.db.WithContext(ctx).Create(entry).Error
+}
+
+func (r *interestRepository) WithTx(ctx context.Context, fn func(txRepo InterestRepository) error) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(&interestRepository{db: tx})
+	})
+}
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"sync"
+	"time"
+	"wallet-service/internal/tenant"
+	"wallet-service/internal/wallet/model"
+)
+
+// MemoryCustomerRepository is a CustomerRepository kept in process memory.
+// Writes are applied immediately and are not rolled back when WithTx
+// fails.
+type MemoryCustomerRepository struct {
+	mu        sync.Mutex
+	customers []model.Customer
+	audit     *MemoryAuditLog
+}
+
+var _ CustomerRepository = (*MemoryCustomerRepository)(nil)
+
+func NewMemoryCustomerRepository() *MemoryCustomerRepository {
+	return &MemoryCustomerRepository{audit: NewMemoryAuditLog()}
+}
+
+// SetAuditLog makes the repository append its audit entries to log, so
+// that they can be read together with those of a MemoryWalletRepository.
+func (r *MemoryCustomerRepository) SetAuditLog(log *MemoryAuditLog) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.audit = log
+}
+
+func (r *MemoryCustomerRepository) CreateCustomerTx(ctx context.Context, customer *model.Customer) error {
+	if customer.TenantID == "" {
+		customer.TenantID = tenant.Default
+	}
+	if customer.CreatedAt.IsZero() {
+		customer.CreatedAt = time.Now()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, c := range r.customers {
+		if c.TenantID == customer.TenantID && c.ExternalID == customer.ExternalID {
+			return ErrDuplicateCustomer
+		}
+	}
+	r.customers = append(r.customers, *customer)
+	return nil
+}
+
+func (r *MemoryCustomerRepository) GetCustomerByExternalID(ctx context.Context, externalID string) (*model.Customer, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, c := range r.customers {
+		if c.ExternalID == externalID && tenant.Visible(ctx, c.TenantID) {
+			return &c, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *MemoryCustomerRepository) SaveAuditEntryTx(ctx context.Context, entry *model.AuditEntry) error {
+	stampAuditEntry(entry)
+	r.mu.Lock()
+	log := r.audit
+	r.mu.Unlock()
+	log.append(*entry)
+	return nil
+}
+
+func (r *MemoryCustomerRepository) WithTx(ctx context.Context, fn func(txRepo CustomerRepository) error) error {
+	return fn(r)
+}
@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"sort"
+	"sync"
+	"time"
+	"wallet-service/internal/wallet/model"
+)
+
+// MemoryFeeRepository is a FeeRepository kept in process memory. Writes are
+// applied immediately and are not rolled back when WithTx fails.
+type MemoryFeeRepository struct {
+	mu        sync.Mutex
+	schedules map[uuid.UUID]model.FeeSchedule
+	audit     *MemoryAuditLog
+}
+
+var _ FeeRepository = (*MemoryFeeRepository)(nil)
+
+func NewMemoryFeeRepository() *MemoryFeeRepository {
+	return &MemoryFeeRepository{
+		schedules: make(map[uuid.UUID]model.FeeSchedule),
+		audit:     NewMemoryAuditLog(),
+	}
+}
+
+// SetAuditLog makes the repository append its audit entries to log, so
+// that they can be read together with those of a MemoryWalletRepository.
+func (r *MemoryFeeRepository) SetAuditLog(log *MemoryAuditLog) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.audit = log
+}
+
+func (r *MemoryFeeRepository) GetSchedule(ctx context.Context, id uuid.UUID) (*model.FeeSchedule, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	schedule, ok := r.schedules[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &schedule, nil
+}
+
+func (r *MemoryFeeRepository) FindSchedule(ctx context.Context, opType, product string) (*model.FeeSchedule, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, schedule := range r.schedules {
+		if schedule.OperationType == opType && schedule.Product == product {
+			return &schedule, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *MemoryFeeRepository) ListSchedules(ctx context.Context) ([]model.FeeSchedule, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	schedules := make([]model.FeeSchedule, 0, len(r.schedules))
+	for _, schedule := range r.schedules {
+		schedules = append(schedules, schedule)
+	}
+	sort.Slice(schedules, func(i, j int) bool {
+		if schedules[i].OperationType != schedules[j].OperationType {
+			return schedules[i].OperationType < schedules[j].OperationType
+		}
+		return schedules[i].Product < schedules[j].Product
+	})
+	return schedules, nil
+}
+
+func (r *MemoryFeeRepository) SaveScheduleTx(ctx context.Context, schedule *model.FeeSchedule) error {
+	now := time.Now()
+	if schedule.CreatedAt.IsZero() {
+		schedule.CreatedAt = now
+	}
+	schedule.UpdatedAt = now
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.schedules[schedule.ID] = *schedule
+	return nil
+}
+
+func (r *MemoryFeeRepository) DeleteScheduleTx(ctx context.Context, id uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.schedules[id]; !ok {
+		return gorm.ErrRecordNotFound
+	}
+	delete(r.schedules, id)
+	return nil
+}
+
+func (r *MemoryFeeRepository) SaveAuditEntryTx(ctx context.Context, entry *model.AuditEntry) error {
+	stampAuditEntry(entry)
+	r.mu.Lock()
+	log := r.audit
+	r.mu.Unlock()
+	log.append(*entry)
+	return nil
+}
+
+func (r *MemoryFeeRepository) WithTx(ctx context.Context, fn func(txRepo FeeRepository) error) error {
+	return fn(r)
+}
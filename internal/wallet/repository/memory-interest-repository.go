@@ -0,0 +1,207 @@
+package repository
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"sort"
+	"sync"
+	"time"
+	"wallet-service/internal/wallet/model"
+)
+
+// MemoryInterestRepository is an InterestRepository kept in process memory.
+// Writes are applied immediately and are not rolled back when WithTx fails.
+type MemoryInterestRepository struct {
+	mu       sync.Mutex
+	rules    map[uuid.UUID]model.InterestRule
+	accruals map[uuid.UUID][]model.InterestAccrual
+	audit    *MemoryAuditLog
+}
+
+var _ InterestRepository = (*MemoryInterestRepository)(nil)
+
+func NewMemoryInterestRepository() *MemoryInterestRepository {
+	return &MemoryInterestRepository{
+		rules:    make(map[uuid.UUID]model.InterestRule),
+		accruals: make(map[uuid.UUID][]model.InterestAccrual),
+		audit:    NewMemoryAuditLog(),
+	}
+}
+
+// SetAuditLog makes the repository append its audit entries to log, so
+// that they can be read together with those of a MemoryWalletRepository.
+func (r *MemoryInterestRepository) SetAuditLog(log *MemoryAuditLog) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.audit = log
+}
+
+func (r *MemoryInterestRepository) GetRule(ctx context.Context, id uuid.UUID) (*model.InterestRule, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rule, ok := r.rules[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &rule, nil
+}
+
+func (r *MemoryInterestRepository) FindRule(ctx context.Context, walletID *uuid.UUID, product string) (*model.InterestRule, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, rule := range r.rules {
+		switch {
+		case walletID != nil:
+			if rule.WalletID != nil && *rule.WalletID == *walletID {
+				return &rule, nil
+			}
+		case rule.WalletID == nil && rule.Product == product:
+			return &rule, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *MemoryInterestRepository) ListRules(ctx context.Context) ([]model.InterestRule, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rules := make([]model.InterestRule, 0, len(r.rules))
+	for _, rule := range r.rules {
+		rules = append(rules, rule)
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].CreatedAt.Before(rules[j].CreatedAt) })
+	return rules, nil
+}
+
+func (r *MemoryInterestRepository) SaveRuleTx(ctx context.Context, rule *model.InterestRule) error {
+	now := time.Now()
+	if rule.CreatedAt.IsZero() {
+		rule.CreatedAt = now
+	}
+	rule.UpdatedAt = now
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rules[rule.ID] = *rule
+	return nil
+}
+
+func (r *MemoryInterestRepository) DeleteRuleTx(ctx context.Context, id uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.rules[id]; !ok {
+		return gorm.ErrRecordNotFound
+	}
+	delete(r.rules, id)
+	return nil
+}
+
+func (r *MemoryInterestRepository) LastAccrualDate(ctx context.Context, walletID uuid.UUID) (time.Time, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var last time.Time
+	for _, a := range r.accruals[walletID] {
+		if a.Date.After(last) {
+			last = a.Date
+		}
+	}
+	return last, nil
+}
+
+func (r *MemoryInterestRepository) SaveAccrual(ctx context.Context, a *model.InterestAccrual) error {
+	if a.CreatedAt.IsZero() {
+		a.CreatedAt = time.Now()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, existing := range r.accruals[a.WalletID] {
+		if existing.Date.Equal(a.Date) {
+			return nil
+		}
+	}
+	r.accruals[a.WalletID] = append(r.accruals[a.WalletID], *a)
+	return nil
+}
+
+func (r *MemoryInterestRepository) ListAccruals(ctx context.Context, walletID uuid.UUID, from, to time.Time) ([]model.InterestAccrual, error) {
+	return r.selectAccruals(walletID, func(a *model.InterestAccrual) bool {
+		return (from.IsZero() || !a.Date.Before(from)) && (to.IsZero() || a.Date.Before(to))
+	}), nil
+}
+
+func (r *MemoryInterestRepository) UnpaidAccruals(ctx context.Context, walletID uuid.UUID, before time.Time, overdraft bool) ([]model.InterestAccrual, error) {
+	return r.selectAccruals(walletID, func(a *model.InterestAccrual) bool {
+		return unpaid(a, before, overdraft)
+	}), nil
+}
+
+func unpaid(a *model.InterestAccrual, before time.Time, overdraft bool) bool {
+	signed := a.Amount > 0
+	if overdraft {
+		signed = a.Amount < 0
+	}
+	return a.PayoutID == nil && signed && a.Date.Before(before)
+}
+
+func (r *MemoryInterestRepository) selectAccruals(walletID uuid.UUID, keep func(*model.InterestAccrual) bool) []model.InterestAccrual {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var out []model.InterestAccrual
+	for i := range r.accruals[walletID] {
+		if keep(&r.accruals[walletID][i]) {
+			out = append(out, r.accruals[walletID][i])
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Date.Before(out[j].Date) })
+	return out
+}
+
+// markPaid does what MarkAccrualsPaidTx does in Postgres, for the memory
+// wallet repository the accruals are attached to.
+func (r *MemoryInterestRepository) markPaid(p accrualPayout) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.accruals[p.walletID] {
+		a := &r.accruals[p.walletID][i]
+		if unpaid(a, p.before, p.overdraft) {
+			id := p.payoutID
+			a.PayoutID = &id
+		}
+	}
+}
+
+// countUnpaid returns how many accruals markPaid would mark.
+func (r *MemoryInterestRepository) countUnpaid(p accrualPayout) int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var n int64
+	for i := range r.accruals[p.walletID] {
+		if unpaid(&r.accruals[p.walletID][i], p.before, p.overdraft) {
+			n++
+		}
+	}
+	return n
+}
+
+func (r *MemoryInterestRepository) SaveAuditEntryTx(ctx context.Context, entry *model.AuditEntry) error {
+	stampAuditEntry(entry)
+	r.mu.Lock()
+	log := r.audit
+	r.mu.Unlock()
+	log.append(*entry)
+	return nil
+}
+
+func (r *MemoryInterestRepository) WithTx(ctx context.Context, fn func(txRepo InterestRepository) error) error {
+	return fn(r)
+}
@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"sort"
+	"sync"
+	"time"
+	"wallet-service/internal/wallet/model"
+)
+
+// MemoryRateRepository is a RateRepository kept in process memory. Writes
+// are applied immediately and are not rolled back when WithTx fails.
+type MemoryRateRepository struct {
+	mu    sync.Mutex
+	rates []model.ExchangeRate
+	audit *MemoryAuditLog
+}
+
+var _ RateRepository = (*MemoryRateRepository)(nil)
+
+func NewMemoryRateRepository() *MemoryRateRepository {
+	return &MemoryRateRepository{audit: NewMemoryAuditLog()}
+}
+
+// SetAuditLog makes the repository append its audit entries to log, so
+// that they can be read together with those of a MemoryWalletRepository.
+func (r *MemoryRateRepository) SetAuditLog(log *MemoryAuditLog) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.audit = log
+}
+
+func (r *MemoryRateRepository) SaveRatesTx(ctx context.Context, rates []model.ExchangeRate) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+next:
+	for _, rate := range rates {
+		if rate.CreatedAt.IsZero() {
+			rate.CreatedAt = time.Now()
+		}
+		for i := range r.rates {
+			existing := &r.rates[i]
+			if existing.Base == rate.Base && existing.Quote == rate.Quote && existing.ValidFrom.Equal(rate.ValidFrom) {
+				existing.ValidTo, existing.Rate = rate.ValidTo, rate.Rate
+				continue next
+			}
+		}
+		r.rates = append(r.rates, rate)
+	}
+	return nil
+}
+
+func (r *MemoryRateRepository) FindRate(ctx context.Context, base, quote string, at time.Time) (*model.ExchangeRate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var found *model.ExchangeRate
+	for i := range r.rates {
+		rate := &r.rates[i]
+		if rate.Base == base && rate.Quote == quote && rate.Covers(at) &&
+			(found == nil || rate.ValidFrom.After(found.ValidFrom)) {
+			found = rate
+		}
+	}
+	if found == nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+	cp := *found
+	return &cp, nil
+}
+
+func (r *MemoryRateRepository) ListRates(ctx context.Context, base, quote string) ([]model.ExchangeRate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var rates []model.ExchangeRate
+	for _, rate := range r.rates {
+		if (base == "" || rate.Base == base) && (quote == "" || rate.Quote == quote) {
+			rates = append(rates, rate)
+		}
+	}
+	sort.Slice(rates, func(i, j int) bool {
+		a, b := rates[i], rates[j]
+		if a.Base != b.Base {
+			return a.Base < b.Base
+		}
+		if a.Quote != b.Quote {
+			return a.Quote < b.Quote
+		}
+		return a.ValidFrom.After(b.ValidFrom)
+	})
+	return rates, nil
+}
+
+func (r *MemoryRateRepository) SaveAuditEntryTx(ctx context.Context, entry *model.AuditEntry) error {
+	stampAuditEntry(entry)
+	r.mu.Lock()
+	log := r.audit
+	r.mu.Unlock()
+	log.append(*entry)
+	return nil
+}
+
+func (r *MemoryRateRepository) WithTx(ctx context.Context, fn func(txRepo RateRepository) error) error {
+	return fn(r)
+}
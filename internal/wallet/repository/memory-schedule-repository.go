@@ -0,0 +1,211 @@
+package repository
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"sort"
+	"sync"
+	"time"
+	"wallet-service/internal/tenant"
+	"wallet-service/internal/wallet/model"
+)
+
+// MemoryScheduleRepository is a ScheduleRepository kept in process memory.
+// Row locks behave like their Postgres counterparts, but writes are applied
+// immediately and are not rolled back when WithTx fails.
+type MemoryScheduleRepository struct {
+	store *memoryScheduleStore
+	held  map[uuid.UUID]chan struct{}
+}
+
+type memoryScheduleStore struct {
+	mu        sync.Mutex
+	schedules map[uuid.UUID]model.Schedule
+	runs      []model.ScheduleRun
+	audit     *MemoryAuditLog
+	locks     map[uuid.UUID]chan struct{}
+}
+
+var _ ScheduleRepository = (*MemoryScheduleRepository)(nil)
+
+func NewMemoryScheduleRepository() *MemoryScheduleRepository {
+	return &MemoryScheduleRepository{
+		store: &memoryScheduleStore{
+			schedules: make(map[uuid.UUID]model.Schedule),
+			audit:     NewMemoryAuditLog(),
+			locks:     make(map[uuid.UUID]chan struct{}),
+		},
+	}
+}
+
+func (r *MemoryScheduleRepository) CreateSchedule(ctx context.Context, s *model.Schedule) error {
+	now := time.Now()
+	if s.CreatedAt.IsZero() {
+		s.CreatedAt = now
+	}
+	s.UpdatedAt = now
+	if s.TenantID == "" {
+		s.TenantID = tenant.Default
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	r.store.schedules[s.ID] = *s
+	return nil
+}
+
+// SetAuditLog makes the repository append its audit entries to log, so
+// that they can be read together with those of a MemoryWalletRepository.
+func (r *MemoryScheduleRepository) SetAuditLog(log *MemoryAuditLog) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	r.store.audit = log
+}
+
+func (r *MemoryScheduleRepository) SaveAuditEntryTx(ctx context.Context, entry *model.AuditEntry) error {
+	stampAuditEntry(entry)
+	r.store.mu.Lock()
+	log := r.store.audit
+	r.store.mu.Unlock()
+	log.append(*entry)
+	return nil
+}
+
+func (r *MemoryScheduleRepository) GetSchedule(ctx context.Context, id uuid.UUID) (*model.Schedule, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	s, ok := r.store.schedules[id]
+	if !ok || !tenant.Visible(ctx, s.TenantID) {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &s, nil
+}
+
+func (r *MemoryScheduleRepository) GetScheduleForUpdate(ctx context.Context, id uuid.UUID) (*model.Schedule, error) {
+	if _, err := r.GetSchedule(ctx, id); err != nil {
+		return nil, err
+	}
+	if r.held != nil {
+		if _, ok := r.held[id]; !ok {
+			l := r.store.rowLock(id)
+			select {
+			case l <- struct{}{}:
+				r.held[id] = l
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+	}
+	return r.GetSchedule(ctx, id)
+}
+
+func (r *MemoryScheduleRepository) ListSchedules(ctx context.Context, walletID uuid.UUID) ([]model.Schedule, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var out []model.Schedule
+	for _, s := range r.store.schedules {
+		if s.WalletID == walletID && tenant.Visible(ctx, s.TenantID) {
+			out = append(out, s)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	return out, nil
+}
+
+func (r *MemoryScheduleRepository) UpdateScheduleTx(ctx context.Context, s *model.Schedule) error {
+	s.UpdatedAt = time.Now()
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	r.store.schedules[s.ID] = *s
+	return nil
+}
+
+func (r *MemoryScheduleRepository) ClaimDueSchedulesTx(ctx context.Context, now time.Time, limit int) ([]model.Schedule, error) {
+	r.store.mu.Lock()
+	var due []model.Schedule
+	for _, s := range r.store.schedules {
+		if s.Status == model.ScheduleActive && !s.DueAt.After(now) {
+			due = append(due, s)
+		}
+	}
+	r.store.mu.Unlock()
+	sort.Slice(due, func(i, j int) bool { return due[i].DueAt.Before(due[j].DueAt) })
+
+	var claimed []model.Schedule
+	for _, s := range due {
+		if len(claimed) == limit {
+			break
+		}
+		if r.held == nil {
+			claimed = append(claimed, s)
+			continue
+		}
+		l := r.store.rowLock(s.ID)
+		select {
+		case l <- struct{}{}:
+			r.held[s.ID] = l
+		default:
+			continue
+		}
+		// Re-read under the lock: another transaction may have moved it since
+		// the scan above.
+		if cur, err := r.GetSchedule(ctx, s.ID); err == nil && cur.Status == model.ScheduleActive && !cur.DueAt.After(now) {
+			claimed = append(claimed, *cur)
+		}
+	}
+	return claimed, nil
+}
+
+func (r *MemoryScheduleRepository) SaveScheduleRunTx(ctx context.Context, run *model.ScheduleRun) error {
+	if run.CreatedAt.IsZero() {
+		run.CreatedAt = time.Now()
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	r.store.runs = append(r.store.runs, *run)
+	return nil
+}
+
+func (r *MemoryScheduleRepository) ListScheduleRuns(ctx context.Context, scheduleID uuid.UUID, limit int) ([]model.ScheduleRun, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var out []model.ScheduleRun
+	for i := len(r.store.runs) - 1; i >= 0 && len(out) < limit; i-- {
+		if r.store.runs[i].ScheduleID == scheduleID {
+			out = append(out, r.store.runs[i])
+		}
+	}
+	return out, nil
+}
+
+func (r *MemoryScheduleRepository) WithTx(ctx context.Context, fn func(txRepo ScheduleRepository) error) error {
+	if r.held != nil {
+		return fn(r)
+	}
+
+	tx := &MemoryScheduleRepository{store: r.store, held: make(map[uuid.UUID]chan struct{})}
+	defer func() {
+		for _, l := range tx.held {
+			<-l
+		}
+	}()
+	return fn(tx)
+}
+
+func (s *memoryScheduleStore) rowLock(id uuid.UUID) chan struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	l, ok := s.locks[id]
+	if !ok {
+		l = make(chan struct{}, 1)
+		s.locks[id] = l
+	}
+	return l
+}
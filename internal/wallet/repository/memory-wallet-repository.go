@@ -0,0 +1,875 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"sort"
+	"sync"
+	"time"
+	"wallet-service/internal/tenant"
+	"wallet-service/internal/wallet/model"
+)
+
+// MemoryWalletRepository is a thread-safe WalletRepository kept entirely in
+// process memory. It mirrors the transactional behaviour of the Postgres
+// implementation: the ...ForUpdate methods take row locks that are held
+// until the surrounding WithTx returns, writes made inside WithTx are
+// only published when fn succeeds, and reads only see the wallets and
+// operations of the context's tenant.
+type MemoryWalletRepository struct {
+	store *memoryStore
+	tx    *memoryTx
+}
+
+type memoryStore struct {
+	mu          sync.RWMutex
+	wallets     map[uuid.UUID]model.Wallet
+	shards      map[uuid.UUID][]model.WalletShard
+	operations  []model.Operation
+	opIDs       map[uuid.UUID]struct{}
+	adjustments []model.Adjustment
+	fxLegs      []model.FXLeg
+	audit       *MemoryAuditLog
+	events      *MemoryEventLog
+	interest    *MemoryInterestRepository
+	snapshots   map[uuid.UUID][]model.BalanceSnapshot
+	locks       map[rowKey]chan struct{}
+}
+
+// rowKey identifies a lockable row: a wallet (shard -1) or one of its shards.
+type rowKey struct {
+	walletID uuid.UUID
+	shard    int
+}
+
+func walletRow(id uuid.UUID) rowKey {
+	return rowKey{walletID: id, shard: -1}
+}
+
+// memoryTx buffers the writes of one transaction. Nested transactions get a
+// child whose writes are merged into the parent on success, like savepoints.
+// Shard balances are buffered per row so that transactions touching
+// different shards of one wallet do not overwrite each other on commit;
+// shardSets holds whole replacements made by ReplaceShardsTx.
+type memoryTx struct {
+	parent      *memoryTx
+	wallets     map[uuid.UUID]model.Wallet
+	shardSets   map[uuid.UUID][]model.WalletShard
+	shardWrites map[rowKey]float64
+	operations  []model.Operation
+	adjustments []model.Adjustment
+	fxLegs      []model.FXLeg
+	payouts     []accrualPayout
+	audit       []model.AuditEntry
+	events      []model.WalletEvent
+	held        map[rowKey]chan struct{}
+}
+
+// accrualPayout marks the interest accruals a payout pays.
+type accrualPayout struct {
+	walletID  uuid.UUID
+	before    time.Time
+	overdraft bool
+	payoutID  uuid.UUID
+}
+
+func newMemoryTx(parent *memoryTx) *memoryTx {
+	return &memoryTx{
+		parent:      parent,
+		wallets:     make(map[uuid.UUID]model.Wallet),
+		shardSets:   make(map[uuid.UUID][]model.WalletShard),
+		shardWrites: make(map[rowKey]float64),
+	}
+}
+
+var _ WalletRepository = (*MemoryWalletRepository)(nil)
+
+func NewMemoryWalletRepository(wallets ...model.Wallet) *MemoryWalletRepository {
+	r := &MemoryWalletRepository{
+		store: &memoryStore{
+			wallets:   make(map[uuid.UUID]model.Wallet),
+			shards:    make(map[uuid.UUID][]model.WalletShard),
+			opIDs:     make(map[uuid.UUID]struct{}),
+			audit:     NewMemoryAuditLog(),
+			events:    NewMemoryEventLog(),
+			snapshots: make(map[uuid.UUID][]model.BalanceSnapshot),
+			locks:     make(map[rowKey]chan struct{}),
+		},
+	}
+	// Commits append events while holding the store lock, so reading the
+	// balance under it sees the events that made it.
+	r.store.events.head = func(walletID uuid.UUID) (float64, int64) {
+		r.store.mu.RLock()
+		defer r.store.mu.RUnlock()
+		balance := r.store.wallets[walletID].Balance
+		for _, s := range r.store.shards[walletID] {
+			balance += s.Balance
+		}
+		return balance, r.store.events.lastSeq(walletID)
+	}
+	for _, w := range wallets {
+		r.AddWallet(w)
+	}
+	return r
+}
+
+// AddWallet inserts or replaces a wallet outside of any transaction.
+func (r *MemoryWalletRepository) AddWallet(w model.Wallet) {
+	if w.CreatedAt.IsZero() {
+		w.CreatedAt = time.Now()
+	}
+	if w.Status == "" {
+		w.Status = model.WalletActive
+	}
+	if w.Currency == "" {
+		w.Currency = model.DefaultCurrency
+	}
+	if w.TenantID == "" {
+		w.TenantID = tenant.Default
+	}
+	w.ShardBalance, w.ShardVersion = 0, 0
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	r.store.wallets[w.ID] = w
+}
+
+// SeedWallet adds the wallet like AddWallet and credits its balance to it
+// with an OPENING operation, as migrations/022_opening_balances.sql does
+// for the wallets seeded in Postgres, so that its ledger accounts for it.
+func (r *MemoryWalletRepository) SeedWallet(w model.Wallet) error {
+	if w.CreatedAt.IsZero() {
+		w.CreatedAt = time.Now()
+	}
+	r.AddWallet(w)
+	if w.Balance <= 0 {
+		return nil
+	}
+	op := &model.Operation{
+		ID:          uuid.New(),
+		WalletID:    w.ID,
+		Type:        model.OperationOpening,
+		Amount:      w.Balance,
+		CreatedAt:   w.CreatedAt,
+		Description: "Opening balance",
+	}
+	return r.SaveOperationTx(context.Background(), op)
+}
+
+// Operations returns the committed operations of a wallet in insertion order.
+func (r *MemoryWalletRepository) Operations(walletID uuid.UUID) []model.Operation {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var ops []model.Operation
+	for _, op := range r.store.operations {
+		if op.WalletID == walletID {
+			ops = append(ops, op)
+		}
+	}
+	return ops
+}
+
+func (r *MemoryWalletRepository) GetWalletById(ctx context.Context, id uuid.UUID) (*model.Wallet, error) {
+	w, ok := r.lookupWithShards(id)
+	if !ok || !tenant.Visible(ctx, w.TenantID) {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &w, nil
+}
+
+func (r *MemoryWalletRepository) GetWalletByIdForUpdate(ctx context.Context, id uuid.UUID) (*model.Wallet, error) {
+	if r.tx != nil {
+		if w, ok := r.lookup(id); !ok || !tenant.Visible(ctx, w.TenantID) {
+			return nil, gorm.ErrRecordNotFound
+		}
+		if err := r.tx.lock(ctx, r.store, walletRow(id)); err != nil {
+			return nil, err
+		}
+	}
+	return r.GetWalletById(ctx, id)
+}
+
+// GetWalletByIdForShare takes the same row lock as GetWalletByIdForUpdate,
+// as the memory repository has no shared locks. Share lock holders then
+// wait for each other, which Postgres does not make them do.
+func (r *MemoryWalletRepository) GetWalletByIdForShare(ctx context.Context, id uuid.UUID) (*model.Wallet, error) {
+	return r.GetWalletByIdForUpdate(ctx, id)
+}
+
+func (r *MemoryWalletRepository) UpdateWalletTx(ctx context.Context, id uuid.UUID, newBalance float64) error {
+	return r.updateWallet(id, func(w *model.Wallet) {
+		w.Balance = newBalance
+		w.Version++
+	})
+}
+
+func (r *MemoryWalletRepository) UpdateWalletVersionedTx(ctx context.Context, id uuid.UUID, version int64, newBalance float64) error {
+	// Like an UPDATE in Postgres, take the row lock first so the version is
+	// compared against the latest committed state.
+	if r.tx != nil {
+		if _, ok := r.lookup(id); ok {
+			if err := r.tx.lock(ctx, r.store, walletRow(id)); err != nil {
+				return err
+			}
+		}
+	}
+
+	conflict := true
+	err := r.updateWallet(id, func(w *model.Wallet) {
+		if w.Version != version {
+			return
+		}
+		w.Balance = newBalance
+		w.Version++
+		conflict = false
+	})
+	if err != nil {
+		return err
+	}
+	if conflict {
+		return ErrVersionConflict
+	}
+	return nil
+}
+
+func (r *MemoryWalletRepository) SaveOperationTx(ctx context.Context, op *model.Operation) error {
+	wallet, ok := r.lookupWithShards(op.WalletID)
+	if !ok {
+		return fmt.Errorf("operation %s: wallet %s does not exist", op.ID, op.WalletID)
+	}
+	if r.hasOperation(op.ID) {
+		return fmt.Errorf("operation %s: %w", op.ID, ErrDuplicateOperation)
+	}
+	if op.ExternalRef != "" && r.hasExternalRef(op.WalletID, op.ExternalRef) {
+		return fmt.Errorf("operation %s: external reference %q: %w", op.ID, op.ExternalRef, ErrDuplicateExternalRef)
+	}
+	if op.CreatedAt.IsZero() {
+		op.CreatedAt = time.Now()
+	}
+	// Like the triggers in Postgres, take the wallet's tenant and record
+	// its balance as this transaction sees it.
+	op.TenantID = wallet.TenantID
+	event := model.WalletEvent{
+		WalletID:    op.WalletID,
+		OperationID: op.ID,
+		Balance:     wallet.TotalBalance(),
+		CreatedAt:   op.CreatedAt,
+		Operation:   *op,
+	}
+
+	if r.tx == nil {
+		r.store.mu.Lock()
+		r.store.addOperation(*op)
+		r.store.events.append(event)
+		r.store.mu.Unlock()
+		return nil
+	}
+
+	r.tx.operations = append(r.tx.operations, *op)
+	r.tx.events = append(r.tx.events, event)
+	return nil
+}
+
+func (r *MemoryWalletRepository) GetShardForUpdate(ctx context.Context, walletID uuid.UUID, shardNo int) (*model.WalletShard, error) {
+	if r.tx != nil && r.findShard(walletID, shardNo) != nil {
+		if err := r.tx.lock(ctx, r.store, rowKey{walletID: walletID, shard: shardNo}); err != nil {
+			return nil, err
+		}
+	}
+	if s := r.findShard(walletID, shardNo); s != nil {
+		return s, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *MemoryWalletRepository) GetShardsForUpdate(ctx context.Context, walletID uuid.UUID) ([]model.WalletShard, error) {
+	if r.tx != nil {
+		for _, s := range r.lookupShards(walletID) {
+			if err := r.tx.lock(ctx, r.store, rowKey{walletID: walletID, shard: s.ShardNo}); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return r.lookupShards(walletID), nil
+}
+
+func (r *MemoryWalletRepository) UpdateShardTx(ctx context.Context, walletID uuid.UUID, shardNo int, newBalance float64) error {
+	if r.findShard(walletID, shardNo) == nil {
+		return nil
+	}
+
+	key := rowKey{walletID: walletID, shard: shardNo}
+	if r.tx != nil {
+		r.tx.shardWrites[key] = newBalance
+		return nil
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	r.store.applyShardWrites(map[rowKey]float64{key: newBalance})
+	return nil
+}
+
+func (r *MemoryWalletRepository) ReplaceShardsTx(ctx context.Context, walletID uuid.UUID, shards []model.WalletShard) error {
+	if _, ok := r.lookup(walletID); !ok {
+		return nil
+	}
+
+	var dropped int64
+	for _, s := range r.lookupShards(walletID) {
+		dropped += s.Version
+	}
+	replaced := make([]model.WalletShard, len(shards))
+	copy(replaced, shards)
+	for i := range replaced {
+		replaced[i].WalletID = walletID
+	}
+	sort.Slice(replaced, func(i, j int) bool { return replaced[i].ShardNo < replaced[j].ShardNo })
+
+	if r.tx != nil {
+		r.tx.shardSets[walletID] = replaced
+		r.tx.dropShardWrites(walletID)
+	} else {
+		r.store.mu.Lock()
+		r.store.shards[walletID] = replaced
+		r.store.mu.Unlock()
+	}
+	return r.updateWallet(walletID, func(w *model.Wallet) {
+		w.ShardCount = len(replaced)
+		w.Version += 1 + dropped
+	})
+}
+
+func (r *MemoryWalletRepository) CreateWallet(ctx context.Context, wallet *model.Wallet) error {
+	if _, ok := r.lookup(wallet.ID); ok {
+		return ErrDuplicateWallet
+	}
+	if wallet.CustomerID != nil && wallet.Label != "" {
+		if _, ok := r.findByLabel(*wallet.CustomerID, wallet.Label); ok {
+			return ErrDuplicateLabel
+		}
+	}
+	if wallet.CreatedAt.IsZero() {
+		wallet.CreatedAt = time.Now()
+	}
+	if wallet.Status == "" {
+		wallet.Status = model.WalletActive
+	}
+	if wallet.Currency == "" {
+		wallet.Currency = model.DefaultCurrency
+	}
+	if wallet.TenantID == "" {
+		wallet.TenantID = tenant.Default
+	}
+
+	if r.tx == nil {
+		r.AddWallet(*wallet)
+		return nil
+	}
+	w := *wallet
+	w.ShardBalance, w.ShardVersion = 0, 0
+	r.tx.wallets[w.ID] = w
+	return nil
+}
+
+func (r *MemoryWalletRepository) ListWallets(ctx context.Context, after uuid.UUID, limit int) ([]model.Wallet, error) {
+	ids := r.walletIDs()
+	sorted := make([]uuid.UUID, 0, len(ids))
+	for id := range ids {
+		if bytes.Compare(id[:], after[:]) > 0 {
+			sorted = append(sorted, id)
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool { return bytes.Compare(sorted[i][:], sorted[j][:]) < 0 })
+
+	wallets := make([]model.Wallet, 0, len(sorted))
+	for _, id := range sorted {
+		if len(wallets) == limit {
+			break
+		}
+		if w, ok := r.lookupWithShards(id); ok && tenant.Visible(ctx, w.TenantID) {
+			wallets = append(wallets, w)
+		}
+	}
+	return wallets, nil
+}
+
+func (r *MemoryWalletRepository) ListCustomerWallets(ctx context.Context, customerID uuid.UUID) ([]model.Wallet, error) {
+	var wallets []model.Wallet
+	for id := range r.walletIDs() {
+		w, ok := r.lookupWithShards(id)
+		if ok && w.CustomerID != nil && *w.CustomerID == customerID && tenant.Visible(ctx, w.TenantID) {
+			wallets = append(wallets, w)
+		}
+	}
+	sort.Slice(wallets, func(i, j int) bool {
+		if wallets[i].Label != wallets[j].Label {
+			return wallets[i].Label < wallets[j].Label
+		}
+		return bytes.Compare(wallets[i].ID[:], wallets[j].ID[:]) < 0
+	})
+	return wallets, nil
+}
+
+func (r *MemoryWalletRepository) GetWalletByLabel(ctx context.Context, customerID uuid.UUID, label string) (*model.Wallet, error) {
+	id, ok := r.findByLabel(customerID, label)
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return r.GetWalletById(ctx, id)
+}
+
+func (r *MemoryWalletRepository) UpdateWalletStatusTx(ctx context.Context, id uuid.UUID, status model.WalletStatus) error {
+	return r.updateWallet(id, func(w *model.Wallet) {
+		w.Status = status
+		w.Version++
+	})
+}
+
+func (r *MemoryWalletRepository) UpdateCreditLimitTx(ctx context.Context, id uuid.UUID, limit float64) error {
+	return r.updateWallet(id, func(w *model.Wallet) {
+		w.CreditLimit = limit
+		w.Version++
+	})
+}
+
+func (r *MemoryWalletRepository) ListOperations(ctx context.Context, walletID uuid.UUID, filter OperationFilter) ([]model.Operation, error) {
+	var ops []model.Operation
+	for _, op := range r.visibleOperations(walletID) {
+		if tenant.Visible(ctx, op.TenantID) && filter.matches(&op) {
+			ops = append(ops, op)
+		}
+	}
+	if filter.Limit > 0 && len(ops) > filter.Limit {
+		ops = ops[:filter.Limit]
+	}
+	return ops, nil
+}
+
+func (r *MemoryWalletRepository) OperationsNetTotal(ctx context.Context, walletID uuid.UUID, from, to time.Time) (float64, error) {
+	ops, err := r.ListOperations(ctx, walletID, OperationFilter{From: from, To: to})
+	if err != nil {
+		return 0, err
+	}
+	var total float64
+	for _, op := range ops {
+		total += op.SignedAmount()
+	}
+	return total, nil
+}
+
+func (r *MemoryWalletRepository) SaveAdjustmentTx(ctx context.Context, adj *model.Adjustment) error {
+	if adj.CreatedAt.IsZero() {
+		adj.CreatedAt = time.Now()
+	}
+	if r.tx == nil {
+		r.store.mu.Lock()
+		defer r.store.mu.Unlock()
+		r.store.adjustments = append(r.store.adjustments, *adj)
+		return nil
+	}
+	r.tx.adjustments = append(r.tx.adjustments, *adj)
+	return nil
+}
+
+func (r *MemoryWalletRepository) SaveFXLegTx(ctx context.Context, leg *model.FXLeg) error {
+	if leg.CreatedAt.IsZero() {
+		leg.CreatedAt = time.Now()
+	}
+	if r.tx == nil {
+		r.store.mu.Lock()
+		defer r.store.mu.Unlock()
+		r.store.fxLegs = append(r.store.fxLegs, *leg)
+		return nil
+	}
+	r.tx.fxLegs = append(r.tx.fxLegs, *leg)
+	return nil
+}
+
+func (r *MemoryWalletRepository) MarkAccrualsPaidTx(ctx context.Context, walletID uuid.UUID, before time.Time, overdraft bool, payoutID uuid.UUID) (int64, error) {
+	p := accrualPayout{walletID: walletID, before: before, overdraft: overdraft, payoutID: payoutID}
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	if r.store.interest == nil {
+		return 0, nil
+	}
+	n := r.store.interest.countUnpaid(p)
+	if r.tx == nil {
+		r.store.markPaid(p)
+	} else {
+		r.tx.payouts = append(r.tx.payouts, p)
+	}
+	return n, nil
+}
+
+func (r *MemoryWalletRepository) SaveAuditEntryTx(ctx context.Context, entry *model.AuditEntry) error {
+	stampAuditEntry(entry)
+	if r.tx == nil {
+		r.store.audit.append(*entry)
+		return nil
+	}
+	r.tx.audit = append(r.tx.audit, *entry)
+	return nil
+}
+
+func (r *MemoryWalletRepository) LatestBalanceSnapshot(ctx context.Context, walletID uuid.UUID, at time.Time) (*model.BalanceSnapshot, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var latest *model.BalanceSnapshot
+	for i := range r.store.snapshots[walletID] {
+		snap := &r.store.snapshots[walletID][i]
+		if !snap.TakenAt.After(at) && (latest == nil || snap.TakenAt.After(latest.TakenAt)) {
+			latest = snap
+		}
+	}
+	if latest == nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+	cp := *latest
+	return &cp, nil
+}
+
+func (r *MemoryWalletRepository) SaveBalanceSnapshot(ctx context.Context, snap *model.BalanceSnapshot) error {
+	if snap.CreatedAt.IsZero() {
+		snap.CreatedAt = time.Now()
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	if _, ok := r.store.wallets[snap.WalletID]; !ok {
+		return fmt.Errorf("balance snapshot: wallet %s does not exist", snap.WalletID)
+	}
+	for _, existing := range r.store.snapshots[snap.WalletID] {
+		if existing.TakenAt.Equal(snap.TakenAt) {
+			return nil
+		}
+	}
+	r.store.snapshots[snap.WalletID] = append(r.store.snapshots[snap.WalletID], *snap)
+	return nil
+}
+
+// SetInterestRepository attaches the repository holding the interest
+// accruals, which MarkAccrualsPaidTx marks paid as its transaction
+// commits. Without one there are no accruals to mark. Like the wallet row
+// lock in Postgres, the lock a payout holds on its wallet keeps others
+// from counting the same accruals unpaid.
+func (r *MemoryWalletRepository) SetInterestRepository(interest *MemoryInterestRepository) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	r.store.interest = interest
+}
+
+// EventLog returns the log events of committed operations are appended to.
+func (r *MemoryWalletRepository) EventLog() *MemoryEventLog {
+	return r.store.events
+}
+
+// AuditLog returns the log committed audit entries are appended to.
+func (r *MemoryWalletRepository) AuditLog() *MemoryAuditLog {
+	return r.store.audit
+}
+
+// Adjustments returns every committed adjustment.
+func (r *MemoryWalletRepository) Adjustments() []model.Adjustment {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+	return append([]model.Adjustment(nil), r.store.adjustments...)
+}
+
+// FXLegs returns every committed FX leg.
+func (r *MemoryWalletRepository) FXLegs() []model.FXLeg {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+	return append([]model.FXLeg(nil), r.store.fxLegs...)
+}
+
+func (r *MemoryWalletRepository) WithTx(ctx context.Context, fn func(txRepo WalletRepository) error) error {
+	tx := newMemoryTx(r.tx)
+	if r.tx == nil {
+		tx.held = make(map[rowKey]chan struct{})
+		defer tx.unlockAll()
+	}
+
+	if err := fn(&MemoryWalletRepository{store: r.store, tx: tx}); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if tx.parent != nil {
+		tx.mergeInto(tx.parent)
+		return nil
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	for id, w := range tx.wallets {
+		r.store.wallets[id] = w
+	}
+	for id, set := range tx.shardSets {
+		r.store.shards[id] = set
+	}
+	r.store.applyShardWrites(tx.shardWrites)
+	for _, op := range tx.operations {
+		r.store.addOperation(op)
+	}
+	r.store.adjustments = append(r.store.adjustments, tx.adjustments...)
+	r.store.fxLegs = append(r.store.fxLegs, tx.fxLegs...)
+	for _, p := range tx.payouts {
+		r.store.markPaid(p)
+	}
+	r.store.audit.append(tx.audit...)
+	r.store.events.append(tx.events...)
+	return nil
+}
+
+// lookup resolves a wallet through the transaction chain, falling back to
+// the committed state.
+func (r *MemoryWalletRepository) lookup(id uuid.UUID) (model.Wallet, bool) {
+	for tx := r.tx; tx != nil; tx = tx.parent {
+		if w, ok := tx.wallets[id]; ok {
+			return w, true
+		}
+	}
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+	w, ok := r.store.wallets[id]
+	return w, ok
+}
+
+// walletIDs returns the IDs of every wallet r sees, committed or written
+// by an enclosing transaction.
+func (r *MemoryWalletRepository) walletIDs() map[uuid.UUID]struct{} {
+	r.store.mu.RLock()
+	ids := make(map[uuid.UUID]struct{}, len(r.store.wallets))
+	for id := range r.store.wallets {
+		ids[id] = struct{}{}
+	}
+	r.store.mu.RUnlock()
+	for tx := r.tx; tx != nil; tx = tx.parent {
+		for id := range tx.wallets {
+			ids[id] = struct{}{}
+		}
+	}
+	return ids
+}
+
+// findByLabel returns the ID of the customer's wallet with the label,
+// whatever its tenant.
+func (r *MemoryWalletRepository) findByLabel(customerID uuid.UUID, label string) (uuid.UUID, bool) {
+	for id := range r.walletIDs() {
+		w, ok := r.lookup(id)
+		if ok && w.CustomerID != nil && *w.CustomerID == customerID && w.Label == label {
+			return id, true
+		}
+	}
+	return uuid.Nil, false
+}
+
+// lookupWithShards is lookup with ShardBalance and ShardVersion filled in.
+func (r *MemoryWalletRepository) lookupWithShards(id uuid.UUID) (model.Wallet, bool) {
+	w, ok := r.lookup(id)
+	if !ok {
+		return w, false
+	}
+	for _, s := range r.lookupShards(id) {
+		w.ShardBalance += s.Balance
+		w.ShardVersion += s.Version
+	}
+	return w, true
+}
+
+// lookupShards returns a copy of the wallet's shards as seen by r: the
+// committed rows with every enclosing transaction's writes applied.
+func (r *MemoryWalletRepository) lookupShards(id uuid.UUID) []model.WalletShard {
+	r.store.mu.RLock()
+	view := append([]model.WalletShard(nil), r.store.shards[id]...)
+	r.store.mu.RUnlock()
+
+	var chain []*memoryTx
+	for tx := r.tx; tx != nil; tx = tx.parent {
+		chain = append(chain, tx)
+	}
+	for i := len(chain) - 1; i >= 0; i-- {
+		tx := chain[i]
+		if set, ok := tx.shardSets[id]; ok {
+			view = append(view[:0:0], set...)
+		}
+		for j := range view {
+			if b, ok := tx.shardWrites[rowKey{walletID: id, shard: view[j].ShardNo}]; ok {
+				view[j].Balance = b
+				view[j].Version++
+			}
+		}
+	}
+	return view
+}
+
+func (r *MemoryWalletRepository) findShard(walletID uuid.UUID, shardNo int) *model.WalletShard {
+	for _, s := range r.lookupShards(walletID) {
+		if s.ShardNo == shardNo {
+			return &s
+		}
+	}
+	return nil
+}
+
+func (r *MemoryWalletRepository) updateWallet(id uuid.UUID, mutate func(w *model.Wallet)) error {
+	if r.tx == nil {
+		r.store.mu.Lock()
+		defer r.store.mu.Unlock()
+		if w, ok := r.store.wallets[id]; ok {
+			mutate(&w)
+			r.store.wallets[id] = w
+		}
+		return nil
+	}
+
+	w, ok := r.lookup(id)
+	if !ok {
+		return nil
+	}
+	mutate(&w)
+	r.tx.wallets[id] = w
+	return nil
+}
+
+// visibleOperations returns the wallet's operations as seen by r, ordered
+// by creation time.
+func (r *MemoryWalletRepository) visibleOperations(walletID uuid.UUID) []model.Operation {
+	ops := r.Operations(walletID)
+	for tx := r.tx; tx != nil; tx = tx.parent {
+		for _, op := range tx.operations {
+			if op.WalletID == walletID {
+				ops = append(ops, op)
+			}
+		}
+	}
+	sort.SliceStable(ops, func(i, j int) bool { return ops[i].CreatedAt.Before(ops[j].CreatedAt) })
+	return ops
+}
+
+func (r *MemoryWalletRepository) hasOperation(id uuid.UUID) bool {
+	for tx := r.tx; tx != nil; tx = tx.parent {
+		for _, op := range tx.operations {
+			if op.ID == id {
+				return true
+			}
+		}
+	}
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+	_, ok := r.store.opIDs[id]
+	return ok
+}
+
+func (r *MemoryWalletRepository) hasExternalRef(walletID uuid.UUID, ref string) bool {
+	for _, op := range r.visibleOperations(walletID) {
+		if op.ExternalRef == ref {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *memoryStore) addOperation(op model.Operation) {
+	s.operations = append(s.operations, op)
+	s.opIDs[op.ID] = struct{}{}
+}
+
+func (s *memoryStore) markPaid(p accrualPayout) {
+	if s.interest != nil {
+		s.interest.markPaid(p)
+	}
+}
+
+func (s *memoryStore) applyShardWrites(writes map[rowKey]float64) {
+	for key, balance := range writes {
+		shards := s.shards[key.walletID]
+		for i := range shards {
+			if shards[i].ShardNo == key.shard {
+				shards[i].Balance = balance
+				shards[i].Version++
+			}
+		}
+	}
+}
+
+func (s *memoryStore) rowLock(key rowKey) chan struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	l, ok := s.locks[key]
+	if !ok {
+		l = make(chan struct{}, 1)
+		s.locks[key] = l
+	}
+	return l
+}
+
+func (tx *memoryTx) root() *memoryTx {
+	for tx.parent != nil {
+		tx = tx.parent
+	}
+	return tx
+}
+
+// lock acquires a row lock on behalf of the root transaction, waiting until
+// it is released or ctx is done.
+func (tx *memoryTx) lock(ctx context.Context, store *memoryStore, key rowKey) error {
+	root := tx.root()
+	if _, ok := root.held[key]; ok {
+		return nil
+	}
+
+	l := store.rowLock(key)
+	select {
+	case l <- struct{}{}:
+		root.held[key] = l
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (tx *memoryTx) unlockAll() {
+	for key, l := range tx.held {
+		<-l
+		delete(tx.held, key)
+	}
+}
+
+func (tx *memoryTx) mergeInto(parent *memoryTx) {
+	for id, w := range tx.wallets {
+		parent.wallets[id] = w
+	}
+	for id, set := range tx.shardSets {
+		parent.shardSets[id] = set
+		parent.dropShardWrites(id)
+	}
+	for key, balance := range tx.shardWrites {
+		parent.shardWrites[key] = balance
+	}
+	parent.operations = append(parent.operations, tx.operations...)
+	parent.adjustments = append(parent.adjustments, tx.adjustments...)
+	parent.fxLegs = append(parent.fxLegs, tx.fxLegs...)
+	parent.payouts = append(parent.payouts, tx.payouts...)
+	parent.audit = append(parent.audit, tx.audit...)
+	parent.events = append(parent.events, tx.events...)
+}
+
+func (tx *memoryTx) dropShardWrites(walletID uuid.UUID) {
+	for key := range tx.shardWrites {
+		if key.walletID == walletID {
+			delete(tx.shardWrites, key)
+		}
+	}
+}
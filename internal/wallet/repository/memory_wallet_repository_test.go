@@ -0,0 +1,27 @@
+package repository_test
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+
+	"wallet-service/internal/wallet/model"
+	"wallet-service/internal/wallet/repository"
+	"wallet-service/internal/wallet/repository/repositorytest"
+)
+
+func TestMemoryWalletRepository_Conformance(t *testing.T) {
+	repositorytest.Run(t, func(t *testing.T) repositorytest.Harness {
+		repo := repository.NewMemoryWalletRepository()
+		return repositorytest.Harness{
+			Repo: repo,
+			CreateWallet: func(t *testing.T, w model.Wallet) {
+				repo.AddWallet(w)
+			},
+			Operations: func(t *testing.T, walletID uuid.UUID) []model.Operation {
+				return repo.Operations(walletID)
+			},
+			CreateCustomer: func(t *testing.T, c model.Customer) {},
+		}
+	})
+}
@@ -2,6 +2,7 @@ package mocks
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/mock"
@@ -23,6 +24,14 @@ func (m *WalletRepositoryMock) GetWalletById(ctx context.Context, id uuid.UUID)
 	return nil, args.Error(1)
 }
 
+func (m *WalletRepositoryMock) GetWalletByIdForShare(ctx context.Context, id uuid.UUID) (*model.Wallet, error) {
+	args := m.Called(ctx, id)
+	if w, ok := args.Get(0).(*model.Wallet); ok {
+		return w, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
 func (m *WalletRepositoryMock) GetWalletByIdForUpdate(ctx context.Context, id uuid.UUID) (*model.Wallet, error) {
 	args := m.Called(ctx, id)
 	if w, ok := args.Get(0).(*model.Wallet); ok {
@@ -36,6 +45,11 @@ func (m *WalletRepositoryMock) UpdateWalletTx(ctx context.Context, id uuid.UUID,
 	return args.Error(0)
 }
 
+func (m *WalletRepositoryMock) UpdateWalletVersionedTx(ctx context.Context, id uuid.UUID, version int64, newBalance float64) error {
+	args := m.Called(ctx, id, version, newBalance)
+	return args.Error(0)
+}
+
 func (m *WalletRepositoryMock) SaveOperationTx(ctx context.Context, op *model.Operation) error {
 	args := m.Called(ctx, op)
 	return args.Error(0)
@@ -48,3 +62,104 @@ func (m *WalletRepositoryMock) WithTx(ctx context.Context, fn func(txRepo reposi
 	}
 	return nil
 }
+
+func (m *WalletRepositoryMock) GetShardForUpdate(ctx context.Context, walletID uuid.UUID, shardNo int) (*model.WalletShard, error) {
+	args := m.Called(ctx, walletID, shardNo)
+	if s, ok := args.Get(0).(*model.WalletShard); ok {
+		return s, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *WalletRepositoryMock) GetShardsForUpdate(ctx context.Context, walletID uuid.UUID) ([]model.WalletShard, error) {
+	args := m.Called(ctx, walletID)
+	if s, ok := args.Get(0).([]model.WalletShard); ok {
+		return s, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *WalletRepositoryMock) UpdateShardTx(ctx context.Context, walletID uuid.UUID, shardNo int, newBalance float64) error {
+	args := m.Called(ctx, walletID, shardNo, newBalance)
+	return args.Error(0)
+}
+
+func (m *WalletRepositoryMock) ReplaceShardsTx(ctx context.Context, walletID uuid.UUID, shards []model.WalletShard) error {
+	args := m.Called(ctx, walletID, shards)
+	return args.Error(0)
+}
+
+func (m *WalletRepositoryMock) CreateWallet(ctx context.Context, wallet *model.Wallet) error {
+	args := m.Called(ctx, wallet)
+	return args.Error(0)
+}
+
+func (m *WalletRepositoryMock) ListWallets(ctx context.Context, after uuid.UUID, limit int) ([]model.Wallet, error) {
+	args := m.Called(ctx, after, limit)
+	wallets, _ := args.Get(0).([]model.Wallet)
+	return wallets, args.Error(1)
+}
+
+func (m *WalletRepositoryMock) ListCustomerWallets(ctx context.Context, customerID uuid.UUID) ([]model.Wallet, error) {
+	args := m.Called(ctx, customerID)
+	wallets, _ := args.Get(0).([]model.Wallet)
+	return wallets, args.Error(1)
+}
+
+func (m *WalletRepositoryMock) GetWalletByLabel(ctx context.Context, customerID uuid.UUID, label string) (*model.Wallet, error) {
+	args := m.Called(ctx, customerID, label)
+	wallet, _ := args.Get(0).(*model.Wallet)
+	return wallet, args.Error(1)
+}
+
+func (m *WalletRepositoryMock) UpdateWalletStatusTx(ctx context.Context, id uuid.UUID, status model.WalletStatus) error {
+	args := m.Called(ctx, id, status)
+	return args.Error(0)
+}
+
+func (m *WalletRepositoryMock) UpdateCreditLimitTx(ctx context.Context, id uuid.UUID, limit float64) error {
+	args := m.Called(ctx, id, limit)
+	return args.Error(0)
+}
+
+func (m *WalletRepositoryMock) ListOperations(ctx context.Context, walletID uuid.UUID, filter repository.OperationFilter) ([]model.Operation, error) {
+	args := m.Called(ctx, walletID, filter)
+	ops, _ := args.Get(0).([]model.Operation)
+	return ops, args.Error(1)
+}
+
+func (m *WalletRepositoryMock) OperationsNetTotal(ctx context.Context, walletID uuid.UUID, from, to time.Time) (float64, error) {
+	args := m.Called(ctx, walletID, from, to)
+	return args.Get(0).(float64), args.Error(1)
+}
+
+func (m *WalletRepositoryMock) SaveAdjustmentTx(ctx context.Context, adj *model.Adjustment) error {
+	args := m.Called(ctx, adj)
+	return args.Error(0)
+}
+
+func (m *WalletRepositoryMock) SaveFXLegTx(ctx context.Context, leg *model.FXLeg) error {
+	args := m.Called(ctx, leg)
+	return args.Error(0)
+}
+
+func (m *WalletRepositoryMock) MarkAccrualsPaidTx(ctx context.Context, walletID uuid.UUID, before time.Time, overdraft bool, payoutID uuid.UUID) (int64, error) {
+	args := m.Called(ctx, walletID, before, overdraft, payoutID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *WalletRepositoryMock) SaveAuditEntryTx(ctx context.Context, entry *model.AuditEntry) error {
+	args := m.Called(ctx, entry)
+	return args.Error(0)
+}
+
+func (m *WalletRepositoryMock) LatestBalanceSnapshot(ctx context.Context, walletID uuid.UUID, at time.Time) (*model.BalanceSnapshot, error) {
+	args := m.Called(ctx, walletID, at)
+	snap, _ := args.Get(0).(*model.BalanceSnapshot)
+	return snap, args.Error(1)
+}
+
+func (m *WalletRepositoryMock) SaveBalanceSnapshot(ctx context.Context, snap *model.BalanceSnapshot) error {
+	args := m.Called(ctx, snap)
+	return args.Error(0)
+}
@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+	"wallet-service/internal/wallet/model"
+)
+
+type RateRepository interface {
+	// SaveRatesTx stores the rates, replacing any with the same pair and
+	// ValidFrom.
+	SaveRatesTx(ctx context.Context, rates []model.ExchangeRate) error
+	// FindRate returns the rate of the pair valid at the given time with
+	// the latest ValidFrom, or gorm.ErrRecordNotFound.
+	FindRate(ctx context.Context, base, quote string, at time.Time) (*model.ExchangeRate, error)
+	// ListRates returns the rates of the pair, or of every pair when base
+	// and quote are empty, newest first.
+	ListRates(ctx context.Context, base, quote string) ([]model.ExchangeRate, error)
+
+	SaveAuditEntryTx(ctx context.Context, entry *model.AuditEntry) error
+	WithTx(ctx context.Context, fn func(txRepo RateRepository) error) error
+}
+
+type rateRepository struct {
+	db *gorm.DB
+}
+
+func NewRateRepository(db *gorm.DB) RateRepository {
+	return &rateRepository{db: db}
+}
+
+func (r *rateRepository) SaveRatesTx(ctx context.Context, rates []model.ExchangeRate) error {
+	if len(rates) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "base"}, {Name: "quote"}, {Name: "valid_from"}},
+		DoUpdates: clause.AssignmentColumns([]string{"valid_to", "rate"}),
+	}).Create(&rates).Error
+}
+
+func (r *rateRepository) FindRate(ctx context.Context, base, quote string, at time.Time) (*model.ExchangeRate, error) {
+	var rate model.ExchangeRate
+	err := r.db.WithContext(ctx).
+		Where("base = ? AND quote = ? AND valid_from <= ? AND (valid_to IS NULL OR valid_to > ?)", base, quote, at, at).
+		Order("valid_from DESC").
+		First(&rate).Error
+	if err != nil {
+		return nil, err
+	}
+	return &rate, nil
+}
+
+func (r *rateRepository) ListRates(ctx context.Context, base, quote string) ([]model.ExchangeRate, error) {
+	q := r.db.WithContext(ctx).Order("base, quote, valid_from DESC")
+	if base != "" {
+		q = q.Where("base = ?", base)
+	}
+	if quote != "" {
+		q = q.Where("quote = ?", quote)
+	}
+
+	var rates []model.ExchangeRate
+	if err := q.Find(&rates).Error; err != nil {
+		return nil, err
+	}
+	return rates, nil
+}
+
+func (r *rateRepository) SaveAuditEntryTx(ctx context.Context, entry *model.AuditEntry) error {
+	return r.db.WithContext(ctx).Create(entry).Error
+}
+
+func (r *rateRepository) WithTx(ctx context.Context, fn func(txRepo RateRepository) error) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(&rateRepository{db: tx})
+	})
+}
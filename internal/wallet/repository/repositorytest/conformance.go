@@ -0,0 +1,898 @@
+// Package repositorytest provides a conformance suite that every
+// repository.WalletRepository implementation must pass.
+package repositorytest
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"wallet-service/internal/tenant"
+	"wallet-service/internal/wallet/model"
+	"wallet-service/internal/wallet/repository"
+)
+
+// Harness gives the suite access to an implementation under test together
+// with the out-of-band helpers the WalletRepository interface lacks.
+type Harness struct {
+	Repo repository.WalletRepository
+	// CreateWallet persists w outside of any transaction.
+	CreateWallet func(t *testing.T, w model.Wallet)
+	// Operations returns the committed operations of a wallet.
+	Operations func(t *testing.T, walletID uuid.UUID) []model.Operation
+	// CreateCustomer persists a customer wallets can then belong to.
+	CreateCustomer func(t *testing.T, c model.Customer)
+}
+
+// Factory builds a fresh harness. Implementations backed by shared storage
+// may reuse it across calls; the suite only touches wallets it created.
+type Factory func(t *testing.T) Harness
+
+// Run executes the conformance suite against the implementation built by
+// newHarness.
+func Run(t *testing.T, newHarness Factory) {
+	cases := []struct {
+		name string
+		run  func(t *testing.T, h Harness)
+	}{
+		{"GetWalletById", testGetWalletById},
+		{"GetWalletById_NotFound", testGetWalletByIdNotFound},
+		{"GetWalletByIdForUpdate_NotFound", testGetWalletByIdForUpdateNotFound},
+		{"UpdateWalletTx_OutsideTx", testUpdateWalletOutsideTx},
+		{"SaveOperationTx", testSaveOperation},
+		{"SaveOperationTx_DuplicateID", testSaveOperationDuplicateID},
+		{"SaveOperationTx_UnknownWallet", testSaveOperationUnknownWallet},
+		{"SaveOperationTx_DuplicateExternalRef", testSaveOperationDuplicateExternalRef},
+		{"WithTx_Commit", testWithTxCommit},
+		{"WithTx_Rollback", testWithTxRollback},
+		{"WithTx_RollbackOnPanic", testWithTxRollbackOnPanic},
+		{"WithTx_NestedRollback", testWithTxNestedRollback},
+		{"WithTx_Isolation", testWithTxIsolation},
+		{"ForUpdate_BlocksConcurrentTx", testForUpdateBlocks},
+		{"ForUpdate_RespectsContext", testForUpdateRespectsContext},
+		{"ForUpdate_ConcurrentIncrements", testConcurrentIncrements},
+		{"Version_BumpedByUpdate", testVersionBumpedByUpdate},
+		{"Versioned_StaleVersionConflicts", testVersionedStaleConflicts},
+		{"Versioned_ConcurrentIncrements", testVersionedConcurrentIncrements},
+		{"Shards_ReplaceAndRead", testShardsReplaceAndRead},
+		{"Shards_UpdateRollback", testShardsUpdateRollback},
+		{"Shards_NotFound", testShardNotFound},
+		{"Shards_LockPerShard", testShardLocksAreIndependent},
+		{"Shards_Revision", testShardsRevision},
+		{"CreateWallet", testCreateWallet},
+		{"CreateWallet_Duplicate", testCreateWalletDuplicate},
+		{"UpdateWalletStatusTx", testUpdateWalletStatus},
+		{"UpdateCreditLimitTx", testUpdateCreditLimit},
+		{"ListOperations_Range", testListOperationsRange},
+		{"ListOperations_Search", testListOperationsSearch},
+		{"OperationsNetTotal", testOperationsNetTotal},
+		{"BalanceSnapshots", testBalanceSnapshots},
+		{"TenantIsolation", testTenantIsolation},
+		{"CustomerWallets", testCustomerWallets},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.run(t, newHarness(t))
+		})
+	}
+}
+
+func newWallet(t *testing.T, h Harness, balance float64) uuid.UUID {
+	t.Helper()
+	id := uuid.New()
+	h.CreateWallet(t, model.Wallet{ID: id, Balance: balance})
+	return id
+}
+
+func balanceOf(t *testing.T, h Harness, id uuid.UUID) float64 {
+	t.Helper()
+	w, err := h.Repo.GetWalletById(context.Background(), id)
+	require.NoError(t, err)
+	return w.Balance
+}
+
+func newOperation(walletID uuid.UUID, opType string, amount float64) *model.Operation {
+	return &model.Operation{ID: uuid.New(), WalletID: walletID, Type: opType, Amount: amount}
+}
+
+func testGetWalletById(t *testing.T, h Harness) {
+	id := newWallet(t, h, 100)
+
+	w, err := h.Repo.GetWalletById(context.Background(), id)
+
+	require.NoError(t, err)
+	assert.Equal(t, id, w.ID)
+	assert.Equal(t, float64(100), w.Balance)
+	assert.False(t, w.CreatedAt.IsZero())
+}
+
+func testGetWalletByIdNotFound(t *testing.T, h Harness) {
+	_, err := h.Repo.GetWalletById(context.Background(), uuid.New())
+
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func testGetWalletByIdForUpdateNotFound(t *testing.T, h Harness) {
+	ctx := context.Background()
+
+	err := h.Repo.WithTx(ctx, func(tx repository.WalletRepository) error {
+		_, err := tx.GetWalletByIdForUpdate(ctx, uuid.New())
+		return err
+	})
+
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func testUpdateWalletOutsideTx(t *testing.T, h Harness) {
+	id := newWallet(t, h, 100)
+
+	require.NoError(t, h.Repo.UpdateWalletTx(context.Background(), id, 42))
+
+	assert.Equal(t, float64(42), balanceOf(t, h, id))
+}
+
+func testSaveOperation(t *testing.T, h Harness) {
+	id := newWallet(t, h, 0)
+	op := newOperation(id, "DEPOSIT", 10)
+
+	require.NoError(t, h.Repo.SaveOperationTx(context.Background(), op))
+
+	assert.False(t, op.CreatedAt.IsZero(), "CreatedAt must be populated on save")
+	ops := h.Operations(t, id)
+	require.Len(t, ops, 1)
+	assert.Equal(t, op.ID, ops[0].ID)
+	assert.Equal(t, "DEPOSIT", ops[0].Type)
+	assert.Equal(t, float64(10), ops[0].Amount)
+}
+
+func testSaveOperationDuplicateID(t *testing.T, h Harness) {
+	ctx := context.Background()
+	id := newWallet(t, h, 0)
+	op := newOperation(id, "DEPOSIT", 10)
+	require.NoError(t, h.Repo.SaveOperationTx(ctx, op))
+
+	dup := *op
+	err := h.Repo.SaveOperationTx(ctx, &dup)
+
+	assert.ErrorIs(t, err, repository.ErrDuplicateOperation)
+	assert.Len(t, h.Operations(t, id), 1)
+}
+
+func testSaveOperationDuplicateExternalRef(t *testing.T, h Harness) {
+	ctx := context.Background()
+	id, other := newWallet(t, h, 0), newWallet(t, h, 0)
+	op := newOperation(id, "DEPOSIT", 10)
+	op.ExternalRef = "psp-1"
+	require.NoError(t, h.Repo.SaveOperationTx(ctx, op))
+
+	dup := newOperation(id, "DEPOSIT", 10)
+	dup.ExternalRef = "psp-1"
+	err := h.Repo.SaveOperationTx(ctx, dup)
+	assert.ErrorIs(t, err, repository.ErrDuplicateExternalRef)
+
+	// References are unique per wallet, and operations without one never
+	// clash.
+	elsewhere := newOperation(other, "DEPOSIT", 10)
+	elsewhere.ExternalRef = "psp-1"
+	require.NoError(t, h.Repo.SaveOperationTx(ctx, elsewhere))
+	require.NoError(t, h.Repo.SaveOperationTx(ctx, newOperation(id, "DEPOSIT", 1)))
+	require.NoError(t, h.Repo.SaveOperationTx(ctx, newOperation(id, "DEPOSIT", 1)))
+	assert.Len(t, h.Operations(t, id), 3)
+}
+
+func testSaveOperationUnknownWallet(t *testing.T, h Harness) {
+	err := h.Repo.SaveOperationTx(context.Background(), newOperation(uuid.New(), "DEPOSIT", 10))
+
+	assert.Error(t, err)
+}
+
+func testWithTxCommit(t *testing.T, h Harness) {
+	ctx := context.Background()
+	id := newWallet(t, h, 100)
+
+	err := h.Repo.WithTx(ctx, func(tx repository.WalletRepository) error {
+		w, err := tx.GetWalletByIdForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := tx.UpdateWalletTx(ctx, id, w.Balance+50); err != nil {
+			return err
+		}
+
+		own, err := tx.GetWalletById(ctx, id)
+		if err != nil {
+			return err
+		}
+		assert.Equal(t, float64(150), own.Balance, "a transaction must read its own writes")
+
+		return tx.SaveOperationTx(ctx, newOperation(id, "DEPOSIT", 50))
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, float64(150), balanceOf(t, h, id))
+	assert.Len(t, h.Operations(t, id), 1)
+}
+
+func testWithTxRollback(t *testing.T, h Harness) {
+	ctx := context.Background()
+	id := newWallet(t, h, 100)
+	boom := errors.New("boom")
+
+	err := h.Repo.WithTx(ctx, func(tx repository.WalletRepository) error {
+		if err := tx.UpdateWalletTx(ctx, id, 0); err != nil {
+			return err
+		}
+		if err := tx.SaveOperationTx(ctx, newOperation(id, "WITHDRAW", 100)); err != nil {
+			return err
+		}
+		return boom
+	})
+
+	assert.ErrorIs(t, err, boom)
+	assert.Equal(t, float64(100), balanceOf(t, h, id))
+	assert.Empty(t, h.Operations(t, id))
+}
+
+func testWithTxRollbackOnPanic(t *testing.T, h Harness) {
+	ctx := context.Background()
+	id := newWallet(t, h, 100)
+
+	assert.Panics(t, func() {
+		_ = h.Repo.WithTx(ctx, func(tx repository.WalletRepository) error {
+			_, _ = tx.GetWalletByIdForUpdate(ctx, id)
+			_ = tx.UpdateWalletTx(ctx, id, 0)
+			panic("boom")
+		})
+	})
+
+	assert.Equal(t, float64(100), balanceOf(t, h, id))
+
+	// The lock taken before the panic must have been released.
+	lockCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	err := h.Repo.WithTx(lockCtx, func(tx repository.WalletRepository) error {
+		_, err := tx.GetWalletByIdForUpdate(lockCtx, id)
+		return err
+	})
+	assert.NoError(t, err)
+}
+
+func testWithTxNestedRollback(t *testing.T, h Harness) {
+	ctx := context.Background()
+	id := newWallet(t, h, 100)
+
+	err := h.Repo.WithTx(ctx, func(tx repository.WalletRepository) error {
+		if err := tx.UpdateWalletTx(ctx, id, 110); err != nil {
+			return err
+		}
+		inner := tx.WithTx(ctx, func(inner repository.WalletRepository) error {
+			if err := inner.UpdateWalletTx(ctx, id, 999); err != nil {
+				return err
+			}
+			return errors.New("inner failed")
+		})
+		assert.Error(t, inner)
+		return nil
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, float64(110), balanceOf(t, h, id))
+}
+
+func testWithTxIsolation(t *testing.T, h Harness) {
+	ctx := context.Background()
+	id := newWallet(t, h, 100)
+
+	err := h.Repo.WithTx(ctx, func(tx repository.WalletRepository) error {
+		if err := tx.UpdateWalletTx(ctx, id, 500); err != nil {
+			return err
+		}
+		assert.Equal(t, float64(100), balanceOf(t, h, id), "uncommitted writes must not be visible outside the transaction")
+		return nil
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, float64(500), balanceOf(t, h, id))
+}
+
+func testForUpdateBlocks(t *testing.T, h Harness) {
+	ctx := context.Background()
+	id := newWallet(t, h, 100)
+
+	locked := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan error, 1)
+
+	go func() {
+		done <- h.Repo.WithTx(ctx, func(tx repository.WalletRepository) error {
+			if _, err := tx.GetWalletByIdForUpdate(ctx, id); err != nil {
+				close(locked)
+				return err
+			}
+			close(locked)
+			<-release
+			return tx.UpdateWalletTx(ctx, id, 200)
+		})
+	}()
+	<-locked
+
+	acquired := make(chan float64, 1)
+	go func() {
+		_ = h.Repo.WithTx(ctx, func(tx repository.WalletRepository) error {
+			w, err := tx.GetWalletByIdForUpdate(ctx, id)
+			if err != nil {
+				return err
+			}
+			acquired <- w.Balance
+			return nil
+		})
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("second transaction acquired the lock while the first still held it")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(release)
+	require.NoError(t, <-done)
+
+	select {
+	case balance := <-acquired:
+		assert.Equal(t, float64(200), balance, "the waiter must see the committed balance")
+	case <-time.After(5 * time.Second):
+		t.Fatal("second transaction never acquired the lock")
+	}
+}
+
+func testForUpdateRespectsContext(t *testing.T, h Harness) {
+	ctx := context.Background()
+	id := newWallet(t, h, 100)
+
+	locked := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan error, 1)
+	go func() {
+		done <- h.Repo.WithTx(ctx, func(tx repository.WalletRepository) error {
+			_, err := tx.GetWalletByIdForUpdate(ctx, id)
+			close(locked)
+			<-release
+			return err
+		})
+	}()
+	<-locked
+	defer func() {
+		close(release)
+		<-done
+	}()
+
+	waitCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := h.Repo.WithTx(waitCtx, func(tx repository.WalletRepository) error {
+		_, err := tx.GetWalletByIdForUpdate(waitCtx, id)
+		return err
+	})
+
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+func testConcurrentIncrements(t *testing.T, h Harness) {
+	ctx := context.Background()
+	id := newWallet(t, h, 0)
+	const workers = 20
+
+	var wg sync.WaitGroup
+	errs := make(chan error, workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- h.Repo.WithTx(ctx, func(tx repository.WalletRepository) error {
+				w, err := tx.GetWalletByIdForUpdate(ctx, id)
+				if err != nil {
+					return err
+				}
+				if err := tx.UpdateWalletTx(ctx, id, w.Balance+1); err != nil {
+					return err
+				}
+				return tx.SaveOperationTx(ctx, newOperation(id, "DEPOSIT", 1))
+			})
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, float64(workers), balanceOf(t, h, id))
+	assert.Len(t, h.Operations(t, id), workers)
+}
+
+func versionOf(t *testing.T, h Harness, id uuid.UUID) int64 {
+	t.Helper()
+	w, err := h.Repo.GetWalletById(context.Background(), id)
+	require.NoError(t, err)
+	return w.Version
+}
+
+func testVersionBumpedByUpdate(t *testing.T, h Harness) {
+	ctx := context.Background()
+	id := newWallet(t, h, 10)
+	before := versionOf(t, h, id)
+
+	require.NoError(t, h.Repo.WithTx(ctx, func(tx repository.WalletRepository) error {
+		return tx.UpdateWalletTx(ctx, id, 20)
+	}))
+
+	assert.Equal(t, before+1, versionOf(t, h, id))
+}
+
+func testVersionedStaleConflicts(t *testing.T, h Harness) {
+	ctx := context.Background()
+	id := newWallet(t, h, 10)
+	v := versionOf(t, h, id)
+
+	require.NoError(t, h.Repo.WithTx(ctx, func(tx repository.WalletRepository) error {
+		return tx.UpdateWalletVersionedTx(ctx, id, v, 15)
+	}))
+	err := h.Repo.WithTx(ctx, func(tx repository.WalletRepository) error {
+		return tx.UpdateWalletVersionedTx(ctx, id, v, 99)
+	})
+
+	assert.ErrorIs(t, err, repository.ErrVersionConflict)
+	assert.Equal(t, float64(15), balanceOf(t, h, id))
+	assert.Equal(t, v+1, versionOf(t, h, id))
+}
+
+func testVersionedConcurrentIncrements(t *testing.T, h Harness) {
+	ctx := context.Background()
+	id := newWallet(t, h, 0)
+	const workers = 10
+
+	var wg sync.WaitGroup
+	errs := make(chan error, workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				err := h.Repo.WithTx(ctx, func(tx repository.WalletRepository) error {
+					w, err := tx.GetWalletById(ctx, id)
+					if err != nil {
+						return err
+					}
+					return tx.UpdateWalletVersionedTx(ctx, id, w.Version, w.Balance+1)
+				})
+				if !errors.Is(err, repository.ErrVersionConflict) {
+					errs <- err
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, float64(workers), balanceOf(t, h, id))
+}
+
+func newShards(walletID uuid.UUID, balances ...float64) []model.WalletShard {
+	shards := make([]model.WalletShard, len(balances))
+	for i, b := range balances {
+		shards[i] = model.WalletShard{WalletID: walletID, ShardNo: i, Balance: b}
+	}
+	return shards
+}
+
+func testShardsReplaceAndRead(t *testing.T, h Harness) {
+	ctx := context.Background()
+	id := newWallet(t, h, 5)
+
+	err := h.Repo.WithTx(ctx, func(tx repository.WalletRepository) error {
+		return tx.ReplaceShardsTx(ctx, id, newShards(id, 10, 20, 30))
+	})
+	require.NoError(t, err)
+
+	w, err := h.Repo.GetWalletById(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, 3, w.ShardCount)
+	assert.Equal(t, float64(5), w.Balance)
+	assert.Equal(t, float64(60), w.ShardBalance)
+	assert.Equal(t, float64(65), w.TotalBalance())
+
+	err = h.Repo.WithTx(ctx, func(tx repository.WalletRepository) error {
+		shards, err := tx.GetShardsForUpdate(ctx, id)
+		require.NoError(t, err)
+		require.Len(t, shards, 3)
+		for i, s := range shards {
+			assert.Equal(t, i, s.ShardNo)
+		}
+		return tx.ReplaceShardsTx(ctx, id, nil)
+	})
+	require.NoError(t, err)
+
+	w, err = h.Repo.GetWalletById(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, 0, w.ShardCount)
+	assert.Equal(t, float64(0), w.ShardBalance)
+}
+
+func testShardsUpdateRollback(t *testing.T, h Harness) {
+	ctx := context.Background()
+	id := newWallet(t, h, 0)
+	require.NoError(t, h.Repo.WithTx(ctx, func(tx repository.WalletRepository) error {
+		return tx.ReplaceShardsTx(ctx, id, newShards(id, 10, 10))
+	}))
+
+	err := h.Repo.WithTx(ctx, func(tx repository.WalletRepository) error {
+		shard, err := tx.GetShardForUpdate(ctx, id, 1)
+		if err != nil {
+			return err
+		}
+		if err := tx.UpdateShardTx(ctx, id, 1, shard.Balance+5); err != nil {
+			return err
+		}
+		return errors.New("boom")
+	})
+	assert.Error(t, err)
+
+	w, err := h.Repo.GetWalletById(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, float64(20), w.ShardBalance)
+}
+
+func testShardNotFound(t *testing.T, h Harness) {
+	ctx := context.Background()
+	id := newWallet(t, h, 0)
+
+	err := h.Repo.WithTx(ctx, func(tx repository.WalletRepository) error {
+		_, err := tx.GetShardForUpdate(ctx, id, 0)
+		return err
+	})
+
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func testShardLocksAreIndependent(t *testing.T, h Harness) {
+	ctx := context.Background()
+	id := newWallet(t, h, 0)
+	require.NoError(t, h.Repo.WithTx(ctx, func(tx repository.WalletRepository) error {
+		return tx.ReplaceShardsTx(ctx, id, newShards(id, 0, 0))
+	}))
+
+	locked := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan error, 1)
+	go func() {
+		done <- h.Repo.WithTx(ctx, func(tx repository.WalletRepository) error {
+			shard, err := tx.GetShardForUpdate(ctx, id, 0)
+			close(locked)
+			if err != nil {
+				return err
+			}
+			<-release
+			return tx.UpdateShardTx(ctx, id, 0, shard.Balance+1)
+		})
+	}()
+	<-locked
+
+	// A different shard of the same wallet must not be blocked.
+	otherCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	err := h.Repo.WithTx(otherCtx, func(tx repository.WalletRepository) error {
+		shard, err := tx.GetShardForUpdate(otherCtx, id, 1)
+		if err != nil {
+			return err
+		}
+		return tx.UpdateShardTx(otherCtx, id, 1, shard.Balance+1)
+	})
+	assert.NoError(t, err)
+
+	close(release)
+	require.NoError(t, <-done)
+
+	w, err := h.Repo.GetWalletById(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, float64(2), w.ShardBalance, "updates to different shards must both be kept")
+}
+
+func revisionOf(t *testing.T, h Harness, id uuid.UUID) int64 {
+	t.Helper()
+	w, err := h.Repo.GetWalletById(context.Background(), id)
+	require.NoError(t, err)
+	return w.Revision()
+}
+
+func testShardsRevision(t *testing.T, h Harness) {
+	ctx := context.Background()
+	id := newWallet(t, h, 0)
+	require.NoError(t, h.Repo.WithTx(ctx, func(tx repository.WalletRepository) error {
+		return tx.ReplaceShardsTx(ctx, id, newShards(id, 0, 0))
+	}))
+	before, row := revisionOf(t, h, id), versionOf(t, h, id)
+
+	require.NoError(t, h.Repo.WithTx(ctx, func(tx repository.WalletRepository) error {
+		return tx.UpdateShardTx(ctx, id, 1, 5)
+	}))
+	assert.Equal(t, before+1, revisionOf(t, h, id), "shard writes count towards the revision")
+	assert.Equal(t, row, versionOf(t, h, id), "without writing the wallet row")
+
+	require.NoError(t, h.Repo.WithTx(ctx, func(tx repository.WalletRepository) error {
+		return tx.ReplaceShardsTx(ctx, id, nil)
+	}))
+	assert.Equal(t, before+2, revisionOf(t, h, id), "replacing the shards keeps their writes counted")
+}
+
+func testCreateWallet(t *testing.T, h Harness) {
+	ctx := context.Background()
+	id := uuid.New()
+
+	require.NoError(t, h.Repo.CreateWallet(ctx, &model.Wallet{ID: id}))
+
+	w, err := h.Repo.GetWalletById(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, model.WalletActive, w.Status)
+	assert.Zero(t, w.Balance)
+}
+
+func testCreateWalletDuplicate(t *testing.T, h Harness) {
+	id := newWallet(t, h, 10)
+
+	err := h.Repo.CreateWallet(context.Background(), &model.Wallet{ID: id})
+
+	assert.ErrorIs(t, err, repository.ErrDuplicateWallet)
+	assert.Equal(t, float64(10), balanceOf(t, h, id))
+}
+
+func testUpdateWalletStatus(t *testing.T, h Harness) {
+	ctx := context.Background()
+	id := newWallet(t, h, 0)
+	before := versionOf(t, h, id)
+
+	require.NoError(t, h.Repo.WithTx(ctx, func(tx repository.WalletRepository) error {
+		return tx.UpdateWalletStatusTx(ctx, id, model.WalletFrozen)
+	}))
+
+	w, err := h.Repo.GetWalletById(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, model.WalletFrozen, w.Status)
+	assert.Equal(t, before+1, w.Version)
+}
+
+func testUpdateCreditLimit(t *testing.T, h Harness) {
+	ctx := context.Background()
+	id := newWallet(t, h, 0)
+	before := versionOf(t, h, id)
+
+	require.NoError(t, h.Repo.WithTx(ctx, func(tx repository.WalletRepository) error {
+		return tx.UpdateCreditLimitTx(ctx, id, 250.5)
+	}))
+
+	w, err := h.Repo.GetWalletById(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, 250.5, w.CreditLimit)
+	assert.Equal(t, before+1, w.Version)
+}
+
+// saveOperations stores one operation per amount, a second apart starting
+// at start. Negative amounts are saved as withdrawals.
+func saveOperations(t *testing.T, h Harness, walletID uuid.UUID, start time.Time, amounts ...float64) {
+	t.Helper()
+	for i, amount := range amounts {
+		op := newOperation(walletID, model.OperationDeposit, amount)
+		if amount < 0 {
+			op.Type, op.Amount = model.OperationWithdraw, -amount
+		}
+		op.CreatedAt = start.Add(time.Duration(i) * time.Second)
+		require.NoError(t, h.Repo.SaveOperationTx(context.Background(), op))
+	}
+}
+
+func testListOperationsRange(t *testing.T, h Harness) {
+	ctx := context.Background()
+	id := newWallet(t, h, 0)
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	saveOperations(t, h, id, start, 1, 2, 3, 4)
+
+	all, err := h.Repo.ListOperations(ctx, id, repository.OperationFilter{})
+	require.NoError(t, err)
+	require.Len(t, all, 4)
+	for i, op := range all {
+		assert.Equal(t, float64(i+1), op.Amount)
+	}
+
+	middle, err := h.Repo.ListOperations(ctx, id, repository.OperationFilter{From: start.Add(time.Second), To: start.Add(3 * time.Second)})
+	require.NoError(t, err)
+	require.Len(t, middle, 2)
+	assert.Equal(t, float64(2), middle[0].Amount)
+	assert.Equal(t, float64(3), middle[1].Amount)
+
+	limited, err := h.Repo.ListOperations(ctx, id, repository.OperationFilter{From: start.Add(time.Second), Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, limited, 1)
+	assert.Equal(t, float64(2), limited[0].Amount)
+}
+
+func testListOperationsSearch(t *testing.T, h Harness) {
+	ctx := context.Background()
+	id := newWallet(t, h, 0)
+	for _, op := range []*model.Operation{
+		{Description: "Card top-up", ExternalRef: "psp-1", Metadata: model.Metadata{"channel": "card", "attempt": 1}},
+		{Description: "Payout 50%", ExternalRef: "psp-2", Metadata: model.Metadata{"channel": "bank"}},
+		{},
+	} {
+		op.ID, op.WalletID, op.Type, op.Amount = uuid.New(), id, model.OperationDeposit, 1
+		require.NoError(t, h.Repo.SaveOperationTx(ctx, op))
+		time.Sleep(time.Millisecond)
+	}
+
+	for _, tc := range []struct {
+		name   string
+		filter repository.OperationFilter
+		want   []string
+	}{
+		{"external ref", repository.OperationFilter{ExternalRef: "psp-2"}, []string{"psp-2"}},
+		{"description ignores case", repository.OperationFilter{Description: "TOP-UP"}, []string{"psp-1"}},
+		{"description wildcards are literal", repository.OperationFilter{Description: "0%"}, []string{"psp-2"}},
+		{"description underscore is literal", repository.OperationFilter{Description: "_"}, nil},
+		{"metadata", repository.OperationFilter{Metadata: map[string]string{"channel": "card"}}, []string{"psp-1"}},
+		{"metadata must all match", repository.OperationFilter{Metadata: map[string]string{"channel": "card", "attempt": "2"}}, nil},
+		{"combined", repository.OperationFilter{ExternalRef: "psp-1", Description: "payout"}, nil},
+	} {
+		ops, err := h.Repo.ListOperations(ctx, id, tc.filter)
+		require.NoError(t, err, tc.name)
+		var refs []string
+		for _, op := range ops {
+			refs = append(refs, op.ExternalRef)
+		}
+		assert.Equal(t, tc.want, refs, tc.name)
+	}
+
+	ops, err := h.Repo.ListOperations(ctx, id, repository.OperationFilter{ExternalRef: "psp-1"})
+	require.NoError(t, err)
+	require.Len(t, ops, 1)
+	assert.Equal(t, "Card top-up", ops[0].Description)
+	assert.Equal(t, "card", ops[0].Metadata["channel"])
+}
+
+func testOperationsNetTotal(t *testing.T, h Harness) {
+	ctx := context.Background()
+	id := newWallet(t, h, 0)
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	saveOperations(t, h, id, start, 100, -30, 5)
+
+	total, err := h.Repo.OperationsNetTotal(ctx, id, time.Time{}, time.Time{})
+	require.NoError(t, err)
+	assert.Equal(t, float64(75), total)
+
+	total, err = h.Repo.OperationsNetTotal(ctx, id, time.Time{}, start.Add(2*time.Second))
+	require.NoError(t, err)
+	assert.Equal(t, float64(70), total)
+
+	total, err = h.Repo.OperationsNetTotal(ctx, id, start.Add(time.Second), time.Time{})
+	require.NoError(t, err)
+	assert.Equal(t, float64(-25), total)
+
+	total, err = h.Repo.OperationsNetTotal(ctx, uuid.New(), time.Time{}, time.Time{})
+	require.NoError(t, err)
+	assert.Zero(t, total)
+}
+
+func testBalanceSnapshots(t *testing.T, h Harness) {
+	ctx := context.Background()
+	id := newWallet(t, h, 0)
+	day := time.Date(2025, 3, 31, 0, 0, 0, 0, time.UTC)
+
+	_, err := h.Repo.LatestBalanceSnapshot(ctx, id, day)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	require.NoError(t, h.Repo.SaveBalanceSnapshot(ctx, &model.BalanceSnapshot{WalletID: id, TakenAt: day, Balance: 10}))
+	require.NoError(t, h.Repo.SaveBalanceSnapshot(ctx, &model.BalanceSnapshot{WalletID: id, TakenAt: day.AddDate(0, 0, 1), Balance: 20}))
+	// A second snapshot for the same time is ignored.
+	require.NoError(t, h.Repo.SaveBalanceSnapshot(ctx, &model.BalanceSnapshot{WalletID: id, TakenAt: day, Balance: 99}))
+
+	snap, err := h.Repo.LatestBalanceSnapshot(ctx, id, day.Add(time.Hour))
+	require.NoError(t, err)
+	assert.True(t, snap.TakenAt.Equal(day))
+	assert.Equal(t, float64(10), snap.Balance)
+
+	snap, err = h.Repo.LatestBalanceSnapshot(ctx, id, day.AddDate(0, 0, 1))
+	require.NoError(t, err)
+	assert.Equal(t, float64(20), snap.Balance)
+
+	_, err = h.Repo.LatestBalanceSnapshot(ctx, id, day.Add(-time.Second))
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func testTenantIsolation(t *testing.T, h Harness) {
+	id := uuid.New()
+	h.CreateWallet(t, model.Wallet{ID: id, Balance: 10, TenantID: "tenant-a"})
+	own := tenant.NewContext(context.Background(), "tenant-a")
+	other := tenant.NewContext(context.Background(), "tenant-b")
+	op := newOperation(id, model.OperationDeposit, 5)
+	require.NoError(t, h.Repo.SaveOperationTx(own, op))
+
+	w, err := h.Repo.GetWalletById(own, id)
+	require.NoError(t, err)
+	assert.Equal(t, "tenant-a", w.TenantID)
+	ops, err := h.Repo.ListOperations(own, id, repository.OperationFilter{})
+	require.NoError(t, err)
+	require.Len(t, ops, 1)
+	assert.Equal(t, "tenant-a", ops[0].TenantID, "operations take the tenant of their wallet")
+
+	_, err = h.Repo.GetWalletById(other, id)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	err = h.Repo.WithTx(other, func(tx repository.WalletRepository) error {
+		_, err := tx.GetWalletByIdForUpdate(other, id)
+		return err
+	})
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	ops, err = h.Repo.ListOperations(other, id, repository.OperationFilter{})
+	require.NoError(t, err)
+	assert.Empty(t, ops)
+	wallets, err := h.Repo.ListWallets(other, uuid.Nil, 1000)
+	require.NoError(t, err)
+	for _, w := range wallets {
+		assert.NotEqual(t, id, w.ID)
+	}
+
+	// Work done for no tenant in particular sees every wallet.
+	_, err = h.Repo.GetWalletById(context.Background(), id)
+	assert.NoError(t, err)
+}
+
+func testCustomerWallets(t *testing.T, h Harness) {
+	ctx := tenant.NewContext(context.Background(), "tenant-a")
+	customer := model.Customer{ID: uuid.New(), TenantID: "tenant-a", ExternalID: "cust-" + uuid.NewString()}
+	h.CreateCustomer(t, customer)
+	stranger := model.Customer{ID: uuid.New(), TenantID: "tenant-a", ExternalID: "cust-" + uuid.NewString()}
+	h.CreateCustomer(t, stranger)
+
+	create := func(customerID uuid.UUID, label string) uuid.UUID {
+		w := &model.Wallet{ID: uuid.New(), TenantID: "tenant-a", CustomerID: &customerID, Label: label}
+		require.NoError(t, h.Repo.CreateWallet(ctx, w))
+		return w.ID
+	}
+	savings := create(customer.ID, "savings")
+	main := create(customer.ID, "main")
+	create(stranger.ID, "main")
+
+	wallets, err := h.Repo.ListCustomerWallets(ctx, customer.ID)
+	require.NoError(t, err)
+	require.Len(t, wallets, 2)
+	assert.Equal(t, main, wallets[0].ID, "wallets are ordered by label")
+	assert.Equal(t, savings, wallets[1].ID)
+
+	w, err := h.Repo.GetWalletByLabel(ctx, customer.ID, "savings")
+	require.NoError(t, err)
+	assert.Equal(t, savings, w.ID)
+	_, err = h.Repo.GetWalletByLabel(ctx, customer.ID, "holidays")
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	dup := &model.Wallet{ID: uuid.New(), TenantID: "tenant-a", CustomerID: &customer.ID, Label: "main"}
+	assert.ErrorIs(t, h.Repo.CreateWallet(ctx, dup), repository.ErrDuplicateLabel)
+	// Unlabelled wallets do not clash.
+	create(customer.ID, "")
+	create(customer.ID, "")
+
+	other := tenant.NewContext(context.Background(), "tenant-b")
+	wallets, err = h.Repo.ListCustomerWallets(other, customer.ID)
+	require.NoError(t, err)
+	assert.Empty(t, wallets)
+	_, err = h.Repo.GetWalletByLabel(other, customer.ID, "main")
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
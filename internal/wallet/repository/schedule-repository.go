@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+	"wallet-service/internal/wallet/model"
+)
+
+type ScheduleRepository interface {
+	CreateSchedule(ctx context.Context, s *model.Schedule) error
+	GetSchedule(ctx context.Context, id uuid.UUID) (*model.Schedule, error)
+	GetScheduleForUpdate(ctx context.Context, id uuid.UUID) (*model.Schedule, error)
+	ListSchedules(ctx context.Context, walletID uuid.UUID) ([]model.Schedule, error)
+	UpdateScheduleTx(ctx context.Context, s *model.Schedule) error
+	// ClaimDueSchedulesTx locks up to limit active schedules due at now,
+	// skipping rows another transaction has already claimed.
+	ClaimDueSchedulesTx(ctx context.Context, now time.Time, limit int) ([]model.Schedule, error)
+	SaveScheduleRunTx(ctx context.Context, run *model.ScheduleRun) error
+	// ListScheduleRuns returns the most recent runs of a schedule first.
+	ListScheduleRuns(ctx context.Context, scheduleID uuid.UUID, limit int) ([]model.ScheduleRun, error)
+	SaveAuditEntryTx(ctx context.Context, entry *model.AuditEntry) error
+	WithTx(ctx context.Context, fn func(txRepo ScheduleRepository) error) error
+}
+
+type scheduleRepository struct {
+	db *gorm.DB
+}
+
+func NewScheduleRepository(db *gorm.DB) ScheduleRepository {
+	return &scheduleRepository{db: db}
+}
+
+func (r *scheduleRepository) CreateSchedule(ctx context.Context, s *model.Schedule) error {
+	return r.db.WithContext(ctx).Create(s).Error
+}
+
+func (r *scheduleRepository) GetSchedule(ctx context.Context, id uuid.UUID) (*model.Schedule, error) {
+	var s model.Schedule
+	if err := r.db.WithContext(ctx).First(&s, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (r *scheduleRepository) GetScheduleForUpdate(ctx context.Context, id uuid.UUID) (*model.Schedule, error) {
+	var s model.Schedule
+	if err := r.db.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).First(&s, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (r *scheduleRepository) ListSchedules(ctx context.Context, walletID uuid.UUID) ([]model.Schedule, error) {
+	var schedules []model.Schedule
+	if err := r.db.WithContext(ctx).Where("wallet_id = ?", walletID).Order("created_at").Find(&schedules).Error; err != nil {
+		return nil, err
+	}
+	return schedules, nil
+}
+
+func (r *scheduleRepository) UpdateScheduleTx(ctx context.Context, s *model.Schedule) error {
+	return r.db.WithContext(ctx).Save(s).Error
+}
+
+func (r *scheduleRepository) ClaimDueSchedulesTx(ctx context.Context, now time.Time, limit int) ([]model.Schedule, error) {
+	var schedules []model.Schedule
+	err := r.db.WithContext(ctx).
+		Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("status = ? AND due_at <= ?", model.ScheduleActive, now).
+		Order("due_at").
+		Limit(limit).
+		Find(&schedules).Error
+	if err != nil {
+		return nil, err
+	}
+	return schedules, nil
+}
+
+func (r *scheduleRepository) SaveScheduleRunTx(ctx context.Context, run *model.ScheduleRun) error {
+	return r.db.WithContext(ctx).Create(run).Error
+}
+
+func (r *scheduleRepository) ListScheduleRuns(ctx context.Context, scheduleID uuid.UUID, limit int) ([]model.ScheduleRun, error) {
+	var runs []model.ScheduleRun
+	err := r.db.WithContext(ctx).Where("schedule_id = ?", scheduleID).
+		Order("created_at DESC").Limit(limit).Find(&runs).Error
+	if err != nil {
+		return nil, err
+	}
+	return runs, nil
+}
+
+func (r *scheduleRepository) SaveAuditEntryTx(ctx context.Context, entry *model.AuditEntry) error {
+	return r.db.WithContext(ctx).Create(entry).Error
+}
+
+func (r *scheduleRepository) WithTx(ctx context.Context, fn func(txRepo ScheduleRepository) error) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(&scheduleRepository{db: tx})
+	})
+}
@@ -2,28 +2,169 @@ package repository
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
+	"strings"
+	"time"
+	"wallet-service/internal/db"
 	"wallet-service/internal/wallet/model"
 )
 
+// ErrDuplicateOperation is returned by SaveOperationTx when an operation
+// with the same ID already exists.
+var ErrDuplicateOperation = errors.New("operation already exists")
+
+// ErrDuplicateExternalRef is returned by SaveOperationTx when the wallet
+// already has an operation with the same external reference.
+var ErrDuplicateExternalRef = errors.New("external reference already used")
+
+// ErrDuplicateWallet is returned by CreateWallet when the ID is taken.
+var ErrDuplicateWallet = errors.New("wallet already exists")
+
+// ErrDuplicateLabel is returned by CreateWallet when the customer already
+// has a wallet with the same label.
+var ErrDuplicateLabel = errors.New("wallet label already used")
+
+// ErrVersionConflict is returned by UpdateWalletVersionedTx when the wallet
+// no longer has the expected version.
+var ErrVersionConflict = errors.New("wallet was modified concurrently")
+
+// ErrRangeArchived is returned by OperationsNetTotal when a bound of the
+// time range falls inside an archived partition, whose operations are only
+// known in total.
+var ErrRangeArchived = errors.New("time range cuts through archived operations")
+
 type WalletRepository interface {
 	GetWalletById(ctx context.Context, id uuid.UUID) (*model.Wallet, error)
 	UpdateWalletTx(ctx context.Context, id uuid.UUID, newBalance float64) error
+	// UpdateWalletVersionedTx sets the balance only if the wallet is still at
+	// version, and bumps the version. It returns ErrVersionConflict otherwise.
+	UpdateWalletVersionedTx(ctx context.Context, id uuid.UUID, version int64, newBalance float64) error
 	SaveOperationTx(ctx context.Context, op *model.Operation) error
 	GetWalletByIdForUpdate(ctx context.Context, id uuid.UUID) (*model.Wallet, error)
+	// GetWalletByIdForShare locks the wallet row against changes, but not
+	// against other share locks.
+	GetWalletByIdForShare(ctx context.Context, id uuid.UUID) (*model.Wallet, error)
 	WithTx(ctx context.Context, fn func(txRepo WalletRepository) error) error
+
+	// GetShardForUpdate locks a single shard row of a sharded wallet.
+	GetShardForUpdate(ctx context.Context, walletID uuid.UUID, shardNo int) (*model.WalletShard, error)
+	// GetShardsForUpdate locks every shard of a wallet in shard order.
+	GetShardsForUpdate(ctx context.Context, walletID uuid.UUID) ([]model.WalletShard, error)
+	UpdateShardTx(ctx context.Context, walletID uuid.UUID, shardNo int, newBalance float64) error
+	// ReplaceShardsTx swaps the wallet's shards for the given ones and sets
+	// its shard count accordingly. An empty slice un-shards the wallet.
+	ReplaceShardsTx(ctx context.Context, walletID uuid.UUID, shards []model.WalletShard) error
+
+	CreateWallet(ctx context.Context, wallet *model.Wallet) error
+	// ListWallets returns up to limit wallets ordered by ID, starting after
+	// the given ID (uuid.Nil for the first page).
+	ListWallets(ctx context.Context, after uuid.UUID, limit int) ([]model.Wallet, error)
+	// ListCustomerWallets returns every wallet of the customer ordered by
+	// label, then ID.
+	ListCustomerWallets(ctx context.Context, customerID uuid.UUID) ([]model.Wallet, error)
+	// GetWalletByLabel returns the customer's wallet with the label, or
+	// gorm.ErrRecordNotFound.
+	GetWalletByLabel(ctx context.Context, customerID uuid.UUID, label string) (*model.Wallet, error)
+	UpdateWalletStatusTx(ctx context.Context, id uuid.UUID, status model.WalletStatus) error
+	// UpdateCreditLimitTx sets how far below zero the wallet's balance may
+	// be debited.
+	UpdateCreditLimitTx(ctx context.Context, id uuid.UUID, limit float64) error
+	// ListOperations returns the wallet's operations matching filter in
+	// order, oldest first.
+	ListOperations(ctx context.Context, walletID uuid.UUID, filter OperationFilter) ([]model.Operation, error)
+	// OperationsNetTotal sums the signed amounts of the wallet's operations
+	// created in [from, to). Zero times leave that end open. Archived
+	// operations count through the totals of their partitions, so a range
+	// cutting through one fails with ErrRangeArchived.
+	OperationsNetTotal(ctx context.Context, walletID uuid.UUID, from, to time.Time) (float64, error)
+	SaveAdjustmentTx(ctx context.Context, adj *model.Adjustment) error
+	SaveFXLegTx(ctx context.Context, leg *model.FXLeg) error
+	// MarkAccrualsPaidTx sets payoutID on the wallet's unpaid interest
+	// accruals dated before the given date, those with a positive amount
+	// or with overdraft set those with a negative one, and returns how
+	// many it marked.
+	MarkAccrualsPaidTx(ctx context.Context, walletID uuid.UUID, before time.Time, overdraft bool, payoutID uuid.UUID) (int64, error)
+	SaveAuditEntryTx(ctx context.Context, entry *model.AuditEntry) error
+	// LatestBalanceSnapshot returns the wallet's most recent snapshot taken
+	// at or before the given time, or gorm.ErrRecordNotFound.
+	LatestBalanceSnapshot(ctx context.Context, walletID uuid.UUID, at time.Time) (*model.BalanceSnapshot, error)
+	// SaveBalanceSnapshot stores snap unless the wallet already has a
+	// snapshot taken at the same time.
+	SaveBalanceSnapshot(ctx context.Context, snap *model.BalanceSnapshot) error
+}
+
+// OperationFilter selects operations of a wallet. Zero fields match
+// everything; From is inclusive, To exclusive and a Limit of 0 means no
+// limit.
+type OperationFilter struct {
+	From, To    time.Time
+	ExternalRef string
+	// Description matches descriptions containing it, ignoring case.
+	Description string
+	// Metadata matches operations whose metadata has each key set to the
+	// given string.
+	Metadata map[string]string
+	Limit    int
+}
+
+func (f OperationFilter) matches(op *model.Operation) bool {
+	if (!f.From.IsZero() && op.CreatedAt.Before(f.From)) ||
+		(!f.To.IsZero() && !op.CreatedAt.Before(f.To)) ||
+		(f.ExternalRef != "" && op.ExternalRef != f.ExternalRef) ||
+		(f.Description != "" && !strings.Contains(strings.ToLower(op.Description), strings.ToLower(f.Description))) {
+		return false
+	}
+	for k, want := range f.Metadata {
+		if got, ok := op.Metadata[k].(string); !ok || got != want {
+			return false
+		}
+	}
+	return true
 }
 
+// likeEscaper escapes the wildcards of a LIKE pattern; backslash is the
+// default escape character in Postgres.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
 type walletRepository struct {
 	db *gorm.DB
+	// reads, if set, routes wallet lookups and history reads to replicas.
+	// Repositories of a transaction have none.
+	reads *db.Replicas
+}
+
+// read runs fn with the connection that reads outside transactions use.
+func (w *walletRepository) read(ctx context.Context, fn func(conn *gorm.DB) error) error {
+	if w.reads == nil {
+		return fn(w.db)
+	}
+	return w.reads.Read(ctx, fn)
+}
+
+// walletQuery selects wallets together with the sum of their shards in a
+// single statement, so both come from the same snapshot.
+func (w *walletRepository) walletQuery(ctx context.Context) *gorm.DB {
+	return walletsWithShards(w.db.WithContext(ctx))
+}
+
+func walletsWithShards(conn *gorm.DB) *gorm.DB {
+	return conn.Model(&model.Wallet{}).
+		Select(`wallets.*,
+			COALESCE((SELECT SUM(s.balance) FROM wallet_shards s WHERE s.wallet_id = wallets.id), 0) AS shard_balance,
+			COALESCE((SELECT SUM(s.version) FROM wallet_shards s WHERE s.wallet_id = wallets.id), 0) AS shard_version`)
 }
 
 func (w *walletRepository) GetWalletById(ctx context.Context, id uuid.UUID) (*model.Wallet, error) {
 	var wallet model.Wallet
-
-	if err := w.db.First(&wallet, "id = ?", id).Error; err != nil {
+	err := w.read(ctx, func(conn *gorm.DB) error {
+		return walletsWithShards(conn.WithContext(ctx)).First(&wallet, "wallets.id = ?", id).Error
+	})
+	if err != nil {
 		return nil, err
 	}
 	return &wallet, nil
@@ -31,18 +172,269 @@ func (w *walletRepository) GetWalletById(ctx context.Context, id uuid.UUID) (*mo
 
 func (w *walletRepository) GetWalletByIdForUpdate(ctx context.Context, id uuid.UUID) (*model.Wallet, error) {
 	var wallet model.Wallet
-	if err := w.db.Clauses(clause.Locking{Strength: "UPDATE"}).First(&wallet, "id = ?", id).Error; err != nil {
+	if err := w.walletQuery(ctx).Clauses(clause.Locking{Strength: "UPDATE", Table: clause.Table{Name: "wallets"}}).First(&wallet, "wallets.id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &wallet, nil
+}
+
+func (w *walletRepository) GetWalletByIdForShare(ctx context.Context, id uuid.UUID) (*model.Wallet, error) {
+	var wallet model.Wallet
+	if err := w.walletQuery(ctx).Clauses(clause.Locking{Strength: "SHARE", Table: clause.Table{Name: "wallets"}}).First(&wallet, "wallets.id = ?", id).Error; err != nil {
 		return nil, err
 	}
 	return &wallet, nil
 }
 
 func (w *walletRepository) UpdateWalletTx(ctx context.Context, id uuid.UUID, newBalance float64) error {
-	return w.db.Model(&model.Wallet{}).Where("id = ?", id).Update("balance", newBalance).Error
+	return w.db.WithContext(ctx).Model(&model.Wallet{}).Where("id = ?", id).
+		Updates(map[string]any{"balance": newBalance, "version": gorm.Expr("version + 1")}).Error
+}
+
+func (w *walletRepository) UpdateWalletVersionedTx(ctx context.Context, id uuid.UUID, version int64, newBalance float64) error {
+	res := w.db.WithContext(ctx).Model(&model.Wallet{}).Where("id = ? AND version = ?", id, version).
+		Updates(map[string]any{"balance": newBalance, "version": version + 1})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrVersionConflict
+	}
+	return nil
 }
 
 func (w *walletRepository) SaveOperationTx(ctx context.Context, op *model.Operation) error {
-	return w.db.Create(op).Error
+	err := w.db.WithContext(ctx).Create(op).Error
+	switch {
+	case db.IsUniqueViolation(err, "operation_keys_pkey"):
+		return fmt.Errorf("operation %s: %w", op.ID, ErrDuplicateOperation)
+	case db.IsUniqueViolation(err, "operation_keys_wallet_external_ref_key"):
+		return fmt.Errorf("operation %s: external reference %q: %w", op.ID, op.ExternalRef, ErrDuplicateExternalRef)
+	}
+	return err
+}
+
+func (w *walletRepository) GetShardForUpdate(ctx context.Context, walletID uuid.UUID, shardNo int) (*model.WalletShard, error) {
+	var shard model.WalletShard
+	if err := w.db.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&shard, "wallet_id = ? AND shard_no = ?", walletID, shardNo).Error; err != nil {
+		return nil, err
+	}
+	return &shard, nil
+}
+
+func (w *walletRepository) GetShardsForUpdate(ctx context.Context, walletID uuid.UUID) ([]model.WalletShard, error) {
+	var shards []model.WalletShard
+	if err := w.db.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("wallet_id = ?", walletID).Order("shard_no").Find(&shards).Error; err != nil {
+		return nil, err
+	}
+	return shards, nil
+}
+
+func (w *walletRepository) UpdateShardTx(ctx context.Context, walletID uuid.UUID, shardNo int, newBalance float64) error {
+	return w.db.WithContext(ctx).Model(&model.WalletShard{}).
+		Where("wallet_id = ? AND shard_no = ?", walletID, shardNo).
+		Updates(map[string]any{"balance": newBalance, "version": gorm.Expr("version + 1")}).Error
+}
+
+func (w *walletRepository) ReplaceShardsTx(ctx context.Context, walletID uuid.UUID, shards []model.WalletShard) error {
+	db := w.db.WithContext(ctx)
+	// The wallet's revision must not go back when the old shards go.
+	err := db.Model(&model.Wallet{}).Where("id = ?", walletID).Updates(map[string]any{
+		"shard_count": len(shards),
+		"version":     gorm.Expr("version + 1 + (SELECT COALESCE(SUM(version), 0) FROM wallet_shards WHERE wallet_id = ?)", walletID),
+	}).Error
+	if err != nil {
+		return err
+	}
+	if err := db.Where("wallet_id = ?", walletID).Delete(&model.WalletShard{}).Error; err != nil {
+		return err
+	}
+	if len(shards) == 0 {
+		return nil
+	}
+	return db.Create(&shards).Error
+}
+
+func (w *walletRepository) CreateWallet(ctx context.Context, wallet *model.Wallet) error {
+	err := w.db.WithContext(ctx).Create(wallet).Error
+	switch {
+	case db.IsUniqueViolation(err, "wallets_pkey"):
+		return ErrDuplicateWallet
+	case db.IsUniqueViolation(err, "wallets_customer_label_key"):
+		return ErrDuplicateLabel
+	}
+	return err
+}
+
+func (w *walletRepository) ListWallets(ctx context.Context, after uuid.UUID, limit int) ([]model.Wallet, error) {
+	q := w.walletQuery(ctx).Order("wallets.id").Limit(limit)
+	if after != uuid.Nil {
+		q = q.Where("wallets.id > ?", after)
+	}
+
+	var wallets []model.Wallet
+	if err := q.Find(&wallets).Error; err != nil {
+		return nil, err
+	}
+	return wallets, nil
+}
+
+func (w *walletRepository) ListCustomerWallets(ctx context.Context, customerID uuid.UUID) ([]model.Wallet, error) {
+	var wallets []model.Wallet
+	err := w.walletQuery(ctx).
+		Where("wallets.customer_id = ?", customerID).
+		Order("wallets.label, wallets.id").
+		Find(&wallets).Error
+	if err != nil {
+		return nil, err
+	}
+	return wallets, nil
+}
+
+func (w *walletRepository) GetWalletByLabel(ctx context.Context, customerID uuid.UUID, label string) (*model.Wallet, error) {
+	var wallet model.Wallet
+	if err := w.walletQuery(ctx).First(&wallet, "wallets.customer_id = ? AND wallets.label = ?", customerID, label).Error; err != nil {
+		return nil, err
+	}
+	return &wallet, nil
+}
+
+func (w *walletRepository) UpdateWalletStatusTx(ctx context.Context, id uuid.UUID, status model.WalletStatus) error {
+	return w.db.WithContext(ctx).Model(&model.Wallet{}).Where("id = ?", id).
+		Updates(map[string]any{"status": status, "version": gorm.Expr("version + 1")}).Error
+}
+
+func (w *walletRepository) UpdateCreditLimitTx(ctx context.Context, id uuid.UUID, limit float64) error {
+	return w.db.WithContext(ctx).Model(&model.Wallet{}).Where("id = ?", id).
+		Updates(map[string]any{"credit_limit": limit, "version": gorm.Expr("version + 1")}).Error
+}
+
+func (w *walletRepository) ListOperations(ctx context.Context, walletID uuid.UUID, filter OperationFilter) ([]model.Operation, error) {
+	var contains []byte
+	if len(filter.Metadata) > 0 {
+		var err error
+		if contains, err = json.Marshal(filter.Metadata); err != nil {
+			return nil, err
+		}
+	}
+
+	var ops []model.Operation
+	err := w.read(ctx, func(conn *gorm.DB) error {
+		q := conn.WithContext(ctx).Where("wallet_id = ?", walletID).Order("created_at, id")
+		if !filter.From.IsZero() {
+			q = q.Where("created_at >= ?", filter.From)
+		}
+		if !filter.To.IsZero() {
+			q = q.Where("created_at < ?", filter.To)
+		}
+		if filter.ExternalRef != "" {
+			q = q.Where("external_ref = ?", filter.ExternalRef)
+		}
+		if filter.Description != "" {
+			q = q.Where("description ILIKE ?", "%"+likeEscaper.Replace(filter.Description)+"%")
+		}
+		if contains != nil {
+			q = q.Where("metadata @> ?", string(contains))
+		}
+		if filter.Limit > 0 {
+			q = q.Limit(filter.Limit)
+		}
+		ops = nil
+		return q.Find(&ops).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ops, nil
+}
+
+func (w *walletRepository) OperationsNetTotal(ctx context.Context, walletID uuid.UUID, from, to time.Time) (float64, error) {
+	var total, net float64
+	err := w.read(ctx, func(conn *gorm.DB) error {
+		var cut int64
+		for _, t := range []time.Time{from, to} {
+			if t.IsZero() {
+				continue
+			}
+			err := conn.WithContext(ctx).Model(&model.OperationArchive{}).
+				Where("range_start < ? AND range_end > ?", t, t).
+				Count(&cut).Error
+			if err != nil {
+				return err
+			}
+			if cut > 0 {
+				return ErrRangeArchived
+			}
+		}
+
+		q := conn.WithContext(ctx).Model(&model.Operation{}).
+			Select("COALESCE(SUM(CASE WHEN type IN ? THEN -amount ELSE amount END), 0)", model.DebitTypes).
+			Where("wallet_id = ?", walletID)
+		if !from.IsZero() {
+			q = q.Where("created_at >= ?", from)
+		}
+		if !to.IsZero() {
+			q = q.Where("created_at < ?", to)
+		}
+		if err := q.Scan(&total).Error; err != nil {
+			return err
+		}
+
+		archived := conn.WithContext(ctx).Model(&model.OperationArchiveTotal{}).
+			Select("COALESCE(SUM(net), 0)").
+			Where("wallet_id = ?", walletID)
+		if !from.IsZero() {
+			archived = archived.Where("range_start >= ?", from)
+		}
+		if !to.IsZero() {
+			archived = archived.Where("range_end <= ?", to)
+		}
+		return archived.Scan(&net).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+	return total + net, nil
+}
+
+func (w *walletRepository) SaveAdjustmentTx(ctx context.Context, adj *model.Adjustment) error {
+	return w.db.WithContext(ctx).Create(adj).Error
+}
+
+func (w *walletRepository) SaveFXLegTx(ctx context.Context, leg *model.FXLeg) error {
+	return w.db.WithContext(ctx).Create(leg).Error
+}
+
+func (w *walletRepository) MarkAccrualsPaidTx(ctx context.Context, walletID uuid.UUID, before time.Time, overdraft bool, payoutID uuid.UUID) (int64, error) {
+	res := w.db.WithContext(ctx).Model(&model.InterestAccrual{}).
+		Where("wallet_id = ? AND date < ? AND payout_id IS NULL", walletID, before).
+		Where(unpaidSign(overdraft)).
+		Update("payout_id", payoutID)
+	return res.RowsAffected, res.Error
+}
+
+func (w *walletRepository) SaveAuditEntryTx(ctx context.Context, entry *model.AuditEntry) error {
+	return w.db.WithContext(ctx).Create(entry).Error
+}
+
+func (w *walletRepository) LatestBalanceSnapshot(ctx context.Context, walletID uuid.UUID, at time.Time) (*model.BalanceSnapshot, error) {
+	var snap model.BalanceSnapshot
+	err := w.read(ctx, func(conn *gorm.DB) error {
+		return conn.WithContext(ctx).
+			Where("wallet_id = ? AND taken_at <= ?", walletID, at).
+			Order("taken_at DESC").
+			First(&snap).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &snap, nil
+}
+
+func (w *walletRepository) SaveBalanceSnapshot(ctx context.Context, snap *model.BalanceSnapshot) error {
+	return w.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(snap).Error
 }
 
 func (w *walletRepository) WithTx(ctx context.Context, fn func(txRepo WalletRepository) error) error {
@@ -55,3 +447,11 @@ func (w *walletRepository) WithTx(ctx context.Context, fn func(txRepo WalletRepo
 func NewWalletRepository(db *gorm.DB) WalletRepository {
 	return &walletRepository{db: db}
 }
+
+// NewReplicatedWalletRepository returns a WalletRepository on conn whose
+// wallet lookups and history reads outside transactions go through reads:
+// GetWalletById, ListOperations, OperationsNetTotal and
+// LatestBalanceSnapshot.
+func NewReplicatedWalletRepository(conn *gorm.DB, reads *db.Replicas) WalletRepository {
+	return &walletRepository{db: conn, reads: reads}
+}
@@ -0,0 +1,380 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"math"
+	"slices"
+	"time"
+	"wallet-service/internal/audit"
+	"wallet-service/internal/dto"
+	"wallet-service/internal/retry"
+	"wallet-service/internal/tenant"
+	"wallet-service/internal/wallet/model"
+	"wallet-service/internal/wallet/repository"
+	svcErrors "wallet-service/internal/wallet/service/errors"
+)
+
+// reconcileBatch is how many wallets ReconcileAll loads per page.
+const reconcileBatch = 500
+
+// Reconciliation compares a wallet's stored balance with the net total of
+// its operations.
+type Reconciliation struct {
+	WalletID uuid.UUID
+	Balance  float64
+	Ledger   float64
+}
+
+func (r Reconciliation) Difference() float64 {
+	return r.Balance - r.Ledger
+}
+
+// Balanced reports whether balance and ledger agree to the cent.
+func (r Reconciliation) Balanced() bool {
+	return math.Abs(r.Difference()) < 0.005
+}
+
+// Statement lists a wallet's operations in a period with the balance after
+// each of them.
+type Statement struct {
+	WalletID       uuid.UUID
+	From, To       time.Time
+	OpeningBalance float64
+	ClosingBalance float64
+	Lines          []StatementLine
+}
+
+type StatementLine struct {
+	Operation model.Operation
+	Balance   float64
+}
+
+// CreateWallet opens an empty, active wallet for the request's tenant. A
+// nil ID is replaced by a random one. A wallet given to a customer must
+// have a label the customer's other wallets do not use.
+func (s *WalletService) CreateWallet(ctx context.Context, req dto.CreateWalletRequest) (*model.Wallet, error) {
+	id := req.WalletID
+	if id == uuid.Nil {
+		id = uuid.New()
+	}
+	currency := req.Currency
+	if currency == "" {
+		currency = model.DefaultCurrency
+	}
+	if err := s.checkCurrencyAllowed(ctx, currency); err != nil {
+		return nil, err
+	}
+	owner, ok := tenant.FromContext(ctx)
+	if !ok {
+		owner = tenant.Default
+	}
+	wallet := &model.Wallet{ID: id, Status: model.WalletActive, Product: req.Product, Currency: currency, TenantID: owner, Label: req.Label}
+	if req.CustomerID != "" {
+		customer, err := s.findCustomer(ctx, req.CustomerID)
+		if err != nil {
+			return nil, err
+		}
+		wallet.CustomerID = &customer.ID
+	}
+	err := s.repo.WithTx(ctx, func(txRepo repository.WalletRepository) error {
+		if err := txRepo.CreateWallet(ctx, wallet); err != nil {
+			return err
+		}
+		return writeAudit(ctx, txRepo.SaveAuditEntryTx, audit.FromContext(ctx), AuditCreateWallet, id, nil, walletAuditStateOf(wallet))
+	})
+	switch {
+	case errors.Is(err, repository.ErrDuplicateWallet):
+		return nil, svcErrors.ErrWalletExists
+	case errors.Is(err, repository.ErrDuplicateLabel):
+		return nil, svcErrors.ErrWalletLabelExists
+	case err != nil:
+		return nil, err
+	}
+	return wallet, nil
+}
+
+// FreezeWallet stops customer operations on an active wallet. Scheduled
+// operations are retried until it is unfrozen; manual adjustments are
+// still possible.
+func (s *WalletService) FreezeWallet(ctx context.Context, id uuid.UUID) (*model.Wallet, error) {
+	return s.changeStatus(ctx, id, AuditFreezeWallet, model.WalletFrozen, model.WalletActive)
+}
+
+func (s *WalletService) UnfreezeWallet(ctx context.Context, id uuid.UUID) (*model.Wallet, error) {
+	return s.changeStatus(ctx, id, AuditUnfreezeWallet, model.WalletActive, model.WalletFrozen)
+}
+
+// CloseWallet permanently closes a wallet whose balance is zero. Its shards
+// are folded back into the wallet row.
+func (s *WalletService) CloseWallet(ctx context.Context, id uuid.UUID) (*model.Wallet, error) {
+	return s.changeStatus(ctx, id, AuditCloseWallet, model.WalletClosed, model.WalletActive, model.WalletFrozen)
+}
+
+func (s *WalletService) changeStatus(ctx context.Context, id uuid.UUID, action string, to model.WalletStatus, from ...model.WalletStatus) (*model.Wallet, error) {
+	var out *model.Wallet
+	err := retry.Do(ctx, "change_wallet_status", s.retry, classifyRetryable, func() error {
+		return s.repo.WithTx(ctx, func(txRepo repository.WalletRepository) error {
+			wallet, err := txRepo.GetWalletByIdForUpdate(ctx, id)
+			if err != nil {
+				return walletLookupError(err)
+			}
+			if !slices.Contains(from, walletStatus(wallet)) {
+				return svcErrors.ErrWalletStatus
+			}
+			before := walletAuditStateOf(wallet)
+
+			if to == model.WalletClosed {
+				shards, err := txRepo.GetShardsForUpdate(ctx, id)
+				if err != nil {
+					return err
+				}
+				if math.Abs(wallet.Balance+sumShards(shards)) >= 0.005 {
+					return svcErrors.ErrWalletNotEmpty
+				}
+				if wallet.Sharded() {
+					if err := txRepo.ReplaceShardsTx(ctx, id, nil); err != nil {
+						return err
+					}
+				}
+			}
+
+			if err := txRepo.UpdateWalletStatusTx(ctx, id, to); err != nil {
+				return err
+			}
+			if out, err = txRepo.GetWalletById(ctx, id); err != nil {
+				return err
+			}
+			return writeAudit(ctx, txRepo.SaveAuditEntryTx, audit.FromContext(ctx), action, id, before, walletAuditStateOf(out))
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SetCreditLimit sets how far below zero debits may take the wallet's
+// balance. Lowering the limit below the current overdraft is allowed; the
+// wallet then only accepts credits until it is back within the limit. The
+// limit of an overdrawn wallet cannot be removed altogether, as only wallets
+// with a credit line may have a negative balance.
+func (s *WalletService) SetCreditLimit(ctx context.Context, id uuid.UUID, limit float64) (*model.Wallet, error) {
+	if limit < 0 || math.IsNaN(limit) || math.IsInf(limit, 0) {
+		return nil, svcErrors.ErrInvalidCreditLimit
+	}
+	limit = math.Round(limit*100) / 100
+
+	var out *model.Wallet
+	err := retry.Do(ctx, "set_credit_limit", s.retry, classifyRetryable, func() error {
+		return s.repo.WithTx(ctx, func(txRepo repository.WalletRepository) error {
+			wallet, err := txRepo.GetWalletByIdForUpdate(ctx, id)
+			if err != nil {
+				return walletLookupError(err)
+			}
+			if wallet.Status == model.WalletClosed {
+				return svcErrors.ErrWalletStatus
+			}
+			if limit == 0 && wallet.TotalBalance() < 0 {
+				return svcErrors.ErrWalletOverdrawn
+			}
+			before := walletAuditStateOf(wallet)
+
+			if err := txRepo.UpdateCreditLimitTx(ctx, id, limit); err != nil {
+				return err
+			}
+			if out, err = txRepo.GetWalletById(ctx, id); err != nil {
+				return err
+			}
+			return writeAudit(ctx, txRepo.SaveAuditEntryTx, audit.FromContext(ctx), AuditSetCreditLimit, id, before, walletAuditStateOf(out))
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Adjust applies a manual correction. Adjustments bypass a freeze but not
+// a closure, and like other debits stop at the wallet's credit limit.
+func (s *WalletService) Adjust(ctx context.Context, req dto.AdjustmentRequest) (*model.Operation, error) {
+	if req.Amount == 0 || math.IsNaN(req.Amount) {
+		return nil, svcErrors.ErrInvalidAmount
+	}
+	if req.Reason == "" || req.Actor == "" {
+		return nil, svcErrors.ErrInvalidReason
+	}
+
+	opID, opType, amount := uuid.New(), model.OperationAdjCredit, req.Amount
+	if amount < 0 {
+		opType, amount = model.OperationAdjDebit, -amount
+	}
+
+	var op *model.Operation
+	err := retry.Do(ctx, "adjust_wallet", s.retry, classifyRetryable, func() error {
+		return s.repo.WithTx(ctx, func(txRepo repository.WalletRepository) error {
+			wallet, err := txRepo.GetWalletByIdForUpdate(ctx, req.WalletID)
+			if err != nil {
+				return walletLookupError(err)
+			}
+			if wallet.Status == model.WalletClosed {
+				return svcErrors.ErrWalletClosed
+			}
+			before := walletAuditStateOf(wallet)
+
+			switch {
+			case opType == model.OperationAdjCredit:
+				err = txRepo.UpdateWalletTx(ctx, wallet.ID, wallet.Balance+amount)
+			case wallet.Sharded():
+				err = s.withdrawFromShards(ctx, txRepo, wallet, amount)
+			case wallet.Available() < amount:
+				err = svcErrors.ErrInsufficientFunds
+			default:
+				err = txRepo.UpdateWalletTx(ctx, wallet.ID, wallet.Balance-amount)
+			}
+			if err != nil {
+				return err
+			}
+
+			op = &model.Operation{ID: opID, WalletID: wallet.ID, Type: opType, Amount: amount}
+			if err := txRepo.SaveOperationTx(ctx, op); err != nil {
+				return err
+			}
+			err = txRepo.SaveAdjustmentTx(ctx, &model.Adjustment{
+				OperationID: opID,
+				Reason:      req.Reason,
+				Actor:       req.Actor,
+			})
+			if err != nil {
+				return err
+			}
+			return auditOperation(ctx, txRepo, audit.FromContext(ctx), op, before)
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return op, nil
+}
+
+// ListWallets returns up to limit wallets ordered by ID, starting after the
+// given one.
+func (s *WalletService) ListWallets(ctx context.Context, after uuid.UUID, limit int) ([]model.Wallet, error) {
+	return s.repo.ListWallets(ctx, after, limit)
+}
+
+// ListOperations returns the wallet's operations matching filter, oldest
+// first.
+func (s *WalletService) ListOperations(ctx context.Context, id uuid.UUID, filter repository.OperationFilter) ([]model.Operation, error) {
+	if _, err := s.LookupWallet(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.repo.ListOperations(ctx, id, filter)
+}
+
+// Reconcile checks the wallet's balance against its operations. The wallet
+// and its shards are locked while both are read, so in-flight operations
+// cannot make them disagree.
+func (s *WalletService) Reconcile(ctx context.Context, id uuid.UUID) (*Reconciliation, error) {
+	var out *Reconciliation
+	err := retry.Do(ctx, "reconcile_wallet", s.retry, classifyRetryable, func() error {
+		return s.repo.WithTx(ctx, func(txRepo repository.WalletRepository) error {
+			wallet, err := txRepo.GetWalletByIdForUpdate(ctx, id)
+			if err != nil {
+				return walletLookupError(err)
+			}
+			shards, err := txRepo.GetShardsForUpdate(ctx, id)
+			if err != nil {
+				return err
+			}
+			ledger, err := txRepo.OperationsNetTotal(ctx, id, time.Time{}, time.Time{})
+			if err != nil {
+				return err
+			}
+			out = &Reconciliation{
+				WalletID: id,
+				Balance:  wallet.Balance + sumShards(shards),
+				Ledger:   ledger,
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ReconcileAll reconciles every wallet, one at a time, and returns how many
+// were checked together with the ones that do not balance.
+func (s *WalletService) ReconcileAll(ctx context.Context) (int, []Reconciliation, error) {
+	var (
+		checked    int
+		mismatches []Reconciliation
+		after      uuid.UUID
+	)
+	for {
+		wallets, err := s.repo.ListWallets(ctx, after, reconcileBatch)
+		if err != nil {
+			return checked, mismatches, err
+		}
+		for _, w := range wallets {
+			rec, err := s.Reconcile(ctx, w.ID)
+			if errors.Is(err, svcErrors.ErrWalletNotFound) {
+				continue
+			}
+			if err != nil {
+				return checked, mismatches, err
+			}
+			checked++
+			if !rec.Balanced() {
+				mismatches = append(mismatches, *rec)
+			}
+		}
+		if len(wallets) < reconcileBatch {
+			return checked, mismatches, nil
+		}
+		after = wallets[len(wallets)-1].ID
+	}
+}
+
+// Statement builds the wallet's statement for [from, to). Zero times leave
+// that end open. Balances are derived from the operations alone, so a
+// wallet that does not reconcile has a statement that does not end at its
+// current balance.
+func (s *WalletService) Statement(ctx context.Context, id uuid.UUID, from, to time.Time) (*Statement, error) {
+	if _, err := s.LookupWallet(ctx, id); err != nil {
+		return nil, err
+	}
+
+	st := &Statement{WalletID: id, From: from, To: to}
+	if !from.IsZero() {
+		opening, err := s.ledgerBalance(ctx, id, from)
+		if err != nil {
+			return nil, err
+		}
+		st.OpeningBalance = opening
+	}
+
+	ops, err := s.repo.ListOperations(ctx, id, repository.OperationFilter{From: from, To: to})
+	if err != nil {
+		return nil, err
+	}
+	balance := st.OpeningBalance
+	st.Lines = make([]StatementLine, len(ops))
+	for i, op := range ops {
+		balance += op.SignedAmount()
+		st.Lines[i] = StatementLine{Operation: op, Balance: balance}
+	}
+	st.ClosingBalance = balance
+	return st, nil
+}
+
+// walletStatus treats rows written before wallets had a status as active.
+func walletStatus(w *model.Wallet) model.WalletStatus {
+	if w.Status == "" {
+		return model.WalletActive
+	}
+	return w.Status
+}
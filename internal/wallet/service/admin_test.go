@@ -0,0 +1,313 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"wallet-service/internal/dto"
+	"wallet-service/internal/wallet/model"
+	"wallet-service/internal/wallet/repository"
+	svcErrors "wallet-service/internal/wallet/service/errors"
+)
+
+func newAdminFixture(t *testing.T) (*WalletService, *repository.MemoryWalletRepository, uuid.UUID) {
+	t.Helper()
+	repo := repository.NewMemoryWalletRepository()
+	svc := NewWalletService(repo, WithSharding())
+	wallet, err := svc.CreateWallet(context.Background(), dto.CreateWalletRequest{})
+	require.NoError(t, err)
+	return svc, repo, wallet.ID
+}
+
+func operate(t *testing.T, svc *WalletService, walletID uuid.UUID, opType string, amount float64) error {
+	t.Helper()
+	_, err := svc.UpdateWalletBalance(context.Background(), dto.WalletOperationRequest{
+		WalletID: walletID, OperationType: opType, Amount: amount,
+	})
+	return err
+}
+
+func TestCreateWallet(t *testing.T) {
+	svc, _, id := newAdminFixture(t)
+	ctx := context.Background()
+
+	wallet, err := svc.LookupWallet(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, model.WalletActive, wallet.Status)
+	assert.Zero(t, wallet.TotalBalance())
+
+	_, err = svc.CreateWallet(ctx, dto.CreateWalletRequest{WalletID: id})
+	assert.ErrorIs(t, err, svcErrors.ErrWalletExists)
+}
+
+func TestFreezeWallet_BlocksCustomerOperations(t *testing.T) {
+	svc, _, id := newAdminFixture(t)
+	ctx := context.Background()
+	require.NoError(t, operate(t, svc, id, "DEPOSIT", 50))
+
+	frozen, err := svc.FreezeWallet(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, model.WalletFrozen, frozen.Status)
+
+	assert.ErrorIs(t, operate(t, svc, id, "DEPOSIT", 1), svcErrors.ErrWalletFrozen)
+	assert.ErrorIs(t, operate(t, svc, id, "WITHDRAW", 1), svcErrors.ErrWalletFrozen)
+	_, err = svc.FreezeWallet(ctx, id)
+	assert.ErrorIs(t, err, svcErrors.ErrWalletStatus)
+
+	// Staff can still correct a frozen wallet.
+	_, err = svc.Adjust(ctx, dto.AdjustmentRequest{WalletID: id, Amount: -20, Reason: "chargeback", Actor: "ops"})
+	require.NoError(t, err)
+
+	_, err = svc.UnfreezeWallet(ctx, id)
+	require.NoError(t, err)
+	require.NoError(t, operate(t, svc, id, "WITHDRAW", 30))
+
+	balance, err := svc.GetWallet(ctx, id)
+	require.NoError(t, err)
+	assert.Zero(t, balance)
+}
+
+func TestFreezeWallet_ShardedDeposits(t *testing.T) {
+	svc, _, id := newAdminFixture(t)
+	ctx := context.Background()
+	require.NoError(t, svc.SetWalletShards(ctx, id, 4, nil))
+
+	_, err := svc.FreezeWallet(ctx, id)
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, operate(t, svc, id, "DEPOSIT", 1), svcErrors.ErrWalletFrozen)
+}
+
+func TestFreezeWallet_ShardedDepositWaits(t *testing.T) {
+	svc, repo, id := newAdminFixture(t)
+	ctx := context.Background()
+	require.NoError(t, svc.SetWalletShards(ctx, id, 4, nil))
+
+	// A deposit arriving while a freeze holds the wallet row waits for it,
+	// then finds the wallet frozen.
+	locked, done := make(chan struct{}), make(chan error, 1)
+	go func() {
+		<-locked
+		done <- operate(t, svc, id, "DEPOSIT", 1)
+	}()
+	err := repo.WithTx(ctx, func(tx repository.WalletRepository) error {
+		if _, err := tx.GetWalletByIdForUpdate(ctx, id); err != nil {
+			return err
+		}
+		close(locked)
+		select {
+		case err := <-done:
+			t.Error("the deposit did not wait for the freeze")
+			done <- err
+		case <-time.After(50 * time.Millisecond):
+		}
+		return tx.UpdateWalletStatusTx(ctx, id, model.WalletFrozen)
+	})
+	require.NoError(t, err)
+	assert.ErrorIs(t, <-done, svcErrors.ErrWalletFrozen)
+}
+
+func TestCloseWallet(t *testing.T) {
+	svc, _, id := newAdminFixture(t)
+	ctx := context.Background()
+	require.NoError(t, svc.SetWalletShards(ctx, id, 2, nil))
+	require.NoError(t, operate(t, svc, id, "DEPOSIT", 10))
+
+	_, err := svc.CloseWallet(ctx, id)
+	assert.ErrorIs(t, err, svcErrors.ErrWalletNotEmpty)
+
+	require.NoError(t, operate(t, svc, id, "WITHDRAW", 10))
+	closed, err := svc.CloseWallet(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, model.WalletClosed, closed.Status)
+	assert.False(t, closed.Sharded(), "closing folds the shards back")
+
+	assert.ErrorIs(t, operate(t, svc, id, "DEPOSIT", 1), svcErrors.ErrWalletClosed)
+	_, err = svc.Adjust(ctx, dto.AdjustmentRequest{WalletID: id, Amount: 1, Reason: "test", Actor: "ops"})
+	assert.ErrorIs(t, err, svcErrors.ErrWalletClosed)
+	_, err = svc.UnfreezeWallet(ctx, id)
+	assert.ErrorIs(t, err, svcErrors.ErrWalletStatus)
+}
+
+func TestSetCreditLimit(t *testing.T) {
+	svc, repo, id := newAdminFixture(t)
+	ctx := context.Background()
+	require.NoError(t, operate(t, svc, id, "DEPOSIT", 20))
+	assert.ErrorIs(t, operate(t, svc, id, "WITHDRAW", 30), svcErrors.ErrInsufficientFunds)
+
+	wallet, err := svc.SetCreditLimit(ctx, id, 50)
+	require.NoError(t, err)
+	assert.Equal(t, float64(50), wallet.CreditLimit)
+	assert.Equal(t, float64(50), wallet.AvailableCredit())
+
+	// Debits may take the balance down to exactly -limit, not a cent more.
+	require.NoError(t, operate(t, svc, id, "WITHDRAW", 30))
+	require.NoError(t, operate(t, svc, id, "WITHDRAW", 40))
+	assert.ErrorIs(t, operate(t, svc, id, "WITHDRAW", 0.01), svcErrors.ErrInsufficientFunds)
+	_, err = svc.Adjust(ctx, dto.AdjustmentRequest{WalletID: id, Amount: -1, Reason: "test", Actor: "ops"})
+	assert.ErrorIs(t, err, svcErrors.ErrInsufficientFunds)
+	wallet = mustWallet(t, svc, id)
+	assert.Equal(t, float64(-50), wallet.TotalBalance())
+	assert.Zero(t, wallet.AvailableCredit())
+
+	// Lowering the limit below the overdraft only blocks further debits.
+	require.NoError(t, operate(t, svc, id, "DEPOSIT", 30))
+	wallet, err = svc.SetCreditLimit(ctx, id, 10)
+	require.NoError(t, err)
+	assert.Zero(t, wallet.AvailableCredit())
+	assert.ErrorIs(t, operate(t, svc, id, "WITHDRAW", 1), svcErrors.ErrInsufficientFunds)
+	_, err = svc.CloseWallet(ctx, id)
+	assert.ErrorIs(t, err, svcErrors.ErrWalletNotEmpty)
+	_, err = svc.SetCreditLimit(ctx, id, 0)
+	assert.ErrorIs(t, err, svcErrors.ErrWalletOverdrawn)
+
+	_, err = svc.SetCreditLimit(ctx, id, -1)
+	assert.ErrorIs(t, err, svcErrors.ErrInvalidCreditLimit)
+	_, err = svc.SetCreditLimit(ctx, uuid.New(), 10)
+	assert.ErrorIs(t, err, svcErrors.ErrWalletNotFound)
+
+	entries, err := repo.AuditLog().ListAuditEntries(ctx, repository.AuditFilter{WalletID: id})
+	require.NoError(t, err)
+	var limits int
+	for _, e := range entries {
+		if e.Action == AuditSetCreditLimit {
+			limits++
+		}
+	}
+	assert.Equal(t, 2, limits)
+
+	closed, err := svc.CreateWallet(ctx, dto.CreateWalletRequest{})
+	require.NoError(t, err)
+	_, err = svc.CloseWallet(ctx, closed.ID)
+	require.NoError(t, err)
+	_, err = svc.SetCreditLimit(ctx, closed.ID, 10)
+	assert.ErrorIs(t, err, svcErrors.ErrWalletStatus)
+}
+
+func TestAdjust(t *testing.T) {
+	svc, repo, id := newAdminFixture(t)
+	ctx := context.Background()
+
+	credit, err := svc.Adjust(ctx, dto.AdjustmentRequest{WalletID: id, Amount: 25, Reason: "goodwill", Actor: "alice"})
+	require.NoError(t, err)
+	assert.Equal(t, model.OperationAdjCredit, credit.Type)
+	assert.Equal(t, float64(25), credit.Amount)
+
+	debit, err := svc.Adjust(ctx, dto.AdjustmentRequest{WalletID: id, Amount: -5, Reason: "fee correction", Actor: "bob"})
+	require.NoError(t, err)
+	assert.Equal(t, model.OperationAdjDebit, debit.Type)
+	assert.Equal(t, float64(5), debit.Amount)
+
+	_, err = svc.Adjust(ctx, dto.AdjustmentRequest{WalletID: id, Amount: -100, Reason: "too much", Actor: "bob"})
+	assert.ErrorIs(t, err, svcErrors.ErrInsufficientFunds)
+	_, err = svc.Adjust(ctx, dto.AdjustmentRequest{WalletID: id, Amount: 1, Actor: "bob"})
+	assert.ErrorIs(t, err, svcErrors.ErrInvalidReason)
+
+	balance, err := svc.GetWallet(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, float64(20), balance)
+
+	adjustments := repo.Adjustments()
+	require.Len(t, adjustments, 2)
+	assert.Equal(t, credit.ID, adjustments[0].OperationID)
+	assert.Equal(t, "goodwill", adjustments[0].Reason)
+	assert.Equal(t, "bob", adjustments[1].Actor)
+}
+
+func TestReconcile(t *testing.T) {
+	svc, repo, id := newAdminFixture(t)
+	ctx := context.Background()
+	require.NoError(t, svc.SetWalletShards(ctx, id, 3, nil))
+	require.NoError(t, operate(t, svc, id, "DEPOSIT", 100))
+	require.NoError(t, operate(t, svc, id, "DEPOSIT", 7))
+	require.NoError(t, operate(t, svc, id, "WITHDRAW", 40))
+
+	rec, err := svc.Reconcile(ctx, id)
+	require.NoError(t, err)
+	assert.True(t, rec.Balanced(), "%+v", rec)
+	assert.Equal(t, float64(67), rec.Ledger)
+
+	// A balance changed behind the ledger's back.
+	drifted := uuid.New()
+	repo.AddWallet(model.Wallet{ID: drifted, Balance: 12})
+
+	checked, mismatches, err := svc.ReconcileAll(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, checked)
+	require.Len(t, mismatches, 1)
+	assert.Equal(t, drifted, mismatches[0].WalletID)
+	assert.Equal(t, float64(12), mismatches[0].Difference())
+}
+
+func TestReconcile_SeededWallet(t *testing.T) {
+	ctx := context.Background()
+	id := uuid.New()
+	created := historyStart.Add(-time.Hour)
+	repo := repository.NewMemoryWalletRepository()
+	require.NoError(t, repo.SeedWallet(model.Wallet{ID: id, Balance: 10000, CreatedAt: created}))
+	svc := NewWalletService(repo)
+	require.NoError(t, operate(t, svc, id, "WITHDRAW", 25))
+
+	rec, err := svc.Reconcile(ctx, id)
+	require.NoError(t, err)
+	assert.True(t, rec.Balanced(), "%+v", rec)
+
+	ops := repo.Operations(id)
+	require.Len(t, ops, 2)
+	assert.Equal(t, model.OperationOpening, ops[0].Type)
+	assert.Equal(t, created, ops[0].CreatedAt)
+
+	balance, err := svc.BalanceAsOf(ctx, id, historyStart)
+	require.NoError(t, err)
+	assert.Equal(t, float64(10000), balance, "history starts from the opening balance")
+}
+
+func TestStatement(t *testing.T) {
+	svc, _, id := newAdminFixture(t)
+	ctx := context.Background()
+	require.NoError(t, operate(t, svc, id, "DEPOSIT", 100))
+	time.Sleep(2 * time.Millisecond)
+	from := time.Now()
+	require.NoError(t, operate(t, svc, id, "WITHDRAW", 30))
+	_, err := svc.Adjust(ctx, dto.AdjustmentRequest{WalletID: id, Amount: 5, Reason: "refund", Actor: "ops"})
+	require.NoError(t, err)
+
+	st, err := svc.Statement(ctx, id, from, time.Time{})
+	require.NoError(t, err)
+	assert.Equal(t, float64(100), st.OpeningBalance)
+	require.Len(t, st.Lines, 2)
+	assert.Equal(t, float64(70), st.Lines[0].Balance)
+	assert.Equal(t, float64(75), st.Lines[1].Balance)
+	assert.Equal(t, float64(75), st.ClosingBalance)
+
+	ops, err := svc.ListOperations(ctx, id, repository.OperationFilter{To: from})
+	require.NoError(t, err)
+	require.Len(t, ops, 1)
+	assert.Equal(t, model.OperationDeposit, ops[0].Type)
+
+	_, err = svc.Statement(ctx, uuid.New(), time.Time{}, time.Time{})
+	assert.ErrorIs(t, err, svcErrors.ErrWalletNotFound)
+}
+
+func TestRunDue_FrozenAndClosedWallets(t *testing.T) {
+	f := newScheduleFixture(t, 0, WithScheduleRetry(3, time.Minute))
+	ctx := context.Background()
+	sch := f.create(t, dto.CreateScheduleRequest{OperationType: "DEPOSIT", Amount: 5, Interval: "1h", StartAt: &scheduleEpoch})
+
+	_, err := f.svc.wallets.FreezeWallet(ctx, f.walletID)
+	require.NoError(t, err)
+	f.runAt(t, scheduleEpoch)
+	got := f.reload(t, sch.ID)
+	assert.Equal(t, model.ScheduleActive, got.Status)
+	assert.Equal(t, 1, got.Attempts)
+
+	_, err = f.svc.wallets.CloseWallet(ctx, f.walletID)
+	require.NoError(t, err)
+	f.runAt(t, scheduleEpoch.Add(time.Minute))
+	assert.Equal(t, model.ScheduleFailed, f.reload(t, sch.ID).Status)
+}
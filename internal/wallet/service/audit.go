@@ -0,0 +1,138 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/google/uuid"
+	"strings"
+	"time"
+	"wallet-service/internal/audit"
+	"wallet-service/internal/wallet/model"
+	"wallet-service/internal/wallet/repository"
+)
+
+// Audit actions. Balance operations are recorded as "operation.<type>",
+// e.g. operation.deposit or operation.adj_debit.
+const (
+	AuditCreateWallet   = "wallet.create"
+	AuditFreezeWallet   = "wallet.freeze"
+	AuditUnfreezeWallet = "wallet.unfreeze"
+	AuditCloseWallet    = "wallet.close"
+	AuditSetShards      = "wallet.set_shards"
+	AuditSetCreditLimit = "wallet.set_credit_limit"
+
+	AuditCreateSchedule = "schedule.create"
+	AuditPauseSchedule  = "schedule.pause"
+	AuditResumeSchedule = "schedule.resume"
+	AuditCancelSchedule = "schedule.cancel"
+
+	AuditSetInterestRule    = "interest.set_rule"
+	AuditDeleteInterestRule = "interest.delete_rule"
+
+	AuditSetFeeSchedule    = "fee.set_schedule"
+	AuditDeleteFeeSchedule = "fee.delete_schedule"
+
+	AuditSetRates = "fx.set_rates"
+
+	AuditCreateCustomer = "customer.create"
+)
+
+// SchedulerActor is the actor recorded for operations run by the schedule
+// worker.
+const SchedulerActor = "scheduler"
+
+type walletAuditState struct {
+	Balance     float64            `json:"balance"`
+	Status      model.WalletStatus `json:"status"`
+	ShardCount  int                `json:"shardCount,omitempty"`
+	Label       string             `json:"label,omitempty"`
+	CreditLimit float64            `json:"creditLimit,omitempty"`
+	OperationID *uuid.UUID         `json:"operationId,omitempty"`
+}
+
+func walletAuditStateOf(w *model.Wallet) walletAuditState {
+	return walletAuditState{
+		Balance:     w.TotalBalance(),
+		Status:      walletStatus(w),
+		ShardCount:  w.ShardCount,
+		Label:       w.Label,
+		CreditLimit: w.CreditLimit,
+	}
+}
+
+type scheduleAuditState struct {
+	ScheduleID      uuid.UUID            `json:"scheduleId"`
+	OperationType   string               `json:"operationType"`
+	Amount          float64              `json:"amount"`
+	Cron            string               `json:"cron,omitempty"`
+	IntervalSeconds int64                `json:"intervalSeconds,omitempty"`
+	Status          model.ScheduleStatus `json:"status"`
+	NextRunAt       time.Time            `json:"nextRunAt"`
+}
+
+func scheduleAuditStateOf(s *model.Schedule) scheduleAuditState {
+	return scheduleAuditState{
+		ScheduleID:      s.ID,
+		OperationType:   s.OperationType,
+		Amount:          s.Amount,
+		Cron:            s.Cron,
+		IntervalSeconds: s.IntervalSeconds,
+		Status:          s.Status,
+		NextRunAt:       s.NextRunAt.UTC(),
+	}
+}
+
+// writeAudit records a change made on behalf of info. A nil before means
+// the call created its target, a nil after that it removed it.
+func writeAudit(ctx context.Context, save func(context.Context, *model.AuditEntry) error, info audit.Info, action string, walletID uuid.UUID, before, after any) error {
+	entry := &model.AuditEntry{
+		Actor:     info.Actor,
+		Action:    action,
+		WalletID:  walletID,
+		RequestID: info.RequestID,
+	}
+	var err error
+	if entry.Before, err = auditState(before); err != nil {
+		return err
+	}
+	if entry.After, err = auditState(after); err != nil {
+		return err
+	}
+	return save(ctx, entry)
+}
+
+func auditState(v any) (json.RawMessage, error) {
+	if v == nil {
+		return nil, nil
+	}
+	return json.Marshal(v)
+}
+
+// auditOperation records op, applied to a wallet that was in state before.
+func auditOperation(ctx context.Context, txRepo repository.WalletRepository, info audit.Info, op *model.Operation, before walletAuditState) error {
+	after := before
+	after.Balance += op.SignedAmount()
+	after.OperationID = &op.ID
+	return writeAudit(ctx, txRepo.SaveAuditEntryTx, info, "operation."+strings.ToLower(op.Type), op.WalletID, before, after)
+}
+
+// MaxAuditEntries bounds how many audit entries one query returns.
+const MaxAuditEntries = 1000
+
+// AuditService queries the audit log.
+type AuditService struct {
+	repo repository.AuditRepository
+}
+
+func NewAuditService(repo repository.AuditRepository) *AuditService {
+	return &AuditService{repo: repo}
+}
+
+// ListAuditEntries returns matching entries, newest first. A limit outside
+// 1..MaxAuditEntries is replaced by MaxAuditEntries.
+func (s *AuditService) ListAuditEntries(ctx context.Context, filter repository.AuditFilter) ([]model.AuditEntry, error) {
+	if filter.Limit < 1 || filter.Limit > MaxAuditEntries {
+		filter.Limit = MaxAuditEntries
+	}
+	return s.repo.ListAuditEntries(ctx, filter)
+}
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"wallet-service/internal/audit"
+	"wallet-service/internal/dto"
+	"wallet-service/internal/wallet/model"
+	"wallet-service/internal/wallet/repository"
+	svcErrors "wallet-service/internal/wallet/service/errors"
+)
+
+func TestAudit_RecordsMutatingCalls(t *testing.T) {
+	svc, repo, id := newAdminFixture(t)
+	ctx := audit.NewContext(context.Background(), audit.Info{Actor: "key:abc", RequestID: "req-1"})
+	log := NewAuditService(repo.AuditLog())
+
+	_, err := svc.UpdateWalletBalance(ctx, dto.WalletOperationRequest{WalletID: id, OperationType: "DEPOSIT", Amount: 40})
+	require.NoError(t, err)
+	_, err = svc.UpdateWalletBalance(ctx, dto.WalletOperationRequest{WalletID: id, OperationType: "WITHDRAW", Amount: 100})
+	require.ErrorIs(t, err, svcErrors.ErrInsufficientFunds)
+
+	adminCtx := audit.NewContext(context.Background(), audit.Info{Actor: "admin:alice", RequestID: "req-2"})
+	_, err = svc.FreezeWallet(adminCtx, id)
+	require.NoError(t, err)
+	_, err = svc.Adjust(adminCtx, dto.AdjustmentRequest{WalletID: id, Amount: -15, Reason: "fee", Actor: "alice"})
+	require.NoError(t, err)
+
+	entries, err := log.ListAuditEntries(context.Background(), repository.AuditFilter{WalletID: id})
+	require.NoError(t, err)
+	actions := make([]string, len(entries))
+	for i, e := range entries {
+		actions[i] = e.Action
+	}
+	// Newest first; the rejected withdrawal left no entry.
+	assert.Equal(t, []string{"operation.adj_debit", AuditFreezeWallet, "operation.deposit", AuditCreateWallet}, actions)
+
+	deposit := entries[2]
+	assert.Equal(t, "key:abc", deposit.Actor)
+	assert.Equal(t, "req-1", deposit.RequestID)
+	var before, after walletAuditState
+	require.NoError(t, json.Unmarshal(deposit.Before, &before))
+	require.NoError(t, json.Unmarshal(deposit.After, &after))
+	assert.Zero(t, before.Balance)
+	assert.Equal(t, float64(40), after.Balance)
+	assert.NotNil(t, after.OperationID)
+
+	freeze := entries[1]
+	assert.Equal(t, "admin:alice", freeze.Actor)
+	assert.JSONEq(t, `{"balance":40,"status":"ACTIVE"}`, string(freeze.Before))
+	assert.JSONEq(t, `{"balance":40,"status":"FROZEN"}`, string(freeze.After))
+
+	created := entries[3]
+	assert.Equal(t, audit.Unknown, created.Actor)
+	assert.Nil(t, created.Before)
+
+	byActor, err := log.ListAuditEntries(context.Background(), repository.AuditFilter{Actor: "admin:alice", Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, byActor, 1)
+	assert.Equal(t, "operation.adj_debit", byActor[0].Action)
+
+	later, err := log.ListAuditEntries(context.Background(), repository.AuditFilter{From: time.Now().Add(time.Minute)})
+	require.NoError(t, err)
+	assert.Empty(t, later)
+}
+
+func TestAudit_ScheduleRuns(t *testing.T) {
+	f := newScheduleFixture(t, 100)
+	f.schedules.SetAuditLog(f.wallets.AuditLog())
+	ctx := audit.NewContext(context.Background(), audit.Info{Actor: "key:abc", RequestID: "req-1"})
+
+	sch, err := f.svc.CreateSchedule(ctx, f.walletID, dto.CreateScheduleRequest{
+		OperationType: "WITHDRAW", Amount: 10, Interval: "1h", StartAt: &scheduleEpoch,
+	})
+	require.NoError(t, err)
+	f.runAt(t, scheduleEpoch)
+
+	entries, err := f.wallets.AuditLog().ListAuditEntries(context.Background(), repository.AuditFilter{})
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+
+	run, created := entries[0], entries[1]
+	assert.Equal(t, AuditCreateSchedule, created.Action)
+	assert.Equal(t, "key:abc", created.Actor)
+	assert.Equal(t, f.walletID, created.WalletID)
+
+	assert.Equal(t, "operation.withdraw", run.Action)
+	assert.Equal(t, SchedulerActor, run.Actor)
+	assert.Equal(t, "schedule:"+sch.ID.String(), run.RequestID)
+	assert.Equal(t, model.ScheduleActive, f.reload(t, sch.ID).Status)
+}
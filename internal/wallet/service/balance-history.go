@@ -0,0 +1,116 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"time"
+	"wallet-service/internal/db"
+	"wallet-service/internal/wallet/model"
+	"wallet-service/internal/wallet/repository"
+	svcErrors "wallet-service/internal/wallet/service/errors"
+)
+
+// snapshotBatch is how many wallets SnapshotBalances loads per page.
+const snapshotBatch = 500
+
+// BalanceAsOf returns the wallet's balance at the given time as derived from
+// its operations; operations created at or after at are not included. Like
+// statements, it does not reflect changes made outside the ledger.
+func (s *WalletService) BalanceAsOf(ctx context.Context, id uuid.UUID, at time.Time) (float64, error) {
+	if _, err := s.LookupWallet(ctx, id); err != nil {
+		return 0, err
+	}
+	return s.ledgerBalance(ctx, id, at)
+}
+
+// ledgerBalance adds the operations created in [snapshot, at) to the latest
+// balance snapshot taken at or before at, or sums every operation before at
+// if there is none. Either time inside an archived month fails with
+// ErrRangeArchived, as only the month's total is left.
+func (s *WalletService) ledgerBalance(ctx context.Context, id uuid.UUID, at time.Time) (float64, error) {
+	var (
+		base float64
+		from time.Time
+	)
+	snap, err := s.repo.LatestBalanceSnapshot(ctx, id, at)
+	switch {
+	case err == nil:
+		base, from = snap.Balance, snap.TakenAt
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return 0, err
+	}
+
+	net, err := s.repo.OperationsNetTotal(ctx, id, from, at)
+	if errors.Is(err, repository.ErrRangeArchived) {
+		return 0, svcErrors.ErrRangeArchived
+	}
+	if err != nil {
+		return 0, err
+	}
+	return base + net, nil
+}
+
+// SnapshotBalances records a balance snapshot taken at the given time for
+// every wallet with operations since its previous snapshot, and returns how
+// many it recorded. at should trail the clock by more than the longest
+// transaction, so that no operation created before it is still to commit.
+func (s *WalletService) SnapshotBalances(ctx context.Context, at time.Time) (int, error) {
+	// Snapshots are final, so the operations they sum must not lag.
+	ctx = db.WithPrimary(ctx)
+	var (
+		taken int
+		after uuid.UUID
+	)
+	for {
+		wallets, err := s.repo.ListWallets(ctx, after, snapshotBatch)
+		if err != nil {
+			return taken, err
+		}
+		for _, w := range wallets {
+			ok, err := s.snapshotBalance(ctx, w.ID, at)
+			if err != nil {
+				return taken, err
+			}
+			if ok {
+				taken++
+			}
+		}
+		if len(wallets) < snapshotBatch {
+			return taken, nil
+		}
+		after = wallets[len(wallets)-1].ID
+	}
+}
+
+func (s *WalletService) snapshotBalance(ctx context.Context, id uuid.UUID, at time.Time) (bool, error) {
+	var (
+		base float64
+		from time.Time
+	)
+	prev, err := s.repo.LatestBalanceSnapshot(ctx, id, at)
+	switch {
+	case err == nil:
+		if prev.TakenAt.Equal(at) {
+			return false, nil
+		}
+		base, from = prev.Balance, prev.TakenAt
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return false, err
+	}
+
+	// Without new operations the previous snapshot, or the empty ledger,
+	// already answers queries just as quickly.
+	ops, err := s.repo.ListOperations(ctx, id, repository.OperationFilter{From: from, To: at, Limit: 1})
+	if err != nil || len(ops) == 0 {
+		return false, err
+	}
+
+	net, err := s.repo.OperationsNetTotal(ctx, id, from, at)
+	if err != nil {
+		return false, err
+	}
+	err = s.repo.SaveBalanceSnapshot(ctx, &model.BalanceSnapshot{WalletID: id, TakenAt: at, Balance: base + net})
+	return err == nil, err
+}
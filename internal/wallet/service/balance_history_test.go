@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"wallet-service/internal/wallet/model"
+	"wallet-service/internal/wallet/repository"
+	"wallet-service/internal/wallet/repository/mocks"
+	svcErrors "wallet-service/internal/wallet/service/errors"
+)
+
+var historyStart = time.Date(2025, 3, 30, 12, 0, 0, 0, time.UTC)
+
+// newHistoryFixture creates a wallet with one operation per amount, an hour
+// apart starting at historyStart. Negative amounts are withdrawals.
+func newHistoryFixture(t *testing.T, amounts ...float64) (*WalletService, *repository.MemoryWalletRepository, uuid.UUID) {
+	t.Helper()
+	id := uuid.New()
+	repo := repository.NewMemoryWalletRepository(model.Wallet{ID: id})
+	for i, amount := range amounts {
+		op := &model.Operation{ID: uuid.New(), WalletID: id, Type: model.OperationDeposit, Amount: amount}
+		if amount < 0 {
+			op.Type, op.Amount = model.OperationWithdraw, -amount
+		}
+		op.CreatedAt = historyStart.Add(time.Duration(i) * time.Hour)
+		require.NoError(t, repo.SaveOperationTx(context.Background(), op))
+	}
+	return NewWalletService(repo), repo, id
+}
+
+func TestBalanceAsOf(t *testing.T) {
+	svc, _, id := newHistoryFixture(t, 100, -30, 5)
+	ctx := context.Background()
+
+	for _, tc := range []struct {
+		at   time.Time
+		want float64
+	}{
+		{historyStart.Add(-time.Hour), 0},
+		{historyStart, 0},
+		{historyStart.Add(time.Minute), 100},
+		{historyStart.Add(90 * time.Minute), 70},
+		{historyStart.Add(24 * time.Hour), 75},
+	} {
+		got, err := svc.BalanceAsOf(ctx, id, tc.at)
+		require.NoError(t, err)
+		assert.Equal(t, tc.want, got, "as of %s", tc.at)
+	}
+
+	_, err := svc.BalanceAsOf(ctx, uuid.New(), historyStart)
+	assert.ErrorIs(t, err, svcErrors.ErrWalletNotFound)
+}
+
+func TestBalanceAsOf_ArchivedMonth(t *testing.T) {
+	ctx := context.Background()
+	id := uuid.New()
+	mockRepo := new(mocks.WalletRepositoryMock)
+	mockRepo.On("GetWalletById", ctx, id).Return(&model.Wallet{ID: id}, nil)
+	mockRepo.On("LatestBalanceSnapshot", ctx, id, historyStart).Return(nil, gorm.ErrRecordNotFound)
+	mockRepo.On("OperationsNetTotal", ctx, id, time.Time{}, historyStart).Return(float64(0), repository.ErrRangeArchived)
+
+	_, err := NewWalletService(mockRepo).BalanceAsOf(ctx, id, historyStart)
+
+	assert.ErrorIs(t, err, svcErrors.ErrRangeArchived)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestSnapshotBalances(t *testing.T) {
+	svc, repo, id := newHistoryFixture(t, 100, -30, 5)
+	idle := uuid.New()
+	repo.AddWallet(model.Wallet{ID: idle, Balance: 50})
+	ctx := context.Background()
+	cutoff := historyStart.Add(90 * time.Minute)
+
+	n, err := svc.SnapshotBalances(ctx, cutoff)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n, "wallets without operations need no snapshot")
+
+	snap, err := repo.LatestBalanceSnapshot(ctx, id, cutoff)
+	require.NoError(t, err)
+	assert.Equal(t, float64(70), snap.Balance)
+
+	n, err = svc.SnapshotBalances(ctx, cutoff)
+	require.NoError(t, err)
+	assert.Zero(t, n, "a snapshot is only taken once")
+	n, err = svc.SnapshotBalances(ctx, cutoff.Add(30*time.Minute))
+	require.NoError(t, err)
+	assert.Zero(t, n, "no operations since the last snapshot")
+
+	// Later snapshots build on earlier ones.
+	n, err = svc.SnapshotBalances(ctx, cutoff.Add(24*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	snap, err = repo.LatestBalanceSnapshot(ctx, id, cutoff.Add(24*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, float64(75), snap.Balance)
+
+	// Queries start from the latest snapshot before the requested time.
+	got, err := svc.BalanceAsOf(ctx, id, cutoff.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, float64(75), got)
+	got, err = svc.BalanceAsOf(ctx, id, historyStart.Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, float64(100), got)
+}
@@ -0,0 +1,245 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"github.com/google/uuid"
+	"sync"
+	"sync/atomic"
+	"time"
+	"wallet-service/internal/audit"
+	"wallet-service/internal/dto"
+	"wallet-service/internal/retry"
+	"wallet-service/internal/tenant"
+	"wallet-service/internal/wallet/model"
+	"wallet-service/internal/wallet/repository"
+	svcErrors "wallet-service/internal/wallet/service/errors"
+)
+
+// CoalesceStats counts coalesced batches, the deposits they carried and the
+// batches that had to be split up and applied one deposit at a time.
+var CoalesceStats = expvar.NewMap("deposit_coalescing")
+
+const (
+	depositWaiting int32 = iota
+	depositClaimed
+	depositAbandoned
+)
+
+type pendingDeposit struct {
+	ctx   context.Context
+	opID  uuid.UUID
+	req   dto.WalletOperationRequest
+	state atomic.Int32
+	done  chan depositResult
+}
+
+type depositResult struct {
+	op  *model.Operation
+	err error
+}
+
+type depositBatch struct {
+	key      batchKey
+	walletID uuid.UUID
+	deposits []*pendingDeposit
+	timer    *time.Timer
+}
+
+// batchKey keeps deposits of different tenants apart: a batch runs with
+// the context of its first deposit, so it must only carry deposits that
+// context may make.
+type batchKey struct {
+	walletID uuid.UUID
+	tenant   string
+}
+
+// depositCoalescer groups deposits to the same wallet that arrive within
+// window of the first one. A batch is flushed when the window elapses or it
+// reaches maxBatch deposits, whichever comes first.
+type depositCoalescer struct {
+	window   time.Duration
+	maxBatch int
+	flush    func(*depositBatch)
+
+	mu      sync.Mutex
+	pending map[batchKey]*depositBatch
+}
+
+func newDepositCoalescer(window time.Duration, maxBatch int, flush func(*depositBatch)) *depositCoalescer {
+	return &depositCoalescer{
+		window:   window,
+		maxBatch: maxBatch,
+		flush:    flush,
+		pending:  make(map[batchKey]*depositBatch),
+	}
+}
+
+func (c *depositCoalescer) add(d *pendingDeposit) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := batchKey{walletID: d.req.WalletID}
+	key.tenant, _ = tenant.FromContext(d.ctx)
+	b := c.pending[key]
+	if b == nil {
+		b = &depositBatch{key: key, walletID: d.req.WalletID}
+		c.pending[key] = b
+		b.timer = time.AfterFunc(c.window, func() {
+			if c.detach(b) {
+				c.flush(b)
+			}
+		})
+	}
+	b.deposits = append(b.deposits, d)
+
+	if len(b.deposits) >= c.maxBatch && c.detachLocked(b) {
+		b.timer.Stop()
+		go c.flush(b)
+	}
+}
+
+func (c *depositCoalescer) detach(b *depositBatch) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.detachLocked(b)
+}
+
+// detachLocked removes b from the pending set so no further deposits join
+// it. It reports false if b was already detached.
+func (c *depositCoalescer) detachLocked(b *depositBatch) bool {
+	if c.pending[b.key] != b {
+		return false
+	}
+	delete(c.pending, b.key)
+	return true
+}
+
+// coalesceDeposit queues the deposit and waits for its batch. A caller whose
+// context ends before the batch is picked up is dropped from it; once the
+// batch is running the caller waits for the real outcome instead of
+// reporting an error for a deposit that may still commit.
+func (s *WalletService) coalesceDeposit(ctx context.Context, opID uuid.UUID, req dto.WalletOperationRequest) (*model.Operation, error) {
+	d := &pendingDeposit{
+		ctx:  ctx,
+		opID: opID,
+		req:  req,
+		done: make(chan depositResult, 1),
+	}
+	s.coalescer.add(d)
+
+	select {
+	case r := <-d.done:
+		return r.op, r.err
+	case <-ctx.Done():
+		if d.state.CompareAndSwap(depositWaiting, depositAbandoned) {
+			return nil, ctx.Err()
+		}
+		r := <-d.done
+		return r.op, r.err
+	}
+}
+
+func (s *WalletService) flushDeposits(b *depositBatch) {
+	deposits := make([]*pendingDeposit, 0, len(b.deposits))
+	for _, d := range b.deposits {
+		if d.state.CompareAndSwap(depositWaiting, depositClaimed) {
+			deposits = append(deposits, d)
+		}
+	}
+	if len(deposits) == 0 {
+		return
+	}
+	if len(deposits) == 1 {
+		d := deposits[0]
+		op, err := s.execute(d.ctx, d.opID, d.req)
+		d.done <- depositResult{op: op, err: err}
+		return
+	}
+
+	CoalesceStats.Add("batches", 1)
+	CoalesceStats.Add("deposits", int64(len(deposits)))
+
+	ctx, cancel := batchContext(deposits)
+	defer cancel()
+
+	ops, err := s.applyDeposits(ctx, b.walletID, deposits)
+	if err == nil {
+		for i, d := range deposits {
+			d.done <- depositResult{op: ops[i]}
+		}
+		return
+	}
+	// These reject every deposit to the wallet alike.
+	if errors.Is(err, svcErrors.ErrWalletNotFound) || errors.Is(err, svcErrors.ErrWalletFrozen) || errors.Is(err, svcErrors.ErrWalletClosed) {
+		for _, d := range deposits {
+			d.done <- depositResult{err: err}
+		}
+		return
+	}
+
+	// Something in the batch failed, e.g. one operation was rejected. Apply
+	// the deposits one by one so each caller gets its own outcome.
+	CoalesceStats.Add("fallbacks", 1)
+	for _, d := range deposits {
+		op, err := s.execute(d.ctx, d.opID, d.req)
+		d.done <- depositResult{op: op, err: err}
+	}
+}
+
+// applyDeposits credits the sum of the deposits with a single balance update
+// and records one operation per deposit, all in one transaction.
+func (s *WalletService) applyDeposits(ctx context.Context, walletID uuid.UUID, deposits []*pendingDeposit) ([]*model.Operation, error) {
+	var total float64
+	for _, d := range deposits {
+		total += d.req.Amount
+	}
+
+	var ops []*model.Operation
+	err := retry.Do(ctx, "coalesced_deposit", s.retry, classifyRetryable, func() error {
+		return s.repo.WithTx(ctx, func(txRepo repository.WalletRepository) error {
+			state, err := s.credit(ctx, txRepo, walletID, total, nil)
+			if err != nil {
+				return err
+			}
+			ops = make([]*model.Operation, len(deposits))
+			for i, d := range deposits {
+				op, err := s.saveOperation(ctx, txRepo, d.opID, walletID, d.req)
+				if err != nil {
+					return err
+				}
+				// Each deposit is audited on behalf of its own caller, as
+				// if the deposits had been applied one after another.
+				if err := auditOperation(ctx, txRepo, audit.FromContext(d.ctx), op, state); err != nil {
+					return err
+				}
+				state.Balance += op.Amount
+				ops[i] = op
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ops, nil
+}
+
+// batchContext returns a context for a batch that is not cancelled when any
+// single caller gives up, bounded by the latest deadline among the callers.
+func batchContext(deposits []*pendingDeposit) (context.Context, context.CancelFunc) {
+	ctx := context.WithoutCancel(deposits[0].ctx)
+
+	var latest time.Time
+	for _, d := range deposits {
+		deadline, ok := d.ctx.Deadline()
+		if !ok {
+			return context.WithCancel(ctx)
+		}
+		if deadline.After(latest) {
+			latest = deadline
+		}
+	}
+	return context.WithDeadline(ctx, latest)
+}
@@ -0,0 +1,167 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"wallet-service/internal/dto"
+	"wallet-service/internal/wallet/model"
+	"wallet-service/internal/wallet/repository"
+	svcErrors "wallet-service/internal/wallet/service/errors"
+)
+
+// countingRepo counts transactions and rejects operations of one amount.
+type countingRepo struct {
+	repository.WalletRepository
+	txs          *atomic.Int32
+	rejectAmount float64
+}
+
+func (r countingRepo) WithTx(ctx context.Context, fn func(repository.WalletRepository) error) error {
+	r.txs.Add(1)
+	return r.WalletRepository.WithTx(ctx, func(tx repository.WalletRepository) error {
+		return fn(countingRepo{WalletRepository: tx, txs: r.txs, rejectAmount: r.rejectAmount})
+	})
+}
+
+func (r countingRepo) SaveOperationTx(ctx context.Context, op *model.Operation) error {
+	if r.rejectAmount != 0 && op.Amount == r.rejectAmount {
+		return errors.New("operation rejected")
+	}
+	return r.WalletRepository.SaveOperationTx(ctx, op)
+}
+
+func depositConcurrently(svc *WalletService, ctx context.Context, walletID uuid.UUID, amounts ...float64) ([]*model.Operation, []error) {
+	ops := make([]*model.Operation, len(amounts))
+	errs := make([]error, len(amounts))
+	var wg sync.WaitGroup
+	for i, amount := range amounts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ops[i], errs[i] = svc.UpdateWalletBalance(ctx, dto.WalletOperationRequest{
+				WalletID:      walletID,
+				OperationType: "DEPOSIT",
+				Amount:        amount,
+			})
+		}()
+	}
+	wg.Wait()
+	return ops, errs
+}
+
+func TestCoalescing_GroupsConcurrentDeposits(t *testing.T) {
+	ctx := context.Background()
+	walletID := uuid.New()
+	mem := repository.NewMemoryWalletRepository(model.Wallet{ID: walletID, Balance: 100})
+	repo := countingRepo{WalletRepository: mem, txs: new(atomic.Int32)}
+	svc := NewWalletService(repo, WithDepositCoalescing(20*time.Millisecond, 100))
+
+	amounts := make([]float64, 50)
+	for i := range amounts {
+		amounts[i] = 10
+	}
+	ops, errs := depositConcurrently(svc, ctx, walletID, amounts...)
+
+	seen := make(map[uuid.UUID]bool)
+	for i := range ops {
+		require.NoError(t, errs[i])
+		assert.Equal(t, walletID, ops[i].WalletID)
+		assert.False(t, seen[ops[i].ID], "operation IDs must be unique")
+		seen[ops[i].ID] = true
+	}
+
+	balance, err := svc.GetWallet(ctx, walletID)
+	require.NoError(t, err)
+	assert.Equal(t, float64(600), balance)
+	assert.Len(t, mem.Operations(walletID), 50)
+	assert.Less(t, int(repo.txs.Load()), 50)
+}
+
+func TestCoalescing_FlushesAtMaxBatch(t *testing.T) {
+	walletID := uuid.New()
+	repo := repository.NewMemoryWalletRepository(model.Wallet{ID: walletID})
+	svc := NewWalletService(repo, WithDepositCoalescing(time.Hour, 4))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, errs := depositConcurrently(svc, ctx, walletID, 1, 2, 3, 4)
+	for _, err := range errs {
+		require.NoError(t, err)
+	}
+
+	balance, err := svc.GetWallet(ctx, walletID)
+	require.NoError(t, err)
+	assert.Equal(t, float64(10), balance)
+}
+
+func TestCoalescing_IsolatesFailingDeposit(t *testing.T) {
+	ctx := context.Background()
+	walletID := uuid.New()
+	mem := repository.NewMemoryWalletRepository(model.Wallet{ID: walletID})
+	repo := countingRepo{WalletRepository: mem, txs: new(atomic.Int32), rejectAmount: 13}
+	svc := NewWalletService(repo, WithDepositCoalescing(time.Hour, 4))
+
+	ops, errs := depositConcurrently(svc, ctx, walletID, 1, 13, 2, 3)
+
+	assert.Error(t, errs[1])
+	assert.Nil(t, ops[1])
+	for _, i := range []int{0, 2, 3} {
+		require.NoError(t, errs[i])
+		require.NotNil(t, ops[i])
+	}
+
+	balance, err := svc.GetWallet(ctx, walletID)
+	require.NoError(t, err)
+	assert.Equal(t, float64(6), balance)
+	assert.Len(t, mem.Operations(walletID), 3)
+}
+
+func TestCoalescing_SkipsAbandonedDeposit(t *testing.T) {
+	walletID := uuid.New()
+	repo := repository.NewMemoryWalletRepository(model.Wallet{ID: walletID})
+	svc := NewWalletService(repo, WithDepositCoalescing(time.Hour, 2))
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := svc.UpdateWalletBalance(cancelled, dto.WalletOperationRequest{WalletID: walletID, OperationType: "DEPOSIT", Amount: 5})
+	assert.ErrorIs(t, err, context.Canceled)
+
+	// The second deposit fills the batch and flushes it.
+	_, err = svc.UpdateWalletBalance(context.Background(), dto.WalletOperationRequest{WalletID: walletID, OperationType: "DEPOSIT", Amount: 7})
+	require.NoError(t, err)
+
+	balance, err := svc.GetWallet(context.Background(), walletID)
+	require.NoError(t, err)
+	assert.Equal(t, float64(7), balance)
+	assert.Len(t, repo.Operations(walletID), 1)
+}
+
+func TestCoalescing_WalletNotFound(t *testing.T) {
+	repo := repository.NewMemoryWalletRepository()
+	svc := NewWalletService(repo, WithDepositCoalescing(time.Hour, 2))
+
+	_, errs := depositConcurrently(svc, context.Background(), uuid.New(), 1, 2)
+	for _, err := range errs {
+		assert.ErrorIs(t, err, svcErrors.ErrWalletNotFound)
+	}
+}
+
+func TestCoalescing_WithdrawNotDelayed(t *testing.T) {
+	walletID := uuid.New()
+	repo := repository.NewMemoryWalletRepository(model.Wallet{ID: walletID, Balance: 10})
+	svc := NewWalletService(repo, WithDepositCoalescing(time.Hour, 100))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	_, err := svc.UpdateWalletBalance(ctx, dto.WalletOperationRequest{WalletID: walletID, OperationType: "WITHDRAW", Amount: 4})
+	require.NoError(t, err)
+}
@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"math"
+	"slices"
+	"strings"
+	"wallet-service/internal/audit"
+	"wallet-service/internal/dto"
+	"wallet-service/internal/tenant"
+	"wallet-service/internal/wallet/model"
+	"wallet-service/internal/wallet/repository"
+	svcErrors "wallet-service/internal/wallet/service/errors"
+)
+
+type customerAuditState struct {
+	CustomerID uuid.UUID `json:"customerId"`
+	ExternalID string    `json:"externalId"`
+	Name       string    `json:"name,omitempty"`
+}
+
+// WithCustomers lets CreateWallet give new wallets to the customers kept
+// in repo.
+func WithCustomers(repo repository.CustomerRepository) Option {
+	return func(s *WalletService) {
+		s.customers = repo
+	}
+}
+
+// findCustomer resolves a customer's external ID in the request's tenant.
+func (s *WalletService) findCustomer(ctx context.Context, externalID string) (*model.Customer, error) {
+	if s.customers == nil {
+		return nil, svcErrors.ErrCustomerNotFound
+	}
+	return findCustomer(ctx, s.customers, externalID)
+}
+
+func findCustomer(ctx context.Context, repo repository.CustomerRepository, externalID string) (*model.Customer, error) {
+	customer, err := repo.GetCustomerByExternalID(ctx, externalID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, svcErrors.ErrCustomerNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return customer, nil
+}
+
+// CustomerBalance is the total balance of a customer's wallets in one
+// currency.
+type CustomerBalance struct {
+	Currency string
+	Balance  float64
+	Wallets  int
+}
+
+// CustomerService keeps the customers owning wallets and looks up their
+// wallets by customer.
+type CustomerService struct {
+	repo    repository.CustomerRepository
+	wallets *WalletService
+}
+
+func NewCustomerService(repo repository.CustomerRepository, wallets *WalletService) *CustomerService {
+	return &CustomerService{repo: repo, wallets: wallets}
+}
+
+// CreateCustomer adds a customer to the request's tenant. Wallets are
+// given to it when they are created.
+func (s *CustomerService) CreateCustomer(ctx context.Context, req dto.CreateCustomerRequest) (*model.Customer, error) {
+	owner, ok := tenant.FromContext(ctx)
+	if !ok {
+		owner = tenant.Default
+	}
+	customer := &model.Customer{ID: uuid.New(), TenantID: owner, ExternalID: req.ExternalID, Name: req.Name}
+	err := s.repo.WithTx(ctx, func(txRepo repository.CustomerRepository) error {
+		if err := txRepo.CreateCustomerTx(ctx, customer); err != nil {
+			return err
+		}
+		state := customerAuditState{CustomerID: customer.ID, ExternalID: customer.ExternalID, Name: customer.Name}
+		return writeAudit(ctx, txRepo.SaveAuditEntryTx, audit.FromContext(ctx), AuditCreateCustomer, uuid.Nil, nil, state)
+	})
+	if errors.Is(err, repository.ErrDuplicateCustomer) {
+		return nil, svcErrors.ErrCustomerExists
+	}
+	if err != nil {
+		return nil, err
+	}
+	return customer, nil
+}
+
+func (s *CustomerService) GetCustomer(ctx context.Context, externalID string) (*model.Customer, error) {
+	return findCustomer(ctx, s.repo, externalID)
+}
+
+// ListWallets returns the customer's wallets ordered by label.
+func (s *CustomerService) ListWallets(ctx context.Context, externalID string) ([]model.Wallet, error) {
+	customer, err := s.GetCustomer(ctx, externalID)
+	if err != nil {
+		return nil, err
+	}
+	return s.wallets.repo.ListCustomerWallets(ctx, customer.ID)
+}
+
+// WalletByLabel returns the customer's wallet with the label.
+func (s *CustomerService) WalletByLabel(ctx context.Context, externalID, label string) (*model.Wallet, error) {
+	customer, err := s.GetCustomer(ctx, externalID)
+	if err != nil {
+		return nil, err
+	}
+	wallet, err := s.wallets.repo.GetWalletByLabel(ctx, customer.ID, label)
+	if err != nil {
+		return nil, walletLookupError(err)
+	}
+	return wallet, nil
+}
+
+// Balances totals the balances of the customer's wallets per currency,
+// ordered by currency. Closed wallets are left out.
+func (s *CustomerService) Balances(ctx context.Context, externalID string) ([]CustomerBalance, error) {
+	wallets, err := s.ListWallets(ctx, externalID)
+	if err != nil {
+		return nil, err
+	}
+
+	var balances []CustomerBalance
+	index := make(map[string]int)
+	for i := range wallets {
+		w := &wallets[i]
+		if walletStatus(w) == model.WalletClosed {
+			continue
+		}
+		j, ok := index[w.Currency]
+		if !ok {
+			j = len(balances)
+			index[w.Currency] = j
+			balances = append(balances, CustomerBalance{Currency: w.Currency})
+		}
+		balances[j].Balance += w.TotalBalance()
+		balances[j].Wallets++
+	}
+	for i := range balances {
+		balances[i].Balance = math.Round(balances[i].Balance*100) / 100
+	}
+	slices.SortFunc(balances, func(a, b CustomerBalance) int { return strings.Compare(a.Currency, b.Currency) })
+	return balances, nil
+}
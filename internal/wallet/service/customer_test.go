@@ -0,0 +1,115 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"wallet-service/internal/dto"
+	"wallet-service/internal/tenant"
+	"wallet-service/internal/wallet/repository"
+	svcErrors "wallet-service/internal/wallet/service/errors"
+)
+
+type customerFixture struct {
+	repo      *repository.MemoryWalletRepository
+	wallets   *WalletService
+	customers *CustomerService
+}
+
+func newCustomerFixture() customerFixture {
+	repo := repository.NewMemoryWalletRepository()
+	customerRepo := repository.NewMemoryCustomerRepository()
+	customerRepo.SetAuditLog(repo.AuditLog())
+	wallets := NewWalletService(repo, WithCustomers(customerRepo))
+	return customerFixture{
+		repo:      repo,
+		wallets:   wallets,
+		customers: NewCustomerService(customerRepo, wallets),
+	}
+}
+
+func (f customerFixture) deposit(t *testing.T, ctx context.Context, req dto.CreateWalletRequest, amount float64) {
+	t.Helper()
+	wallet, err := f.wallets.CreateWallet(ctx, req)
+	require.NoError(t, err)
+	if amount > 0 {
+		_, err = f.wallets.UpdateWalletBalance(ctx, dto.WalletOperationRequest{WalletID: wallet.ID, OperationType: "DEPOSIT", Amount: amount})
+		require.NoError(t, err)
+	}
+}
+
+func TestCustomer_Create(t *testing.T) {
+	f := newCustomerFixture()
+	ctx := tenant.NewContext(context.Background(), "acme")
+
+	customer, err := f.customers.CreateCustomer(ctx, dto.CreateCustomerRequest{ExternalID: "c-1", Name: "Ada"})
+	require.NoError(t, err)
+	assert.Equal(t, "acme", customer.TenantID)
+	_, err = f.customers.CreateCustomer(ctx, dto.CreateCustomerRequest{ExternalID: "c-1"})
+	assert.ErrorIs(t, err, svcErrors.ErrCustomerExists)
+
+	// External IDs are only unique within a tenant.
+	globex := tenant.NewContext(context.Background(), "globex")
+	_, err = f.customers.GetCustomer(globex, "c-1")
+	assert.ErrorIs(t, err, svcErrors.ErrCustomerNotFound)
+	_, err = f.customers.CreateCustomer(globex, dto.CreateCustomerRequest{ExternalID: "c-1"})
+	assert.NoError(t, err)
+
+	entries, err := f.repo.AuditLog().ListAuditEntries(ctx, repository.AuditFilter{Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, AuditCreateCustomer, entries[0].Action)
+}
+
+func TestCustomer_Wallets(t *testing.T) {
+	f := newCustomerFixture()
+	ctx := tenant.NewContext(context.Background(), "acme")
+	_, err := f.customers.CreateCustomer(ctx, dto.CreateCustomerRequest{ExternalID: "c-1"})
+	require.NoError(t, err)
+
+	f.deposit(t, ctx, dto.CreateWalletRequest{CustomerID: "c-1", Label: "main", Currency: "EUR"}, 10.10)
+	f.deposit(t, ctx, dto.CreateWalletRequest{CustomerID: "c-1", Label: "savings", Currency: "EUR"}, 20.20)
+	f.deposit(t, ctx, dto.CreateWalletRequest{CustomerID: "c-1", Label: "travel", Currency: "USD"}, 5)
+	f.deposit(t, ctx, dto.CreateWalletRequest{Currency: "EUR"}, 100)
+
+	_, err = f.wallets.CreateWallet(ctx, dto.CreateWalletRequest{CustomerID: "c-1", Label: "main"})
+	assert.ErrorIs(t, err, svcErrors.ErrWalletLabelExists)
+	_, err = f.wallets.CreateWallet(ctx, dto.CreateWalletRequest{CustomerID: "c-2"})
+	assert.ErrorIs(t, err, svcErrors.ErrCustomerNotFound)
+
+	wallets, err := f.customers.ListWallets(ctx, "c-1")
+	require.NoError(t, err)
+	require.Len(t, wallets, 3)
+	assert.Equal(t, []string{"main", "savings", "travel"}, []string{wallets[0].Label, wallets[1].Label, wallets[2].Label})
+
+	savings, err := f.customers.WalletByLabel(ctx, "c-1", "savings")
+	require.NoError(t, err)
+	assert.Equal(t, wallets[1].ID, savings.ID)
+	_, err = f.customers.WalletByLabel(ctx, "c-1", "holidays")
+	assert.ErrorIs(t, err, svcErrors.ErrWalletNotFound)
+
+	balances, err := f.customers.Balances(ctx, "c-1")
+	require.NoError(t, err)
+	assert.Equal(t, []CustomerBalance{
+		{Currency: "EUR", Balance: 30.30, Wallets: 2},
+		{Currency: "USD", Balance: 5, Wallets: 1},
+	}, balances)
+
+	// Closed wallets drop out of the totals.
+	travel, err := f.customers.WalletByLabel(ctx, "c-1", "travel")
+	require.NoError(t, err)
+	_, err = f.wallets.UpdateWalletBalance(ctx, dto.WalletOperationRequest{WalletID: travel.ID, OperationType: "WITHDRAW", Amount: 5})
+	require.NoError(t, err)
+	_, err = f.wallets.CloseWallet(ctx, travel.ID)
+	require.NoError(t, err)
+	balances, err = f.customers.Balances(ctx, "c-1")
+	require.NoError(t, err)
+	assert.Len(t, balances, 1)
+
+	globex := tenant.NewContext(context.Background(), "globex")
+	_, err = f.customers.Balances(globex, "c-1")
+	assert.ErrorIs(t, err, svcErrors.ErrCustomerNotFound)
+}
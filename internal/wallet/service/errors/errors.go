@@ -1,10 +1,119 @@
 package errors
 
-import "errors"
+import "net/http"
+
+// Error is a domain error with a stable, machine-readable Code and the HTTP
+// status it is reported with. The sentinels below are matched with
+// errors.Is; errors.As reads the code, status and details of an error
+// however deeply it is wrapped.
+type Error struct {
+	Code    string
+	Status  int
+	Message string
+	// Details become extra members of the problem document, e.g. the
+	// failing fields of a validation error.
+	Details map[string]any
+}
+
+func New(code string, status int, message string) *Error {
+	return &Error{Code: code, Status: status, Message: message}
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Is matches errors with the same code, so that the copies made by
+// WithMessage and WithDetail still match their sentinel.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// WithMessage returns a copy of e with a more specific message.
+func (e *Error) WithMessage(message string) *Error {
+	cp := *e
+	cp.Message = message
+	return &cp
+}
+
+// WithDetail returns a copy of e carrying an extra problem member.
+func (e *Error) WithDetail(key string, value any) *Error {
+	cp := *e
+	cp.Details = make(map[string]any, len(e.Details)+1)
+	for k, v := range e.Details {
+		cp.Details[k] = v
+	}
+	cp.Details[key] = value
+	return &cp
+}
 
 var (
-	ErrWalletNotFound    = errors.New("wallet not found")
-	ErrInvalidOperation  = errors.New("invalid operation type")
-	ErrInsufficientFunds = errors.New("insufficient funds")
-	ErrInvalidAmount     = errors.New("amount must be positive")
+	ErrWalletNotFound       = New("wallet_not_found", http.StatusNotFound, "wallet not found")
+	ErrInvalidOperation     = New("invalid_operation", http.StatusBadRequest, "invalid operation type")
+	ErrInsufficientFunds    = New("insufficient_funds", http.StatusConflict, "insufficient funds")
+	ErrInvalidAmount        = New("invalid_amount", http.StatusBadRequest, "amount must be positive")
+	ErrInvalidMetadata      = New("invalid_metadata", http.StatusBadRequest, "metadata must encode to at most 4096 bytes of JSON")
+	ErrDuplicateExternalRef = New("duplicate_external_ref", http.StatusConflict, "the wallet already has an operation with that externalRef")
+	ErrInvalidShardCount    = New("invalid_shard_count", http.StatusBadRequest, "invalid shard count")
+	ErrInvalidCreditLimit   = New("invalid_credit_limit", http.StatusBadRequest, "credit limit must not be negative")
+	ErrRangeArchived        = New("range_archived", http.StatusUnprocessableEntity, "the time falls inside a month whose operations are archived")
+
+	ErrWalletExists    = New("wallet_exists", http.StatusConflict, "wallet already exists")
+	ErrWalletFrozen    = New("wallet_frozen", http.StatusConflict, "wallet is frozen")
+	ErrWalletClosed    = New("wallet_closed", http.StatusConflict, "wallet is closed")
+	ErrWalletNotEmpty  = New("wallet_not_empty", http.StatusConflict, "wallet balance must be zero to close it")
+	ErrWalletOverdrawn = New("wallet_overdrawn", http.StatusConflict, "an overdrawn wallet keeps a credit line until it is repaid")
+	ErrWalletStatus    = New("wallet_status_conflict", http.StatusConflict, "wallet cannot make that change in its current status")
+	ErrInvalidReason   = New("invalid_adjustment", http.StatusBadRequest, "adjustments need a reason and an actor")
+
+	ErrPreconditionFailed     = New("precondition_failed", http.StatusPreconditionFailed, "wallet version does not match If-Match")
+	ErrConcurrentModification = New("concurrent_modification", http.StatusConflict, "wallet was modified concurrently, please retry")
+
+	ErrScheduleNotFound = New("schedule_not_found", http.StatusNotFound, "schedule not found")
+	ErrInvalidSchedule  = New("invalid_schedule", http.StatusBadRequest, "schedule needs a valid cron expression, an interval of at least 1m, or a startAt")
+	ErrScheduleStatus   = New("schedule_status_conflict", http.StatusConflict, "schedule cannot make that change in its current status")
+
+	ErrInterestRuleNotFound = New("interest_rule_not_found", http.StatusNotFound, "interest rule not found")
+	ErrInvalidInterestRule  = New("invalid_interest_rule", http.StatusBadRequest, "interest rules apply to either a walletId or a product")
+
+	ErrFeeScheduleNotFound = New("fee_schedule_not_found", http.StatusNotFound, "fee schedule not found")
+	ErrInvalidFeeSchedule  = New("invalid_fee_schedule", http.StatusBadRequest, "fee schedule minFee must not exceed maxFee")
+
+	ErrRateNotFound    = New("rate_not_found", http.StatusUnprocessableEntity, "no exchange rate for that currency pair")
+	ErrInvalidRate     = New("invalid_rate", http.StatusBadRequest, "exchange rates need two currency codes, a positive rate and a validTo after validFrom")
+	ErrInvalidTransfer = New("invalid_transfer", http.StatusBadRequest, "cannot transfer from a wallet to itself")
+
+	ErrCustomerNotFound  = New("customer_not_found", http.StatusNotFound, "customer not found")
+	ErrCustomerExists    = New("customer_exists", http.StatusConflict, "the tenant already has a customer with that externalId")
+	ErrWalletLabelExists = New("wallet_label_exists", http.StatusConflict, "the customer already has a wallet with that label")
+
+	ErrCurrencyNotAllowed     = New("currency_not_allowed", http.StatusBadRequest, "the tenant does not allow wallets in that currency")
+	ErrOperationLimitExceeded = New("operation_limit_exceeded", http.StatusUnprocessableEntity, "amount exceeds the tenant's operation limit")
 )
+
+// Request errors, raised by the HTTP layer before a request reaches a
+// service.
+var (
+	ErrMalformedBody    = New("malformed_body", http.StatusBadRequest, "request body is not valid JSON")
+	ErrValidation       = New("validation_failed", http.StatusBadRequest, "request body failed validation")
+	ErrInvalidParameter = New("invalid_parameter", http.StatusBadRequest, "invalid request parameter")
+	ErrUnauthorized     = New("unauthorized", http.StatusUnauthorized, "unauthorized")
+	ErrTenantForbidden  = New("tenant_forbidden", http.StatusForbidden, "the request may not act for that tenant")
+	ErrUnknownTenant    = New("unknown_tenant", http.StatusBadRequest, "unknown tenant")
+	ErrRateLimited      = New("rate_limited", http.StatusTooManyRequests, "rate limit exceeded")
+	ErrUpgradeRequired  = New("upgrade_required", http.StatusUpgradeRequired, "this endpoint only accepts WebSocket connections")
+	ErrInternal         = New("internal_error", http.StatusInternalServerError, "internal server error")
+)
+
+// InvalidParameter reports a path or query parameter that could not be
+// used, naming it in the problem's "parameter" member.
+func InvalidParameter(name, message string) *Error {
+	return ErrInvalidParameter.WithMessage(message).WithDetail("parameter", name)
+}
+
+// Validation reports the failing fields of a request body, keyed by their
+// JSON name, in the problem's "errors" member.
+func Validation(fields map[string]string) *Error {
+	return ErrValidation.WithDetail("errors", fields)
+}
@@ -0,0 +1,179 @@
+package service
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"log/slog"
+	"sync"
+	"time"
+	"wallet-service/internal/db"
+	"wallet-service/internal/wallet/model"
+	"wallet-service/internal/wallet/repository"
+)
+
+// eventBatch bounds the events a subscription fetches at once.
+const eventBatch = 100
+
+// EventService streams wallet events to subscribers. Run listens for new
+// events and wakes the subscriptions of their wallet, which then read the
+// events from the log. Notifications only say where to look, and a
+// wallet's events are numbered in commit order without gaps, so a
+// subscription sees every event once and in order even if notifications
+// are lost or coalesced.
+type EventService struct {
+	repo       repository.EventRepository
+	wallets    *WalletService
+	retryDelay time.Duration
+
+	mu   sync.Mutex
+	subs map[uuid.UUID]map[*Subscription]struct{}
+}
+
+func NewEventService(repo repository.EventRepository, wallets *WalletService) *EventService {
+	return &EventService{
+		repo:       repo,
+		wallets:    wallets,
+		retryDelay: time.Second,
+		subs:       make(map[uuid.UUID]map[*Subscription]struct{}),
+	}
+}
+
+// Run listens for events until ctx is done, starting over after a
+// failure. Every subscription is woken whenever listening (re)starts, as
+// events committed while nobody listened were not notified.
+func (s *EventService) Run(ctx context.Context) {
+	for {
+		err := s.repo.Listen(ctx, s.wakeAll, s.wake)
+		if ctx.Err() != nil {
+			return
+		}
+		slog.Error("listening for wallet events failed", "err", err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(s.retryDelay):
+		}
+	}
+}
+
+// Subscribe opens a subscription to the wallet's events after the event
+// numbered after, or after its latest event if after is nil. The caller
+// must Close it.
+func (s *EventService) Subscribe(ctx context.Context, walletID uuid.UUID, after *int64) (*Subscription, error) {
+	sub := &Subscription{
+		WalletID: walletID,
+		svc:      s,
+		ready:    make(chan struct{}, 1),
+	}
+	// Register before reading the log, so that events committed from here
+	// on wake the subscription.
+	s.mu.Lock()
+	if s.subs[walletID] == nil {
+		s.subs[walletID] = make(map[*Subscription]struct{})
+	}
+	s.subs[walletID][sub] = struct{}{}
+	s.mu.Unlock()
+
+	if err := sub.init(ctx, after); err != nil {
+		sub.Close()
+		return nil, err
+	}
+	sub.wake()
+	return sub, nil
+}
+
+func (s *EventService) wake(walletID uuid.UUID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for sub := range s.subs[walletID] {
+		sub.wake()
+	}
+}
+
+func (s *EventService) wakeAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, subs := range s.subs {
+		for sub := range subs {
+			sub.wake()
+		}
+	}
+}
+
+// Subscription delivers the events of one wallet. It is not safe for
+// concurrent use.
+type Subscription struct {
+	WalletID uuid.UUID
+	// Balance is the wallet's balance when the subscription was opened.
+	Balance float64
+
+	svc    *EventService
+	cursor int64
+	ready  chan struct{}
+}
+
+// init reads the balance and the latest event from the primary, as of the
+// same moment, so that the events following pick up from the balance.
+func (sub *Subscription) init(ctx context.Context, after *int64) error {
+	ctx = db.WithPrimary(ctx)
+	if _, err := sub.svc.wallets.LookupWallet(ctx, sub.WalletID); err != nil {
+		return err
+	}
+	balance, seq, err := sub.svc.repo.Head(ctx, sub.WalletID)
+	if err != nil {
+		return walletLookupError(err)
+	}
+	sub.Balance = balance
+
+	if after != nil {
+		sub.cursor = *after
+		return nil
+	}
+	sub.cursor = seq
+	return nil
+}
+
+// Cursor returns the number of the last event fetched, or of the event the
+// subscription started after.
+func (sub *Subscription) Cursor() int64 {
+	return sub.cursor
+}
+
+// Ready is signalled when new events may be available to Fetch.
+func (sub *Subscription) Ready() <-chan struct{} {
+	return sub.ready
+}
+
+// Fetch returns the next events after the cursor, oldest first, and moves
+// the cursor past them. Ready stays signalled while more events remain.
+func (sub *Subscription) Fetch(ctx context.Context) ([]model.WalletEvent, error) {
+	events, err := sub.svc.repo.ListEvents(ctx, sub.WalletID, sub.cursor, eventBatch)
+	if err != nil {
+		return nil, err
+	}
+	if len(events) > 0 {
+		sub.cursor = events[len(events)-1].Seq
+	}
+	if len(events) == eventBatch {
+		sub.wake()
+	}
+	return events, nil
+}
+
+// Close stops waking the subscription.
+func (sub *Subscription) Close() {
+	sub.svc.mu.Lock()
+	defer sub.svc.mu.Unlock()
+	delete(sub.svc.subs[sub.WalletID], sub)
+	if len(sub.svc.subs[sub.WalletID]) == 0 {
+		delete(sub.svc.subs, sub.WalletID)
+	}
+}
+
+func (sub *Subscription) wake() {
+	select {
+	case sub.ready <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"wallet-service/internal/dto"
+	"wallet-service/internal/wallet/model"
+	"wallet-service/internal/wallet/repository"
+	svcErrors "wallet-service/internal/wallet/service/errors"
+)
+
+func newEventFixture(t *testing.T) (*EventService, *WalletService, *repository.MemoryWalletRepository, uuid.UUID) {
+	t.Helper()
+	id := uuid.New()
+	repo := repository.NewMemoryWalletRepository(model.Wallet{ID: id, Balance: 100})
+	wallets := NewWalletService(repo)
+	events := NewEventService(repo.EventLog(), wallets)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go events.Run(ctx)
+	return events, wallets, repo, id
+}
+
+// nextEvents waits for the subscription to be woken and fetches.
+func nextEvents(t *testing.T, sub *Subscription) []model.WalletEvent {
+	t.Helper()
+	select {
+	case <-sub.Ready():
+	case <-time.After(time.Second):
+		t.Fatal("subscription was not woken")
+	}
+	events, err := sub.Fetch(context.Background())
+	require.NoError(t, err)
+	return events
+}
+
+func TestEvents_DeliversCommittedOperations(t *testing.T) {
+	events, wallets, repo, id := newEventFixture(t)
+	ctx := context.Background()
+
+	sub, err := events.Subscribe(ctx, id, nil)
+	require.NoError(t, err)
+	defer sub.Close()
+	assert.Equal(t, float64(100), sub.Balance)
+	assert.Empty(t, nextEvents(t, sub))
+
+	_, err = wallets.UpdateWalletBalance(ctx, dto.WalletOperationRequest{WalletID: id, OperationType: "DEPOSIT", Amount: 25})
+	require.NoError(t, err)
+	// A rolled back transaction leaves no event.
+	err = repo.WithTx(ctx, func(txRepo repository.WalletRepository) error {
+		if err := txRepo.SaveOperationTx(ctx, &model.Operation{ID: uuid.New(), WalletID: id, Type: model.OperationDeposit, Amount: 1}); err != nil {
+			return err
+		}
+		return errors.New("rollback")
+	})
+	require.Error(t, err)
+	_, err = wallets.UpdateWalletBalance(ctx, dto.WalletOperationRequest{WalletID: id, OperationType: "WITHDRAW", Amount: 5})
+	require.NoError(t, err)
+
+	var got []model.WalletEvent
+	for len(got) < 2 {
+		got = append(got, nextEvents(t, sub)...)
+	}
+	require.Len(t, got, 2)
+	assert.Equal(t, model.OperationDeposit, got[0].Operation.Type)
+	assert.Equal(t, float64(125), got[0].Balance)
+	assert.Equal(t, model.OperationWithdraw, got[1].Operation.Type)
+	assert.Equal(t, float64(120), got[1].Balance)
+	assert.Equal(t, got[0].Seq+1, got[1].Seq)
+	assert.Equal(t, got[1].Seq, sub.Cursor())
+}
+
+func TestEvents_ResumesAfterEventID(t *testing.T) {
+	events, wallets, _, id := newEventFixture(t)
+	ctx := context.Background()
+
+	for _, amount := range []float64{1, 2, 3} {
+		_, err := wallets.UpdateWalletBalance(ctx, dto.WalletOperationRequest{WalletID: id, OperationType: "DEPOSIT", Amount: amount})
+		require.NoError(t, err)
+	}
+
+	after := int64(1)
+	sub, err := events.Subscribe(ctx, id, &after)
+	require.NoError(t, err)
+	defer sub.Close()
+
+	got := nextEvents(t, sub)
+	require.Len(t, got, 2)
+	assert.Equal(t, float64(2), got[0].Operation.Amount)
+	assert.Equal(t, float64(3), got[1].Operation.Amount)
+}
+
+func TestEvents_UnknownWallet(t *testing.T) {
+	events, _, _, _ := newEventFixture(t)
+
+	_, err := events.Subscribe(context.Background(), uuid.New(), nil)
+	assert.ErrorIs(t, err, svcErrors.ErrWalletNotFound)
+	assert.Empty(t, events.subs)
+}
+
+func TestEvents_NumberedPerWallet(t *testing.T) {
+	events, wallets, repo, id := newEventFixture(t)
+	ctx := context.Background()
+	other := uuid.New()
+	repo.AddWallet(model.Wallet{ID: other})
+
+	for _, walletID := range []uuid.UUID{id, other, id, other, id} {
+		_, err := wallets.UpdateWalletBalance(ctx, dto.WalletOperationRequest{WalletID: walletID, OperationType: "DEPOSIT", Amount: 1})
+		require.NoError(t, err)
+	}
+
+	sub, err := events.Subscribe(ctx, id, nil)
+	require.NoError(t, err)
+	defer sub.Close()
+	assert.Equal(t, int64(3), sub.Cursor())
+	assert.Equal(t, float64(103), sub.Balance)
+
+	after := int64(0)
+	sub, err = events.Subscribe(ctx, other, &after)
+	require.NoError(t, err)
+	defer sub.Close()
+	got := nextEvents(t, sub)
+	require.Len(t, got, 2)
+	assert.Equal(t, []int64{1, 2}, []int64{got[0].Seq, got[1].Seq})
+}
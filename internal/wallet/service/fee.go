@@ -0,0 +1,201 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"wallet-service/internal/audit"
+	"wallet-service/internal/dto"
+	"wallet-service/internal/tenant"
+	"wallet-service/internal/wallet/model"
+	"wallet-service/internal/wallet/repository"
+	svcErrors "wallet-service/internal/wallet/service/errors"
+)
+
+// feeOperationSpace namespaces the IDs of fee operations, which are derived
+// from the ID of the charged operation so that retries cannot charge twice.
+var feeOperationSpace = uuid.MustParse("0b6c1f0e-5a2d-4c8e-b1f7-3e9d2a6c4b81")
+
+// FeeCalculator prices the fee of an operation on a wallet.
+type FeeCalculator interface {
+	Fee(ctx context.Context, wallet *model.Wallet, opType string, amount float64) (float64, error)
+}
+
+// WithFees charges the fees priced by calc on withdrawals and FX transfers
+// and credits them to the revenue wallet, which is itself never charged.
+func WithFees(calc FeeCalculator, revenueWallet uuid.UUID) Option {
+	return func(s *WalletService) {
+		s.fees = calc
+		s.revenueWallet = revenueWallet
+	}
+}
+
+type feeScheduleAuditState struct {
+	ScheduleID    uuid.UUID      `json:"scheduleId"`
+	OperationType string         `json:"operationType"`
+	Product       string         `json:"product,omitempty"`
+	Flat          float64        `json:"flat"`
+	Percent       float64        `json:"percent"`
+	Tiers         model.FeeTiers `json:"tiers,omitempty"`
+	MinFee        *float64       `json:"minFee,omitempty"`
+	MaxFee        *float64       `json:"maxFee,omitempty"`
+}
+
+func feeScheduleAuditStateOf(f *model.FeeSchedule) feeScheduleAuditState {
+	return feeScheduleAuditState{
+		ScheduleID:    f.ID,
+		OperationType: f.OperationType,
+		Product:       f.Product,
+		Flat:          f.Flat,
+		Percent:       f.Percent,
+		Tiers:         f.Tiers,
+		MinFee:        f.MinFee,
+		MaxFee:        f.MaxFee,
+	}
+}
+
+// FeeService keeps fee schedules and prices fees from them.
+type FeeService struct {
+	repo repository.FeeRepository
+}
+
+var _ FeeCalculator = (*FeeService)(nil)
+
+func NewFeeService(repo repository.FeeRepository) *FeeService {
+	return &FeeService{repo: repo}
+}
+
+// Fee prices the fee with the schedule of the wallet's product, falling back
+// to the default schedule. Without either the operation is free.
+func (s *FeeService) Fee(ctx context.Context, wallet *model.Wallet, opType string, amount float64) (float64, error) {
+	schedule, err := s.repo.FindSchedule(ctx, opType, wallet.Product)
+	if errors.Is(err, gorm.ErrRecordNotFound) && wallet.Product != "" {
+		schedule, err = s.repo.FindSchedule(ctx, opType, "")
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return schedule.Fee(amount), nil
+}
+
+// SetSchedule creates or replaces the schedule for the request's operation
+// type and product.
+func (s *FeeService) SetSchedule(ctx context.Context, req dto.FeeScheduleRequest) (*model.FeeSchedule, error) {
+	if req.MinFee != nil && req.MaxFee != nil && *req.MinFee > *req.MaxFee {
+		return nil, svcErrors.ErrInvalidFeeSchedule
+	}
+
+	var schedule *model.FeeSchedule
+	err := s.repo.WithTx(ctx, func(txRepo repository.FeeRepository) error {
+		var before any
+		existing, err := txRepo.FindSchedule(ctx, req.OperationType, req.Product)
+		switch {
+		case err == nil:
+			schedule, before = existing, feeScheduleAuditStateOf(existing)
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			schedule = &model.FeeSchedule{ID: uuid.New(), OperationType: req.OperationType, Product: req.Product}
+		default:
+			return err
+		}
+
+		schedule.Flat, schedule.Percent = req.Flat, req.Percent
+		schedule.MinFee, schedule.MaxFee = req.MinFee, req.MaxFee
+		schedule.Tiers = make(model.FeeTiers, len(req.Tiers))
+		for i, t := range req.Tiers {
+			schedule.Tiers[i] = model.FeeTier{From: t.From, Flat: t.Flat, Percent: t.Percent}
+		}
+		if err := txRepo.SaveScheduleTx(ctx, schedule); err != nil {
+			return err
+		}
+		return writeAudit(ctx, txRepo.SaveAuditEntryTx, audit.FromContext(ctx), AuditSetFeeSchedule, uuid.Nil, before, feeScheduleAuditStateOf(schedule))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return schedule, nil
+}
+
+func (s *FeeService) DeleteSchedule(ctx context.Context, id uuid.UUID) error {
+	return s.repo.WithTx(ctx, func(txRepo repository.FeeRepository) error {
+		schedule, err := txRepo.GetSchedule(ctx, id)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return svcErrors.ErrFeeScheduleNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if err := txRepo.DeleteScheduleTx(ctx, id); err != nil {
+			return err
+		}
+		return writeAudit(ctx, txRepo.SaveAuditEntryTx, audit.FromContext(ctx), AuditDeleteFeeSchedule, uuid.Nil, feeScheduleAuditStateOf(schedule), nil)
+	})
+}
+
+func (s *FeeService) ListSchedules(ctx context.Context) ([]model.FeeSchedule, error) {
+	return s.repo.ListSchedules(ctx)
+}
+
+// QuoteFee returns the fee the operation would be charged if it were made
+// now.
+func (s *WalletService) QuoteFee(ctx context.Context, req dto.WalletOperationRequest) (float64, error) {
+	if req.Amount < 0 {
+		return 0, svcErrors.ErrInvalidAmount
+	}
+	wallet, err := s.LookupWallet(ctx, req.WalletID)
+	if err != nil {
+		return 0, err
+	}
+	return s.fee(ctx, wallet, req.OperationType, req.Amount)
+}
+
+func (s *WalletService) fee(ctx context.Context, wallet *model.Wallet, opType string, amount float64) (float64, error) {
+	if s.fees == nil || wallet.ID == s.revenueWallet {
+		return 0, nil
+	}
+	return s.fees.Fee(ctx, wallet, opType, amount)
+}
+
+// chargeFee records the fee of op, already taken from its wallet, as a FEE
+// operation and credits it to the revenue wallet as a FEE_INCOME operation.
+// before is the payer's state before op.
+func (s *WalletService) chargeFee(ctx context.Context, txRepo repository.WalletRepository, op *model.Operation, fee float64, before walletAuditState) error {
+	info := audit.FromContext(ctx)
+	charge := &model.Operation{
+		ID:       uuid.NewSHA1(feeOperationSpace, []byte(op.ID.String()+"/fee")),
+		WalletID: op.WalletID,
+		Type:     model.OperationFee,
+		Amount:   fee,
+		ParentID: &op.ID,
+	}
+	if err := txRepo.SaveOperationTx(ctx, charge); err != nil {
+		return err
+	}
+	before.Balance += op.SignedAmount()
+	if err := auditOperation(ctx, txRepo, info, charge, before); err != nil {
+		return err
+	}
+
+	// The revenue wallet is the service's own and belongs to no tenant's
+	// view.
+	houseCtx := tenant.Unscoped(ctx)
+	revenueBefore, err := s.credit(houseCtx, txRepo, s.revenueWallet, fee, nil)
+	if err != nil {
+		return err
+	}
+	income := &model.Operation{
+		ID:       uuid.NewSHA1(feeOperationSpace, []byte(op.ID.String()+"/income")),
+		WalletID: s.revenueWallet,
+		Type:     model.OperationFeeIncome,
+		Amount:   fee,
+		ParentID: &op.ID,
+	}
+	if err := txRepo.SaveOperationTx(houseCtx, income); err != nil {
+		return err
+	}
+	op.Fee = fee
+	return auditOperation(houseCtx, txRepo, info, income, revenueBefore)
+}
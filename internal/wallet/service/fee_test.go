@@ -0,0 +1,210 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"wallet-service/internal/dto"
+	"wallet-service/internal/wallet/model"
+	"wallet-service/internal/wallet/repository"
+	svcErrors "wallet-service/internal/wallet/service/errors"
+)
+
+func ptr(v float64) *float64 { return &v }
+
+func TestFeeSchedule_Fee(t *testing.T) {
+	tiered := model.FeeSchedule{
+		Flat: 2,
+		Tiers: model.FeeTiers{
+			{From: 1000, Percent: 0.001},
+			{From: 100, Flat: 1, Percent: 0.005},
+		},
+	}
+
+	for name, tc := range map[string]struct {
+		schedule model.FeeSchedule
+		amount   float64
+		want     float64
+	}{
+		"flat":             {model.FeeSchedule{Flat: 1.5}, 10, 1.5},
+		"percent":          {model.FeeSchedule{Percent: 0.015}, 33.33, 0.5},
+		"flat and percent": {model.FeeSchedule{Flat: 0.3, Percent: 0.029}, 100, 3.2},
+		"min":              {model.FeeSchedule{Percent: 0.01, MinFee: ptr(0.5)}, 10, 0.5},
+		"max":              {model.FeeSchedule{Percent: 0.01, MaxFee: ptr(5)}, 1000, 5},
+		"below tiers":      {tiered, 50, 2},
+		"first tier":       {tiered, 100, 1.5},
+		"top tier":         {tiered, 5000, 5},
+	} {
+		assert.Equal(t, tc.want, tc.schedule.Fee(tc.amount), name)
+	}
+}
+
+type feeFixture struct {
+	svc     *WalletService
+	fees    *FeeService
+	repo    *repository.MemoryWalletRepository
+	revenue uuid.UUID
+}
+
+func newFeeFixture(t *testing.T) *feeFixture {
+	t.Helper()
+	revenue := uuid.New()
+	repo := repository.NewMemoryWalletRepository(model.Wallet{ID: revenue})
+	feeRepo := repository.NewMemoryFeeRepository()
+	feeRepo.SetAuditLog(repo.AuditLog())
+	fees := NewFeeService(feeRepo)
+	return &feeFixture{
+		svc:     NewWalletService(repo, WithFees(fees, revenue)),
+		fees:    fees,
+		repo:    repo,
+		revenue: revenue,
+	}
+}
+
+func (f *feeFixture) setSchedule(t *testing.T, req dto.FeeScheduleRequest) {
+	t.Helper()
+	_, err := f.fees.SetSchedule(context.Background(), req)
+	require.NoError(t, err)
+}
+
+func (f *feeFixture) balance(t *testing.T, id uuid.UUID) float64 {
+	t.Helper()
+	balance, err := f.svc.GetWallet(context.Background(), id)
+	require.NoError(t, err)
+	return balance
+}
+
+func TestFees_ChargedOnWithdrawal(t *testing.T) {
+	f := newFeeFixture(t)
+	ctx := context.Background()
+	f.setSchedule(t, dto.FeeScheduleRequest{OperationType: "WITHDRAW", Flat: 1})
+	f.setSchedule(t, dto.FeeScheduleRequest{OperationType: "WITHDRAW", Product: "premium", Percent: 0.01, MinFee: ptr(0.5)})
+
+	basic, err := f.svc.CreateWallet(ctx, dto.CreateWalletRequest{})
+	require.NoError(t, err)
+	premium, err := f.svc.CreateWallet(ctx, dto.CreateWalletRequest{Product: "premium"})
+	require.NoError(t, err)
+	for _, id := range []uuid.UUID{basic.ID, premium.ID} {
+		require.NoError(t, operate(t, f.svc, id, "DEPOSIT", 100))
+	}
+
+	quote, err := f.svc.QuoteFee(ctx, dto.WalletOperationRequest{WalletID: premium.ID, OperationType: "WITHDRAW", Amount: 10})
+	require.NoError(t, err)
+	assert.Equal(t, 0.5, quote)
+
+	op, err := f.svc.UpdateWalletBalance(ctx, dto.WalletOperationRequest{WalletID: premium.ID, OperationType: "WITHDRAW", Amount: 10})
+	require.NoError(t, err)
+	assert.Equal(t, quote, op.Fee)
+	require.NoError(t, operate(t, f.svc, basic.ID, "WITHDRAW", 10))
+	require.NoError(t, operate(t, f.svc, basic.ID, "DEPOSIT", 10), "deposits are free")
+
+	assert.Equal(t, 89.5, f.balance(t, premium.ID))
+	assert.Equal(t, float64(99), f.balance(t, basic.ID))
+	assert.Equal(t, 1.5, f.balance(t, f.revenue))
+
+	ops := f.repo.Operations(premium.ID)
+	require.Len(t, ops, 3)
+	charge := ops[2]
+	assert.Equal(t, model.OperationFee, charge.Type)
+	assert.Equal(t, 0.5, charge.Amount)
+	require.NotNil(t, charge.ParentID)
+	assert.Equal(t, op.ID, *charge.ParentID)
+
+	income := f.repo.Operations(f.revenue)
+	require.Len(t, income, 2)
+	assert.Equal(t, model.OperationFeeIncome, income[0].Type)
+	assert.Equal(t, op.ID, *income[0].ParentID)
+
+	// The fee has to be covered as well as the amount.
+	err = operate(t, f.svc, premium.ID, "WITHDRAW", 89)
+	assert.ErrorIs(t, err, svcErrors.ErrInsufficientFunds)
+	assert.Equal(t, 89.5, f.balance(t, premium.ID))
+	assert.Len(t, f.repo.Operations(f.revenue), 2)
+
+	// The revenue wallet pays no fees on itself.
+	require.NoError(t, operate(t, f.svc, f.revenue, "WITHDRAW", 1.5))
+	assert.Zero(t, f.balance(t, f.revenue))
+
+	for _, id := range []uuid.UUID{basic.ID, premium.ID, f.revenue} {
+		rec, err := f.svc.Reconcile(ctx, id)
+		require.NoError(t, err)
+		assert.True(t, rec.Balanced(), "wallet %s: balance %.2f, ledger %.2f", id, rec.Balance, rec.Ledger)
+	}
+}
+
+func TestFees_ChargedOnFXTransfer(t *testing.T) {
+	f := newFeeFixture(t)
+	ctx := context.Background()
+	f.setSchedule(t, dto.FeeScheduleRequest{OperationType: "FX_OUT", Percent: 0.02})
+	rates := repository.NewMemoryRateRepository()
+	rates.SetAuditLog(f.repo.AuditLog())
+	fx := NewFXService(rates, f.svc)
+	require.NoError(t, fx.SetRates(ctx, []dto.ExchangeRate{
+		{Base: "EUR", Quote: "USD", Rate: 1.25, ValidFrom: time.Now().Add(-time.Hour)},
+	}))
+
+	usd, err := f.svc.CreateWallet(ctx, dto.CreateWalletRequest{})
+	require.NoError(t, err)
+	eur, err := f.svc.CreateWallet(ctx, dto.CreateWalletRequest{Currency: "EUR"})
+	require.NoError(t, err)
+	require.NoError(t, operate(t, f.svc, usd.ID, "DEPOSIT", 100))
+
+	transfer, err := fx.Transfer(ctx, dto.FXTransferRequest{FromWalletID: usd.ID, ToWalletID: eur.ID, Amount: 50})
+	require.NoError(t, err)
+	assert.Equal(t, float64(1), transfer.Debit.Fee)
+	assert.Equal(t, float64(49), f.balance(t, usd.ID))
+	assert.Equal(t, float64(40), f.balance(t, eur.ID), "the fee is not converted")
+	assert.Equal(t, float64(1), f.balance(t, f.revenue))
+
+	ops := f.repo.Operations(usd.ID)
+	require.Len(t, ops, 3)
+	assert.Equal(t, model.OperationFee, ops[2].Type)
+	assert.Equal(t, transfer.Debit.ID, *ops[2].ParentID)
+
+	// The fee has to be covered as well as the amount, in the same
+	// transaction.
+	_, err = fx.Transfer(ctx, dto.FXTransferRequest{FromWalletID: usd.ID, ToWalletID: eur.ID, Amount: 49})
+	assert.ErrorIs(t, err, svcErrors.ErrInsufficientFunds)
+	assert.Equal(t, float64(49), f.balance(t, usd.ID))
+	assert.Len(t, f.repo.Operations(f.revenue), 1)
+
+	for _, id := range []uuid.UUID{usd.ID, eur.ID, f.revenue} {
+		rec, err := f.svc.Reconcile(ctx, id)
+		require.NoError(t, err)
+		assert.True(t, rec.Balanced(), "wallet %s: balance %.2f, ledger %.2f", id, rec.Balance, rec.Ledger)
+	}
+}
+
+func TestFees_Schedules(t *testing.T) {
+	f := newFeeFixture(t)
+	ctx := context.Background()
+
+	_, err := f.fees.SetSchedule(ctx, dto.FeeScheduleRequest{OperationType: "WITHDRAW", MinFee: ptr(2), MaxFee: ptr(1)})
+	assert.ErrorIs(t, err, svcErrors.ErrInvalidFeeSchedule)
+
+	first, err := f.fees.SetSchedule(ctx, dto.FeeScheduleRequest{OperationType: "WITHDRAW", Flat: 1})
+	require.NoError(t, err)
+	second, err := f.fees.SetSchedule(ctx, dto.FeeScheduleRequest{OperationType: "WITHDRAW", Flat: 2})
+	require.NoError(t, err)
+	assert.Equal(t, first.ID, second.ID, "one schedule per operation type and product")
+
+	wallet, err := f.svc.CreateWallet(ctx, dto.CreateWalletRequest{})
+	require.NoError(t, err)
+	fee, err := f.svc.QuoteFee(ctx, dto.WalletOperationRequest{WalletID: wallet.ID, OperationType: "WITHDRAW", Amount: 10})
+	require.NoError(t, err)
+	assert.Equal(t, float64(2), fee)
+
+	require.NoError(t, f.fees.DeleteSchedule(ctx, first.ID))
+	assert.ErrorIs(t, f.fees.DeleteSchedule(ctx, first.ID), svcErrors.ErrFeeScheduleNotFound)
+	fee, err = f.svc.QuoteFee(ctx, dto.WalletOperationRequest{WalletID: wallet.ID, OperationType: "WITHDRAW", Amount: 10})
+	require.NoError(t, err)
+	assert.Zero(t, fee)
+
+	_, err = f.svc.QuoteFee(ctx, dto.WalletOperationRequest{WalletID: uuid.New(), OperationType: "WITHDRAW", Amount: 10})
+	assert.ErrorIs(t, err, svcErrors.ErrWalletNotFound)
+}
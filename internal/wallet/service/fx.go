@@ -0,0 +1,347 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"wallet-service/internal/audit"
+	"wallet-service/internal/dto"
+	"wallet-service/internal/retry"
+	"wallet-service/internal/wallet/model"
+	"wallet-service/internal/wallet/repository"
+	svcErrors "wallet-service/internal/wallet/service/errors"
+)
+
+// RoundingMode selects how converted amounts are rounded to the cent.
+type RoundingMode string
+
+const (
+	// RoundHalfUp rounds halves away from zero.
+	RoundHalfUp RoundingMode = "half_up"
+	// RoundHalfEven rounds halves to the even cent.
+	RoundHalfEven RoundingMode = "half_even"
+	// RoundDown truncates towards zero.
+	RoundDown RoundingMode = "down"
+)
+
+// round rounds v to the cent. v is first snapped to a millionth of a cent
+// so that binary representation error cannot tip it across a boundary.
+func (m RoundingMode) round(v float64) float64 {
+	cents := math.Round(v*1e8) / 1e6
+	switch m {
+	case RoundHalfEven:
+		cents = math.RoundToEven(cents)
+	case RoundDown:
+		cents = math.Trunc(cents)
+	default:
+		cents = math.Round(cents)
+	}
+	return cents / 100
+}
+
+// fxOperationSpace namespaces the ID of an FX transfer's credit leg, which
+// is derived from the debit leg's ID.
+var fxOperationSpace = uuid.MustParse("9a4e7c2b-1d3f-4b6a-8e5c-7f0a2d9b3c16")
+
+var currencyCode = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// Conversion is the price of converting Amount of From into To.
+type Conversion struct {
+	From      string
+	To        string
+	Amount    float64
+	Converted float64
+	MidRate   float64
+	Spread    float64
+	// Rate is MidRate less the spread: what one unit of From buys.
+	Rate float64
+}
+
+// FXTransfer is the pair of operations that moved money between wallets in
+// different currencies.
+type FXTransfer struct {
+	Debit      *model.Operation
+	Credit     *model.Operation
+	Conversion Conversion
+}
+
+type FXOption func(*FXService)
+
+// WithSpread keeps the given fraction of the mid rate on every conversion.
+func WithSpread(spread float64) FXOption {
+	return func(s *FXService) {
+		s.spread = spread
+	}
+}
+
+// WithRounding sets how converted amounts are rounded; the default is
+// RoundHalfEven.
+func WithRounding(mode RoundingMode) FXOption {
+	return func(s *FXService) {
+		s.rounding = mode
+	}
+}
+
+// FXService keeps exchange rates, prices conversions with them and makes
+// FX transfers between wallets.
+type FXService struct {
+	rates    repository.RateRepository
+	wallets  *WalletService
+	spread   float64
+	rounding RoundingMode
+}
+
+func NewFXService(rates repository.RateRepository, wallets *WalletService, opts ...FXOption) *FXService {
+	s := &FXService{rates: rates, wallets: wallets, rounding: RoundHalfEven}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Convert prices amount of one currency in another with the rate valid at
+// the given time. A pair without a rate of its own is priced with the
+// inverse of the opposite pair.
+func (s *FXService) Convert(ctx context.Context, amount float64, from, to string, at time.Time) (*Conversion, error) {
+	if amount <= 0 || math.IsNaN(amount) {
+		return nil, svcErrors.ErrInvalidAmount
+	}
+
+	conv := &Conversion{From: from, To: to, Amount: amount, MidRate: 1}
+	if from != to {
+		mid, err := s.midRate(ctx, from, to, at)
+		if err != nil {
+			return nil, err
+		}
+		conv.MidRate, conv.Spread = mid, s.spread
+	}
+	conv.Rate = conv.MidRate * (1 - conv.Spread)
+	conv.Converted = s.rounding.round(amount * conv.Rate)
+	if conv.Converted <= 0 {
+		return nil, svcErrors.ErrInvalidAmount
+	}
+	return conv, nil
+}
+
+func (s *FXService) midRate(ctx context.Context, from, to string, at time.Time) (float64, error) {
+	rate, err := s.rates.FindRate(ctx, from, to, at)
+	if err == nil {
+		return rate.Rate, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, err
+	}
+
+	rate, err = s.rates.FindRate(ctx, to, from, at)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, svcErrors.ErrRateNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+	return 1 / rate.Rate, nil
+}
+
+// SetRates stores the rates, replacing any with the same pair and
+// ValidFrom.
+func (s *FXService) SetRates(ctx context.Context, rates []dto.ExchangeRate) error {
+	stored := make([]model.ExchangeRate, len(rates))
+	for i, r := range rates {
+		if !currencyCode.MatchString(r.Base) || !currencyCode.MatchString(r.Quote) || r.Base == r.Quote ||
+			!(r.Rate > 0) || math.IsInf(r.Rate, 0) || r.ValidFrom.IsZero() ||
+			(r.ValidTo != nil && !r.ValidTo.After(r.ValidFrom)) {
+			return svcErrors.ErrInvalidRate
+		}
+		stored[i] = model.ExchangeRate{Base: r.Base, Quote: r.Quote, Rate: r.Rate, ValidFrom: r.ValidFrom, ValidTo: r.ValidTo}
+	}
+
+	return s.rates.WithTx(ctx, func(txRepo repository.RateRepository) error {
+		if err := txRepo.SaveRatesTx(ctx, stored); err != nil {
+			return err
+		}
+		return writeAudit(ctx, txRepo.SaveAuditEntryTx, audit.FromContext(ctx), AuditSetRates, uuid.Nil, nil, rates)
+	})
+}
+
+// LoadRates reads rates from CSV with the header
+// base,quote,rate,valid_from,valid_to and stores them with SetRates. Times
+// are RFC 3339; valid_to may be empty. It returns how many rates it read.
+func (s *FXService) LoadRates(ctx context.Context, r io.Reader) (int, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = 5
+	cr.TrimLeadingSpace = true
+	records, err := cr.ReadAll()
+	if err != nil {
+		return 0, err
+	}
+	if len(records) == 0 || strings.Join(records[0], ",") != "base,quote,rate,valid_from,valid_to" {
+		return 0, errors.New("rates file: expected the header base,quote,rate,valid_from,valid_to")
+	}
+
+	rates := make([]dto.ExchangeRate, 0, len(records)-1)
+	for i, rec := range records[1:] {
+		line := i + 2
+		rate, err := strconv.ParseFloat(rec[2], 64)
+		if err != nil {
+			return 0, fmt.Errorf("rates file line %d: invalid rate %q", line, rec[2])
+		}
+		validFrom, err := time.Parse(time.RFC3339, rec[3])
+		if err != nil {
+			return 0, fmt.Errorf("rates file line %d: invalid valid_from %q", line, rec[3])
+		}
+		r := dto.ExchangeRate{Base: rec[0], Quote: rec[1], Rate: rate, ValidFrom: validFrom}
+		if rec[4] != "" {
+			validTo, err := time.Parse(time.RFC3339, rec[4])
+			if err != nil {
+				return 0, fmt.Errorf("rates file line %d: invalid valid_to %q", line, rec[4])
+			}
+			r.ValidTo = &validTo
+		}
+		rates = append(rates, r)
+	}
+
+	if err := s.SetRates(ctx, rates); err != nil {
+		return 0, err
+	}
+	return len(rates), nil
+}
+
+func (s *FXService) ListRates(ctx context.Context, base, quote string) ([]model.ExchangeRate, error) {
+	return s.rates.ListRates(ctx, base, quote)
+}
+
+// Transfer converts the amount from the source wallet's currency into the
+// destination's at the current rate and moves it. The debit is an FX_OUT
+// operation on the source and the credit an FX_IN operation on the
+// destination; both record the conversion in an FXLeg. The source also
+// pays the fee of its FX_OUT schedule on the amount.
+func (s *FXService) Transfer(ctx context.Context, req dto.FXTransferRequest) (*FXTransfer, error) {
+	if req.FromWalletID == req.ToWalletID {
+		return nil, svcErrors.ErrInvalidTransfer
+	}
+	if err := s.wallets.checkOperationLimit(ctx, req.Amount); err != nil {
+		return nil, err
+	}
+	from, err := s.wallets.lookupForWrite(ctx, req.FromWalletID)
+	if err != nil {
+		return nil, err
+	}
+	to, err := s.wallets.lookupForWrite(ctx, req.ToWalletID)
+	if err != nil {
+		return nil, err
+	}
+
+	conv, err := s.Convert(ctx, req.Amount, from.Currency, to.Currency, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	opID := req.OperationID
+	if opID == uuid.Nil {
+		opID = uuid.New()
+	}
+
+	debit, credit, err := s.wallets.transferFX(ctx, opID, from.ID, to.ID, conv)
+	if err != nil {
+		return nil, err
+	}
+	return &FXTransfer{Debit: debit, Credit: credit, Conversion: *conv}, nil
+}
+
+// transferFX applies a priced FX transfer. Both wallet rows are locked in ID
+// order so that opposite transfers between the same wallets cannot
+// deadlock.
+func (s *WalletService) transferFX(ctx context.Context, opID, fromID, toID uuid.UUID, conv *Conversion) (debit, credit *model.Operation, err error) {
+	err = retry.Do(ctx, "fx_transfer", s.retry, classifyRetryable, func() error {
+		return s.repo.WithTx(ctx, func(txRepo repository.WalletRepository) error {
+			wallets := make(map[uuid.UUID]*model.Wallet, 2)
+			first, second := fromID, toID
+			if bytes.Compare(second[:], first[:]) < 0 {
+				first, second = second, first
+			}
+			for _, id := range []uuid.UUID{first, second} {
+				wallet, err := s.loadWallet(ctx, txRepo, id)
+				if err != nil {
+					return err
+				}
+				if err := checkStatus(wallet); err != nil {
+					return err
+				}
+				wallets[id] = wallet
+			}
+			from, to := wallets[fromID], wallets[toID]
+			fromBefore, toBefore := walletAuditStateOf(from), walletAuditStateOf(to)
+
+			fee, err := s.fee(ctx, from, model.OperationFXOut, conv.Amount)
+			if err != nil {
+				return err
+			}
+			total := conv.Amount + fee
+			if from.Sharded() {
+				err = s.withdrawFromShards(ctx, txRepo, from, total)
+			} else if from.Available() < total {
+				err = svcErrors.ErrInsufficientFunds
+			} else {
+				from.Balance -= total
+				err = s.writeBalance(ctx, txRepo, from, nil)
+			}
+			if err != nil {
+				return err
+			}
+			to.Balance += conv.Converted
+			if err := s.writeBalance(ctx, txRepo, to, nil); err != nil {
+				return err
+			}
+
+			debit = &model.Operation{ID: opID, WalletID: fromID, Type: model.OperationFXOut, Amount: conv.Amount}
+			if err := s.recordFXLeg(ctx, txRepo, debit, conv, fromBefore); err != nil {
+				return err
+			}
+			credit = &model.Operation{
+				ID:       uuid.NewSHA1(fxOperationSpace, []byte(opID.String()+"/in")),
+				WalletID: toID,
+				Type:     model.OperationFXIn,
+				Amount:   conv.Converted,
+				ParentID: &debit.ID,
+			}
+			if err := s.recordFXLeg(ctx, txRepo, credit, conv, toBefore); err != nil {
+				return err
+			}
+			if fee > 0 {
+				return s.chargeFee(ctx, txRepo, debit, fee, fromBefore)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, nil, conflictError(err)
+	}
+	return debit, credit, nil
+}
+
+func (s *WalletService) recordFXLeg(ctx context.Context, txRepo repository.WalletRepository, op *model.Operation, conv *Conversion, before walletAuditState) error {
+	if err := txRepo.SaveOperationTx(ctx, op); err != nil {
+		return err
+	}
+	err := txRepo.SaveFXLegTx(ctx, &model.FXLeg{
+		OperationID:  op.ID,
+		FromCurrency: conv.From,
+		ToCurrency:   conv.To,
+		MidRate:      conv.MidRate,
+		Spread:       conv.Spread,
+		Rate:         conv.Rate,
+	})
+	if err != nil {
+		return err
+	}
+	return auditOperation(ctx, txRepo, audit.FromContext(ctx), op, before)
+}
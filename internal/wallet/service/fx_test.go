@@ -0,0 +1,154 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"wallet-service/internal/dto"
+	"wallet-service/internal/wallet/model"
+	"wallet-service/internal/wallet/repository"
+	svcErrors "wallet-service/internal/wallet/service/errors"
+)
+
+var fxEpoch = time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+
+func TestRoundingMode(t *testing.T) {
+	for _, tc := range []struct {
+		mode RoundingMode
+		in   float64
+		want float64
+	}{
+		{RoundHalfUp, 1.005, 1.01},
+		{RoundHalfEven, 1.005, 1.00},
+		{RoundHalfEven, 1.015, 1.02},
+		{RoundDown, 0.29, 0.29},
+		{RoundDown, 1.239, 1.23},
+		{RoundHalfUp, 1.2349, 1.23},
+	} {
+		assert.Equal(t, tc.want, tc.mode.round(tc.in), "%s of %v", tc.mode, tc.in)
+	}
+}
+
+func newFXFixture(t *testing.T, opts ...FXOption) (*FXService, *repository.MemoryWalletRepository) {
+	t.Helper()
+	repo := repository.NewMemoryWalletRepository()
+	rates := repository.NewMemoryRateRepository()
+	rates.SetAuditLog(repo.AuditLog())
+	svc := NewFXService(rates, NewWalletService(repo), opts...)
+
+	until := fxEpoch.Add(24 * time.Hour)
+	require.NoError(t, svc.SetRates(context.Background(), []dto.ExchangeRate{
+		{Base: "EUR", Quote: "USD", Rate: 1.1, ValidFrom: fxEpoch, ValidTo: &until},
+		{Base: "EUR", Quote: "USD", Rate: 1.2, ValidFrom: until},
+	}))
+	return svc, repo
+}
+
+func TestFX_Convert(t *testing.T) {
+	svc, _ := newFXFixture(t, WithSpread(0.01))
+	ctx := context.Background()
+
+	conv, err := svc.Convert(ctx, 100, "EUR", "USD", fxEpoch.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 1.1, conv.MidRate)
+	assert.InDelta(t, 1.089, conv.Rate, 1e-12)
+	assert.Equal(t, 108.9, conv.Converted)
+
+	conv, err = svc.Convert(ctx, 100, "EUR", "USD", fxEpoch.Add(48*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 118.8, conv.Converted, "the later rate takes over")
+
+	conv, err = svc.Convert(ctx, 110, "USD", "EUR", fxEpoch.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 99.0, conv.Converted, "the opposite pair is inverted")
+
+	conv, err = svc.Convert(ctx, 10, "USD", "USD", fxEpoch)
+	require.NoError(t, err)
+	assert.Equal(t, float64(10), conv.Converted, "no spread within a currency")
+
+	_, err = svc.Convert(ctx, 100, "EUR", "USD", fxEpoch.Add(-time.Second))
+	assert.ErrorIs(t, err, svcErrors.ErrRateNotFound)
+	_, err = svc.Convert(ctx, 100, "EUR", "GBP", fxEpoch)
+	assert.ErrorIs(t, err, svcErrors.ErrRateNotFound)
+	_, err = svc.Convert(ctx, 0.001, "EUR", "USD", fxEpoch)
+	assert.ErrorIs(t, err, svcErrors.ErrInvalidAmount)
+}
+
+func TestFX_Transfer(t *testing.T) {
+	svc, repo := newFXFixture(t)
+	require.NoError(t, svc.SetRates(context.Background(), []dto.ExchangeRate{
+		{Base: "EUR", Quote: "USD", Rate: 1.25, ValidFrom: time.Now().Add(-time.Hour)},
+	}))
+	ctx := context.Background()
+	usd, err := svc.wallets.CreateWallet(ctx, dto.CreateWalletRequest{})
+	require.NoError(t, err)
+	eur, err := svc.wallets.CreateWallet(ctx, dto.CreateWalletRequest{Currency: "EUR"})
+	require.NoError(t, err)
+	require.NoError(t, operate(t, svc.wallets, usd.ID, "DEPOSIT", 100))
+
+	transfer, err := svc.Transfer(ctx, dto.FXTransferRequest{FromWalletID: usd.ID, ToWalletID: eur.ID, Amount: 50})
+	require.NoError(t, err)
+	assert.Equal(t, float64(40), transfer.Conversion.Converted)
+	assert.Equal(t, model.OperationFXOut, transfer.Debit.Type)
+	assert.Equal(t, model.OperationFXIn, transfer.Credit.Type)
+	assert.Equal(t, transfer.Debit.ID, *transfer.Credit.ParentID)
+
+	balance, err := svc.wallets.GetWallet(ctx, usd.ID)
+	require.NoError(t, err)
+	assert.Equal(t, float64(50), balance)
+	balance, err = svc.wallets.GetWallet(ctx, eur.ID)
+	require.NoError(t, err)
+	assert.Equal(t, float64(40), balance)
+
+	legs := repo.FXLegs()
+	require.Len(t, legs, 2)
+	for _, leg := range legs {
+		assert.Equal(t, "USD", leg.FromCurrency)
+		assert.Equal(t, "EUR", leg.ToCurrency)
+		assert.Equal(t, 0.8, leg.Rate)
+	}
+
+	_, err = svc.Transfer(ctx, dto.FXTransferRequest{FromWalletID: usd.ID, ToWalletID: eur.ID, Amount: 51})
+	assert.ErrorIs(t, err, svcErrors.ErrInsufficientFunds)
+	_, err = svc.Transfer(ctx, dto.FXTransferRequest{FromWalletID: usd.ID, ToWalletID: usd.ID, Amount: 1})
+	assert.ErrorIs(t, err, svcErrors.ErrInvalidTransfer)
+	_, err = svc.Transfer(ctx, dto.FXTransferRequest{FromWalletID: usd.ID, ToWalletID: uuid.New(), Amount: 1})
+	assert.ErrorIs(t, err, svcErrors.ErrWalletNotFound)
+
+	for _, id := range []uuid.UUID{usd.ID, eur.ID} {
+		rec, err := svc.wallets.Reconcile(ctx, id)
+		require.NoError(t, err)
+		assert.True(t, rec.Balanced())
+	}
+}
+
+func TestFX_LoadRates(t *testing.T) {
+	svc, _ := newFXFixture(t)
+	ctx := context.Background()
+
+	n, err := svc.LoadRates(ctx, strings.NewReader(
+		"base,quote,rate,valid_from,valid_to\n"+
+			"GBP,USD,1.27,2025-06-01T00:00:00Z,\n"+
+			"GBP,EUR,1.17,2025-06-01T00:00:00Z,2025-07-01T00:00:00Z\n"))
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	conv, err := svc.Convert(ctx, 100, "GBP", "EUR", fxEpoch)
+	require.NoError(t, err)
+	assert.Equal(t, float64(117), conv.Converted)
+
+	rates, err := svc.ListRates(ctx, "GBP", "")
+	require.NoError(t, err)
+	assert.Len(t, rates, 2)
+
+	_, err = svc.LoadRates(ctx, strings.NewReader("base,quote,rate,valid_from,valid_to\nGBP,GBP,1,2025-06-01T00:00:00Z,\n"))
+	assert.ErrorIs(t, err, svcErrors.ErrInvalidRate)
+	_, err = svc.LoadRates(ctx, strings.NewReader("GBP,USD,1.27,2025-06-01T00:00:00Z,\n"))
+	assert.Error(t, err)
+}
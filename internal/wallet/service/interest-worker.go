@@ -0,0 +1,46 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// InterestWorker accrues and pays out interest once a day has ended.
+type InterestWorker struct {
+	svc      *InterestService
+	interval time.Duration
+	lag      time.Duration
+}
+
+func NewInterestWorker(svc *InterestService, interval, lag time.Duration) *InterestWorker {
+	return &InterestWorker{svc: svc, interval: interval, lag: lag}
+}
+
+// Run polls until ctx is done. A day is processed once it ended more than
+// lag ago; a failed run is repeated at the next poll.
+func (w *InterestWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	var done time.Time
+	for {
+		through := utcDate(time.Now().Add(-w.lag)).AddDate(0, 0, -1)
+		if through.After(done) {
+			accrued, paid, err := w.svc.RunInterest(ctx, through)
+			switch {
+			case err != nil && ctx.Err() == nil:
+				slog.Error("running interest failed", "through", through, "err", err)
+			case err == nil:
+				done = through
+				slog.Info("ran interest", "through", through, "accruals", accrued, "payouts", paid)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
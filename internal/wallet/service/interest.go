@@ -0,0 +1,359 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"math"
+	"time"
+	"wallet-service/internal/audit"
+	"wallet-service/internal/db"
+	"wallet-service/internal/dto"
+	"wallet-service/internal/retry"
+	"wallet-service/internal/wallet/model"
+	"wallet-service/internal/wallet/repository"
+	svcErrors "wallet-service/internal/wallet/service/errors"
+)
+
+// InterestActor is the actor recorded for interest payouts.
+const InterestActor = "interest"
+
+// interestBatch is how many wallets RunInterest loads per page.
+const interestBatch = 500
+
+// interestPayoutSpace namespaces payout operation IDs. A payout's ID is
+// derived from its wallet and cut-off date, so posting it a second time
+// fails on the duplicate ID instead of paying twice.
+var interestPayoutSpace = uuid.MustParse("6f1d2a8c-3b47-4e55-9a0e-0c7b5d1e8f42")
+
+type interestRuleAuditState struct {
+	RuleID        uuid.UUID      `json:"ruleId"`
+	Product       string         `json:"product,omitempty"`
+	AnnualRate    float64        `json:"annualRate"`
+	OverdraftRate float64        `json:"overdraftRate,omitempty"`
+	DayCount      model.DayCount `json:"dayCount"`
+}
+
+func interestRuleAuditStateOf(r *model.InterestRule) interestRuleAuditState {
+	return interestRuleAuditState{
+		RuleID:        r.ID,
+		Product:       r.Product,
+		AnnualRate:    r.AnnualRate,
+		OverdraftRate: r.OverdraftRate,
+		DayCount:      r.DayCount,
+	}
+}
+
+// InterestService keeps interest rules, accrues interest daily on
+// end-of-day balances and pays it out monthly as INTEREST operations.
+// Interest on overdrafts is charged monthly as OVERDRAFT operations.
+type InterestService struct {
+	repo    repository.InterestRepository
+	wallets *WalletService
+}
+
+func NewInterestService(repo repository.InterestRepository, wallets *WalletService) *InterestService {
+	return &InterestService{repo: repo, wallets: wallets}
+}
+
+// SetRule creates or replaces the interest rule of a wallet or a product.
+// A wallet's own rule takes precedence over its product's. The new rate
+// applies to days not accrued yet.
+func (s *InterestService) SetRule(ctx context.Context, req dto.InterestRuleRequest) (*model.InterestRule, error) {
+	if (req.WalletID == nil) == (req.Product == "") || req.AnnualRate == nil ||
+		*req.AnnualRate < 0 || math.IsNaN(*req.AnnualRate) ||
+		req.OverdraftRate < 0 || math.IsNaN(req.OverdraftRate) {
+		return nil, svcErrors.ErrInvalidInterestRule
+	}
+	var walletID uuid.UUID
+	if req.WalletID != nil {
+		walletID = *req.WalletID
+		if _, err := s.wallets.lookupForWrite(ctx, walletID); err != nil {
+			return nil, err
+		}
+	}
+
+	var rule *model.InterestRule
+	err := s.repo.WithTx(ctx, func(txRepo repository.InterestRepository) error {
+		var before any
+		existing, err := txRepo.FindRule(ctx, req.WalletID, req.Product)
+		switch {
+		case err == nil:
+			rule, before = existing, interestRuleAuditStateOf(existing)
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			rule = &model.InterestRule{ID: uuid.New(), WalletID: req.WalletID, Product: req.Product}
+		default:
+			return err
+		}
+
+		rule.AnnualRate = *req.AnnualRate
+		rule.OverdraftRate = req.OverdraftRate
+		rule.DayCount = model.DayCount(req.DayCount)
+		if err := txRepo.SaveRuleTx(ctx, rule); err != nil {
+			return err
+		}
+		return writeAudit(ctx, txRepo.SaveAuditEntryTx, audit.FromContext(ctx), AuditSetInterestRule, walletID, before, interestRuleAuditStateOf(rule))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+// DeleteRule removes a rule. Interest already accrued under it is still
+// paid out.
+func (s *InterestService) DeleteRule(ctx context.Context, id uuid.UUID) error {
+	return s.repo.WithTx(ctx, func(txRepo repository.InterestRepository) error {
+		rule, err := txRepo.GetRule(ctx, id)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return svcErrors.ErrInterestRuleNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if err := txRepo.DeleteRuleTx(ctx, id); err != nil {
+			return err
+		}
+		var walletID uuid.UUID
+		if rule.WalletID != nil {
+			walletID = *rule.WalletID
+		}
+		return writeAudit(ctx, txRepo.SaveAuditEntryTx, audit.FromContext(ctx), AuditDeleteInterestRule, walletID, interestRuleAuditStateOf(rule), nil)
+	})
+}
+
+func (s *InterestService) ListRules(ctx context.Context) ([]model.InterestRule, error) {
+	return s.repo.ListRules(ctx)
+}
+
+// ListAccruals returns the wallet's daily accruals dated in [from, to),
+// oldest first. Zero times leave that end open.
+func (s *InterestService) ListAccruals(ctx context.Context, walletID uuid.UUID, from, to time.Time) ([]model.InterestAccrual, error) {
+	if _, err := s.wallets.LookupWallet(ctx, walletID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListAccruals(ctx, walletID, from, to)
+}
+
+// RunInterest accrues interest for every day up to and including through
+// that has not been accrued yet, then pays out the accruals of every month
+// that ended by through. It returns how many accruals it recorded and how
+// many payouts and overdraft charges it posted. Both steps are idempotent,
+// so runs may be repeated and may overlap. through is a date (midnight UTC)
+// whose end should trail the clock by more than the longest transaction.
+func (s *InterestService) RunInterest(ctx context.Context, through time.Time) (accrued, paid int, err error) {
+	// Accruals are final, so the balances they use must not lag.
+	ctx = db.WithPrimary(ctx)
+	rules, err := s.repo.ListRules(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+	byWallet := make(map[uuid.UUID]*model.InterestRule)
+	byProduct := make(map[string]*model.InterestRule)
+	for i := range rules {
+		if r := &rules[i]; r.WalletID != nil {
+			byWallet[*r.WalletID] = r
+		} else {
+			byProduct[r.Product] = r
+		}
+	}
+
+	// Accruals dated before the first of the month after through belong to
+	// months that have ended.
+	next := through.AddDate(0, 0, 1)
+	cutoff := time.Date(next.Year(), next.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	var after uuid.UUID
+	for {
+		wallets, err := s.wallets.ListWallets(ctx, after, interestBatch)
+		if err != nil {
+			return accrued, paid, err
+		}
+		for i := range wallets {
+			w := &wallets[i]
+			if walletStatus(w) == model.WalletClosed {
+				continue
+			}
+			rule := byWallet[w.ID]
+			if rule == nil && w.Product != "" {
+				rule = byProduct[w.Product]
+			}
+			if rule != nil {
+				n, err := s.accrue(ctx, w, rule, through)
+				accrued += n
+				if err != nil {
+					return accrued, paid, err
+				}
+			}
+			n, err := s.payOut(ctx, w.ID, cutoff)
+			paid += n
+			if err != nil {
+				return accrued, paid, err
+			}
+		}
+		if len(wallets) < interestBatch {
+			return accrued, paid, nil
+		}
+		after = wallets[len(wallets)-1].ID
+	}
+}
+
+// accrue records the wallet's interest for each day through the given date
+// that is after its last accrual and no earlier than the day the rule and
+// the wallet were created.
+func (s *InterestService) accrue(ctx context.Context, w *model.Wallet, rule *model.InterestRule, through time.Time) (int, error) {
+	last, err := s.repo.LastAccrualDate(ctx, w.ID)
+	if err != nil {
+		return 0, err
+	}
+	start := utcDate(w.CreatedAt)
+	for _, t := range []time.Time{utcDate(rule.CreatedAt), last.AddDate(0, 0, 1)} {
+		if t.After(start) {
+			start = t
+		}
+	}
+
+	n := 0
+	for day := start; !day.After(through); day = day.AddDate(0, 0, 1) {
+		balance, err := s.wallets.ledgerBalance(ctx, w.ID, day.AddDate(0, 0, 1))
+		if err != nil {
+			return n, err
+		}
+		a := &model.InterestAccrual{
+			WalletID:   w.ID,
+			Date:       day,
+			Balance:    balance,
+			AnnualRate: rule.AnnualRate,
+			DayCount:   rule.DayCount,
+		}
+		switch {
+		case balance > 0:
+			a.Amount = balance * rule.AnnualRate / rule.DayCount.DaysInYear(day)
+		case balance < 0:
+			a.AnnualRate = rule.OverdraftRate
+			a.Amount = balance * rule.OverdraftRate / rule.DayCount.DaysInYear(day)
+		}
+		if err := s.repo.SaveAccrual(ctx, a); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// payOut settles the wallet's unpaid accruals dated before cutoff: the
+// interest earned and the overdraft interest owed are each posted as one
+// operation. It returns how many operations it posted.
+func (s *InterestService) payOut(ctx context.Context, walletID uuid.UUID, cutoff time.Time) (int, error) {
+	n := 0
+	for _, overdraft := range []bool{false, true} {
+		posted, err := s.settle(ctx, walletID, cutoff, overdraft)
+		if err != nil {
+			return n, err
+		}
+		if posted {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// settle posts the wallet's unpaid accruals of one sign as an INTEREST or
+// OVERDRAFT operation, rounded to the cent. Less than half a cent is left
+// to accumulate into a later payout.
+func (s *InterestService) settle(ctx context.Context, walletID uuid.UUID, cutoff time.Time, overdraft bool) (bool, error) {
+	accruals, err := s.repo.UnpaidAccruals(ctx, walletID, cutoff, overdraft)
+	if err != nil || len(accruals) == 0 {
+		return false, err
+	}
+	var total float64
+	for _, a := range accruals {
+		total += a.Amount
+	}
+	amount := math.Round(math.Abs(total)*100) / 100
+	if amount <= 0 {
+		return false, nil
+	}
+
+	date := cutoff.Format(time.DateOnly)
+	key, post := walletID.String()+"/"+date, s.wallets.PostInterest
+	if overdraft {
+		key, post = key+"/overdraft", s.wallets.ChargeOverdraftInterest
+	}
+	opID := uuid.NewSHA1(interestPayoutSpace, []byte(key))
+	ctx = audit.NewContext(ctx, audit.Info{Actor: InterestActor, RequestID: "interest:" + date})
+	_, err = post(ctx, walletID, opID, amount, cutoff, len(accruals))
+	if errors.Is(err, repository.ErrDuplicateOperation) || errors.Is(err, errAccrualsPaid) {
+		// A concurrent run paid these accruals; whatever accrued since is
+		// left to the next run.
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// errAccrualsPaid fails a payout whose accruals were not all unpaid when
+// it came to mark them.
+var errAccrualsPaid = errors.New("interest accruals changed during payout")
+
+// PostInterest credits an interest payout to the wallet as an INTEREST
+// operation with the given ID. It pays the wallet's unpaid accruals of
+// interest dated before cutoff, which must number accruals, and marks them
+// paid in the same transaction. Like adjustments, payouts reach frozen
+// wallets but not closed ones.
+func (s *WalletService) PostInterest(ctx context.Context, walletID, opID uuid.UUID, amount float64, cutoff time.Time, accruals int) (*model.Operation, error) {
+	return s.postInterest(ctx, walletID, opID, model.OperationInterest, amount, cutoff, accruals)
+}
+
+// ChargeOverdraftInterest debits overdraft interest from the wallet as an
+// OVERDRAFT operation with the given ID, settling the accruals of overdraft
+// interest like PostInterest. The charge is taken even if it goes beyond
+// the wallet's credit limit.
+func (s *WalletService) ChargeOverdraftInterest(ctx context.Context, walletID, opID uuid.UUID, amount float64, cutoff time.Time, accruals int) (*model.Operation, error) {
+	return s.postInterest(ctx, walletID, opID, model.OperationOverdraft, amount, cutoff, accruals)
+}
+
+func (s *WalletService) postInterest(ctx context.Context, walletID, opID uuid.UUID, opType string, amount float64, cutoff time.Time, accruals int) (*model.Operation, error) {
+	if amount <= 0 || math.IsNaN(amount) {
+		return nil, svcErrors.ErrInvalidAmount
+	}
+
+	var op *model.Operation
+	err := retry.Do(ctx, "post_interest", s.retry, classifyRetryable, func() error {
+		return s.repo.WithTx(ctx, func(txRepo repository.WalletRepository) error {
+			wallet, err := txRepo.GetWalletByIdForUpdate(ctx, walletID)
+			if err != nil {
+				return walletLookupError(err)
+			}
+			if wallet.Status == model.WalletClosed {
+				return svcErrors.ErrWalletClosed
+			}
+			before := walletAuditStateOf(wallet)
+			op = &model.Operation{ID: opID, WalletID: wallet.ID, Type: opType, Amount: amount}
+			if err := txRepo.UpdateWalletTx(ctx, wallet.ID, wallet.Balance+op.SignedAmount()); err != nil {
+				return err
+			}
+
+			if err := txRepo.SaveOperationTx(ctx, op); err != nil {
+				return err
+			}
+			marked, err := txRepo.MarkAccrualsPaidTx(ctx, wallet.ID, cutoff, opType == model.OperationOverdraft, op.ID)
+			if err != nil {
+				return err
+			}
+			if marked != int64(accruals) {
+				return errAccrualsPaid
+			}
+			return auditOperation(ctx, txRepo, audit.FromContext(ctx), op, before)
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return op, nil
+}
+
+func utcDate(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
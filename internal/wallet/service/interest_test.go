@@ -0,0 +1,245 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"wallet-service/internal/dto"
+	"wallet-service/internal/wallet/model"
+	"wallet-service/internal/wallet/repository"
+	svcErrors "wallet-service/internal/wallet/service/errors"
+)
+
+var interestEpoch = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+type interestFixture struct {
+	svc      *InterestService
+	wallets  *WalletService
+	repo     *repository.MemoryWalletRepository
+	interest *repository.MemoryInterestRepository
+}
+
+func newInterestFixture(t *testing.T) *interestFixture {
+	t.Helper()
+	repo := repository.NewMemoryWalletRepository()
+	interest := repository.NewMemoryInterestRepository()
+	interest.SetAuditLog(repo.AuditLog())
+	repo.SetInterestRepository(interest)
+	wallets := NewWalletService(repo)
+	return &interestFixture{svc: NewInterestService(interest, wallets), wallets: wallets, repo: repo, interest: interest}
+}
+
+// addWallet creates a wallet at interestEpoch holding balance, deposited
+// or, when negative, withdrawn at that time.
+func (f *interestFixture) addWallet(t *testing.T, product string, balance float64) uuid.UUID {
+	t.Helper()
+	id := uuid.New()
+	f.repo.AddWallet(model.Wallet{ID: id, Product: product, Balance: balance, CreditLimit: max(0, -balance), CreatedAt: interestEpoch})
+	if balance != 0 {
+		op := &model.Operation{ID: uuid.New(), WalletID: id, Type: model.OperationDeposit, Amount: balance, CreatedAt: interestEpoch}
+		if balance < 0 {
+			op.Type, op.Amount = model.OperationWithdraw, -balance
+		}
+		require.NoError(t, f.repo.SaveOperationTx(context.Background(), op))
+	}
+	return id
+}
+
+// addRule saves a rule as if it had been set before interestEpoch.
+func (f *interestFixture) addRule(t *testing.T, rule model.InterestRule) {
+	t.Helper()
+	rule.ID = uuid.New()
+	rule.CreatedAt = interestEpoch.Add(-time.Hour)
+	require.NoError(t, f.interest.SaveRuleTx(context.Background(), &rule))
+}
+
+func TestInterest_AccruesAndPaysMonthly(t *testing.T) {
+	f := newInterestFixture(t)
+	ctx := context.Background()
+	saver := f.addWallet(t, "savings", 1000)
+	custom := f.addWallet(t, "savings", 1000)
+	plain := f.addWallet(t, "", 1000)
+	empty := f.addWallet(t, "savings", 0)
+	f.addRule(t, model.InterestRule{Product: "savings", AnnualRate: 0.0365, DayCount: model.DayCountActual365})
+	f.addRule(t, model.InterestRule{WalletID: &custom, AnnualRate: 0.072, DayCount: model.DayCountActual360})
+
+	through := time.Date(2025, 1, 31, 0, 0, 0, 0, time.UTC)
+	accrued, paid, err := f.svc.RunInterest(ctx, through)
+	require.NoError(t, err)
+	assert.Equal(t, 3*31, accrued, "one accrual per day for each wallet with a rule")
+	assert.Equal(t, 2, paid, "nothing is paid on an empty wallet")
+
+	accruals, err := f.svc.ListAccruals(ctx, custom, time.Time{}, time.Time{})
+	require.NoError(t, err)
+	require.Len(t, accruals, 31)
+	assert.Equal(t, interestEpoch, accruals[0].Date)
+	assert.Equal(t, model.DayCountActual360, accruals[0].DayCount, "a wallet's own rule overrides its product's")
+	assert.InDelta(t, 0.2, accruals[0].Amount, 1e-9)
+	require.NotNil(t, accruals[0].PayoutID)
+
+	for id, want := range map[uuid.UUID]float64{saver: 1003.10, custom: 1006.20, plain: 1000, empty: 0} {
+		w, err := f.wallets.LookupWallet(ctx, id)
+		require.NoError(t, err)
+		assert.InDelta(t, want, w.Balance, 1e-9)
+	}
+	ops := f.repo.Operations(saver)
+	require.Len(t, ops, 2)
+	assert.Equal(t, model.OperationInterest, ops[1].Type)
+	assert.Equal(t, *accruals[0].PayoutID, uuid.NewSHA1(interestPayoutSpace, []byte(custom.String()+"/2025-02-01")))
+
+	// Running again neither accrues nor pays anything twice.
+	accrued, paid, err = f.svc.RunInterest(ctx, through)
+	require.NoError(t, err)
+	assert.Zero(t, accrued)
+	assert.Zero(t, paid)
+	assert.Len(t, f.repo.Operations(saver), 2)
+
+	// February's accruals wait for the month to end.
+	accrued, paid, err = f.svc.RunInterest(ctx, through.AddDate(0, 0, 2))
+	require.NoError(t, err)
+	assert.Equal(t, 3*2, accrued)
+	assert.Zero(t, paid)
+}
+
+func TestInterest_PaysAccrualsOnce(t *testing.T) {
+	f := newInterestFixture(t)
+	ctx := context.Background()
+	id := f.addWallet(t, "savings", 1000)
+	f.addRule(t, model.InterestRule{Product: "savings", AnnualRate: 0.0365, DayCount: model.DayCountActual365})
+
+	through := time.Date(2025, 1, 31, 0, 0, 0, 0, time.UTC)
+	cutoff := through.AddDate(0, 0, 1)
+	_, err := f.svc.accrue(ctx, mustWallet(t, f.wallets, id), mustRule(t, f.interest, "savings"), through)
+	require.NoError(t, err)
+
+	// A payout finding other accruals unpaid than it counted, as after a
+	// concurrent run paid some, posts nothing.
+	_, err = f.wallets.PostInterest(ctx, id, uuid.New(), 3.10, cutoff, 30)
+	assert.ErrorIs(t, err, errAccrualsPaid)
+	assert.InDelta(t, 1000, mustWallet(t, f.wallets, id).Balance, 1e-9)
+
+	_, paid, err := f.svc.RunInterest(ctx, through)
+	require.NoError(t, err)
+	assert.Equal(t, 1, paid)
+	assert.InDelta(t, 1003.10, mustWallet(t, f.wallets, id).Balance, 1e-9)
+
+	// A later run, with a later cutoff, pays February alone.
+	_, paid, err = f.svc.RunInterest(ctx, time.Date(2025, 2, 28, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	assert.Equal(t, 1, paid)
+	accruals, err := f.svc.ListAccruals(ctx, id, time.Time{}, cutoff)
+	require.NoError(t, err)
+	require.Len(t, accruals, 31)
+	payout := *accruals[0].PayoutID
+	for _, a := range accruals {
+		assert.Equal(t, payout, *a.PayoutID)
+	}
+	assert.Len(t, f.repo.Operations(id), 3, "the deposit and two payouts")
+}
+
+func TestInterest_ChargesOverdraft(t *testing.T) {
+	f := newInterestFixture(t)
+	ctx := context.Background()
+	overdrawn := f.addWallet(t, "current", -1000)
+	// mixed is in credit for the first half of January and overdrawn for
+	// the rest.
+	mixed := f.addWallet(t, "current", 1000)
+	f.repo.AddWallet(model.Wallet{ID: mixed, Product: "current", Balance: -1000, CreditLimit: 1000, CreatedAt: interestEpoch})
+	withdrawal := &model.Operation{ID: uuid.New(), WalletID: mixed, Type: model.OperationWithdraw, Amount: 2000, CreatedAt: interestEpoch.AddDate(0, 0, 15)}
+	require.NoError(t, f.repo.SaveOperationTx(ctx, withdrawal))
+	f.addRule(t, model.InterestRule{Product: "current", AnnualRate: 0.0365, OverdraftRate: 0.1825, DayCount: model.DayCountActual365})
+
+	through := time.Date(2025, 1, 31, 0, 0, 0, 0, time.UTC)
+	accrued, paid, err := f.svc.RunInterest(ctx, through)
+	require.NoError(t, err)
+	assert.Equal(t, 2*31, accrued)
+	assert.Equal(t, 3, paid, "one charge for each wallet and one payout for mixed")
+
+	accruals, err := f.svc.ListAccruals(ctx, overdrawn, time.Time{}, time.Time{})
+	require.NoError(t, err)
+	require.Len(t, accruals, 31)
+	assert.Equal(t, 0.1825, accruals[0].AnnualRate, "the overdraft rate applies to negative balances")
+	assert.InDelta(t, -0.5, accruals[0].Amount, 1e-9)
+	require.NotNil(t, accruals[0].PayoutID)
+	assert.Equal(t, *accruals[0].PayoutID, uuid.NewSHA1(interestPayoutSpace, []byte(overdrawn.String()+"/2025-02-01/overdraft")))
+
+	assert.InDelta(t, -1015.50, mustWallet(t, f.wallets, overdrawn).Balance, 1e-9)
+	ops := f.repo.Operations(overdrawn)
+	require.Len(t, ops, 2)
+	assert.Equal(t, model.OperationOverdraft, ops[1].Type)
+	assert.Equal(t, 15.50, ops[1].Amount)
+
+	// 15 days at 0.10 earned, 16 days at 0.50 charged.
+	assert.InDelta(t, -1000+1.50-8, mustWallet(t, f.wallets, mixed).Balance, 1e-9)
+	var types []string
+	for _, op := range f.repo.Operations(mixed)[2:] {
+		types = append(types, op.Type)
+	}
+	assert.Equal(t, []string{model.OperationInterest, model.OperationOverdraft}, types)
+
+	_, paid, err = f.svc.RunInterest(ctx, through)
+	require.NoError(t, err)
+	assert.Zero(t, paid)
+}
+
+func TestInterest_Rules(t *testing.T) {
+	f := newInterestFixture(t)
+	ctx := context.Background()
+	id := f.addWallet(t, "", 0)
+	rate := 0.05
+
+	_, err := f.svc.SetRule(ctx, dto.InterestRuleRequest{WalletID: &id, Product: "savings", AnnualRate: &rate, DayCount: "ACT/365"})
+	assert.ErrorIs(t, err, svcErrors.ErrInvalidInterestRule)
+	_, err = f.svc.SetRule(ctx, dto.InterestRuleRequest{WalletID: &id, AnnualRate: &rate, OverdraftRate: -0.1, DayCount: "ACT/365"})
+	assert.ErrorIs(t, err, svcErrors.ErrInvalidInterestRule)
+	missing := uuid.New()
+	_, err = f.svc.SetRule(ctx, dto.InterestRuleRequest{WalletID: &missing, AnnualRate: &rate, DayCount: "ACT/365"})
+	assert.ErrorIs(t, err, svcErrors.ErrWalletNotFound)
+
+	rule, err := f.svc.SetRule(ctx, dto.InterestRuleRequest{WalletID: &id, AnnualRate: &rate, DayCount: "ACT/365"})
+	require.NoError(t, err)
+	rate = 0.04
+	updated, err := f.svc.SetRule(ctx, dto.InterestRuleRequest{WalletID: &id, AnnualRate: &rate, OverdraftRate: 0.15, DayCount: "ACT/ACT"})
+	require.NoError(t, err)
+	assert.Equal(t, rule.ID, updated.ID, "a wallet has one rule")
+	assert.Equal(t, model.DayCountActualActual, updated.DayCount)
+	assert.Equal(t, 0.15, updated.OverdraftRate)
+
+	require.NoError(t, f.svc.DeleteRule(ctx, rule.ID))
+	assert.ErrorIs(t, f.svc.DeleteRule(ctx, rule.ID), svcErrors.ErrInterestRuleNotFound)
+
+	entries, err := f.repo.AuditLog().ListAuditEntries(ctx, repository.AuditFilter{WalletID: id})
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	assert.Equal(t, AuditDeleteInterestRule, entries[0].Action)
+	assert.Nil(t, entries[0].After)
+	assert.Equal(t, AuditSetInterestRule, entries[2].Action)
+	assert.Nil(t, entries[2].Before)
+}
+
+func TestDayCount_DaysInYear(t *testing.T) {
+	leap := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, float64(366), model.DayCountActualActual.DaysInYear(leap))
+	assert.Equal(t, float64(365), model.DayCountActualActual.DaysInYear(interestEpoch))
+	assert.Equal(t, float64(365), model.DayCountActual365.DaysInYear(leap))
+	assert.Equal(t, float64(360), model.DayCountActual360.DaysInYear(leap))
+}
+
+func mustWallet(t *testing.T, svc *WalletService, id uuid.UUID) *model.Wallet {
+	t.Helper()
+	w, err := svc.LookupWallet(context.Background(), id)
+	require.NoError(t, err)
+	return w
+}
+
+func mustRule(t *testing.T, repo repository.InterestRepository, product string) *model.InterestRule {
+	t.Helper()
+	rule, err := repo.FindRule(context.Background(), nil, product)
+	require.NoError(t, err)
+	return rule
+}
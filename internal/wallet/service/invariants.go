@@ -0,0 +1,211 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"math"
+	"slices"
+	"time"
+	"wallet-service/internal/db"
+	"wallet-service/internal/tenant"
+	"wallet-service/internal/wallet/model"
+	"wallet-service/internal/wallet/repository"
+	svcErrors "wallet-service/internal/wallet/service/errors"
+)
+
+// The invariants CheckInvariants verifies. The database enforces some of
+// them as well; checking them again catches rows written before it did.
+const (
+	// InvariantLedger: the balance equals the net total of the operations.
+	InvariantLedger = "ledger"
+	// InvariantOverdraft: only wallets with a credit line are overdrawn.
+	InvariantOverdraft = "overdraft"
+	// InvariantClosed: closed wallets hold nothing.
+	InvariantClosed = "closed"
+	// InvariantOperation: operations have a known type and a positive
+	// amount.
+	InvariantOperation = "operation"
+	// InvariantTenant: operations belong to the tenant of their wallet.
+	InvariantTenant = "tenant"
+	// InvariantFeeIncome: every fee charged is credited to the revenue
+	// wallet for the same amount, and nothing else is.
+	InvariantFeeIncome = "fee_income"
+	// InvariantFXLegs: every FX_OUT leg has exactly one FX_IN leg and
+	// every FX_IN leg has its FX_OUT leg.
+	InvariantFXLegs = "fx_legs"
+)
+
+// Violation is one breach of an invariant. OperationID is uuid.Nil when the
+// wallet as a whole is at fault.
+type Violation struct {
+	Invariant   string
+	WalletID    uuid.UUID
+	OperationID uuid.UUID
+	Detail      string
+}
+
+// InvariantReport is the outcome of CheckInvariants.
+type InvariantReport struct {
+	Wallets    int
+	Operations int
+	Violations []Violation
+}
+
+func (r *InvariantReport) OK() bool {
+	return len(r.Violations) == 0
+}
+
+// legPair collects the operations linked to one parent operation: the fee
+// charged for it and the income credited, or an FX_OUT leg and its FX_IN
+// legs. walletID and opID locate the FEE or FX_OUT operation, or the
+// first of the others while it has not been seen.
+type legPair struct {
+	walletID uuid.UUID
+	opID     uuid.UUID
+	out      float64
+	in       float64
+	outs     int
+	ins      int
+}
+
+// CheckInvariants reads the whole ledger, wallet by wallet, and reports
+// every violation of the invariants above. It is not confined to a tenant:
+// fee income lands on the revenue wallet, which belongs to no tenant's
+// view. Operations are only checked if created before settled. The two
+// halves of a fee or an FX transfer are written together but read from
+// different wallets, so settled should trail the clock by more than the
+// longest transaction.
+func (s *WalletService) CheckInvariants(ctx context.Context, settled time.Time) (*InvariantReport, error) {
+	ctx = db.WithPrimary(tenant.Unscoped(ctx))
+	filter := repository.OperationFilter{To: settled}
+	report := &InvariantReport{}
+	fees := make(map[uuid.UUID]*legPair)
+	fx := make(map[uuid.UUID]*legPair)
+	pair := func(pairs map[uuid.UUID]*legPair, id uuid.UUID) *legPair {
+		p, ok := pairs[id]
+		if !ok {
+			p = &legPair{}
+			pairs[id] = p
+		}
+		return p
+	}
+
+	var after uuid.UUID
+	for {
+		wallets, err := s.repo.ListWallets(ctx, after, reconcileBatch)
+		if err != nil {
+			return report, err
+		}
+		for _, w := range wallets {
+			rec, err := s.Reconcile(ctx, w.ID)
+			if errors.Is(err, svcErrors.ErrWalletNotFound) {
+				continue
+			}
+			if err != nil {
+				return report, err
+			}
+			report.Wallets++
+			report.Violations = append(report.Violations, walletViolations(&w, rec)...)
+
+			ops, err := s.repo.ListOperations(ctx, w.ID, filter)
+			if err != nil {
+				return report, err
+			}
+			for _, op := range ops {
+				report.Operations++
+				report.Violations = append(report.Violations, operationViolations(&w, &op)...)
+
+				switch op.Type {
+				case model.OperationFee, model.OperationFeeIncome:
+					if op.ParentID == nil {
+						report.Violations = append(report.Violations, Violation{InvariantFeeIncome, w.ID, op.ID, op.Type + " is not linked to a charged operation"})
+						continue
+					}
+					p := pair(fees, *op.ParentID)
+					if op.Type == model.OperationFee {
+						p.walletID, p.opID = w.ID, op.ID
+						p.out += op.Amount
+						p.outs++
+					} else {
+						if p.outs == 0 {
+							p.walletID, p.opID = w.ID, op.ID
+						}
+						p.in += op.Amount
+					}
+				case model.OperationFXOut:
+					p := pair(fx, op.ID)
+					p.walletID, p.opID = w.ID, op.ID
+					p.outs++
+				case model.OperationFXIn:
+					if op.ParentID == nil {
+						report.Violations = append(report.Violations, Violation{InvariantFXLegs, w.ID, op.ID, "FX_IN is not linked to an FX_OUT leg"})
+						continue
+					}
+					p := pair(fx, *op.ParentID)
+					if p.outs == 0 {
+						p.walletID, p.opID = w.ID, op.ID
+					}
+					p.ins++
+				}
+			}
+		}
+		if len(wallets) < reconcileBatch {
+			break
+		}
+		after = wallets[len(wallets)-1].ID
+	}
+
+	var pairs []Violation
+	for _, p := range fees {
+		switch {
+		case p.outs == 0:
+			pairs = append(pairs, Violation{InvariantFeeIncome, p.walletID, p.opID, fmt.Sprintf("%.2f fee income credited for no fee", p.in)})
+		case math.Abs(p.out-p.in) >= 0.005:
+			pairs = append(pairs, Violation{InvariantFeeIncome, p.walletID, p.opID, fmt.Sprintf("fee %.2f charged, %.2f credited as income", p.out, p.in)})
+		}
+	}
+	for _, p := range fx {
+		switch {
+		case p.outs == 0:
+			pairs = append(pairs, Violation{InvariantFXLegs, p.walletID, p.opID, "FX_IN has no FX_OUT leg"})
+		case p.ins != 1:
+			pairs = append(pairs, Violation{InvariantFXLegs, p.walletID, p.opID, fmt.Sprintf("FX_OUT has %d FX_IN legs", p.ins)})
+		}
+	}
+	slices.SortFunc(pairs, func(a, b Violation) int {
+		return cmp.Or(cmp.Compare(a.Invariant, b.Invariant), cmp.Compare(a.OperationID.String(), b.OperationID.String()))
+	})
+	report.Violations = append(report.Violations, pairs...)
+	return report, nil
+}
+
+func walletViolations(w *model.Wallet, rec *Reconciliation) []Violation {
+	var out []Violation
+	if !rec.Balanced() {
+		out = append(out, Violation{InvariantLedger, w.ID, uuid.Nil, fmt.Sprintf("balance %.2f, ledger %.2f", rec.Balance, rec.Ledger)})
+	}
+	if rec.Balance <= -0.005 && w.CreditLimit == 0 {
+		out = append(out, Violation{InvariantOverdraft, w.ID, uuid.Nil, fmt.Sprintf("balance %.2f without a credit line", rec.Balance)})
+	}
+	if w.Status == model.WalletClosed && (math.Abs(rec.Balance) >= 0.005 || w.Sharded()) {
+		out = append(out, Violation{InvariantClosed, w.ID, uuid.Nil, fmt.Sprintf("closed with balance %.2f in %d shard(s)", rec.Balance, w.ShardCount)})
+	}
+	return out
+}
+
+func operationViolations(w *model.Wallet, op *model.Operation) []Violation {
+	var out []Violation
+	if !slices.Contains(model.OperationTypes, op.Type) {
+		out = append(out, Violation{InvariantOperation, w.ID, op.ID, fmt.Sprintf("unknown type %q", op.Type)})
+	}
+	if !(op.Amount > 0) {
+		out = append(out, Violation{InvariantOperation, w.ID, op.ID, fmt.Sprintf("amount %.2f is not positive", op.Amount)})
+	}
+	if op.TenantID != w.TenantID {
+		out = append(out, Violation{InvariantTenant, w.ID, op.ID, fmt.Sprintf("tenant %q, wallet's %q", op.TenantID, w.TenantID)})
+	}
+	return out
+}
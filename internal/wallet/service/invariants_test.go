@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"wallet-service/internal/wallet/model"
+)
+
+func TestCheckInvariants(t *testing.T) {
+	svc, repo, id := newAdminFixture(t)
+	ctx := context.Background()
+	require.NoError(t, operate(t, svc, id, "DEPOSIT", 50))
+	_, err := svc.SetCreditLimit(ctx, id, 100)
+	require.NoError(t, err)
+	require.NoError(t, operate(t, svc, id, "WITHDRAW", 80))
+
+	settled := time.Now().Add(time.Minute)
+	report, err := svc.CheckInvariants(ctx, settled)
+	require.NoError(t, err)
+	assert.True(t, report.OK(), "%v", report.Violations)
+	assert.Equal(t, 1, report.Wallets)
+	assert.Equal(t, 2, report.Operations)
+
+	// Break one invariant per wallet behind the service's back.
+	addWallet := func(balance float64, ops ...model.Operation) uuid.UUID {
+		t.Helper()
+		w := uuid.New()
+		repo.AddWallet(model.Wallet{ID: w, Balance: balance})
+		for _, op := range ops {
+			op.ID, op.WalletID = uuid.New(), w
+			require.NoError(t, repo.SaveOperationTx(ctx, &op))
+		}
+		return w
+	}
+	drifted := addWallet(10)
+	overdrawn := addWallet(-5, model.Operation{Type: model.OperationWithdraw, Amount: 5})
+	bogus := addWallet(0, model.Operation{Type: "BONUS"})
+	charged := uuid.New()
+	unpaid := addWallet(9,
+		model.Operation{Type: model.OperationDeposit, Amount: 10},
+		model.Operation{Type: model.OperationFee, Amount: 1, ParentID: &charged})
+	orphan := addWallet(3, model.Operation{Type: model.OperationFXIn, Amount: 3, ParentID: &charged})
+	addWallet(0, model.Operation{Type: "BONUS", CreatedAt: settled.Add(time.Second)})
+
+	report, err = svc.CheckInvariants(ctx, settled)
+	require.NoError(t, err)
+	assert.Equal(t, 7, report.Wallets)
+	assert.Equal(t, 7, report.Operations, "operations created after settled are left out")
+
+	type breach struct {
+		invariant string
+		wallet    uuid.UUID
+	}
+	var got []breach
+	for _, v := range report.Violations {
+		got = append(got, breach{v.Invariant, v.WalletID})
+	}
+	assert.ElementsMatch(t, []breach{
+		{InvariantLedger, drifted},
+		{InvariantOverdraft, overdrawn},
+		{InvariantOperation, bogus},
+		{InvariantOperation, bogus},
+		{InvariantFeeIncome, unpaid},
+		{InvariantFXLegs, orphan},
+	}, got)
+}
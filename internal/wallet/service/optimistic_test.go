@@ -0,0 +1,122 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"wallet-service/internal/dto"
+	"wallet-service/internal/retry"
+	"wallet-service/internal/wallet/model"
+	"wallet-service/internal/wallet/repository"
+	"wallet-service/internal/wallet/repository/mocks"
+	svcErrors "wallet-service/internal/wallet/service/errors"
+)
+
+func TestOptimistic_DoesNotLockWallet(t *testing.T) {
+	ctx := context.Background()
+	walletID := uuid.New()
+	wallet := &model.Wallet{ID: walletID, Balance: 100, Version: 7}
+
+	mockRepo := new(mocks.WalletRepositoryMock)
+	svc := NewWalletService(mockRepo, WithConcurrencyMode(Optimistic))
+
+	mockRepo.On("WithTx", ctx, mock.Anything).Run(func(args mock.Arguments) {
+		fn := args.Get(1).(func(repository.WalletRepository) error)
+		_ = fn(mockRepo)
+	}).Return(nil)
+	mockRepo.On("GetWalletById", ctx, walletID).Return(wallet, nil)
+	mockRepo.On("UpdateWalletVersionedTx", ctx, walletID, int64(7), float64(60)).Return(nil)
+	mockRepo.On("SaveOperationTx", ctx, mock.AnythingOfType("*model.Operation")).Return(nil)
+	mockRepo.On("SaveAuditEntryTx", ctx, mock.AnythingOfType("*model.AuditEntry")).Return(nil)
+
+	_, err := svc.UpdateWalletBalance(ctx, dto.WalletOperationRequest{WalletID: walletID, OperationType: "WITHDRAW", Amount: 40})
+
+	require.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+	mockRepo.AssertNotCalled(t, "GetWalletByIdForUpdate", mock.Anything, mock.Anything)
+}
+
+func TestOptimistic_ConcurrentUpdates(t *testing.T) {
+	ctx := context.Background()
+	walletID := uuid.New()
+	repo := repository.NewMemoryWalletRepository(model.Wallet{ID: walletID, Balance: 1000})
+	svc := NewWalletService(repo,
+		WithConcurrencyMode(Optimistic),
+		WithRetryPolicy(retry.Policy{MaxAttempts: 1000, BaseDelay: time.Microsecond, MaxDelay: time.Millisecond, Multiplier: 2, Jitter: 1}),
+	)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_, err := svc.UpdateWalletBalance(ctx, dto.WalletOperationRequest{WalletID: walletID, OperationType: "DEPOSIT", Amount: 3})
+			assert.NoError(t, err)
+		}()
+		go func() {
+			defer wg.Done()
+			_, err := svc.UpdateWalletBalance(ctx, dto.WalletOperationRequest{WalletID: walletID, OperationType: "WITHDRAW", Amount: 1})
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	w, err := svc.LookupWallet(ctx, walletID)
+	require.NoError(t, err)
+	assert.Equal(t, float64(1100), w.Balance)
+	assert.Equal(t, int64(100), w.Version)
+	assert.Len(t, repo.Operations(walletID), 100)
+}
+
+func TestExpectedVersion(t *testing.T) {
+	for _, mode := range []ConcurrencyMode{Pessimistic, Optimistic} {
+		t.Run(string(mode), func(t *testing.T) {
+			ctx := context.Background()
+			walletID := uuid.New()
+			repo := repository.NewMemoryWalletRepository(model.Wallet{ID: walletID, Balance: 50, Version: 3})
+			svc := NewWalletService(repo, WithConcurrencyMode(mode), WithSharding())
+
+			stale, current := int64(2), int64(3)
+			for _, opType := range []string{"DEPOSIT", "WITHDRAW"} {
+				_, err := svc.UpdateWalletBalance(ctx, dto.WalletOperationRequest{WalletID: walletID, OperationType: opType, Amount: 5, ExpectedVersion: &stale})
+				assert.ErrorIs(t, err, svcErrors.ErrPreconditionFailed)
+			}
+			assert.ErrorIs(t, svc.SetWalletShards(ctx, walletID, 2, &stale), svcErrors.ErrPreconditionFailed)
+
+			_, err := svc.UpdateWalletBalance(ctx, dto.WalletOperationRequest{WalletID: walletID, OperationType: "DEPOSIT", Amount: 5, ExpectedVersion: &current})
+			require.NoError(t, err)
+
+			w, err := svc.LookupWallet(ctx, walletID)
+			require.NoError(t, err)
+			assert.Equal(t, float64(55), w.Balance)
+			assert.Equal(t, int64(4), w.Version)
+			assert.Len(t, repo.Operations(walletID), 1)
+		})
+	}
+}
+
+func TestOptimistic_ConflictsExhaustRetries(t *testing.T) {
+	ctx := context.Background()
+	walletID := uuid.New()
+	wallet := &model.Wallet{ID: walletID, Balance: 100}
+
+	mockRepo := new(mocks.WalletRepositoryMock)
+	svc := NewWalletService(mockRepo,
+		WithConcurrencyMode(Optimistic),
+		WithRetryPolicy(retry.Policy{MaxAttempts: 3, Multiplier: 1}),
+	)
+
+	mockRepo.On("WithTx", ctx, mock.Anything).Return(repository.ErrVersionConflict).Times(3)
+
+	_, err := svc.UpdateWalletBalance(ctx, dto.WalletOperationRequest{WalletID: wallet.ID, OperationType: "DEPOSIT", Amount: 1})
+
+	assert.ErrorIs(t, err, svcErrors.ErrConcurrentModification)
+	mockRepo.AssertExpectations(t)
+}
@@ -0,0 +1,363 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/robfig/cron/v3"
+	"gorm.io/gorm"
+	"log/slog"
+	"slices"
+	"time"
+	"wallet-service/internal/audit"
+	"wallet-service/internal/dto"
+	"wallet-service/internal/tenant"
+	"wallet-service/internal/wallet/model"
+	"wallet-service/internal/wallet/repository"
+	svcErrors "wallet-service/internal/wallet/service/errors"
+)
+
+// MinScheduleInterval is the shortest interval a recurring schedule may use.
+const MinScheduleInterval = time.Minute
+
+// ScheduleService manages standing orders and executes the ones that are
+// due through the WalletService.
+type ScheduleService struct {
+	repo       repository.ScheduleRepository
+	wallets    *WalletService
+	maxRetries int
+	retryDelay time.Duration
+	now        func() time.Time
+}
+
+type ScheduleOption func(*ScheduleService)
+
+// WithScheduleRetry sets how often and how far apart a run that failed, e.g.
+// for insufficient funds or a frozen wallet, is retried before its
+// occurrence is given up. The delay is also how long a claimed occurrence
+// waits before another worker may take it over.
+func WithScheduleRetry(maxRetries int, delay time.Duration) ScheduleOption {
+	return func(s *ScheduleService) {
+		s.maxRetries = maxRetries
+		s.retryDelay = delay
+	}
+}
+
+func NewScheduleService(repo repository.ScheduleRepository, wallets *WalletService, opts ...ScheduleOption) *ScheduleService {
+	s := &ScheduleService{
+		repo:       repo,
+		wallets:    wallets,
+		maxRetries: 3,
+		retryDelay: time.Hour,
+		now:        time.Now,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// CreateSchedule adds a standing order to the wallet. A cron schedule first
+// runs at the first match at or after StartAt (default now); an interval
+// schedule at StartAt, or one interval from now. Without cron or interval
+// the operation runs once at StartAt.
+func (s *ScheduleService) CreateSchedule(ctx context.Context, walletID uuid.UUID, req dto.CreateScheduleRequest) (*model.Schedule, error) {
+	wallet, err := s.wallets.lookupForWrite(ctx, walletID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.wallets.checkOperationLimit(ctx, req.Amount); err != nil {
+		return nil, err
+	}
+
+	now := s.now().Truncate(time.Second)
+	start := now
+	if req.StartAt != nil {
+		start = req.StartAt.Truncate(time.Second)
+	}
+
+	sch := &model.Schedule{
+		ID:            uuid.New(),
+		WalletID:      walletID,
+		OperationType: req.OperationType,
+		Amount:        req.Amount,
+		Status:        model.ScheduleActive,
+		TenantID:      wallet.TenantID,
+	}
+
+	switch {
+	case req.Cron != "":
+		spec, err := cron.ParseStandard(req.Cron)
+		if err != nil {
+			return nil, svcErrors.ErrInvalidSchedule
+		}
+		sch.Cron = req.Cron
+		sch.NextRunAt = spec.Next(start.Add(-time.Nanosecond))
+		if sch.NextRunAt.IsZero() {
+			return nil, svcErrors.ErrInvalidSchedule
+		}
+	case req.Interval != "":
+		interval, err := time.ParseDuration(req.Interval)
+		if err != nil || interval < MinScheduleInterval {
+			return nil, svcErrors.ErrInvalidSchedule
+		}
+		sch.IntervalSeconds = int64(interval / time.Second)
+		sch.NextRunAt = start
+		if req.StartAt == nil {
+			sch.NextRunAt = now.Add(sch.Interval())
+		}
+	case req.StartAt != nil:
+		sch.NextRunAt = start
+	default:
+		return nil, svcErrors.ErrInvalidSchedule
+	}
+	sch.DueAt = sch.NextRunAt
+
+	err = s.repo.WithTx(ctx, func(txRepo repository.ScheduleRepository) error {
+		if err := txRepo.CreateSchedule(ctx, sch); err != nil {
+			return err
+		}
+		return writeAudit(ctx, txRepo.SaveAuditEntryTx, audit.FromContext(ctx), AuditCreateSchedule, walletID, nil, scheduleAuditStateOf(sch))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sch, nil
+}
+
+func (s *ScheduleService) ListSchedules(ctx context.Context, walletID uuid.UUID) ([]model.Schedule, error) {
+	if _, err := s.wallets.LookupWallet(ctx, walletID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListSchedules(ctx, walletID)
+}
+
+func (s *ScheduleService) GetSchedule(ctx context.Context, id uuid.UUID) (*model.Schedule, error) {
+	sch, err := s.repo.GetSchedule(ctx, id)
+	if err != nil {
+		return nil, scheduleLookupError(err)
+	}
+	return sch, nil
+}
+
+// ListRuns returns up to limit of the schedule's most recent runs.
+func (s *ScheduleService) ListRuns(ctx context.Context, id uuid.UUID, limit int) ([]model.ScheduleRun, error) {
+	if _, err := s.GetSchedule(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.repo.ListScheduleRuns(ctx, id, limit)
+}
+
+func (s *ScheduleService) PauseSchedule(ctx context.Context, id uuid.UUID) (*model.Schedule, error) {
+	return s.transition(ctx, id, AuditPauseSchedule, model.SchedulePaused, model.ScheduleActive)
+}
+
+// ResumeSchedule reactivates a paused schedule. Occurrences missed while it
+// was paused are skipped.
+func (s *ScheduleService) ResumeSchedule(ctx context.Context, id uuid.UUID) (*model.Schedule, error) {
+	return s.transition(ctx, id, AuditResumeSchedule, model.ScheduleActive, model.SchedulePaused)
+}
+
+func (s *ScheduleService) CancelSchedule(ctx context.Context, id uuid.UUID) (*model.Schedule, error) {
+	return s.transition(ctx, id, AuditCancelSchedule, model.ScheduleCancelled, model.ScheduleActive, model.SchedulePaused)
+}
+
+func (s *ScheduleService) transition(ctx context.Context, id uuid.UUID, action string, to model.ScheduleStatus, from ...model.ScheduleStatus) (*model.Schedule, error) {
+	var out *model.Schedule
+	err := s.repo.WithTx(ctx, func(txRepo repository.ScheduleRepository) error {
+		sch, err := txRepo.GetScheduleForUpdate(ctx, id)
+		if err != nil {
+			return scheduleLookupError(err)
+		}
+		if !slices.Contains(from, sch.Status) {
+			return svcErrors.ErrScheduleStatus
+		}
+		before := scheduleAuditStateOf(sch)
+
+		sch.Status = to
+		if to == model.ScheduleActive {
+			sch.Attempts = 0
+			if now := s.now(); sch.Recurring() && sch.NextRunAt.Before(now) {
+				advance(sch, now, model.ScheduleCompleted)
+			}
+			sch.DueAt = sch.NextRunAt
+		}
+		if err := txRepo.UpdateScheduleTx(ctx, sch); err != nil {
+			return err
+		}
+		out = sch
+		return writeAudit(ctx, txRepo.SaveAuditEntryTx, audit.FromContext(ctx), action, sch.WalletID, before, scheduleAuditStateOf(sch))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// RunDue claims up to limit due schedules and executes them, returning how
+// many were processed. Schedules claimed by another worker are skipped.
+//
+// Claiming moves a schedule's due time one retry delay ahead and commits
+// straight away, so no lock is held while the operations run. Each
+// occurrence then runs and records its outcome on its own; one whose worker
+// dies before recording it is claimed again after the delay.
+func (s *ScheduleService) RunDue(ctx context.Context, limit int) (int, error) {
+	now := s.now()
+	var due []model.Schedule
+	err := s.repo.WithTx(ctx, func(txRepo repository.ScheduleRepository) error {
+		claimed, err := txRepo.ClaimDueSchedulesTx(ctx, now, limit)
+		if err != nil {
+			return err
+		}
+		for i := range claimed {
+			claimed[i].DueAt = now.Add(s.retryDelay)
+			if err := txRepo.UpdateScheduleTx(ctx, &claimed[i]); err != nil {
+				return err
+			}
+		}
+		due = claimed
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for i := range due {
+		if err := s.run(ctx, &due[i], now); err != nil {
+			return i, err
+		}
+	}
+	return len(due), nil
+}
+
+// run executes the claimed occurrence of sch and records the outcome. The
+// operation ID is derived from the schedule and occurrence, so if the
+// bookkeeping below is lost after the operation committed, the next attempt
+// hits the duplicate and is recorded as a success instead of paying twice.
+func (s *ScheduleService) run(ctx context.Context, sch *model.Schedule, now time.Time) error {
+	opID := scheduleOperationID(sch)
+	// The operation is made for the schedule's tenant, whose policy
+	// applies to it.
+	opCtx := tenant.NewContext(ctx, sch.TenantID)
+	opCtx = audit.NewContext(opCtx, audit.Info{Actor: SchedulerActor, RequestID: "schedule:" + sch.ID.String()})
+	_, err := s.wallets.UpdateWalletBalance(opCtx, dto.WalletOperationRequest{
+		WalletID:      sch.WalletID,
+		OperationType: sch.OperationType,
+		Amount:        sch.Amount,
+		OperationID:   opID,
+	})
+
+	run := &model.ScheduleRun{
+		ID:           uuid.New(),
+		ScheduleID:   sch.ID,
+		ScheduledFor: sch.NextRunAt,
+		Attempt:      sch.Attempts + 1,
+		Status:       model.RunSucceeded,
+	}
+
+	switch {
+	case err == nil, errors.Is(err, repository.ErrDuplicateOperation):
+		run.OperationID = &opID
+		advance(sch, now, model.ScheduleCompleted)
+	case transient(ctx, err):
+		// Not the schedule's fault, e.g. the database is unavailable. Try
+		// again later without using up its retries.
+		slog.Warn("scheduled operation failed", "schedule", sch.ID, "err", err)
+		run.Status = model.RunRetrying
+		run.Error = err.Error()
+		sch.DueAt = now.Add(s.retryDelay)
+	case errors.Is(err, svcErrors.ErrWalletNotFound), errors.Is(err, svcErrors.ErrWalletClosed),
+		errors.Is(err, svcErrors.ErrOperationLimitExceeded), errors.Is(err, svcErrors.ErrInvalidOperation),
+		errors.Is(err, svcErrors.ErrInvalidAmount):
+		// Every later occurrence would fail the same way.
+		run.Status = model.RunFailed
+		run.Error = err.Error()
+		sch.Status = model.ScheduleFailed
+	default:
+		// Insufficient funds, a frozen wallet or anything unforeseen uses up
+		// one of the occurrence's retries.
+		if !errors.Is(err, svcErrors.ErrInsufficientFunds) && !errors.Is(err, svcErrors.ErrWalletFrozen) {
+			slog.Warn("scheduled operation failed", "schedule", sch.ID, "err", err)
+		}
+		run.Error = err.Error()
+		sch.Attempts++
+		if sch.Attempts <= s.maxRetries {
+			run.Status = model.RunRetrying
+			sch.DueAt = now.Add(s.retryDelay)
+		} else {
+			run.Status = model.RunFailed
+			advance(sch, now, model.ScheduleFailed)
+		}
+	}
+
+	return s.repo.WithTx(ctx, func(txRepo repository.ScheduleRepository) error {
+		if err := txRepo.SaveScheduleRunTx(ctx, run); err != nil {
+			return err
+		}
+		cur, err := txRepo.GetScheduleForUpdate(ctx, sch.ID)
+		if err != nil {
+			return err
+		}
+		// Paused, cancelled or resumed while the operation ran: the run is
+		// kept, but the change made meanwhile wins.
+		if cur.Status != model.ScheduleActive || !cur.NextRunAt.Equal(run.ScheduledFor) || cur.Attempts != run.Attempt-1 {
+			return nil
+		}
+		return txRepo.UpdateScheduleTx(ctx, sch)
+	})
+}
+
+// transient reports whether err is a failure of the service rather than of
+// the scheduled operation, which is then retried for free.
+func transient(ctx context.Context, err error) bool {
+	if ctx.Err() != nil || errors.Is(err, svcErrors.ErrConcurrentModification) {
+		return true
+	}
+	retryable, _ := classifyRetryable(err)
+	return retryable
+}
+
+func scheduleOperationID(sch *model.Schedule) uuid.UUID {
+	return uuid.NewSHA1(sch.ID, []byte(sch.NextRunAt.UTC().Format(time.RFC3339)))
+}
+
+// advance moves sch to its first occurrence after now, so after downtime
+// at most one missed occurrence is executed. A schedule without further
+// occurrences gets the status done instead.
+func advance(sch *model.Schedule, now time.Time, done model.ScheduleStatus) {
+	sch.Attempts = 0
+	next, ok := nextOccurrence(sch, now)
+	if !ok {
+		sch.Status = done
+		return
+	}
+	sch.NextRunAt = next
+	sch.DueAt = next
+}
+
+func nextOccurrence(sch *model.Schedule, after time.Time) (time.Time, bool) {
+	switch {
+	case sch.Cron != "":
+		spec, err := cron.ParseStandard(sch.Cron)
+		if err != nil {
+			return time.Time{}, false
+		}
+		next := spec.Next(after)
+		return next, !next.IsZero()
+	case sch.IntervalSeconds > 0:
+		interval := sch.Interval()
+		next := sch.NextRunAt.Add(interval)
+		if !next.After(after) {
+			next = next.Add((after.Sub(next)/interval + 1) * interval)
+		}
+		return next, true
+	}
+	return time.Time{}, false
+}
+
+func scheduleLookupError(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return svcErrors.ErrScheduleNotFound
+	}
+	return err
+}
@@ -0,0 +1,47 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// ScheduleWorker periodically executes due schedules. Several workers, in
+// one process or many, may run against the same database.
+type ScheduleWorker struct {
+	svc       *ScheduleService
+	interval  time.Duration
+	batchSize int
+}
+
+func NewScheduleWorker(svc *ScheduleService, interval time.Duration, batchSize int) *ScheduleWorker {
+	return &ScheduleWorker{svc: svc, interval: interval, batchSize: batchSize}
+}
+
+// Run polls until ctx is done. Each poll keeps claiming batches while full
+// ones come back, so a backlog is drained without waiting for the ticker.
+func (w *ScheduleWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			n, err := w.svc.RunDue(ctx, w.batchSize)
+			if err != nil {
+				if ctx.Err() == nil {
+					slog.Error("running due schedules failed", "err", err)
+				}
+				break
+			}
+			if n < w.batchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
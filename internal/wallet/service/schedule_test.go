@@ -0,0 +1,290 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"wallet-service/internal/dto"
+	"wallet-service/internal/wallet/model"
+	"wallet-service/internal/wallet/repository"
+	svcErrors "wallet-service/internal/wallet/service/errors"
+)
+
+var scheduleEpoch = time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+type scheduleFixture struct {
+	wallets   *repository.MemoryWalletRepository
+	schedules *repository.MemoryScheduleRepository
+	svc       *ScheduleService
+	walletID  uuid.UUID
+	now       time.Time
+}
+
+func newScheduleFixture(t *testing.T, balance float64, opts ...ScheduleOption) *scheduleFixture {
+	t.Helper()
+	f := &scheduleFixture{
+		walletID:  uuid.New(),
+		schedules: repository.NewMemoryScheduleRepository(),
+		now:       scheduleEpoch,
+	}
+	f.wallets = repository.NewMemoryWalletRepository(model.Wallet{ID: f.walletID, Balance: balance})
+	f.svc = NewScheduleService(f.schedules, NewWalletService(f.wallets), opts...)
+	f.svc.now = func() time.Time { return f.now }
+	return f
+}
+
+func (f *scheduleFixture) create(t *testing.T, req dto.CreateScheduleRequest) *model.Schedule {
+	t.Helper()
+	sch, err := f.svc.CreateSchedule(context.Background(), f.walletID, req)
+	require.NoError(t, err)
+	return sch
+}
+
+func (f *scheduleFixture) runAt(t *testing.T, at time.Time) int {
+	t.Helper()
+	f.now = at
+	n, err := f.svc.RunDue(context.Background(), 10)
+	require.NoError(t, err)
+	return n
+}
+
+func (f *scheduleFixture) balance(t *testing.T) float64 {
+	t.Helper()
+	b, err := NewWalletService(f.wallets).GetWallet(context.Background(), f.walletID)
+	require.NoError(t, err)
+	return b
+}
+
+func (f *scheduleFixture) reload(t *testing.T, id uuid.UUID) *model.Schedule {
+	t.Helper()
+	sch, err := f.svc.GetSchedule(context.Background(), id)
+	require.NoError(t, err)
+	return sch
+}
+
+func TestCreateSchedule_FirstOccurrence(t *testing.T) {
+	f := newScheduleFixture(t, 0)
+	start := scheduleEpoch.Add(90 * time.Minute)
+
+	monthly := f.create(t, dto.CreateScheduleRequest{OperationType: "DEPOSIT", Amount: 10, Cron: "0 0 1 * *"})
+	assert.Equal(t, time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC), monthly.NextRunAt.UTC())
+
+	hourly := f.create(t, dto.CreateScheduleRequest{OperationType: "DEPOSIT", Amount: 10, Interval: "1h"})
+	assert.Equal(t, scheduleEpoch.Add(time.Hour), hourly.NextRunAt)
+
+	startingLater := f.create(t, dto.CreateScheduleRequest{OperationType: "DEPOSIT", Amount: 10, Interval: "1h", StartAt: &start})
+	assert.Equal(t, start, startingLater.NextRunAt)
+
+	once := f.create(t, dto.CreateScheduleRequest{OperationType: "WITHDRAW", Amount: 10, StartAt: &start})
+	assert.Equal(t, start, once.NextRunAt)
+	assert.False(t, once.Recurring())
+	assert.Equal(t, once.NextRunAt, once.DueAt)
+}
+
+func TestCreateSchedule_Invalid(t *testing.T) {
+	f := newScheduleFixture(t, 0)
+	ctx := context.Background()
+
+	for _, req := range []dto.CreateScheduleRequest{
+		{OperationType: "DEPOSIT", Amount: 1, Cron: "not a cron"},
+		{OperationType: "DEPOSIT", Amount: 1, Interval: "30s"},
+		{OperationType: "DEPOSIT", Amount: 1, Interval: "soon"},
+		{OperationType: "DEPOSIT", Amount: 1},
+	} {
+		_, err := f.svc.CreateSchedule(ctx, f.walletID, req)
+		assert.ErrorIs(t, err, svcErrors.ErrInvalidSchedule, "%+v", req)
+	}
+
+	_, err := f.svc.CreateSchedule(ctx, uuid.New(), dto.CreateScheduleRequest{OperationType: "DEPOSIT", Amount: 1, Interval: "1h"})
+	assert.ErrorIs(t, err, svcErrors.ErrWalletNotFound)
+}
+
+func TestRunDue_RecurringAdvances(t *testing.T) {
+	f := newScheduleFixture(t, 0)
+	sch := f.create(t, dto.CreateScheduleRequest{OperationType: "DEPOSIT", Amount: 25, Interval: "1h", StartAt: &scheduleEpoch})
+
+	assert.Equal(t, 1, f.runAt(t, scheduleEpoch))
+	assert.Equal(t, 0, f.runAt(t, scheduleEpoch.Add(30*time.Minute)))
+	assert.Equal(t, float64(25), f.balance(t))
+	assert.Equal(t, scheduleEpoch.Add(time.Hour), f.reload(t, sch.ID).NextRunAt)
+
+	// After downtime only one missed occurrence is executed.
+	assert.Equal(t, 1, f.runAt(t, scheduleEpoch.Add(5*time.Hour+30*time.Minute)))
+	assert.Equal(t, 0, f.runAt(t, scheduleEpoch.Add(5*time.Hour+31*time.Minute)))
+	assert.Equal(t, float64(50), f.balance(t))
+	assert.Equal(t, scheduleEpoch.Add(6*time.Hour), f.reload(t, sch.ID).NextRunAt)
+
+	runs, err := f.svc.ListRuns(context.Background(), sch.ID, 10)
+	require.NoError(t, err)
+	require.Len(t, runs, 2)
+	for _, run := range runs {
+		assert.Equal(t, model.RunSucceeded, run.Status)
+		assert.NotNil(t, run.OperationID)
+	}
+	assert.Len(t, f.wallets.Operations(f.walletID), 2)
+}
+
+func TestRunDue_OneOffCompletes(t *testing.T) {
+	f := newScheduleFixture(t, 100)
+	at := scheduleEpoch.Add(24 * time.Hour)
+	sch := f.create(t, dto.CreateScheduleRequest{OperationType: "WITHDRAW", Amount: 40, StartAt: &at})
+
+	assert.Equal(t, 0, f.runAt(t, at.Add(-time.Second)))
+	assert.Equal(t, 1, f.runAt(t, at))
+	assert.Equal(t, 0, f.runAt(t, at.Add(48*time.Hour)))
+
+	assert.Equal(t, model.ScheduleCompleted, f.reload(t, sch.ID).Status)
+	assert.Equal(t, float64(60), f.balance(t))
+}
+
+func TestRunDue_InsufficientFundsRetries(t *testing.T) {
+	f := newScheduleFixture(t, 0, WithScheduleRetry(2, 10*time.Minute))
+	sch := f.create(t, dto.CreateScheduleRequest{OperationType: "WITHDRAW", Amount: 100, StartAt: &scheduleEpoch})
+
+	f.runAt(t, scheduleEpoch)
+	got := f.reload(t, sch.ID)
+	assert.Equal(t, model.ScheduleActive, got.Status)
+	assert.Equal(t, 1, got.Attempts)
+	assert.Equal(t, scheduleEpoch.Add(10*time.Minute), got.DueAt)
+	assert.Equal(t, scheduleEpoch, got.NextRunAt)
+
+	assert.Equal(t, 0, f.runAt(t, scheduleEpoch.Add(5*time.Minute)))
+	f.runAt(t, scheduleEpoch.Add(10*time.Minute))
+	f.runAt(t, scheduleEpoch.Add(20*time.Minute))
+
+	assert.Equal(t, model.ScheduleFailed, f.reload(t, sch.ID).Status)
+	runs, err := f.svc.ListRuns(context.Background(), sch.ID, 10)
+	require.NoError(t, err)
+	require.Len(t, runs, 3)
+	assert.Equal(t, model.RunFailed, runs[0].Status)
+	assert.Equal(t, 3, runs[0].Attempt)
+	assert.Equal(t, model.RunRetrying, runs[1].Status)
+	assert.Equal(t, model.RunRetrying, runs[2].Status)
+	assert.Empty(t, f.wallets.Operations(f.walletID))
+}
+
+func TestRunDue_RecurringSkipsOccurrenceAfterRetries(t *testing.T) {
+	f := newScheduleFixture(t, 0, WithScheduleRetry(0, time.Minute))
+	sch := f.create(t, dto.CreateScheduleRequest{OperationType: "WITHDRAW", Amount: 5, Interval: "24h", StartAt: &scheduleEpoch})
+
+	f.runAt(t, scheduleEpoch)
+
+	got := f.reload(t, sch.ID)
+	assert.Equal(t, model.ScheduleActive, got.Status)
+	assert.Equal(t, 0, got.Attempts)
+	assert.Equal(t, scheduleEpoch.Add(24*time.Hour), got.DueAt)
+}
+
+func TestRunDue_InvalidOperationFailsSchedule(t *testing.T) {
+	f := newScheduleFixture(t, 100)
+	sch := f.create(t, dto.CreateScheduleRequest{OperationType: "REFUND", Amount: 5, Interval: "1h", StartAt: &scheduleEpoch})
+
+	assert.Equal(t, 1, f.runAt(t, scheduleEpoch))
+
+	got := f.reload(t, sch.ID)
+	assert.Equal(t, model.ScheduleFailed, got.Status)
+	assert.Equal(t, 0, f.runAt(t, scheduleEpoch.Add(2*time.Hour)))
+	runs, err := f.svc.ListRuns(context.Background(), sch.ID, 10)
+	require.NoError(t, err)
+	require.Len(t, runs, 1)
+	assert.Equal(t, model.RunFailed, runs[0].Status)
+}
+
+func TestRunDue_AbandonedClaimRunsAfterDelay(t *testing.T) {
+	f := newScheduleFixture(t, 0, WithScheduleRetry(3, 10*time.Minute))
+	sch := f.create(t, dto.CreateScheduleRequest{OperationType: "DEPOSIT", Amount: 1, StartAt: &scheduleEpoch})
+
+	// A worker that claimed the occurrence and died before running it.
+	err := f.schedules.WithTx(context.Background(), func(txRepo repository.ScheduleRepository) error {
+		claimed, err := txRepo.ClaimDueSchedulesTx(context.Background(), scheduleEpoch, 10)
+		require.Len(t, claimed, 1)
+		claimed[0].DueAt = scheduleEpoch.Add(10 * time.Minute)
+		return errors.Join(err, txRepo.UpdateScheduleTx(context.Background(), &claimed[0]))
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, 0, f.runAt(t, scheduleEpoch.Add(time.Minute)))
+	assert.Equal(t, 1, f.runAt(t, scheduleEpoch.Add(10*time.Minute)))
+	assert.Equal(t, model.ScheduleCompleted, f.reload(t, sch.ID).Status)
+	assert.Equal(t, float64(1), f.balance(t))
+}
+
+func TestRunDue_AlreadyAppliedOccurrence(t *testing.T) {
+	f := newScheduleFixture(t, 0)
+	sch := f.create(t, dto.CreateScheduleRequest{OperationType: "DEPOSIT", Amount: 10, Interval: "1h", StartAt: &scheduleEpoch})
+
+	// The operation committed but the schedule bookkeeping was lost.
+	_, err := f.svc.wallets.UpdateWalletBalance(context.Background(), dto.WalletOperationRequest{
+		WalletID: f.walletID, OperationType: "DEPOSIT", Amount: 10, OperationID: scheduleOperationID(sch),
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, f.runAt(t, scheduleEpoch))
+	assert.Equal(t, float64(10), f.balance(t))
+	assert.Equal(t, scheduleEpoch.Add(time.Hour), f.reload(t, sch.ID).NextRunAt)
+}
+
+func TestRunDue_ConcurrentWorkersRunEachScheduleOnce(t *testing.T) {
+	f := newScheduleFixture(t, 0)
+	for i := 0; i < 20; i++ {
+		f.create(t, dto.CreateScheduleRequest{OperationType: "DEPOSIT", Amount: 1, StartAt: &scheduleEpoch})
+	}
+	f.now = scheduleEpoch
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				n, err := f.svc.RunDue(context.Background(), 3)
+				if !assert.NoError(t, err) || n == 0 {
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, float64(20), f.balance(t))
+	assert.Len(t, f.wallets.Operations(f.walletID), 20)
+}
+
+func TestScheduleStatusTransitions(t *testing.T) {
+	f := newScheduleFixture(t, 0)
+	ctx := context.Background()
+	sch := f.create(t, dto.CreateScheduleRequest{OperationType: "DEPOSIT", Amount: 1, Interval: "1h", StartAt: &scheduleEpoch})
+
+	paused, err := f.svc.PauseSchedule(ctx, sch.ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.SchedulePaused, paused.Status)
+	assert.Equal(t, 0, f.runAt(t, scheduleEpoch.Add(3*time.Hour)))
+
+	_, err = f.svc.PauseSchedule(ctx, sch.ID)
+	assert.ErrorIs(t, err, svcErrors.ErrScheduleStatus)
+
+	f.now = scheduleEpoch.Add(3*time.Hour + 10*time.Minute)
+	resumed, err := f.svc.ResumeSchedule(ctx, sch.ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.ScheduleActive, resumed.Status)
+	assert.Equal(t, scheduleEpoch.Add(4*time.Hour), resumed.NextRunAt)
+
+	cancelled, err := f.svc.CancelSchedule(ctx, sch.ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.ScheduleCancelled, cancelled.Status)
+
+	_, err = f.svc.ResumeSchedule(ctx, sch.ID)
+	assert.ErrorIs(t, err, svcErrors.ErrScheduleStatus)
+	_, err = f.svc.CancelSchedule(ctx, uuid.New())
+	assert.ErrorIs(t, err, svcErrors.ErrScheduleNotFound)
+
+	assert.Equal(t, 0, f.runAt(t, scheduleEpoch.Add(10*time.Hour)))
+	assert.Zero(t, f.balance(t))
+}
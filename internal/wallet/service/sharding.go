@@ -0,0 +1,143 @@
+package service
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"math"
+	"math/rand/v2"
+	"wallet-service/internal/audit"
+	"wallet-service/internal/retry"
+	"wallet-service/internal/wallet/model"
+	"wallet-service/internal/wallet/repository"
+	svcErrors "wallet-service/internal/wallet/service/errors"
+)
+
+// MaxShards bounds how many shard rows a single wallet may be split into.
+const MaxShards = 64
+
+// SetWalletShards splits the wallet's balance evenly across n shard rows,
+// or folds it back into the wallet row when n is 0. The total balance is
+// unchanged. A non-nil expectedVersion must match the wallet's revision.
+func (s *WalletService) SetWalletShards(ctx context.Context, walletID uuid.UUID, n int, expectedVersion *int64) error {
+	if n < 0 || n > MaxShards {
+		return svcErrors.ErrInvalidShardCount
+	}
+
+	return retry.Do(ctx, "set_wallet_shards", s.retry, classifyRetryable, func() error {
+		return s.repo.WithTx(ctx, func(txRepo repository.WalletRepository) error {
+			wallet, err := txRepo.GetWalletByIdForUpdate(ctx, walletID)
+			if err != nil {
+				return walletLookupError(err)
+			}
+			if err := checkVersion(wallet, expectedVersion); err != nil {
+				return err
+			}
+			current, err := txRepo.GetShardsForUpdate(ctx, walletID)
+			if err != nil {
+				return err
+			}
+
+			total := wallet.Balance + sumShards(current)
+			before := walletAuditStateOf(wallet)
+			before.Balance = total
+			after := before
+			after.ShardCount = n
+			if err := writeAudit(ctx, txRepo.SaveAuditEntryTx, audit.FromContext(ctx), AuditSetShards, walletID, before, after); err != nil {
+				return err
+			}
+
+			if n == 0 {
+				if err := txRepo.ReplaceShardsTx(ctx, walletID, nil); err != nil {
+					return err
+				}
+				return txRepo.UpdateWalletTx(ctx, walletID, total)
+			}
+
+			shards := make([]model.WalletShard, n)
+			for i, part := range splitEvenly(total, n) {
+				shards[i] = model.WalletShard{WalletID: walletID, ShardNo: i, Balance: part}
+			}
+			if err := txRepo.ReplaceShardsTx(ctx, walletID, shards); err != nil {
+				return err
+			}
+			return txRepo.UpdateWalletTx(ctx, walletID, 0)
+		})
+	})
+}
+
+// creditShard credits a random shard so concurrent deposits to one wallet
+// lock different rows. The caller holds a share lock on the wallet row,
+// so the shards cannot be replaced meanwhile.
+func (s *WalletService) creditShard(ctx context.Context, txRepo repository.WalletRepository, wallet *model.Wallet, amount float64) error {
+	shard, err := txRepo.GetShardForUpdate(ctx, wallet.ID, rand.IntN(wallet.ShardCount))
+	if err != nil {
+		return err
+	}
+	return txRepo.UpdateShardTx(ctx, wallet.ID, shard.ShardNo, shard.Balance+amount)
+}
+
+// withdrawFromShards debits a sharded wallet whose row lock is already held.
+// It locks every shard, checks the aggregated balance against the credit
+// limit and spreads what remains evenly across the shards again.
+func (s *WalletService) withdrawFromShards(ctx context.Context, txRepo repository.WalletRepository, wallet *model.Wallet, amount float64) error {
+	shards, err := txRepo.GetShardsForUpdate(ctx, wallet.ID)
+	if err != nil {
+		return err
+	}
+	if len(shards) == 0 {
+		if wallet.Balance+wallet.CreditLimit < amount {
+			return svcErrors.ErrInsufficientFunds
+		}
+		return txRepo.UpdateWalletTx(ctx, wallet.ID, wallet.Balance-amount)
+	}
+
+	total := wallet.Balance + sumShards(shards)
+	if total+wallet.CreditLimit < amount {
+		return svcErrors.ErrInsufficientFunds
+	}
+
+	if wallet.Balance != 0 {
+		if err := txRepo.UpdateWalletTx(ctx, wallet.ID, 0); err != nil {
+			return err
+		}
+	}
+	for i, part := range splitEvenly(total-amount, len(shards)) {
+		if shards[i].Balance == part {
+			continue
+		}
+		if err := txRepo.UpdateShardTx(ctx, wallet.ID, shards[i].ShardNo, part); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func sumShards(shards []model.WalletShard) float64 {
+	var sum float64
+	for _, s := range shards {
+		sum += s.Balance
+	}
+	return sum
+}
+
+// splitEvenly divides total into n parts in whole cents, spreading the
+// remainder over the first parts.
+func splitEvenly(total float64, n int) []float64 {
+	cents := int64(math.Round(total * 100))
+	per, rem := cents/int64(n), cents%int64(n)
+
+	step := int64(1)
+	if rem < 0 {
+		step, rem = -1, -rem
+	}
+
+	parts := make([]float64, n)
+	for i := range parts {
+		c := per
+		if int64(i) < rem {
+			c += step
+		}
+		parts[i] = float64(c) / 100
+	}
+	return parts
+}
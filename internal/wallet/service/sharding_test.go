@@ -0,0 +1,199 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"wallet-service/internal/dto"
+	"wallet-service/internal/wallet/model"
+	"wallet-service/internal/wallet/repository"
+	svcErrors "wallet-service/internal/wallet/service/errors"
+)
+
+func newShardedWallet(t *testing.T, balance float64, shards int) (*repository.MemoryWalletRepository, *WalletService, uuid.UUID) {
+	t.Helper()
+	walletID := uuid.New()
+	repo := repository.NewMemoryWalletRepository(model.Wallet{ID: walletID, Balance: balance})
+	svc := NewWalletService(repo, WithSharding())
+	require.NoError(t, svc.SetWalletShards(context.Background(), walletID, shards, nil))
+	return repo, svc, walletID
+}
+
+func shardBalances(t *testing.T, repo repository.WalletRepository, walletID uuid.UUID) []float64 {
+	t.Helper()
+	var out []float64
+	err := repo.WithTx(context.Background(), func(tx repository.WalletRepository) error {
+		shards, err := tx.GetShardsForUpdate(context.Background(), walletID)
+		for _, s := range shards {
+			out = append(out, s.Balance)
+		}
+		return err
+	})
+	require.NoError(t, err)
+	return out
+}
+
+func TestSetWalletShards_SplitsBalance(t *testing.T) {
+	ctx := context.Background()
+	repo, svc, walletID := newShardedWallet(t, 100, 3)
+
+	assert.Equal(t, []float64{33.34, 33.33, 33.33}, shardBalances(t, repo, walletID))
+	balance, err := svc.GetWallet(ctx, walletID)
+	require.NoError(t, err)
+	assert.Equal(t, float64(100), balance)
+
+	w, _ := repo.GetWalletById(ctx, walletID)
+	assert.Equal(t, 3, w.ShardCount)
+	assert.Equal(t, float64(0), w.Balance)
+}
+
+func TestSetWalletShards_Unshard(t *testing.T) {
+	ctx := context.Background()
+	repo, svc, walletID := newShardedWallet(t, 100, 4)
+
+	require.NoError(t, svc.SetWalletShards(ctx, walletID, 0, nil))
+
+	w, _ := repo.GetWalletById(ctx, walletID)
+	assert.Equal(t, 0, w.ShardCount)
+	assert.Equal(t, float64(100), w.Balance)
+	assert.Empty(t, shardBalances(t, repo, walletID))
+}
+
+func TestSetWalletShards_InvalidCount(t *testing.T) {
+	svc := NewWalletService(repository.NewMemoryWalletRepository())
+
+	err := svc.SetWalletShards(context.Background(), uuid.New(), MaxShards+1, nil)
+
+	assert.ErrorIs(t, err, svcErrors.ErrInvalidShardCount)
+}
+
+func TestShardedWallet_ConcurrentDeposits(t *testing.T) {
+	ctx := context.Background()
+	repo, svc, walletID := newShardedWallet(t, 0, 8)
+	const deposits = 400
+
+	var wg sync.WaitGroup
+	wg.Add(deposits)
+	for i := 0; i < deposits; i++ {
+		go func() {
+			defer wg.Done()
+			_, err := svc.UpdateWalletBalance(ctx, dto.WalletOperationRequest{WalletID: walletID, OperationType: "DEPOSIT", Amount: 1})
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	balance, err := svc.GetWallet(ctx, walletID)
+	require.NoError(t, err)
+	assert.Equal(t, float64(deposits), balance)
+	assert.Len(t, repo.Operations(walletID), deposits)
+
+	touched := 0
+	for _, b := range shardBalances(t, repo, walletID) {
+		if b > 0 {
+			touched++
+		}
+	}
+	assert.Greater(t, touched, 1, "deposits should be spread across shards")
+}
+
+func TestShardedWallet_WithdrawAggregatesAndRebalances(t *testing.T) {
+	ctx := context.Background()
+	repo, svc, walletID := newShardedWallet(t, 90, 3)
+
+	// Skew the shards so that no single shard can cover the withdrawal.
+	require.NoError(t, repo.UpdateShardTx(ctx, walletID, 0, 10))
+	require.NoError(t, repo.UpdateShardTx(ctx, walletID, 1, 10))
+	require.NoError(t, repo.UpdateShardTx(ctx, walletID, 2, 70))
+
+	_, err := svc.UpdateWalletBalance(ctx, dto.WalletOperationRequest{WalletID: walletID, OperationType: "WITHDRAW", Amount: 75})
+	require.NoError(t, err)
+
+	assert.Equal(t, []float64{5, 5, 5}, shardBalances(t, repo, walletID))
+	balance, _ := svc.GetWallet(ctx, walletID)
+	assert.Equal(t, float64(15), balance)
+}
+
+func TestShardedWallet_WithdrawInsufficientFunds(t *testing.T) {
+	ctx := context.Background()
+	repo, svc, walletID := newShardedWallet(t, 30, 3)
+
+	_, err := svc.UpdateWalletBalance(ctx, dto.WalletOperationRequest{WalletID: walletID, OperationType: "WITHDRAW", Amount: 31})
+
+	assert.ErrorIs(t, err, svcErrors.ErrInsufficientFunds)
+	assert.Equal(t, []float64{10, 10, 10}, shardBalances(t, repo, walletID))
+}
+
+func TestShardedWallet_WithdrawIntoOverdraft(t *testing.T) {
+	ctx := context.Background()
+	repo, svc, walletID := newShardedWallet(t, 30, 3)
+	require.NoError(t, repo.UpdateCreditLimitTx(ctx, walletID, 20))
+
+	_, err := svc.UpdateWalletBalance(ctx, dto.WalletOperationRequest{WalletID: walletID, OperationType: "WITHDRAW", Amount: 45})
+	require.NoError(t, err)
+	assert.Equal(t, []float64{-5, -5, -5}, shardBalances(t, repo, walletID))
+
+	_, err = svc.UpdateWalletBalance(ctx, dto.WalletOperationRequest{WalletID: walletID, OperationType: "WITHDRAW", Amount: 5.01})
+	assert.ErrorIs(t, err, svcErrors.ErrInsufficientFunds)
+	balance, _ := svc.GetWallet(ctx, walletID)
+	assert.Equal(t, float64(-15), balance)
+}
+
+func TestShardedWallet_DepositBumpsRevision(t *testing.T) {
+	ctx := context.Background()
+	repo, svc, walletID := newShardedWallet(t, 30, 3)
+	w, err := svc.LookupWallet(ctx, walletID)
+	require.NoError(t, err)
+	stale := w.Revision()
+
+	_, err = svc.UpdateWalletBalance(ctx, dto.WalletOperationRequest{WalletID: walletID, OperationType: "DEPOSIT", Amount: 5})
+	require.NoError(t, err)
+	w, err = svc.LookupWallet(ctx, walletID)
+	require.NoError(t, err)
+	assert.Equal(t, stale+1, w.Revision(), "a shard credit changes the wallet's revision")
+
+	entries, err := repo.AuditLog().ListAuditEntries(ctx, repository.AuditFilter{WalletID: walletID, Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	var after walletAuditState
+	require.NoError(t, json.Unmarshal(entries[0].After, &after))
+	assert.Equal(t, float64(35), after.Balance)
+
+	_, err = svc.UpdateWalletBalance(ctx, dto.WalletOperationRequest{WalletID: walletID, OperationType: "WITHDRAW", Amount: 1, ExpectedVersion: &stale})
+	assert.ErrorIs(t, err, svcErrors.ErrPreconditionFailed)
+	current := w.Revision()
+	_, err = svc.UpdateWalletBalance(ctx, dto.WalletOperationRequest{WalletID: walletID, OperationType: "WITHDRAW", Amount: 1, ExpectedVersion: &current})
+	require.NoError(t, err)
+
+	w, err = svc.LookupWallet(ctx, walletID)
+	require.NoError(t, err)
+	current = w.Revision()
+	require.NoError(t, svc.SetWalletShards(ctx, walletID, 0, &current))
+	w, err = svc.LookupWallet(ctx, walletID)
+	require.NoError(t, err)
+	assert.Greater(t, w.Revision(), current, "dropping the shards keeps their writes counted")
+}
+
+func TestShardedWallet_DepositWithoutShardingOption(t *testing.T) {
+	ctx := context.Background()
+	repo, _, walletID := newShardedWallet(t, 30, 3)
+	plain := NewWalletService(repo)
+
+	_, err := plain.UpdateWalletBalance(ctx, dto.WalletOperationRequest{WalletID: walletID, OperationType: "DEPOSIT", Amount: 5})
+	require.NoError(t, err)
+
+	balance, _ := plain.GetWallet(ctx, walletID)
+	assert.Equal(t, float64(35), balance)
+}
+
+func TestSplitEvenly(t *testing.T) {
+	assert.Equal(t, []float64{0.34, 0.33, 0.33}, splitEvenly(1, 3))
+	assert.Equal(t, []float64{-0.34, -0.33, -0.33}, splitEvenly(-1, 3))
+	assert.Equal(t, []float64{0, 0}, splitEvenly(0, 2))
+}
@@ -0,0 +1,44 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// SnapshotWorker periodically records balance snapshots so that
+// point-in-time balance queries read a bounded number of operations.
+type SnapshotWorker struct {
+	svc      *WalletService
+	interval time.Duration
+	lag      time.Duration
+}
+
+func NewSnapshotWorker(svc *WalletService, interval, lag time.Duration) *SnapshotWorker {
+	return &SnapshotWorker{svc: svc, interval: interval, lag: lag}
+}
+
+// Run takes snapshots once per interval until ctx is done. Snapshot times
+// trail the clock by lag and are aligned to the interval, so workers in
+// several processes record the same snapshots instead of one set each.
+func (w *SnapshotWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		at := time.Now().Add(-w.lag).Truncate(w.interval)
+		n, err := w.svc.SnapshotBalances(ctx, at)
+		switch {
+		case err != nil && ctx.Err() == nil:
+			slog.Error("taking balance snapshots failed", "at", at, "err", err)
+		case n > 0:
+			slog.Info("took balance snapshots", "at", at, "wallets", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
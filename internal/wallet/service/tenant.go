@@ -0,0 +1,51 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"wallet-service/internal/tenant"
+	svcErrors "wallet-service/internal/wallet/service/errors"
+)
+
+// TenantPolicy restricts what a tenant's requests may do. Zero fields
+// restrict nothing.
+type TenantPolicy struct {
+	// Currencies lists the currencies the tenant may open wallets in.
+	Currencies []string
+	// MaxOperationAmount caps the amount of a single deposit, withdrawal,
+	// FX transfer or scheduled operation. Manual adjustments are exempt.
+	MaxOperationAmount float64
+}
+
+// WithTenantPolicies applies the policy of the request's tenant to its
+// operations. Tenants without a policy, and background work acting for
+// no tenant, are unrestricted.
+func WithTenantPolicies(policies map[string]TenantPolicy) Option {
+	return func(s *WalletService) {
+		s.policies = policies
+	}
+}
+
+func (s *WalletService) tenantPolicy(ctx context.Context) TenantPolicy {
+	id, ok := tenant.FromContext(ctx)
+	if !ok {
+		return TenantPolicy{}
+	}
+	return s.policies[id]
+}
+
+func (s *WalletService) checkCurrencyAllowed(ctx context.Context, currency string) error {
+	allowed := s.tenantPolicy(ctx).Currencies
+	if len(allowed) > 0 && !slices.Contains(allowed, currency) {
+		return svcErrors.ErrCurrencyNotAllowed
+	}
+	return nil
+}
+
+func (s *WalletService) checkOperationLimit(ctx context.Context, amount float64) error {
+	limit := s.tenantPolicy(ctx).MaxOperationAmount
+	if limit > 0 && amount > limit {
+		return svcErrors.ErrOperationLimitExceeded
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"wallet-service/internal/dto"
+	"wallet-service/internal/tenant"
+	"wallet-service/internal/wallet/model"
+	"wallet-service/internal/wallet/repository"
+	svcErrors "wallet-service/internal/wallet/service/errors"
+)
+
+func TestTenant_WalletsAreIsolated(t *testing.T) {
+	acme := tenant.NewContext(context.Background(), "acme")
+	globex := tenant.NewContext(context.Background(), "globex")
+	svc := NewWalletService(repository.NewMemoryWalletRepository())
+
+	wallet, err := svc.CreateWallet(acme, dto.CreateWalletRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, "acme", wallet.TenantID)
+
+	_, err = svc.UpdateWalletBalance(globex, dto.WalletOperationRequest{WalletID: wallet.ID, OperationType: "DEPOSIT", Amount: 10})
+	assert.ErrorIs(t, err, svcErrors.ErrWalletNotFound)
+	_, err = svc.GetWallet(globex, wallet.ID)
+	assert.ErrorIs(t, err, svcErrors.ErrWalletNotFound)
+
+	_, err = svc.UpdateWalletBalance(acme, dto.WalletOperationRequest{WalletID: wallet.ID, OperationType: "DEPOSIT", Amount: 10})
+	require.NoError(t, err)
+	balance, err := svc.GetWallet(acme, wallet.ID)
+	require.NoError(t, err)
+	assert.Equal(t, float64(10), balance)
+}
+
+func TestTenant_CoalescedDepositsStayWithinTenant(t *testing.T) {
+	walletID := uuid.New()
+	repo := repository.NewMemoryWalletRepository(model.Wallet{ID: walletID, TenantID: "acme"})
+	svc := NewWalletService(repo, WithDepositCoalescing(20*time.Millisecond, 10))
+	acme := tenant.NewContext(context.Background(), "acme")
+	globex := tenant.NewContext(context.Background(), "globex")
+
+	errs := make(chan error, 2)
+	for _, ctx := range []context.Context{acme, globex} {
+		go func() {
+			_, err := svc.UpdateWalletBalance(ctx, dto.WalletOperationRequest{WalletID: walletID, OperationType: "DEPOSIT", Amount: 5})
+			errs <- err
+		}()
+	}
+
+	var notFound int
+	for range 2 {
+		if err := <-errs; err != nil {
+			assert.ErrorIs(t, err, svcErrors.ErrWalletNotFound)
+			notFound++
+		}
+	}
+	assert.Equal(t, 1, notFound, "the other tenant's deposit must not ride along")
+	balance, err := svc.GetWallet(acme, walletID)
+	require.NoError(t, err)
+	assert.Equal(t, float64(5), balance)
+}
+
+func TestTenant_Policies(t *testing.T) {
+	walletID := uuid.New()
+	repo := repository.NewMemoryWalletRepository(model.Wallet{ID: walletID, Balance: 1000, TenantID: "acme"})
+	svc := NewWalletService(repo, WithTenantPolicies(map[string]TenantPolicy{
+		"acme": {Currencies: []string{"EUR"}, MaxOperationAmount: 100},
+	}))
+	acme := tenant.NewContext(context.Background(), "acme")
+
+	_, err := svc.CreateWallet(acme, dto.CreateWalletRequest{Currency: "USD"})
+	assert.ErrorIs(t, err, svcErrors.ErrCurrencyNotAllowed)
+	_, err = svc.CreateWallet(acme, dto.CreateWalletRequest{Currency: "EUR"})
+	assert.NoError(t, err)
+
+	_, err = svc.UpdateWalletBalance(acme, dto.WalletOperationRequest{WalletID: walletID, OperationType: "WITHDRAW", Amount: 100.01})
+	assert.ErrorIs(t, err, svcErrors.ErrOperationLimitExceeded)
+	_, err = svc.UpdateWalletBalance(acme, dto.WalletOperationRequest{WalletID: walletID, OperationType: "WITHDRAW", Amount: 100})
+	assert.NoError(t, err)
+
+	schedules := NewScheduleService(repository.NewMemoryScheduleRepository(), svc)
+	_, err = schedules.CreateSchedule(acme, walletID, dto.CreateScheduleRequest{OperationType: "DEPOSIT", Amount: 500, Interval: "1h"})
+	assert.ErrorIs(t, err, svcErrors.ErrOperationLimitExceeded)
+
+	// Background work is not bound by any tenant's policy.
+	_, err = svc.UpdateWalletBalance(context.Background(), dto.WalletOperationRequest{WalletID: walletID, OperationType: "DEPOSIT", Amount: 500})
+	assert.NoError(t, err)
+}
+
+func TestTenant_ScheduleRunsForItsTenant(t *testing.T) {
+	f := newScheduleFixture(t, 0)
+	f.wallets.AddWallet(model.Wallet{ID: f.walletID, TenantID: "acme"})
+	acme := tenant.NewContext(context.Background(), "acme")
+
+	sch, err := f.svc.CreateSchedule(acme, f.walletID, dto.CreateScheduleRequest{OperationType: "DEPOSIT", Amount: 25, StartAt: &f.now})
+	require.NoError(t, err)
+	assert.Equal(t, "acme", sch.TenantID)
+
+	_, err = f.svc.GetSchedule(tenant.NewContext(context.Background(), "globex"), sch.ID)
+	assert.ErrorIs(t, err, svcErrors.ErrScheduleNotFound)
+
+	assert.Equal(t, 1, f.runAt(t, f.now))
+	assert.Equal(t, float64(25), f.balance(t))
+	ops := f.wallets.Operations(f.walletID)
+	require.Len(t, ops, 1)
+	assert.Equal(t, "acme", ops[0].TenantID)
+}
+
+func TestTenant_FeesReachTheRevenueWallet(t *testing.T) {
+	f := newFeeFixture(t)
+	walletID := uuid.New()
+	f.repo.AddWallet(model.Wallet{ID: walletID, Balance: 100, TenantID: "acme"})
+	f.setSchedule(t, dto.FeeScheduleRequest{OperationType: "WITHDRAW", Flat: 1})
+
+	op, err := f.svc.UpdateWalletBalance(tenant.NewContext(context.Background(), "acme"),
+		dto.WalletOperationRequest{WalletID: walletID, OperationType: "WITHDRAW", Amount: 10})
+
+	require.NoError(t, err)
+	assert.Equal(t, float64(1), op.Fee)
+	assert.Equal(t, float64(1), f.balance(t, f.revenue))
+}
@@ -2,102 +2,382 @@ package service
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"github.com/google/uuid"
-	"github.com/jackc/pgconn"
 	"gorm.io/gorm"
 	"time"
+	"wallet-service/internal/audit"
+	"wallet-service/internal/db"
 	"wallet-service/internal/dto"
+	"wallet-service/internal/retry"
 	"wallet-service/internal/wallet/model"
 	"wallet-service/internal/wallet/repository"
 	svcErrors "wallet-service/internal/wallet/service/errors"
 )
 
 type WalletService struct {
-	repo repository.WalletRepository
+	repo      repository.WalletRepository
+	retry     retry.Policy
+	mode      ConcurrencyMode
+	sharding  bool
+	coalescer *depositCoalescer
+
+	fees          FeeCalculator
+	revenueWallet uuid.UUID
+
+	policies  map[string]TenantPolicy
+	customers repository.CustomerRepository
+}
+
+// ConcurrencyMode selects how balance changes guard against concurrent
+// writers to the same wallet.
+type ConcurrencyMode string
+
+const (
+	// Pessimistic locks the wallet row with SELECT ... FOR UPDATE for the
+	// whole transaction.
+	Pessimistic ConcurrencyMode = "pessimistic"
+	// Optimistic reads the wallet without locking and writes it only if its
+	// version is unchanged, retrying the transaction on conflict.
+	Optimistic ConcurrencyMode = "optimistic"
+)
+
+type Option func(*WalletService)
+
+// WithConcurrencyMode selects pessimistic (the default) or optimistic
+// concurrency control for balance changes. Sharded wallets always lock the
+// wallet row.
+func WithConcurrencyMode(m ConcurrencyMode) Option {
+	return func(s *WalletService) {
+		s.mode = m
+	}
 }
 
-func NewWalletService(repo repository.WalletRepository) *WalletService {
-	return &WalletService{
-		repo: repo,
+// WithRetryPolicy sets how transactions failing with transient database
+// errors are retried.
+func WithRetryPolicy(p retry.Policy) Option {
+	return func(s *WalletService) {
+		s.retry = p
 	}
 }
+
+// WithSharding routes deposits to sharded wallets onto a random shard row
+// instead of locking the wallet row. Without it, deposits to sharded
+// wallets are still correct but serialize on the wallet row.
+func WithSharding() Option {
+	return func(s *WalletService) {
+		s.sharding = true
+	}
+}
+
+// WithDepositCoalescing groups concurrent deposits to the same wallet that
+// arrive within window of each other, up to maxBatch, and applies each group
+// in one transaction. Every caller still gets its own operation back.
+func WithDepositCoalescing(window time.Duration, maxBatch int) Option {
+	return func(s *WalletService) {
+		s.coalescer = newDepositCoalescer(window, maxBatch, s.flushDeposits)
+	}
+}
+
+func NewWalletService(repo repository.WalletRepository, opts ...Option) *WalletService {
+	s := &WalletService{
+		repo:  repo,
+		retry: retry.DefaultPolicy(),
+		mode:  Pessimistic,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
 func (s *WalletService) GetWallet(ctx context.Context, id uuid.UUID) (float64, error) {
-	wallet, err := s.repo.GetWalletById(ctx, id)
+	wallet, err := s.LookupWallet(ctx, id)
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return 0, svcErrors.ErrWalletNotFound
-		}
 		return 0, err
 	}
-	return wallet.Balance, nil
+	return wallet.TotalBalance(), nil
+}
+
+// LookupWallet returns the wallet including its version, which callers
+// may pass back as the expected version of a later write.
+func (s *WalletService) LookupWallet(ctx context.Context, id uuid.UUID) (*model.Wallet, error) {
+	wallet, err := s.repo.GetWalletById(ctx, id)
+	if err != nil {
+		return nil, walletLookupError(err)
+	}
+	return wallet, nil
+}
+
+// lookupForWrite is LookupWallet reading from the primary, for checks
+// ahead of a write that a lagging replica would fail on a new wallet.
+func (s *WalletService) lookupForWrite(ctx context.Context, id uuid.UUID) (*model.Wallet, error) {
+	return s.LookupWallet(db.WithPrimary(ctx), id)
 }
 
 func (s *WalletService) UpdateWalletBalance(ctx context.Context, req dto.WalletOperationRequest) (*model.Operation, error) {
 	if req.Amount < 0 {
 		return nil, svcErrors.ErrInvalidAmount
 	}
+	if err := checkMetadata(req.Metadata); err != nil {
+		return nil, err
+	}
+	if err := s.checkOperationLimit(ctx, req.Amount); err != nil {
+		return nil, err
+	}
+	// The operation ID is fixed across attempts: if a connection drops after
+	// COMMIT was sent, the retry fails on the duplicate key instead of
+	// applying the operation twice.
+	opID := req.OperationID
+	if opID == uuid.Nil {
+		opID = uuid.New()
+	}
+
+	if s.coalescer != nil && req.OperationType == "DEPOSIT" && req.ExpectedVersion == nil {
+		return s.coalesceDeposit(ctx, opID, req)
+	}
+	return s.execute(ctx, opID, req)
+}
+
+func (s *WalletService) execute(ctx context.Context, opID uuid.UUID, req dto.WalletOperationRequest) (*model.Operation, error) {
 	var op *model.Operation
-	var err error
-	maxRetries := 10
-	for i := 0; i < maxRetries; i++ {
+	err := retry.Do(ctx, "update_wallet_balance", s.retry, classifyRetryable, func() error {
+		return s.repo.WithTx(ctx, func(txRepo repository.WalletRepository) error {
+			var err error
+			op, err = s.apply(ctx, txRepo, opID, req)
+			return err
+		})
+	})
+	if err != nil {
+		return nil, conflictError(err)
+	}
 
-		err = s.repo.WithTx(ctx, func(txRepo repository.WalletRepository) error {
-			wallet, err := txRepo.GetWalletByIdForUpdate(ctx, req.WalletID)
-			if err != nil {
-				if errors.Is(err, gorm.ErrRecordNotFound) {
-					return svcErrors.ErrWalletNotFound
-				}
-				return err
-			}
+	return op, nil
+}
 
-			switch req.OperationType {
-			case "DEPOSIT":
-				wallet.Balance += req.Amount
-			case "WITHDRAW":
-				if wallet.Balance < req.Amount {
-					return svcErrors.ErrInsufficientFunds
-				}
-				wallet.Balance -= req.Amount
-			default:
-				return svcErrors.ErrInvalidOperation
-			}
+func (s *WalletService) apply(ctx context.Context, txRepo repository.WalletRepository, opID uuid.UUID, req dto.WalletOperationRequest) (*model.Operation, error) {
+	if req.OperationType == "DEPOSIT" {
+		before, err := s.credit(ctx, txRepo, req.WalletID, req.Amount, req.ExpectedVersion)
+		if err != nil {
+			return nil, err
+		}
+		return s.recordOperation(ctx, txRepo, opID, before, req)
+	}
 
-			if err := txRepo.UpdateWalletTx(ctx, wallet.ID, wallet.Balance); err != nil {
-				return err
-			}
+	wallet, err := s.loadWallet(ctx, txRepo, req.WalletID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkStatus(wallet); err != nil {
+		return nil, err
+	}
+	if err := checkVersion(wallet, req.ExpectedVersion); err != nil {
+		return nil, err
+	}
+	before := walletAuditStateOf(wallet)
 
-			op = &model.Operation{
-				ID:       uuid.New(),
-				WalletID: wallet.ID,
-				Type:     req.OperationType,
-				Amount:   req.Amount,
+	if req.OperationType != "WITHDRAW" {
+		return nil, svcErrors.ErrInvalidOperation
+	}
+	fee, err := s.fee(ctx, wallet, req.OperationType, req.Amount)
+	if err != nil {
+		return nil, err
+	}
+	// The fee is taken together with the amount, so a withdrawal the wallet
+	// cannot pay the fee for fails as a whole.
+	debit := req.Amount + fee
+	switch {
+	case wallet.Sharded():
+		err = s.withdrawFromShards(ctx, txRepo, wallet, debit)
+	case wallet.Available() < debit:
+		err = svcErrors.ErrInsufficientFunds
+	default:
+		wallet.Balance -= debit
+		err = s.writeBalance(ctx, txRepo, wallet, req.ExpectedVersion)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	op, err := s.recordOperation(ctx, txRepo, opID, before, req)
+	if err != nil {
+		return nil, err
+	}
+	if fee > 0 {
+		if err := s.chargeFee(ctx, txRepo, op, fee, before); err != nil {
+			return nil, err
+		}
+	}
+	return op, nil
+}
+
+// recordOperation saves the operation together with its audit entry.
+func (s *WalletService) recordOperation(ctx context.Context, txRepo repository.WalletRepository, opID uuid.UUID, before walletAuditState, req dto.WalletOperationRequest) (*model.Operation, error) {
+	op, err := s.saveOperation(ctx, txRepo, opID, req.WalletID, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := auditOperation(ctx, txRepo, audit.FromContext(ctx), op, before); err != nil {
+		return nil, err
+	}
+	return op, nil
+}
+
+// credit adds amount to the wallet, on a shard row when sharding is enabled
+// and the wallet is sharded, otherwise on the wallet row. Deposits made
+// against an expected version always go to the wallet row, locking it
+// while they check the version against the wallet's revision. It returns
+// the wallet's state before the credit. A shard credit holds a share lock
+// on the wallet row, which keeps status changes and re-sharding out until
+// it commits but lets credits to other shards through.
+func (s *WalletService) credit(ctx context.Context, txRepo repository.WalletRepository, walletID uuid.UUID, amount float64, expected *int64) (walletAuditState, error) {
+	if s.sharding && expected == nil {
+		wallet, err := txRepo.GetWalletByIdForShare(ctx, walletID)
+		if err != nil {
+			return walletAuditState{}, walletLookupError(err)
+		}
+		if err := checkStatus(wallet); err != nil {
+			return walletAuditState{}, err
+		}
+		if wallet.Sharded() {
+			if err := s.creditShard(ctx, txRepo, wallet, amount); err != nil {
+				return walletAuditState{}, err
+			}
+			// Other shards may have been credited since wallet was read,
+			// so the state is read back rather than derived from it.
+			after, err := txRepo.GetWalletById(ctx, walletID)
+			if err != nil {
+				return walletAuditState{}, err
 			}
+			before := walletAuditStateOf(after)
+			before.Balance -= amount
+			return before, nil
+		}
+	}
 
-			return txRepo.SaveOperationTx(ctx, op)
-		})
+	wallet, err := s.loadWallet(ctx, txRepo, walletID)
+	if err != nil {
+		return walletAuditState{}, err
+	}
+	if err := checkStatus(wallet); err != nil {
+		return walletAuditState{}, err
+	}
+	if err := checkVersion(wallet, expected); err != nil {
+		return walletAuditState{}, err
+	}
+	before := walletAuditStateOf(wallet)
+	wallet.Balance += amount
+	return before, s.writeBalance(ctx, txRepo, wallet, expected)
+}
 
-		if err == nil || !isDeadlock(err) || isSerializationFailure(err) {
-			break
+// loadWallet reads the wallet a balance change is based on. In pessimistic
+// mode, and for sharded wallets in either mode, the row is locked.
+func (s *WalletService) loadWallet(ctx context.Context, txRepo repository.WalletRepository, id uuid.UUID) (*model.Wallet, error) {
+	if s.mode == Optimistic {
+		wallet, err := txRepo.GetWalletById(ctx, id)
+		if err != nil {
+			return nil, walletLookupError(err)
+		}
+		if !wallet.Sharded() {
+			return wallet, nil
 		}
-		time.Sleep(time.Millisecond * time.Duration(50*(i+1)))
 	}
 
-	return op, err
+	wallet, err := txRepo.GetWalletByIdForUpdate(ctx, id)
+	if err != nil {
+		return nil, walletLookupError(err)
+	}
+	return wallet, nil
+}
+
+// writeBalance stores wallet.Balance. In optimistic mode the write only
+// succeeds if nobody changed the wallet since loadWallet; a conflict is
+// retried unless the caller asked for a specific version.
+func (s *WalletService) writeBalance(ctx context.Context, txRepo repository.WalletRepository, wallet *model.Wallet, expected *int64) error {
+	if s.mode != Optimistic {
+		return txRepo.UpdateWalletTx(ctx, wallet.ID, wallet.Balance)
+	}
+
+	err := txRepo.UpdateWalletVersionedTx(ctx, wallet.ID, wallet.Version, wallet.Balance)
+	if errors.Is(err, repository.ErrVersionConflict) && expected != nil {
+		return svcErrors.ErrPreconditionFailed
+	}
+	return err
+}
+
+// checkStatus rejects customer operations on frozen and closed wallets.
+func checkStatus(wallet *model.Wallet) error {
+	switch wallet.Status {
+	case model.WalletFrozen:
+		return svcErrors.ErrWalletFrozen
+	case model.WalletClosed:
+		return svcErrors.ErrWalletClosed
+	}
+	return nil
+}
+
+func checkVersion(wallet *model.Wallet, expected *int64) error {
+	if expected != nil && *expected != wallet.Revision() {
+		return svcErrors.ErrPreconditionFailed
+	}
+	return nil
+}
+
+func (s *WalletService) saveOperation(ctx context.Context, txRepo repository.WalletRepository, opID, walletID uuid.UUID, req dto.WalletOperationRequest) (*model.Operation, error) {
+	op := &model.Operation{
+		ID:          opID,
+		WalletID:    walletID,
+		Type:        req.OperationType,
+		Amount:      req.Amount,
+		Description: req.Description,
+		Metadata:    req.Metadata,
+		ExternalRef: req.ExternalRef,
+	}
+
+	err := txRepo.SaveOperationTx(ctx, op)
+	if errors.Is(err, repository.ErrDuplicateExternalRef) {
+		return nil, svcErrors.ErrDuplicateExternalRef
+	}
+	if err != nil {
+		return nil, err
+	}
+	return op, nil
+}
+
+// checkMetadata bounds the size of client metadata, which is stored with
+// every operation.
+func checkMetadata(metadata map[string]any) error {
+	if metadata == nil {
+		return nil
+	}
+	buf, err := json.Marshal(metadata)
+	if err != nil || len(buf) > model.MaxMetadataSize {
+		return svcErrors.ErrInvalidMetadata
+	}
+	return nil
+}
+
+// classifyRetryable extends db.ClassifyRetryable with optimistic version
+// conflicts, which succeed on a fresh attempt.
+func classifyRetryable(err error) (bool, string) {
+	if errors.Is(err, repository.ErrVersionConflict) {
+		return true, "version_conflict"
+	}
+	return db.ClassifyRetryable(err)
 }
 
-func isDeadlock(err error) bool {
-	var pgErr *pgconn.PgError
-	if errors.As(err, &pgErr) {
-		return pgErr.Code == "40P01"
+// conflictError reports a version conflict that outlasted the retry policy
+// as a service error.
+func conflictError(err error) error {
+	if errors.Is(err, repository.ErrVersionConflict) {
+		return svcErrors.ErrConcurrentModification
 	}
-	return false
+	return err
 }
 
-func isSerializationFailure(err error) bool {
-	var pgErr *pgconn.PgError
-	if errors.As(err, &pgErr) {
-		return pgErr.Code == "40001"
+func walletLookupError(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return svcErrors.ErrWalletNotFound
 	}
-	return false
+	return err
 }
@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"errors"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -13,6 +14,7 @@ import (
 	"github.com/stretchr/testify/mock"
 
 	"wallet-service/internal/dto"
+	"wallet-service/internal/retry"
 	"wallet-service/internal/wallet/model"
 	"wallet-service/internal/wallet/repository"
 	"wallet-service/internal/wallet/repository/mocks"
@@ -80,6 +82,9 @@ func TestUpdateWalletBalance_Deposit_Success(t *testing.T) {
 		}).
 		Return(nil)
 
+	mockRepo.On("SaveAuditEntryTx", ctx, mock.AnythingOfType("*model.AuditEntry")).
+		Return(nil)
+
 	req := dto.WalletOperationRequest{
 		WalletID:      walletID,
 		OperationType: "DEPOSIT",
@@ -118,6 +123,9 @@ func TestUpdateWalletBalance_Withdraw_Success(t *testing.T) {
 	mockRepo.On("SaveOperationTx", ctx, mock.Anything).
 		Return(nil)
 
+	mockRepo.On("SaveAuditEntryTx", ctx, mock.Anything).
+		Return(nil)
+
 	req := dto.WalletOperationRequest{
 		WalletID:      walletID,
 		OperationType: "WITHDRAW",
@@ -229,6 +237,7 @@ func TestUpdateWalletBalance_RetryOnDeadlock(t *testing.T) {
 	mockRepo.On("GetWalletByIdForUpdate", ctx, walletID).Return(wallet, nil)
 	mockRepo.On("UpdateWalletTx", ctx, walletID, float64(150)).Return(nil)
 	mockRepo.On("SaveOperationTx", ctx, mock.AnythingOfType("*model.Operation")).Return(nil)
+	mockRepo.On("SaveAuditEntryTx", ctx, mock.AnythingOfType("*model.AuditEntry")).Return(nil)
 
 	req := dto.WalletOperationRequest{
 		WalletID:      walletID,
@@ -277,6 +286,7 @@ func TestUpdateWalletBalance_ConcurrentAccess(t *testing.T) {
 	mockRepo.On("GetWalletByIdForUpdate", ctx, walletID).Return(wallet, nil)
 	mockRepo.On("UpdateWalletTx", ctx, walletID, mock.Anything).Return(nil)
 	mockRepo.On("SaveOperationTx", ctx, mock.AnythingOfType("*model.Operation")).Return(nil)
+	mockRepo.On("SaveAuditEntryTx", ctx, mock.AnythingOfType("*model.AuditEntry")).Return(nil)
 
 	wg := sync.WaitGroup{}
 	wg.Add(concurrentRequests)
@@ -303,3 +313,113 @@ func TestUpdateWalletBalance_ConcurrentAccess(t *testing.T) {
 	assert.Equal(t, expectedBalance, wallet.Balance)
 	mockRepo.AssertExpectations(t)
 }
+
+func TestUpdateWalletBalance_ConcurrentAccess_MemoryRepository(t *testing.T) {
+	ctx := context.Background()
+	walletID := uuid.New()
+	initialBalance := 1000.0
+	depositAmount := 10.0
+	concurrentRequests := 500
+
+	repo := repository.NewMemoryWalletRepository(model.Wallet{ID: walletID, Balance: initialBalance})
+	svc := NewWalletService(repo)
+
+	var wg sync.WaitGroup
+	wg.Add(concurrentRequests)
+	for i := 0; i < concurrentRequests; i++ {
+		go func() {
+			defer wg.Done()
+			_, err := svc.UpdateWalletBalance(ctx, dto.WalletOperationRequest{
+				WalletID:      walletID,
+				OperationType: "DEPOSIT",
+				Amount:        depositAmount,
+			})
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	balance, err := svc.GetWallet(ctx, walletID)
+	assert.NoError(t, err)
+	assert.Equal(t, initialBalance+float64(concurrentRequests)*depositAmount, balance)
+	assert.Len(t, repo.Operations(walletID), concurrentRequests)
+}
+
+func TestUpdateWalletBalance_RetryOnSerializationFailure(t *testing.T) {
+	ctx := context.Background()
+	walletID := uuid.New()
+
+	mockRepo := new(mocks.WalletRepositoryMock)
+	svc := NewWalletService(mockRepo, WithRetryPolicy(retry.Policy{MaxAttempts: 5, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond, Multiplier: 1}))
+
+	mockRepo.On("WithTx", ctx, mock.Anything).Return(&pgconn.PgError{Code: "40001"}).Twice()
+	mockRepo.On("WithTx", ctx, mock.Anything).Run(func(args mock.Arguments) {
+		fn := args.Get(1).(func(repository.WalletRepository) error)
+		_ = fn(mockRepo)
+	}).Return(nil).Once()
+	mockRepo.On("GetWalletByIdForUpdate", ctx, walletID).Return(&model.Wallet{ID: walletID, Balance: 100}, nil)
+	mockRepo.On("UpdateWalletTx", ctx, walletID, float64(150)).Return(nil)
+	mockRepo.On("SaveOperationTx", ctx, mock.AnythingOfType("*model.Operation")).Return(nil)
+	mockRepo.On("SaveAuditEntryTx", ctx, mock.AnythingOfType("*model.AuditEntry")).Return(nil)
+
+	op, err := svc.UpdateWalletBalance(ctx, dto.WalletOperationRequest{
+		WalletID:      walletID,
+		OperationType: "DEPOSIT",
+		Amount:        50,
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, walletID, op.WalletID)
+	mockRepo.AssertNumberOfCalls(t, "WithTx", 3)
+}
+
+func TestUpdateWalletBalance_NoRetryOnBusinessError(t *testing.T) {
+	ctx := context.Background()
+	walletID := uuid.New()
+
+	mockRepo := new(mocks.WalletRepositoryMock)
+	svc := NewWalletService(mockRepo)
+
+	mockRepo.On("WithTx", ctx, mock.Anything).Return(svcErrors.ErrInsufficientFunds)
+
+	_, err := svc.UpdateWalletBalance(ctx, dto.WalletOperationRequest{
+		WalletID:      walletID,
+		OperationType: "WITHDRAW",
+		Amount:        50,
+	})
+
+	assert.ErrorIs(t, err, svcErrors.ErrInsufficientFunds)
+	mockRepo.AssertNumberOfCalls(t, "WithTx", 1)
+}
+
+func TestUpdateWalletBalance_OperationDetails(t *testing.T) {
+	ctx := context.Background()
+	walletID := uuid.New()
+	repo := repository.NewMemoryWalletRepository(model.Wallet{ID: walletID, Balance: 100})
+	svc := NewWalletService(repo)
+
+	req := dto.WalletOperationRequest{
+		WalletID:      walletID,
+		OperationType: "DEPOSIT",
+		Amount:        10,
+		Description:   "Top-up",
+		Metadata:      map[string]any{"orderId": "A-1"},
+		ExternalRef:   "psp-42",
+	}
+	resp, err := svc.UpdateWalletBalance(ctx, req)
+	assert.NoError(t, err)
+	assert.Equal(t, "psp-42", resp.ExternalRef)
+	assert.Equal(t, "A-1", resp.Metadata["orderId"])
+
+	_, err = svc.UpdateWalletBalance(ctx, req)
+	assert.ErrorIs(t, err, svcErrors.ErrDuplicateExternalRef)
+
+	req.ExternalRef = ""
+	req.Metadata = map[string]any{"blob": strings.Repeat("x", model.MaxMetadataSize)}
+	_, err = svc.UpdateWalletBalance(ctx, req)
+	assert.ErrorIs(t, err, svcErrors.ErrInvalidMetadata)
+
+	balance, err := svc.GetWallet(ctx, walletID)
+	assert.NoError(t, err)
+	assert.Equal(t, 110.0, balance)
+}
@@ -1,4 +1,5 @@
 INSERT INTO wallets (id, balance)
 VALUES
     ('11111111-1111-1111-1111-111111111111', 0),
-    ('22222222-2222-2222-2222-222222222222', 10000);
\ No newline at end of file
+    ('22222222-2222-2222-2222-222222222222', 10000)
+ON CONFLICT (id) DO NOTHING;
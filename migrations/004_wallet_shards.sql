@@ -0,0 +1,8 @@
+ALTER TABLE wallets ADD COLUMN IF NOT EXISTS shard_count INT NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS wallet_shards (
+    wallet_id UUID NOT NULL REFERENCES wallets(id) ON DELETE CASCADE,
+    shard_no INT NOT NULL,
+    balance NUMERIC(20,2) NOT NULL DEFAULT 0,
+    PRIMARY KEY (wallet_id, shard_no)
+);
@@ -0,0 +1,30 @@
+CREATE TABLE IF NOT EXISTS schedules (
+    id UUID PRIMARY KEY,
+    wallet_id UUID NOT NULL REFERENCES wallets(id) ON DELETE CASCADE,
+    operation_type VARCHAR(10) NOT NULL,
+    amount NUMERIC(20,2) NOT NULL CHECK (amount > 0),
+    cron VARCHAR(100) NOT NULL DEFAULT '',
+    interval_seconds BIGINT NOT NULL DEFAULT 0,
+    status VARCHAR(10) NOT NULL,
+    next_run_at TIMESTAMPTZ NOT NULL,
+    due_at TIMESTAMPTZ NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS schedules_wallet_id_idx ON schedules (wallet_id);
+CREATE INDEX IF NOT EXISTS schedules_due_idx ON schedules (due_at) WHERE status = 'ACTIVE';
+
+CREATE TABLE IF NOT EXISTS schedule_runs (
+    id UUID PRIMARY KEY,
+    schedule_id UUID NOT NULL REFERENCES schedules(id) ON DELETE CASCADE,
+    scheduled_for TIMESTAMPTZ NOT NULL,
+    attempt INT NOT NULL,
+    status VARCHAR(10) NOT NULL,
+    operation_id UUID,
+    error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS schedule_runs_schedule_id_idx ON schedule_runs (schedule_id, created_at);
@@ -0,0 +1,10 @@
+ALTER TABLE wallets ADD COLUMN IF NOT EXISTS status VARCHAR(10) NOT NULL DEFAULT 'ACTIVE';
+
+CREATE INDEX IF NOT EXISTS operations_wallet_id_created_at_idx ON operations (wallet_id, created_at);
+
+CREATE TABLE IF NOT EXISTS adjustments (
+    operation_id UUID PRIMARY KEY REFERENCES operations(id) ON DELETE CASCADE,
+    reason TEXT NOT NULL,
+    actor VARCHAR(100) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
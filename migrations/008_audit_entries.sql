@@ -0,0 +1,29 @@
+CREATE TABLE IF NOT EXISTS audit_entries (
+    id BIGSERIAL PRIMARY KEY,
+    actor VARCHAR(200) NOT NULL,
+    action VARCHAR(50) NOT NULL,
+    wallet_id UUID NOT NULL,
+    request_id VARCHAR(100) NOT NULL DEFAULT '',
+    before JSONB,
+    after JSONB,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS audit_entries_wallet_id_created_at_idx ON audit_entries (wallet_id, created_at);
+CREATE INDEX IF NOT EXISTS audit_entries_actor_created_at_idx ON audit_entries (actor, created_at);
+CREATE INDEX IF NOT EXISTS audit_entries_created_at_idx ON audit_entries (created_at);
+
+-- The audit log is append-only.
+CREATE OR REPLACE FUNCTION audit_entries_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS audit_entries_no_update ON audit_entries;
+CREATE TRIGGER audit_entries_no_update BEFORE UPDATE OR DELETE ON audit_entries
+    FOR EACH ROW EXECUTE FUNCTION audit_entries_append_only();
+
+DROP TRIGGER IF EXISTS audit_entries_no_truncate ON audit_entries;
+CREATE TRIGGER audit_entries_no_truncate BEFORE TRUNCATE ON audit_entries
+    FOR EACH STATEMENT EXECUTE FUNCTION audit_entries_append_only();
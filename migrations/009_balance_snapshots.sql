@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS balance_snapshots (
+    wallet_id UUID NOT NULL REFERENCES wallets(id) ON DELETE CASCADE,
+    taken_at TIMESTAMPTZ NOT NULL,
+    balance DECIMAL(20,2) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (wallet_id, taken_at)
+);
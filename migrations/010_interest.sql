@@ -0,0 +1,29 @@
+ALTER TABLE wallets ADD COLUMN IF NOT EXISTS product VARCHAR(50) NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS interest_rules (
+    id UUID PRIMARY KEY,
+    wallet_id UUID REFERENCES wallets(id) ON DELETE CASCADE,
+    product VARCHAR(50) NOT NULL DEFAULT '',
+    annual_rate DECIMAL(9,6) NOT NULL CHECK (annual_rate >= 0),
+    day_count VARCHAR(10) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK ((wallet_id IS NULL) <> (product = ''))
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS interest_rules_wallet_id_key ON interest_rules (wallet_id) WHERE wallet_id IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS interest_rules_product_key ON interest_rules (product) WHERE product <> '';
+
+CREATE TABLE IF NOT EXISTS interest_accruals (
+    wallet_id UUID NOT NULL REFERENCES wallets(id) ON DELETE CASCADE,
+    date DATE NOT NULL,
+    balance DECIMAL(20,2) NOT NULL,
+    annual_rate DECIMAL(9,6) NOT NULL,
+    day_count VARCHAR(10) NOT NULL,
+    amount DECIMAL(20,10) NOT NULL,
+    payout_id UUID REFERENCES operations(id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (wallet_id, date)
+);
+
+CREATE INDEX IF NOT EXISTS interest_accruals_unpaid_idx ON interest_accruals (wallet_id, date) WHERE payout_id IS NULL AND amount > 0;
@@ -0,0 +1,17 @@
+ALTER TABLE operations ADD COLUMN IF NOT EXISTS parent_id UUID REFERENCES operations(id);
+
+CREATE INDEX IF NOT EXISTS operations_parent_id_idx ON operations (parent_id) WHERE parent_id IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS fee_schedules (
+    id UUID PRIMARY KEY,
+    operation_type VARCHAR(10) NOT NULL,
+    product VARCHAR(50) NOT NULL DEFAULT '',
+    flat DECIMAL(20,2) NOT NULL DEFAULT 0 CHECK (flat >= 0),
+    percent DECIMAL(9,6) NOT NULL DEFAULT 0 CHECK (percent >= 0),
+    tiers JSONB NOT NULL DEFAULT '[]',
+    min_fee DECIMAL(20,2) CHECK (min_fee >= 0),
+    max_fee DECIMAL(20,2) CHECK (max_fee >= min_fee),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (operation_type, product)
+);
@@ -0,0 +1,21 @@
+ALTER TABLE wallets ADD COLUMN IF NOT EXISTS currency CHAR(3) NOT NULL DEFAULT 'USD';
+
+CREATE TABLE IF NOT EXISTS exchange_rates (
+    base CHAR(3) NOT NULL,
+    quote CHAR(3) NOT NULL,
+    valid_from TIMESTAMPTZ NOT NULL,
+    valid_to TIMESTAMPTZ CHECK (valid_to > valid_from),
+    rate DECIMAL(20,10) NOT NULL CHECK (rate > 0),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (base, quote, valid_from)
+);
+
+CREATE TABLE IF NOT EXISTS fx_legs (
+    operation_id UUID PRIMARY KEY REFERENCES operations(id) ON DELETE CASCADE,
+    from_currency CHAR(3) NOT NULL,
+    to_currency CHAR(3) NOT NULL,
+    mid_rate DECIMAL(20,10) NOT NULL,
+    spread DECIMAL(9,6) NOT NULL,
+    rate DECIMAL(20,10) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,7 @@
+ALTER TABLE operations ADD COLUMN IF NOT EXISTS description VARCHAR(500) NOT NULL DEFAULT '';
+ALTER TABLE operations ADD COLUMN IF NOT EXISTS metadata JSONB;
+ALTER TABLE operations ADD COLUMN IF NOT EXISTS external_ref VARCHAR(100) NOT NULL DEFAULT '';
+
+CREATE UNIQUE INDEX IF NOT EXISTS operations_wallet_external_ref_key
+    ON operations (wallet_id, external_ref) WHERE external_ref <> '';
+CREATE INDEX IF NOT EXISTS operations_metadata_idx ON operations USING GIN (metadata jsonb_path_ops);
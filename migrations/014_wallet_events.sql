@@ -0,0 +1,29 @@
+CREATE TABLE IF NOT EXISTS wallet_events (
+    id BIGSERIAL PRIMARY KEY,
+    wallet_id UUID NOT NULL REFERENCES wallets(id) ON DELETE CASCADE,
+    operation_id UUID NOT NULL REFERENCES operations(id) ON DELETE CASCADE,
+    balance DECIMAL(20,2) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS wallet_events_wallet_id_id_idx ON wallet_events (wallet_id, id);
+
+-- Every inserted operation gets an event carrying the wallet's balance as
+-- seen by the inserting transaction, and wakes the listeners of every
+-- service instance once that transaction commits. The payload is the
+-- wallet ID; listeners read the events themselves.
+CREATE OR REPLACE FUNCTION operations_wallet_event() RETURNS trigger AS $$
+BEGIN
+    INSERT INTO wallet_events (wallet_id, operation_id, balance)
+    SELECT w.id, NEW.id,
+           w.balance + COALESCE((SELECT SUM(s.balance) FROM wallet_shards s WHERE s.wallet_id = w.id), 0)
+    FROM wallets w
+    WHERE w.id = NEW.wallet_id;
+    PERFORM pg_notify('wallet_events', NEW.wallet_id::text);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS operations_wallet_event ON operations;
+CREATE TRIGGER operations_wallet_event AFTER INSERT ON operations
+    FOR EACH ROW EXECUTE FUNCTION operations_wallet_event();
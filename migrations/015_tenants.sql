@@ -0,0 +1,27 @@
+ALTER TABLE wallets ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(50) NOT NULL DEFAULT 'default';
+ALTER TABLE operations ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(50) NOT NULL DEFAULT 'default';
+ALTER TABLE schedules ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(50) NOT NULL DEFAULT 'default';
+
+CREATE INDEX IF NOT EXISTS wallets_tenant_id_id_idx ON wallets (tenant_id, id);
+CREATE INDEX IF NOT EXISTS operations_tenant_id_wallet_id_idx ON operations (tenant_id, wallet_id);
+
+-- Existing rows take the tenant of their wallet.
+UPDATE operations o SET tenant_id = w.tenant_id
+FROM wallets w
+WHERE w.id = o.wallet_id AND o.tenant_id <> w.tenant_id;
+UPDATE schedules s SET tenant_id = w.tenant_id
+FROM wallets w
+WHERE w.id = s.wallet_id AND s.tenant_id <> w.tenant_id;
+
+-- An operation always belongs to the tenant of its wallet, whatever the
+-- inserting statement says.
+CREATE OR REPLACE FUNCTION operations_wallet_tenant() RETURNS trigger AS $$
+BEGIN
+    NEW.tenant_id := COALESCE((SELECT w.tenant_id FROM wallets w WHERE w.id = NEW.wallet_id), NEW.tenant_id);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS operations_wallet_tenant ON operations;
+CREATE TRIGGER operations_wallet_tenant BEFORE INSERT ON operations
+    FOR EACH ROW EXECUTE FUNCTION operations_wallet_tenant();
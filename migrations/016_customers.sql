@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS customers (
+    id UUID PRIMARY KEY,
+    tenant_id VARCHAR(50) NOT NULL DEFAULT 'default',
+    external_id VARCHAR(100) NOT NULL,
+    name VARCHAR(200) NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (tenant_id, external_id)
+);
+
+ALTER TABLE wallets ADD COLUMN IF NOT EXISTS customer_id UUID REFERENCES customers(id);
+ALTER TABLE wallets ADD COLUMN IF NOT EXISTS label VARCHAR(50) NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS wallets_customer_id_idx ON wallets (customer_id) WHERE customer_id IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS wallets_customer_label_key ON wallets (customer_id, label) WHERE label <> '';
@@ -0,0 +1,6 @@
+ALTER TABLE wallets ADD COLUMN IF NOT EXISTS credit_limit DECIMAL(20,2) NOT NULL DEFAULT 0 CHECK (credit_limit >= 0);
+
+ALTER TABLE interest_rules ADD COLUMN IF NOT EXISTS overdraft_rate DECIMAL(9,6) NOT NULL DEFAULT 0 CHECK (overdraft_rate >= 0);
+
+-- Overdraft interest accrues as negative amounts, settled like payouts.
+CREATE INDEX IF NOT EXISTS interest_accruals_unpaid_charges_idx ON interest_accruals (wallet_id, date) WHERE payout_id IS NULL AND amount < 0;
@@ -0,0 +1,86 @@
+ALTER TABLE wallets ALTER COLUMN balance TYPE DECIMAL(20,2);
+ALTER TABLE wallets ALTER COLUMN credit_limit TYPE DECIMAL(20,2);
+ALTER TABLE operations ALTER COLUMN amount TYPE DECIMAL(20,2);
+
+-- Operation amounts are unsigned; the type says which way they move money.
+ALTER TABLE operations DROP CONSTRAINT IF EXISTS operations_amount_check;
+ALTER TABLE operations ADD CONSTRAINT operations_amount_check CHECK (amount > 0);
+
+ALTER TABLE operations DROP CONSTRAINT IF EXISTS operations_type_check;
+ALTER TABLE operations ADD CONSTRAINT operations_type_check CHECK (type IN (
+    'DEPOSIT', 'WITHDRAW', 'ADJ_CREDIT', 'ADJ_DEBIT', 'INTEREST', 'OVERDRAFT',
+    'FEE', 'FEE_INCOME', 'FX_OUT', 'FX_IN'
+));
+
+ALTER TABLE wallets DROP CONSTRAINT IF EXISTS wallets_status_check;
+ALTER TABLE wallets ADD CONSTRAINT wallets_status_check CHECK (status IN ('ACTIVE', 'FROZEN', 'CLOSED'));
+
+ALTER TABLE schedules DROP CONSTRAINT IF EXISTS schedules_operation_type_check;
+ALTER TABLE schedules ADD CONSTRAINT schedules_operation_type_check CHECK (operation_type IN ('DEPOSIT', 'WITHDRAW'));
+
+ALTER TABLE schedule_runs DROP CONSTRAINT IF EXISTS schedule_runs_operation_id_fkey;
+ALTER TABLE schedule_runs ADD CONSTRAINT schedule_runs_operation_id_fkey
+    FOREIGN KEY (operation_id) REFERENCES operations(id) ON DELETE SET NULL;
+
+-- A wallet's balance, the wallet row plus its shards, may only be negative
+-- while it has a credit line. Withdrawals move money between the row and
+-- the shards within a transaction, so the check waits for the commit.
+CREATE OR REPLACE FUNCTION wallets_overdraft_check() RETURNS trigger AS $$
+DECLARE
+    wid UUID;
+    total DECIMAL;
+    credit DECIMAL;
+BEGIN
+    IF TG_TABLE_NAME = 'wallets' THEN
+        wid := NEW.id;
+    ELSE
+        wid := NEW.wallet_id;
+    END IF;
+    SELECT w.balance + COALESCE((SELECT SUM(s.balance) FROM wallet_shards s WHERE s.wallet_id = w.id), 0), w.credit_limit
+    INTO total, credit
+    FROM wallets w
+    WHERE w.id = wid;
+    IF total < 0 AND credit = 0 THEN
+        RAISE EXCEPTION 'wallet % has a negative balance without a credit line', wid
+            USING ERRCODE = 'check_violation', CONSTRAINT = 'wallets_overdraft_check';
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS wallets_overdraft_check ON wallets;
+CREATE CONSTRAINT TRIGGER wallets_overdraft_check AFTER INSERT OR UPDATE OF balance, credit_limit ON wallets
+    DEFERRABLE INITIALLY DEFERRED
+    FOR EACH ROW EXECUTE FUNCTION wallets_overdraft_check();
+
+DROP TRIGGER IF EXISTS wallet_shards_overdraft_check ON wallet_shards;
+CREATE CONSTRAINT TRIGGER wallet_shards_overdraft_check AFTER INSERT OR UPDATE OF balance ON wallet_shards
+    DEFERRABLE INITIALLY DEFERRED
+    FOR EACH ROW EXECUTE FUNCTION wallets_overdraft_check();
+
+-- Debits may take a wallet's balance down to minus its credit limit and no
+-- further. Overdraft interest is charged on what is owed and may go past
+-- the limit, as may a limit lowered below the overdraft; such a wallet
+-- then only accepts credits until it is back within the limit.
+CREATE OR REPLACE FUNCTION operations_credit_limit_check() RETURNS trigger AS $$
+DECLARE
+    total DECIMAL;
+    credit DECIMAL;
+BEGIN
+    SELECT w.balance + COALESCE((SELECT SUM(s.balance) FROM wallet_shards s WHERE s.wallet_id = w.id), 0), w.credit_limit
+    INTO total, credit
+    FROM wallets w
+    WHERE w.id = NEW.wallet_id;
+    IF total < -credit THEN
+        RAISE EXCEPTION 'operation % takes wallet % past its credit limit', NEW.id, NEW.wallet_id
+            USING ERRCODE = 'check_violation', CONSTRAINT = 'operations_credit_limit_check';
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS operations_credit_limit_check ON operations;
+CREATE CONSTRAINT TRIGGER operations_credit_limit_check AFTER INSERT ON operations
+    DEFERRABLE INITIALLY DEFERRED
+    FOR EACH ROW WHEN (NEW.type IN ('WITHDRAW', 'ADJ_DEBIT', 'FEE', 'FX_OUT'))
+    EXECUTE FUNCTION operations_credit_limit_check();
@@ -0,0 +1,168 @@
+-- Operations are partitioned by month of created_at, in UTC, so that old
+-- months can be archived and dropped whole. A partitioned table cannot
+-- enforce unique IDs or external references across partitions, so
+-- operation_keys does. It keeps the keys of archived operations as well,
+-- and is what other tables reference.
+CREATE TABLE IF NOT EXISTS operation_keys (
+    id UUID PRIMARY KEY,
+    wallet_id UUID NOT NULL REFERENCES wallets(id) ON DELETE CASCADE,
+    external_ref VARCHAR(100) NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS operation_keys_wallet_external_ref_key
+    ON operation_keys (wallet_id, external_ref) WHERE external_ref <> '';
+
+-- operations_partition creates the partition for the month of d unless
+-- it exists, moving the month's rows out of the default partition, and
+-- returns its name. Partitions are named operations_YYYY_MM.
+CREATE OR REPLACE FUNCTION operations_partition(d DATE) RETURNS TEXT AS $$
+DECLARE
+    lo TIMESTAMPTZ := date_trunc('month', d::timestamp) AT TIME ZONE 'UTC';
+    hi TIMESTAMPTZ := (date_trunc('month', d::timestamp) + INTERVAL '1 month') AT TIME ZONE 'UTC';
+    part TEXT := 'operations_' || to_char(d, 'YYYY_MM');
+BEGIN
+    PERFORM pg_advisory_xact_lock(hashtext('operations_partition'));
+    IF to_regclass(part) IS NOT NULL THEN
+        RETURN part;
+    END IF;
+    EXECUTE format('CREATE TABLE %I (LIKE operations INCLUDING DEFAULTS INCLUDING CONSTRAINTS)', part);
+    IF to_regclass('operations_default') IS NOT NULL THEN
+        EXECUTE format('WITH moved AS (DELETE FROM operations_default WHERE created_at >= %L AND created_at < %L RETURNING *) '
+            'INSERT INTO %I SELECT * FROM moved', lo, hi, part);
+    END IF;
+    EXECUTE format('ALTER TABLE operations ATTACH PARTITION %I FOR VALUES FROM (%L) TO (%L)', part, lo, hi);
+    RETURN part;
+END;
+$$ LANGUAGE plpgsql;
+
+DO $$
+DECLARE
+    month DATE;
+BEGIN
+    IF EXISTS (SELECT 1 FROM pg_partitioned_table WHERE partrelid = 'operations'::regclass) THEN
+        RETURN;
+    END IF;
+
+    ALTER TABLE operations RENAME TO operations_unpartitioned;
+    CREATE TABLE operations (
+        id UUID NOT NULL,
+        wallet_id UUID NOT NULL REFERENCES wallets(id) ON DELETE CASCADE,
+        type VARCHAR(10) NOT NULL,
+        amount DECIMAL(20,2) NOT NULL,
+        created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+        tenant_id VARCHAR(50) NOT NULL DEFAULT 'default',
+        parent_id UUID,
+        description VARCHAR(500) NOT NULL DEFAULT '',
+        metadata JSONB,
+        external_ref VARCHAR(100) NOT NULL DEFAULT '',
+        CONSTRAINT operations_amount_check CHECK (amount > 0),
+        CONSTRAINT operations_type_check CHECK (type IN (
+            'DEPOSIT', 'WITHDRAW', 'ADJ_CREDIT', 'ADJ_DEBIT', 'INTEREST', 'OVERDRAFT',
+            'FEE', 'FEE_INCOME', 'FX_OUT', 'FX_IN'
+        ))
+    ) PARTITION BY RANGE (created_at);
+    -- Rows outside the partitions created so far land here until their
+    -- month's partition is created.
+    CREATE TABLE operations_default PARTITION OF operations DEFAULT;
+
+    FOR month IN
+        SELECT generate_series(
+            date_trunc('month', COALESCE(first_at, NOW()) AT TIME ZONE 'UTC'),
+            date_trunc('month', NOW() AT TIME ZONE 'UTC'),
+            INTERVAL '1 month')::date
+        FROM (SELECT MIN(created_at) AS first_at FROM operations_unpartitioned) o
+    LOOP
+        PERFORM operations_partition(month);
+    END LOOP;
+
+    INSERT INTO operations (id, wallet_id, type, amount, created_at, tenant_id, parent_id, description, metadata, external_ref)
+    SELECT id, wallet_id, type, amount, created_at, tenant_id, parent_id, description, metadata, external_ref
+    FROM operations_unpartitioned;
+    INSERT INTO operation_keys (id, wallet_id, external_ref, created_at)
+    SELECT id, wallet_id, external_ref, created_at
+    FROM operations_unpartitioned
+    ON CONFLICT (id) DO NOTHING;
+
+    -- Takes the foreign keys referencing it along; they are added back
+    -- below, on operation_keys.
+    DROP TABLE operations_unpartitioned CASCADE;
+    ALTER TABLE operations ADD CONSTRAINT operations_pkey PRIMARY KEY (id, created_at);
+END;
+$$;
+
+SELECT operations_partition(((NOW() AT TIME ZONE 'UTC') + make_interval(months => n))::date)
+FROM generate_series(0, 2) AS n;
+
+-- History queries and ledger totals read a wallet's operations over a time
+-- range; the covering index spares them the table.
+CREATE INDEX IF NOT EXISTS operations_wallet_id_created_at_idx
+    ON operations (wallet_id, created_at) INCLUDE (type, amount);
+CREATE INDEX IF NOT EXISTS operations_parent_id_idx ON operations (parent_id) WHERE parent_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS operations_tenant_id_wallet_id_idx ON operations (tenant_id, wallet_id);
+CREATE INDEX IF NOT EXISTS operations_metadata_idx ON operations USING GIN (metadata jsonb_path_ops);
+
+ALTER TABLE operations DROP CONSTRAINT IF EXISTS operations_parent_id_fkey;
+ALTER TABLE operations ADD CONSTRAINT operations_parent_id_fkey
+    FOREIGN KEY (parent_id) REFERENCES operation_keys(id);
+ALTER TABLE adjustments DROP CONSTRAINT IF EXISTS adjustments_operation_id_fkey;
+ALTER TABLE adjustments ADD CONSTRAINT adjustments_operation_id_fkey
+    FOREIGN KEY (operation_id) REFERENCES operation_keys(id) ON DELETE CASCADE;
+ALTER TABLE fx_legs DROP CONSTRAINT IF EXISTS fx_legs_operation_id_fkey;
+ALTER TABLE fx_legs ADD CONSTRAINT fx_legs_operation_id_fkey
+    FOREIGN KEY (operation_id) REFERENCES operation_keys(id) ON DELETE CASCADE;
+ALTER TABLE interest_accruals DROP CONSTRAINT IF EXISTS interest_accruals_payout_id_fkey;
+ALTER TABLE interest_accruals ADD CONSTRAINT interest_accruals_payout_id_fkey
+    FOREIGN KEY (payout_id) REFERENCES operation_keys(id);
+ALTER TABLE wallet_events DROP CONSTRAINT IF EXISTS wallet_events_operation_id_fkey;
+ALTER TABLE wallet_events ADD CONSTRAINT wallet_events_operation_id_fkey
+    FOREIGN KEY (operation_id) REFERENCES operation_keys(id) ON DELETE CASCADE;
+ALTER TABLE schedule_runs DROP CONSTRAINT IF EXISTS schedule_runs_operation_id_fkey;
+ALTER TABLE schedule_runs ADD CONSTRAINT schedule_runs_operation_id_fkey
+    FOREIGN KEY (operation_id) REFERENCES operation_keys(id) ON DELETE SET NULL;
+
+-- Every operation records its keys first; a duplicate ID or external
+-- reference fails the insert on operation_keys.
+CREATE OR REPLACE FUNCTION operations_key() RETURNS trigger AS $$
+BEGIN
+    INSERT INTO operation_keys (id, wallet_id, external_ref, created_at)
+    VALUES (NEW.id, NEW.wallet_id, NEW.external_ref, NEW.created_at);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS operations_key ON operations;
+CREATE TRIGGER operations_key BEFORE INSERT ON operations
+    FOR EACH ROW EXECUTE FUNCTION operations_key();
+DROP TRIGGER IF EXISTS operations_wallet_event ON operations;
+CREATE TRIGGER operations_wallet_event AFTER INSERT ON operations
+    FOR EACH ROW EXECUTE FUNCTION operations_wallet_event();
+DROP TRIGGER IF EXISTS operations_wallet_tenant ON operations;
+CREATE TRIGGER operations_wallet_tenant BEFORE INSERT ON operations
+    FOR EACH ROW EXECUTE FUNCTION operations_wallet_tenant();
+DROP TRIGGER IF EXISTS operations_credit_limit_check ON operations;
+CREATE CONSTRAINT TRIGGER operations_credit_limit_check AFTER INSERT ON operations
+    DEFERRABLE INITIALLY DEFERRED
+    FOR EACH ROW WHEN (NEW.type IN ('WITHDRAW', 'ADJ_DEBIT', 'FEE', 'FX_OUT'))
+    EXECUTE FUNCTION operations_credit_limit_check();
+
+-- Archived partitions, exported to file, and the net total of each
+-- wallet's operations in them, which keeps ledger totals whole.
+CREATE TABLE IF NOT EXISTS operation_archives (
+    name VARCHAR(63) PRIMARY KEY,
+    range_start TIMESTAMPTZ NOT NULL,
+    range_end TIMESTAMPTZ NOT NULL,
+    file TEXT NOT NULL,
+    operations BIGINT NOT NULL,
+    archived_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS operation_archive_totals (
+    wallet_id UUID NOT NULL REFERENCES wallets(id) ON DELETE CASCADE,
+    range_start TIMESTAMPTZ NOT NULL,
+    range_end TIMESTAMPTZ NOT NULL,
+    tenant_id VARCHAR(50) NOT NULL DEFAULT 'default',
+    net DECIMAL(20,2) NOT NULL,
+    operations BIGINT NOT NULL,
+    PRIMARY KEY (wallet_id, range_start)
+);
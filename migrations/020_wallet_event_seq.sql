@@ -0,0 +1,41 @@
+-- Events are numbered per wallet, and stream cursors are these numbers.
+-- The global IDs could commit out of order: sharded credits do not lock
+-- the wallet row, so a stream that had moved past an ID would skip an
+-- event committing later with a lower one. An event takes the next number
+-- from its wallet's counter row and keeps the row locked until it
+-- commits, so the numbers commit in order and without gaps.
+CREATE TABLE IF NOT EXISTS wallet_event_seqs (
+    wallet_id UUID PRIMARY KEY REFERENCES wallets(id) ON DELETE CASCADE,
+    seq BIGINT NOT NULL
+);
+
+ALTER TABLE wallet_events ADD COLUMN IF NOT EXISTS seq BIGINT;
+UPDATE wallet_events e SET seq = n.seq
+FROM (SELECT id, ROW_NUMBER() OVER (PARTITION BY wallet_id ORDER BY id) AS seq FROM wallet_events) n
+WHERE e.id = n.id AND e.seq IS NULL;
+ALTER TABLE wallet_events ALTER COLUMN seq SET NOT NULL;
+
+INSERT INTO wallet_event_seqs (wallet_id, seq)
+SELECT wallet_id, MAX(seq) FROM wallet_events GROUP BY wallet_id
+ON CONFLICT (wallet_id) DO NOTHING;
+
+CREATE UNIQUE INDEX IF NOT EXISTS wallet_events_wallet_id_seq_key ON wallet_events (wallet_id, seq);
+DROP INDEX IF EXISTS wallet_events_wallet_id_id_idx;
+
+CREATE OR REPLACE FUNCTION operations_wallet_event() RETURNS trigger AS $$
+DECLARE
+    event_seq BIGINT;
+BEGIN
+    INSERT INTO wallet_event_seqs AS q (wallet_id, seq) VALUES (NEW.wallet_id, 1)
+    ON CONFLICT (wallet_id) DO UPDATE SET seq = q.seq + 1
+    RETURNING q.seq INTO event_seq;
+
+    INSERT INTO wallet_events (wallet_id, seq, operation_id, balance)
+    SELECT w.id, event_seq, NEW.id,
+           w.balance + COALESCE((SELECT SUM(s.balance) FROM wallet_shards s WHERE s.wallet_id = w.id), 0)
+    FROM wallets w
+    WHERE w.id = NEW.wallet_id;
+    PERFORM pg_notify('wallet_events', NEW.wallet_id::text);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,4 @@
+-- Shard credits bump the version of their shard row rather than that of
+-- the wallet row, which they only share-lock. A wallet's revision, the
+-- version its ETag carries, is its row version plus those of its shards.
+ALTER TABLE wallet_shards ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 0;
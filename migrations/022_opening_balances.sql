@@ -0,0 +1,30 @@
+-- Wallets seeded with a balance, by 002_seed_wallets.sql or before their
+-- operations were recorded, have no operation making it up, so the ledger
+-- fell short of their balance. Each gets an OPENING operation for what its
+-- operations do not account for, dated when the wallet was created.
+-- Shortfalls, which no seeding leaves, are left for reconciliation to
+-- report.
+ALTER TABLE operations DROP CONSTRAINT IF EXISTS operations_type_check;
+ALTER TABLE operations ADD CONSTRAINT operations_type_check CHECK (type IN (
+    'DEPOSIT', 'WITHDRAW', 'ADJ_CREDIT', 'ADJ_DEBIT', 'INTEREST', 'OVERDRAFT',
+    'FEE', 'FEE_INCOME', 'FX_OUT', 'FX_IN', 'OPENING'
+));
+
+INSERT INTO operations (id, wallet_id, type, amount, created_at, description)
+SELECT gen_random_uuid(), u.id, 'OPENING', u.amount, u.created_at, 'Opening balance'
+FROM (
+    SELECT w.id, w.created_at,
+        w.balance
+        + COALESCE((SELECT SUM(s.balance) FROM wallet_shards s WHERE s.wallet_id = w.id), 0)
+        - COALESCE((SELECT SUM(CASE WHEN o.type IN ('WITHDRAW', 'ADJ_DEBIT', 'FEE', 'FX_OUT', 'OVERDRAFT') THEN -o.amount ELSE o.amount END)
+            FROM operations o WHERE o.wallet_id = w.id), 0)
+        - COALESCE((SELECT SUM(a.net) FROM operation_archive_totals a WHERE a.wallet_id = w.id), 0) AS amount
+    FROM wallets w
+    WHERE NOT EXISTS (SELECT 1 FROM operations o WHERE o.wallet_id = w.id AND o.type = 'OPENING')
+) u
+WHERE u.amount > 0;
+
+-- Snapshots summed the ledger without the openings, all older than them.
+UPDATE balance_snapshots s SET balance = s.balance + o.amount
+FROM operations o
+WHERE o.wallet_id = s.wallet_id AND o.type = 'OPENING' AND o.created_at < s.taken_at;
@@ -0,0 +1,21 @@
+-- A balance cannot be summed from a time inside an archived month, so a
+-- wallet's snapshot there would leave every later balance unanswerable.
+-- operations_archive_snapshots records a snapshot at the end of the
+-- archived range [lo, hi) for each wallet with operations or a snapshot in
+-- it. It runs once the range's partition is detached and its totals
+-- recorded.
+CREATE OR REPLACE FUNCTION operations_archive_snapshots(lo TIMESTAMPTZ, hi TIMESTAMPTZ) RETURNS VOID AS $$
+    INSERT INTO balance_snapshots (wallet_id, taken_at, balance)
+    SELECT w.wallet_id, hi,
+        COALESCE((SELECT SUM(CASE WHEN o.type IN ('WITHDRAW', 'ADJ_DEBIT', 'FEE', 'FX_OUT', 'OVERDRAFT') THEN -o.amount ELSE o.amount END)
+            FROM operations o WHERE o.wallet_id = w.wallet_id AND o.created_at < hi), 0)
+        + COALESCE((SELECT SUM(a.net) FROM operation_archive_totals a WHERE a.wallet_id = w.wallet_id AND a.range_end <= hi), 0)
+    FROM (
+        SELECT wallet_id FROM operation_archive_totals WHERE range_start = lo
+        UNION
+        SELECT wallet_id FROM balance_snapshots WHERE taken_at > lo AND taken_at < hi
+    ) w
+    ON CONFLICT (wallet_id, taken_at) DO NOTHING;
+$$ LANGUAGE sql;
+
+SELECT operations_archive_snapshots(range_start, range_end) FROM operation_archives ORDER BY range_start;
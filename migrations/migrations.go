@@ -0,0 +1,9 @@
+// Package migrations embeds the SQL migrations of the database schema,
+// applied in file name order by db.Migrate. Each can be run again on a
+// database it was already applied to.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS